@@ -7,21 +7,26 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
 	"github.com/MMN3003/mega/src/config"
+	cron_http_delivery "github.com/MMN3003/mega/src/cron/delivery/http"
 	cron_repo "github.com/MMN3003/mega/src/cron/repository"
 	cron_usecase "github.com/MMN3003/mega/src/cron/usecase"
+	"github.com/MMN3003/mega/src/health"
 	"github.com/MMN3003/mega/src/logger"
+	market_oracle "github.com/MMN3003/mega/src/market/adapter/oracle"
 	market_http_delivery "github.com/MMN3003/mega/src/market/delivery/http"
 	market_repo "github.com/MMN3003/mega/src/market/repository"
 	market "github.com/MMN3003/mega/src/market/usecase"
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/middleware"
 	order_cron_adapter "github.com/MMN3003/mega/src/order/adapter/cron"
 	order_market_adapter "github.com/MMN3003/mega/src/order/adapter/market"
 	order_http_delivery "github.com/MMN3003/mega/src/order/delivery/http"
 	order_repo "github.com/MMN3003/mega/src/order/repository"
 	order_usecase "github.com/MMN3003/mega/src/order/usecase"
+	"github.com/MMN3003/mega/src/tracing"
 
 	_ "github.com/MMN3003/mega/docs" // Swagger docs
 	_ "github.com/lib/pq"
@@ -38,6 +43,22 @@ import (
 func main() {
 	cfg := config.LoadFromEnv()
 	logg := logger.New(cfg.Env)
+	logLevel := cfg.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+		if cfg.Env == "dev" {
+			logLevel = "debug"
+		}
+	}
+	if err := logg.SetLevel(logLevel); err != nil {
+		logg.Fatalf("LOG_LEVEL: %v", err)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.OTelEndpoint, cfg.OTelServiceName)
+	if err != nil {
+		logg.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	// --- Database connection ---
 	logg.Infof("Connecting to database: %s", cfg.DatabaseURL)
@@ -55,44 +76,107 @@ func main() {
 		logg.Fatalf("Failed to get generic DB handle: %v", err)
 	}
 	defer sqlDB.Close()
-	config := ethereum.Config{
-		RPCURL:          cfg.Ethereum.RPCURL,
-		PrivateKey:      cfg.Ethereum.AdminKey,
-		PhoenixContract: cfg.Ethereum.PhoenixContractAddress,
-		ChainID:         big.NewInt(11155111), // Sepolia
-
+	var chainConfigs []ethereum.Config
+	for _, chain := range cfg.Ethereum.Chains {
+		chainConfig := ethereum.Config{
+			Network:         chain.Network,
+			RPCURL:          chain.RPCURL,
+			PrivateKey:      chain.AdminKey,
+			PhoenixContract: chain.PhoenixContractAddress,
+			ChainID:         big.NewInt(chain.ChainID),
+			Confirmations:   cfg.Ethereum.Confirmations,
+			MineTimeout:     cfg.Ethereum.MineTimeout,
+		}
+		if chain.USDTContractAddress != "" {
+			chainConfig.SupportedTokens = map[string]string{"USDT": chain.USDTContractAddress}
+		}
+		chainConfigs = append(chainConfigs, chainConfig)
 	}
 
-	// Create Ethereum client
+	// Create one Ethereum client per chain
 	ctx := context.Background()
-	client, err := ethereum.NewEthereumClient(ctx, config)
+	chains, err := ethereum.NewChains(ctx, chainConfigs,
+		ethereum.WithLegacyGas(cfg.Ethereum.LegacyGas),
+		ethereum.WithGasLimitMultiplier(cfg.Ethereum.GasLimitMultiplier.InexactFloat64()),
+	)
 	if err != nil {
-		logg.Fatalf("Failed to create Ethereum client: %v", err)
+		logg.Fatalf("Failed to create Ethereum clients: %v", err)
 	}
-	defer client.Close()
+	defer chains.Close()
 
-	sqlDB.SetMaxOpenConns(20)
-	sqlDB.SetMaxIdleConns(5)
-	sqlDB.SetConnMaxLifetime(10 * time.Minute)
+	sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
 	c := cron.New(cron.WithSeconds())
 	// --- repos ---
 	marketRepo := market_repo.NewRepo(gormDB, logg)
 	megaMarketRepo := market_repo.NewMegaMarketRepo(gormDB, logg)
 	orderRepo := order_repo.NewOrderRepo(gormDB, logg)
+	quoteRepo := order_repo.NewPostgresQuoteRepo(sqlDB, logg)
 	cronRepo := cron_repo.NewCronRepo(gormDB, logg)
 	// --- services ---
-	marketSvc := market.NewService(marketRepo, megaMarketRepo, logg, cfg)
-	cronSvc := cron_usecase.NewService(cronRepo, logg)
-	orderSvc := order_usecase.NewService(orderRepo, logg, cfg, client)
+	marketSvc, err := market.NewService(marketRepo, megaMarketRepo, logg, cfg)
+	if err != nil {
+		logg.Fatalf("Failed to create market service: %v", err)
+	}
+	if cfg.Oracle.Source == "binance" {
+		marketSvc.SetPriceOracle(market_oracle.NewBinanceOracle(cfg.Oracle.BaseURL), cfg.Oracle.Band)
+	}
+	cronSvc := cron_usecase.NewService(cronRepo, logg, cron_usecase.WithLockTTL(cfg.CronLockTTL))
+	if cfg.QuoteSigningSecret == "" {
+		logg.Errorf("QUOTE_SIGNING_SECRET is empty: quote ids are signed with a random key and stop resolving on restart")
+	}
+	orderSvc, err := order_usecase.NewService(orderRepo, logg, cfg, chains,
+		order_usecase.WithQuoteRepository(quoteRepo),
+		order_usecase.WithMaxConcurrency(cfg.OrderMaxConcurrency))
+	if err != nil {
+		logg.Fatalf("Failed to create order service: %v", err)
+	}
+	metrics.RegisterOrderStatusCounts(func(ctx context.Context) (map[string]int64, error) {
+		counts, err := orderRepo.CountOrdersByStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		byStatus := make(map[string]int64, len(counts))
+		for status, n := range counts {
+			byStatus[string(status)] = n
+		}
+		return byStatus, nil
+	})
 	// --- adapters ---
 	marketAdapter := order_market_adapter.NewMarketPort(marketSvc)
 	cronAdapter := order_cron_adapter.NewCronPort(cronSvc)
 	orderSvc.SetAdapters(context.Background(), marketAdapter)
 	// --- handlers ---
-	market_handler := market_http_delivery.NewHandler(marketSvc, logg)
-	order_handler := order_http_delivery.NewHandler(orderSvc, logg)
+	market_handler := market_http_delivery.NewHandler(marketSvc, logg,
+		market_http_delivery.WithRetryAfter(cfg.ExchangesRetryAfter),
+		market_http_delivery.WithRateLimit(middleware.RateLimit(cfg.RateLimit.Market.Rate, cfg.RateLimit.Market.Burst)),
+	)
+	userJWTSecret := cfg.UserJWTSecret
+	if cfg.UserAuthDisabled {
+		userJWTSecret = ""
+		logg.Errorf("USER_AUTH_DISABLED is set: orders can be submitted, read and listed for any user_id without authentication")
+	}
+	order_handler := order_http_delivery.NewHandler(orderSvc, logg,
+		order_http_delivery.WithUserAuth(middleware.UserAuth(userJWTSecret)),
+		order_http_delivery.WithRateLimit(middleware.RateLimit(cfg.RateLimit.Order.Rate, cfg.RateLimit.Order.Burst)),
+	)
+	cron_handler := cron_http_delivery.NewHandler(cronSvc, logg)
 	// --- cron ---
-	order_usecase.NewCronService(c, orderSvc, cronAdapter)
+	order_usecase.NewCronService(c, orderSvc, cronAdapter, cfg.Cron, logg)
+	// GET /markets only reads the stored markets, so they are refreshed here; once at
+	// startup too, so a fresh deployment has markets to list
+	marketRefresh := order_usecase.Job{
+		ID:       order_usecase.MarketRefreshID,
+		Name:     "market_refresh",
+		Schedule: cfg.Cron.MarketRefresh,
+		Run: func(ctx context.Context) error {
+			_, _, err := marketSvc.FetchAndUpdateMarkets(ctx)
+			return err
+		},
+	}
+	order_usecase.ScheduleJob(c, cronAdapter, marketRefresh, logg)
+	go order_usecase.RunJob(context.Background(), cronAdapter, marketRefresh, logg)
 
 	// --- Router ---
 	r := gin.New()
@@ -100,22 +184,32 @@ func main() {
 	defer c.Stop()
 	// Core middleware
 	r.Use(gin.Recovery())
-	r.Use(func(c *gin.Context) {
-		start := time.Now()
-		c.Next()
-		logg.Infof("%s %s status:%d duration:%s",
-			c.Request.Method,
-			c.Request.URL.Path,
-			c.Writer.Status(),
-			time.Since(start),
-		)
-	})
+	r.Use(middleware.CORS(cfg.CORS.AllowedOrigins, cfg.CORS.AllowedMethods, cfg.CORS.AllowedHeaders, cfg.CORS.MaxAge))
+	r.Use(middleware.RequestID())
+	r.Use(tracing.Middleware())
+	r.Use(middleware.RequestLogger(logg, cfg.LogRedactQueryKeys, cfg.LogMaxPathLength))
 
-	// --- Healthcheck ---
+	// --- Healthcheck (liveness: the process is up, dependencies are /readyz) ---
 	r.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// --- Readiness ---
+	readinessChecks := []health.Check{{Name: "database", Run: sqlDB.PingContext}}
+	if cfg.ReadyzCheckExchanges {
+		for _, exchange := range []string{"ompfinex", "wallex", "nobitex"} {
+			exchange := exchange
+			readinessChecks = append(readinessChecks, health.Check{
+				Name: exchange,
+				Run:  func(ctx context.Context) error { return marketSvc.PingExchange(ctx, exchange) },
+			})
+		}
+	}
+	r.GET("/readyz", health.Readiness(cfg.ReadyzTimeout, readinessChecks...))
+
+	// --- Metrics ---
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// --- Swagger ---
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -123,15 +217,21 @@ func main() {
 	market_handler.RegisterRoutes(r)
 	order_handler.RegisterRoutes(r)
 
+	// --- Admin routes ---
+	admin := r.Group("/admin", middleware.AdminAuth(cfg.AdminToken))
+	market_handler.RegisterAdminRoutes(admin)
+	order_handler.RegisterAdminRoutes(admin)
+	cron_handler.RegisterAdminRoutes(admin)
+
 	// --- Start server ---
 
 	srv := &http.Server{
 		Addr:              cfg.ListenAddr,
 		Handler:           r,
-		ReadTimeout:       5 * time.Second,
-		WriteTimeout:      30 * time.Second,
-		IdleTimeout:       60 * time.Second,
-		ReadHeaderTimeout: 2 * time.Second,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
 	}
 
 	// Channel to listen for errors from server
@@ -159,7 +259,7 @@ func main() {
 		logg.Infof("Received signal: %s. Shutting down gracefully...", sig)
 
 		// Create a context with timeout for graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
 		// Stop accepting new connections
@@ -167,9 +267,15 @@ func main() {
 			logg.Errorf("Server forced to shutdown: %v", err)
 		}
 
-		// Stop cron jobs
-		c.Stop()
-		logg.Infof("Cron jobs stopped")
+		// Stop cron jobs and wait for the running ones, so orders mid on-chain
+		// transaction finish before the database goes away
+		active := orderSvc.ActiveWorkers()
+		select {
+		case <-c.Stop().Done():
+			logg.Infof("Cron jobs stopped, drained %d order workers", active)
+		case <-ctx.Done():
+			logg.Errorf("Timed out waiting for cron jobs, %d order workers still running", orderSvc.ActiveWorkers())
+		}
 
 		// Close database connection
 		if err := sqlDB.Close(); err != nil {
@@ -0,0 +1,81 @@
+package ethereum
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Chains holds one EthereumClient per configured EVM chain, keyed by network name
+// (e.g. "sepolia", "mumbai").
+type Chains struct {
+	clients map[string]*EthereumClient
+	// defaultNetwork serves callers that don't name a network, like orders created
+	// before networks were recorded
+	defaultNetwork string
+}
+
+// NewChains dials every chain in configs; the first one is the default network.
+// opts apply to every client.
+func NewChains(ctx context.Context, configs []Config, opts ...Option) (*Chains, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%w: no chain configured", ErrMissingEnvVars)
+	}
+	// validate every chain before dialing any, so one bad config fails fast
+	for _, config := range configs {
+		if err := config.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", config.Network, err)
+		}
+	}
+	c := &Chains{clients: make(map[string]*EthereumClient, len(configs))}
+	for _, config := range configs {
+		network := strings.ToLower(config.Network)
+		if network == "" {
+			c.Close()
+			return nil, fmt.Errorf("%w: chain network name", ErrMissingEnvVars)
+		}
+		if _, ok := c.clients[network]; ok {
+			c.Close()
+			return nil, fmt.Errorf("network %s configured twice", network)
+		}
+		client, err := NewEthereumClient(ctx, config, opts...)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("%s: %w", network, err)
+		}
+		c.clients[network] = client
+		if c.defaultNetwork == "" {
+			c.defaultNetwork = network
+		}
+	}
+	return c, nil
+}
+
+// ClientFor returns the client of network; an empty network selects the default one
+func (c *Chains) ClientFor(network string) (*EthereumClient, error) {
+	if network == "" {
+		network = c.defaultNetwork
+	}
+	client, ok := c.clients[strings.ToLower(network)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNetwork, network)
+	}
+	return client, nil
+}
+
+// Networks lists the configured network names
+func (c *Chains) Networks() []string {
+	networks := make([]string, 0, len(c.clients))
+	for network := range c.clients {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+func (c *Chains) Close() {
+	for _, client := range c.clients {
+		client.Close()
+	}
+}
@@ -12,13 +12,24 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/tracing"
+	geth "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const phoenixProtocol = "PHOENIX"
@@ -33,6 +44,16 @@ const erc20ABI = `[
 		"outputs": [{"name": "", "type": "bool"}],
 		"type": "function"
 	},
+	{
+		"constant": false,
+		"inputs": [
+			{"name": "spender", "type": "address"},
+			{"name": "amount", "type": "uint256"}
+		],
+		"name": "approve",
+		"outputs": [{"name": "", "type": "bool"}],
+		"type": "function"
+	},
 	{
 		"constant": true,
 		"inputs": [],
@@ -46,6 +67,37 @@ const erc20ABI = `[
 		"name": "symbol",
 		"outputs": [{"name": "", "type": "string"}],
 		"type": "function"
+	},
+	{
+		"constant": true,
+		"inputs": [{"name": "account", "type": "address"}],
+		"name": "balanceOf",
+		"outputs": [{"name": "", "type": "uint256"}],
+		"type": "function"
+	},
+	{
+		"constant": true,
+		"inputs": [
+			{"name": "owner", "type": "address"},
+			{"name": "spender", "type": "address"}
+		],
+		"name": "allowance",
+		"outputs": [{"name": "", "type": "uint256"}],
+		"type": "function"
+	},
+	{
+		"constant": true,
+		"inputs": [],
+		"name": "DOMAIN_SEPARATOR",
+		"outputs": [{"name": "", "type": "bytes32"}],
+		"type": "function"
+	},
+	{
+		"constant": true,
+		"inputs": [{"name": "owner", "type": "address"}],
+		"name": "nonces",
+		"outputs": [{"name": "", "type": "uint256"}],
+		"type": "function"
 	}
 ]`
 
@@ -62,16 +114,78 @@ var (
 	ErrMineTransaction   = errors.New("failed to mine transaction")
 	ErrInvalidAmount     = errors.New("failed to parse amount")
 	ErrUnsupportedToken  = errors.New("unsupported token symbol")
+	ErrEstimateGas       = errors.New("gas estimation failed, transaction would revert")
+	ErrUnknownNetwork    = errors.New("unknown network")
+	ErrInvalidSignature  = errors.New("invalid permit signature")
+	ErrChainIDMismatch   = errors.New("configured chain id does not match the RPC")
+	ErrInvalidAddress    = errors.New("invalid address")
+	// ErrTxPending means the tx was broadcast but whether it got mined is unknown; it must
+	// be reconciled by its hash, not treated as failed
+	ErrTxPending = errors.New("transaction sent, outcome unknown")
 )
 
+// DefaultGasLimitMultiplier pads estimated gas so small state changes before mining don't run the tx out of gas
+const DefaultGasLimitMultiplier = 1.2
+
 // Config holds Ethereum client config
 type Config struct {
+	// Network names the chain (e.g. "sepolia") when registered in Chains
+	Network         string
 	RPCURL          string
 	PrivateKey      string
 	PhoenixContract string
 	ChainID         *big.Int
 	abiFiles        map[string]string // Optional: contract-specific ABIs
 	SupportedTokens map[string]string // Symbol → contract address (e.g. "USDT": "0x...", "DAI": "0x...")
+	// Confirmations is how many blocks, counting the one holding the tx, to wait for; <= 1 waits for inclusion only
+	Confirmations int
+	// MineTimeout bounds the wait for a tx to be mined and confirmed; zero waits as long as ctx allows
+	MineTimeout time.Duration
+}
+
+// Validate checks everything in c that can be checked without dialing the RPC: the
+// private key must parse and every contract address must be a non-zero 20-byte hex address, with
+// a valid EIP-55 checksum when it is mixed case. A typo would otherwise silently become
+// the zero address, or an error on the first transaction.
+func (c Config) Validate() error {
+	if c.RPCURL == "" {
+		return fmt.Errorf("%w: RPC URL", ErrMissingEnvVars)
+	}
+	if c.PrivateKey == "" {
+		return fmt.Errorf("%w: private key", ErrMissingEnvVars)
+	}
+	if _, err := crypto.HexToECDSA(strings.TrimPrefix(c.PrivateKey, "0x")); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	if c.PhoenixContract != "" {
+		if err := validateAddress(c.PhoenixContract); err != nil {
+			return fmt.Errorf("phoenix contract: %w", err)
+		}
+	}
+	for symbol, addr := range c.SupportedTokens {
+		if err := validateAddress(addr); err != nil {
+			return fmt.Errorf("%s token: %w", symbol, err)
+		}
+	}
+	return nil
+}
+
+// validateAddress accepts 0x-prefixed, non-zero 20-byte hex addresses; all lower or all
+// upper case ones carry no checksum, mixed case ones must match their EIP-55 checksum
+func validateAddress(addr string) error {
+	if !common.IsHexAddress(addr) || !strings.HasPrefix(addr, "0x") {
+		return fmt.Errorf("%w: %q is not a 0x-prefixed 20-byte hex address", ErrInvalidAddress, addr)
+	}
+	if common.HexToAddress(addr) == (common.Address{}) {
+		return fmt.Errorf("%w: zero address", ErrInvalidAddress)
+	}
+	hex := addr[2:]
+	if hex != strings.ToLower(hex) && hex != strings.ToUpper(hex) {
+		if checksummed := common.HexToAddress(addr).Hex(); checksummed != addr {
+			return fmt.Errorf("%w: %s fails its EIP-55 checksum, expected %s", ErrInvalidAddress, addr, checksummed)
+		}
+	}
+	return nil
 }
 
 // Params for executeTradeWithPermit
@@ -86,13 +200,17 @@ type Params struct {
 		R common.Hash
 		S common.Hash
 	}
+	// OnSent, when set, is called with the tx hash once it is broadcast, before waiting for it
+	OnSent func(txHash common.Hash)
 }
 
 // WithdrawTreasuryParams for WithdrawTreasury
 type WithdrawTreasuryParams struct {
 	RecipientAddress string
-	Amount           string
+	Amount           decimal.Decimal // in whole tokens, scaled by the token's decimals on send
 	TokenSymbol      string
+	// OnSent, when set, is called with the tx hash once it is broadcast, before waiting for it
+	OnSent func(txHash common.Hash)
 }
 
 // EthereumClient encapsulates everything
@@ -103,6 +221,27 @@ type EthereumClient struct {
 	contracts  map[string]*bind.BoundContract // phoenix + tokens
 	abi        map[string]abi.ABI
 	config     Config
+
+	decimalsMu sync.Mutex
+	decimals   map[string]uint8 // symbol → token decimals, filled lazily
+
+	// legacyGas sends pre-EIP-1559 transactions, for chains without a base fee
+	legacyGas bool
+	// gasLimitMultiplier is applied to every gas estimate
+	gasLimitMultiplier float64
+	tokenAddresses     map[string]common.Address // symbol → ERC20 contract
+}
+
+// Option functional options
+type Option func(*EthereumClient)
+
+func WithLegacyGas(legacy bool) Option { return func(ec *EthereumClient) { ec.legacyGas = legacy } }
+func WithGasLimitMultiplier(m float64) Option {
+	return func(ec *EthereumClient) {
+		if m >= 1 {
+			ec.gasLimitMultiplier = m
+		}
+	}
 }
 
 func phoenixABIPath() string {
@@ -112,9 +251,9 @@ func phoenixABIPath() string {
 }
 
 // NewEthereumClient initializes the client
-func NewEthereumClient(ctx context.Context, config Config) (*EthereumClient, error) {
-	if config.RPCURL == "" || config.PrivateKey == "" {
-		return nil, fmt.Errorf("%w: RPC_URL or PRIVATE_KEY", ErrMissingEnvVars)
+func NewEthereumClient(ctx context.Context, config Config, opts ...Option) (*EthereumClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 	config.abiFiles = map[string]string{
 		"PHOENIX": phoenixABIPath(),
@@ -123,10 +262,23 @@ func NewEthereumClient(ctx context.Context, config Config) (*EthereumClient, err
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrConnectNetwork, err)
 	}
+	// signing for the wrong chain id gets every transaction rejected, fail at startup instead
+	rpcChainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%w: chain id: %v", ErrConnectNetwork, err)
+	}
+	if config.ChainID == nil {
+		config.ChainID = rpcChainID
+	} else if config.ChainID.Cmp(rpcChainID) != 0 {
+		client.Close()
+		return nil, fmt.Errorf("%w: configured %s, RPC reports %s", ErrChainIDMismatch, config.ChainID, rpcChainID)
+	}
 	key := strings.TrimPrefix(config.PrivateKey, "0x")
 
 	privateKey, err := crypto.HexToECDSA(key)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
 	}
 	wallet := crypto.PubkeyToAddress(privateKey.PublicKey)
@@ -158,7 +310,9 @@ func NewEthereumClient(ctx context.Context, config Config) (*EthereumClient, err
 	abis["erc20"] = erc20Parsed
 
 	// Register supported tokens
+	tokenAddresses := make(map[string]common.Address)
 	for symbol, addr := range config.SupportedTokens {
+		tokenAddresses[strings.ToUpper(symbol)] = common.HexToAddress(addr)
 		contracts[strings.ToUpper(symbol)] = bind.NewBoundContract(common.HexToAddress(addr), erc20Parsed, client, client, client)
 	}
 
@@ -166,25 +320,123 @@ func NewEthereumClient(ctx context.Context, config Config) (*EthereumClient, err
 	if config.PhoenixContract != "" {
 		phoenixABI, ok := abis[phoenixProtocol]
 		if !ok {
+			client.Close()
 			return nil, fmt.Errorf("%w: phoenix ABI missing", ErrMissingEnvVars)
 		}
 		contracts[phoenixProtocol] = bind.NewBoundContract(common.HexToAddress(config.PhoenixContract), phoenixABI, client, client, client)
 	}
 
-	return &EthereumClient{
+	ec := &EthereumClient{
 		client:     client,
 		wallet:     wallet,
 		privateKey: privateKey,
 		contracts:  contracts,
 		abi:        abis,
 		config:     config,
-	}, nil
+		decimals:   map[string]uint8{"ETH": 18},
+
+		gasLimitMultiplier: DefaultGasLimitMultiplier,
+		tokenAddresses:     tokenAddresses,
+	}
+	for _, opt := range opts {
+		opt(ec)
+	}
+	return ec, nil
 }
 
 func (ec *EthereumClient) Close() { ec.client.Close() }
 
 func (ec *EthereumClient) WalletAddress() common.Address { return ec.wallet }
 
+// TokenDecimals returns the number of decimals of a supported token, reading the
+// ERC20 contract once and caching the answer.
+func (ec *EthereumClient) TokenDecimals(ctx context.Context, tokenSymbol string) (uint8, error) {
+	symbol := strings.ToUpper(tokenSymbol)
+
+	ec.decimalsMu.Lock()
+	defer ec.decimalsMu.Unlock()
+	if d, ok := ec.decimals[symbol]; ok {
+		return d, nil
+	}
+
+	contract, ok := ec.contracts[symbol]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s not supported", ErrUnsupportedToken, symbol)
+	}
+	var out []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &out, "decimals"); err != nil {
+		return 0, fmt.Errorf("%w: decimals: %v", ErrContractCall, err)
+	}
+	if len(out) != 1 {
+		return 0, fmt.Errorf("%w: unexpected decimals result %v", ErrContractCall, out)
+	}
+	d, ok := out[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("%w: unexpected decimals result %v", ErrContractCall, out)
+	}
+	ec.decimals[symbol] = d
+	return d, nil
+}
+
+// ToUnits scales a whole-token amount to the token's smallest unit using its decimals,
+// e.g. 1.5 USDT (6 decimals) is 1500000. Digits beyond the token's precision are dropped.
+func (ec *EthereumClient) ToUnits(ctx context.Context, tokenSymbol string, amount decimal.Decimal) (*big.Int, error) {
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("%w: negative amount %s", ErrInvalidAmount, amount)
+	}
+	decimals, err := ec.TokenDecimals(ctx, tokenSymbol)
+	if err != nil {
+		return nil, err
+	}
+	return amount.Shift(int32(decimals)).Floor().BigInt(), nil
+}
+
+// TreasuryBalance returns the wallet's balance of a supported token (or ETH) in the
+// token's smallest unit.
+func (ec *EthereumClient) TreasuryBalance(ctx context.Context, tokenSymbol string) (*big.Int, error) {
+	return ec.TokenBalance(ctx, tokenSymbol, ec.wallet)
+}
+
+// TokenBalance returns addr's balance of a supported token (or ETH) in the token's
+// smallest unit. Use TokenDecimals to convert it to human units.
+func (ec *EthereumClient) TokenBalance(ctx context.Context, tokenSymbol string, addr common.Address) (*big.Int, error) {
+	symbol := strings.ToUpper(tokenSymbol)
+	if symbol == "ETH" {
+		balance, err := ec.client.BalanceAt(ctx, addr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: balance: %v", ErrContractCall, err)
+		}
+		return balance, nil
+	}
+	return ec.callUint256(ctx, symbol, "balanceOf", addr)
+}
+
+// TokenAllowance returns how much of a supported ERC20 token spender may move on
+// behalf of owner, in the token's smallest unit.
+func (ec *EthereumClient) TokenAllowance(ctx context.Context, tokenSymbol string, owner, spender common.Address) (*big.Int, error) {
+	return ec.callUint256(ctx, strings.ToUpper(tokenSymbol), "allowance", owner, spender)
+}
+
+// callUint256 calls a read-only ERC20 method returning a single uint256
+func (ec *EthereumClient) callUint256(ctx context.Context, symbol, method string, args ...interface{}) (*big.Int, error) {
+	contract, ok := ec.contracts[symbol]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s not supported", ErrUnsupportedToken, symbol)
+	}
+	var out []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: ctx}, &out, method, args...); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrContractCall, method, err)
+	}
+	if len(out) != 1 {
+		return nil, fmt.Errorf("%w: unexpected %s result %v", ErrContractCall, method, out)
+	}
+	value, ok := out[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected %s result %v", ErrContractCall, method, out)
+	}
+	return value, nil
+}
+
 // ExecuteTradeWithPermit remains phoenix-specific
 func (ec *EthereumClient) ExecuteTradeWithPermit(ctx context.Context, params Params) (*types.Receipt, error) {
 	fmt.Printf("Admin Wallet: %s\n", ec.wallet.Hex())
@@ -211,77 +463,371 @@ func (ec *EthereumClient) ExecuteTradeWithPermit(ctx context.Context, params Par
 	}
 
 	// Send TX
+	_, span := ec.startSpan(ctx, "ethereum.send", attribute.String("method", "executeTradeWithPermit"))
 	tx, err := contract.Transact(auth, "executeTradeWithPermit",
 		params.TokenAddress, params.UserAddress, params.Amount, params.Deadline,
 		quoteIDBytes32, params.Signature.V, params.Signature.R, params.Signature.S,
 	)
+	tracing.End(span, err)
 	if err != nil {
+		metrics.IncEthereumTransaction(ec.config.Network, "send_failed")
 		return nil, fmt.Errorf("%w: %v", ErrSendTransaction, err)
 	}
 
 	fmt.Printf("TX sent: %s\n", tx.Hash().Hex())
-	receipt, err := bind.WaitMined(ctx, ec.client, tx)
+	receipt, err := ec.waitMined(ctx, tx, params.OnSent)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrMineTransaction, err)
+		return nil, err
 	}
 	if receipt.Status != 1 {
-		return receipt, fmt.Errorf("%w: trade failed", ErrMineTransaction)
+		return receipt, fmt.Errorf("%w: trade failed: %s", ErrMineTransaction, ec.revertReason(ctx, tx, receipt))
 	}
 	return receipt, nil
 }
 
+// permitTypeHash is the EIP-2612 Permit struct type hash
+var permitTypeHash = crypto.Keccak256Hash([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"))
+
+// VerifyPermitSignature checks that params.Signature is the user's EIP-2612 permit letting
+// the phoenix contract spend params.Amount of the token until params.Deadline, so a bad
+// signature is caught before a transaction is sent. It reads the token's domain separator
+// and the user's current permit nonce; anything else is computed locally.
+func (ec *EthereumClient) VerifyPermitSignature(ctx context.Context, params Params) error {
+	token := bind.NewBoundContract(params.TokenAddress, ec.abi["erc20"], ec.client, ec.client, ec.client)
+	opts := &bind.CallOpts{Context: ctx}
+
+	var out []interface{}
+	if err := token.Call(opts, &out, "DOMAIN_SEPARATOR"); err != nil {
+		return fmt.Errorf("%w: DOMAIN_SEPARATOR: %v", ErrContractCall, err)
+	}
+	if len(out) != 1 {
+		return fmt.Errorf("%w: unexpected DOMAIN_SEPARATOR result %v", ErrContractCall, out)
+	}
+	domainSeparator, ok := out[0].([32]byte)
+	if !ok {
+		return fmt.Errorf("%w: unexpected DOMAIN_SEPARATOR result %v", ErrContractCall, out)
+	}
+	out = nil
+	if err := token.Call(opts, &out, "nonces", params.UserAddress); err != nil {
+		return fmt.Errorf("%w: nonces: %v", ErrContractCall, err)
+	}
+	if len(out) != 1 {
+		return fmt.Errorf("%w: unexpected nonces result %v", ErrContractCall, out)
+	}
+	nonce, ok := out[0].(*big.Int)
+	if !ok {
+		return fmt.Errorf("%w: unexpected nonces result %v", ErrContractCall, out)
+	}
+
+	spender := common.HexToAddress(ec.config.PhoenixContract)
+	digest := PermitDigest(domainSeparator, params.UserAddress, spender, params.Amount, nonce, params.Deadline)
+	signer, err := recoverSigner(digest, params.Signature.V, params.Signature.R, params.Signature.S)
+	if err != nil {
+		return err
+	}
+	if signer != params.UserAddress {
+		return fmt.Errorf("%w: signed by %s, not %s", ErrInvalidSignature, signer.Hex(), params.UserAddress.Hex())
+	}
+	return nil
+}
+
+// PermitDigest is the EIP-712 hash an owner signs to permit spender to move value of the
+// token whose domain separator is given.
+func PermitDigest(domainSeparator common.Hash, owner, spender common.Address, value, nonce, deadline *big.Int) common.Hash {
+	structHash := crypto.Keccak256(
+		permitTypeHash.Bytes(),
+		common.LeftPadBytes(owner.Bytes(), 32),
+		common.LeftPadBytes(spender.Bytes(), 32),
+		common.LeftPadBytes(value.Bytes(), 32),
+		common.LeftPadBytes(nonce.Bytes(), 32),
+		common.LeftPadBytes(deadline.Bytes(), 32),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), domainSeparator.Bytes(), structHash)
+}
+
+// recoverSigner returns the address whose key produced the signature over digest. Like
+// OpenZeppelin's ECDSA it accepts v as 27/28 (or 0/1) and rejects malleable high-s values.
+func recoverSigner(digest common.Hash, v uint8, r, s common.Hash) (common.Address, error) {
+	if v >= 27 {
+		v -= 27
+	}
+	if !crypto.ValidateSignatureValues(v, r.Big(), s.Big(), true) {
+		return common.Address{}, fmt.Errorf("%w: malformed v, r or s", ErrInvalidSignature)
+	}
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig[0:32], r.Bytes())
+	copy(sig[32:64], s.Bytes())
+	sig[64] = v
+	pub, err := crypto.SigToPub(digest.Bytes(), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
 // WithdrawTreasury is now general
 func (ec *EthereumClient) WithdrawTreasury(ctx context.Context, params WithdrawTreasuryParams) (*types.Receipt, error) {
 	symbol := strings.ToUpper(params.TokenSymbol)
 
 	if symbol == "ETH" {
-		amountWei, ok := new(big.Int).SetString(params.Amount, 10)
-		if !ok {
-			return nil, fmt.Errorf("%w: %s to wei", ErrInvalidAmount, params.Amount)
+		amountWei, err := ec.ToUnits(ctx, symbol, params.Amount)
+		if err != nil {
+			return nil, err
 		}
-		auth, err := bind.NewKeyedTransactorWithChainID(ec.privateKey, ec.config.ChainID)
+		tx, err := ec.newTransferTx(ctx, common.HexToAddress(params.RecipientAddress), amountWei)
 		if err != nil {
-			return nil, fmt.Errorf("%w: %v", ErrCreateTransactor, err)
+			return nil, err
 		}
-		auth.Value = amountWei
-
-		tx := types.NewTransaction(
-			uint64(auth.Nonce.Int64()),
-			common.HexToAddress(params.RecipientAddress),
-			amountWei, auth.GasLimit, auth.GasPrice, nil,
-		)
-		signedTx, err := auth.Signer(auth.From, tx)
+		signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(ec.config.ChainID), ec.privateKey)
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrSendTransaction, err)
 		}
-		if err := ec.client.SendTransaction(ctx, signedTx); err != nil {
+		_, span := ec.startSpan(ctx, "ethereum.send", attribute.String("method", "transfer"),
+			attribute.String("tx.hash", signedTx.Hash().Hex()))
+		err = ec.client.SendTransaction(ctx, signedTx)
+		tracing.End(span, err)
+		if err != nil {
+			metrics.IncEthereumTransaction(ec.config.Network, "send_failed")
 			return nil, fmt.Errorf("%w: %v", ErrSendTransaction, err)
 		}
-		return bind.WaitMined(ctx, ec.client, signedTx)
+		return ec.waitMined(ctx, signedTx, params.OnSent)
 	}
 
 	// ERC20 withdrawal
-	contract, ok := ec.contracts[symbol]
-	if !ok {
-		return nil, fmt.Errorf("%w: %s not supported", ErrUnsupportedToken, symbol)
+	amount, err := ec.ToUnits(ctx, symbol, params.Amount)
+	if err != nil {
+		return nil, err
 	}
+	tx, err := ec.transactERC20(ctx, symbol, "transfer", common.HexToAddress(params.RecipientAddress), amount)
+	if err != nil {
+		return nil, err
+	}
+	return ec.waitMined(ctx, tx, params.OnSent)
+}
 
-	amount, ok := new(big.Int).SetString(params.Amount, 10)
+// Approve lets spender move up to amount (in the token's smallest unit) of a supported
+// ERC20 token out of the wallet.
+func (ec *EthereumClient) Approve(ctx context.Context, symbol string, spender common.Address, amount *big.Int) (*types.Receipt, error) {
+	tx, err := ec.transactERC20(ctx, strings.ToUpper(symbol), "approve", spender, amount)
+	if err != nil {
+		return nil, err
+	}
+	receipt, err := ec.waitMined(ctx, tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != 1 {
+		return receipt, fmt.Errorf("%w: approve failed: %s", ErrMineTransaction, ec.revertReason(ctx, tx, receipt))
+	}
+	return receipt, nil
+}
+
+// EnsureAllowance approves spender for needed of the token unless the wallet's current
+// allowance already covers it. It returns a nil receipt when no approval was sent.
+func (ec *EthereumClient) EnsureAllowance(ctx context.Context, symbol string, spender common.Address, needed *big.Int) (*types.Receipt, error) {
+	allowance, err := ec.TokenAllowance(ctx, symbol, ec.wallet, spender)
+	if err != nil {
+		return nil, err
+	}
+	if allowance.Cmp(needed) >= 0 {
+		return nil, nil
+	}
+	return ec.Approve(ctx, symbol, spender, needed)
+}
+
+// transactERC20 sends a state-changing call to a supported ERC20 token from the wallet,
+// with an estimated gas limit, and returns it unmined.
+func (ec *EthereumClient) transactERC20(ctx context.Context, symbol, method string, args ...interface{}) (*types.Transaction, error) {
+	contract, ok := ec.contracts[symbol]
 	if !ok {
-		return nil, fmt.Errorf("%w: %s", ErrInvalidAmount, params.Amount)
+		return nil, fmt.Errorf("%w: %s not supported", ErrUnsupportedToken, symbol)
 	}
 
 	auth, err := bind.NewKeyedTransactorWithChainID(ec.privateKey, ec.config.ChainID)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrCreateTransactor, err)
 	}
+	auth.Context = ctx
+	calldata, err := ec.abi["erc20"].Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pack %s: %v", ErrSendTransaction, method, err)
+	}
+	tokenAddress := ec.tokenAddresses[symbol]
+	if auth.GasLimit, err = ec.estimateGas(ctx, geth.CallMsg{From: ec.wallet, To: &tokenAddress, Data: calldata}); err != nil {
+		return nil, err
+	}
+	if ec.legacyGas {
+		// bind picks EIP-1559 whenever GasPrice is nil
+		if auth.GasPrice, err = ec.client.SuggestGasPrice(ctx); err != nil {
+			return nil, fmt.Errorf("%w: gas price: %v", ErrSendTransaction, err)
+		}
+	}
 
-	tx, err := contract.Transact(auth, "transfer",
-		common.HexToAddress(params.RecipientAddress),
-		amount,
-	)
+	_, span := ec.startSpan(ctx, "ethereum.send", attribute.String("method", method), attribute.String("token", symbol))
+	tx, err := contract.Transact(auth, method, args...)
+	tracing.End(span, err)
 	if err != nil {
+		metrics.IncEthereumTransaction(ec.config.Network, "send_failed")
 		return nil, fmt.Errorf("%w: %v", ErrSendTransaction, err)
 	}
-	return bind.WaitMined(ctx, ec.client, tx)
+	return tx, nil
+}
+
+// newTransferTx builds an unsigned ETH transfer from the wallet, priced with EIP-1559
+// fees unless the client was created WithLegacyGas.
+func (ec *EthereumClient) newTransferTx(ctx context.Context, to common.Address, value *big.Int) (*types.Transaction, error) {
+	nonce, err := ec.client.PendingNonceAt(ctx, ec.wallet)
+	if err != nil {
+		return nil, fmt.Errorf("%w: nonce: %v", ErrSendTransaction, err)
+	}
+	gasLimit, err := ec.estimateGas(ctx, geth.CallMsg{From: ec.wallet, To: &to, Value: value})
+	if err != nil {
+		return nil, err
+	}
+
+	if ec.legacyGas {
+		gasPrice, err := ec.client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: gas price: %v", ErrSendTransaction, err)
+		}
+		return types.NewTx(&types.LegacyTx{
+			Nonce: nonce, To: &to, Value: value, Gas: gasLimit, GasPrice: gasPrice,
+		}), nil
+	}
+
+	tip, err := ec.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: gas tip: %v", ErrSendTransaction, err)
+	}
+	head, err := ec.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: latest header: %v", ErrSendTransaction, err)
+	}
+	if head.BaseFee == nil {
+		return nil, fmt.Errorf("%w: chain has no base fee, use legacy gas", ErrSendTransaction)
+	}
+	// twice the base fee keeps the tx includable through several full blocks
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   ec.config.ChainID,
+		Nonce:     nonce,
+		To:        &to,
+		Value:     value,
+		Gas:       gasLimit,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+	}), nil
+}
+
+// estimateGas simulates msg and pads the estimate with the gas limit multiplier. A revert
+// surfaces as ErrEstimateGas, so a send that would fail is never broadcast.
+func (ec *EthereumClient) estimateGas(ctx context.Context, msg geth.CallMsg) (uint64, error) {
+	gas, err := ec.client.EstimateGas(ctx, msg)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrEstimateGas, err)
+	}
+	return uint64(float64(gas) * ec.gasLimitMultiplier), nil
+}
+
+// confirmationPollInterval is how often the chain head is polled while waiting for confirmations
+const confirmationPollInterval = 2 * time.Second
+
+// waitMined reports the sent tx to onSent, if set, then waits until it is mined and has
+// the configured number of confirmations, and counts the outcome.
+func (ec *EthereumClient) waitMined(ctx context.Context, tx *types.Transaction, onSent func(common.Hash)) (*types.Receipt, error) {
+	if onSent != nil {
+		onSent(tx.Hash())
+	}
+	ctx, span := ec.startSpan(ctx, "ethereum.wait_mined", attribute.String("tx.hash", tx.Hash().Hex()))
+	receipt, err := ec.waitConfirmed(ctx, tx)
+	switch {
+	case err != nil:
+		metrics.IncEthereumTransaction(ec.config.Network, "unconfirmed")
+	case receipt.Status != types.ReceiptStatusSuccessful:
+		metrics.IncEthereumTransaction(ec.config.Network, "reverted")
+		span.SetStatus(codes.Error, "reverted")
+	default:
+		metrics.IncEthereumTransaction(ec.config.Network, "success")
+	}
+	tracing.End(span, err)
+	return receipt, err
+}
+
+// startSpan opens a span tagged with the client's network
+func (ec *EthereumClient) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name, append(attrs, attribute.String("network", ec.config.Network))...)
+}
+
+// waitConfirmed waits until tx is mined and has the configured number of confirmations.
+// It gives up with ErrTxPending once MineTimeout elapses or ctx is cancelled, so a stuck
+// transaction never blocks the caller forever. The tx may still be mined after that.
+func (ec *EthereumClient) waitConfirmed(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	if ec.config.MineTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ec.config.MineTimeout)
+		defer cancel()
+	}
+
+	receipt, err := bind.WaitMined(ctx, ec.client, tx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrTxPending, tx.Hash().Hex(), err)
+	}
+	if ec.config.Confirmations <= 1 {
+		return receipt, nil
+	}
+
+	target := new(big.Int).Add(receipt.BlockNumber, big.NewInt(int64(ec.config.Confirmations-1))).Uint64()
+	ticker := time.NewTicker(confirmationPollInterval)
+	defer ticker.Stop()
+	for {
+		head, err := ec.client.BlockNumber(ctx)
+		if err == nil && head >= target {
+			// re-read the receipt, a reorg may have moved or dropped the tx meanwhile
+			receipt, err = ec.client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s dropped after reorg: %v", ErrTxPending, tx.Hash().Hex(), err)
+			}
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return receipt, fmt.Errorf("%w: %s not confirmed: %v", ErrTxPending, tx.Hash().Hex(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// revertReason replays a reverted tx as a call on the state it ran against and extracts
+// the revert reason. Nodes that can't replay it (pruned state, no error data) yield a
+// placeholder instead of an error, the revert itself is already being reported.
+func (ec *EthereumClient) revertReason(ctx context.Context, tx *types.Transaction, receipt *types.Receipt) string {
+	const unknown = "unknown revert reason"
+	if receipt.BlockNumber == nil || receipt.BlockNumber.Sign() == 0 {
+		return unknown
+	}
+	// the parent block's state is what the tx saw, replaying on its own block sees the tx's effects
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, err := ec.client.CallContract(ctx, geth.CallMsg{
+		From:  ec.wallet,
+		To:    tx.To(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}, parent)
+	if err == nil {
+		return unknown
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if hexData, ok := dataErr.ErrorData().(string); ok {
+			if data, decodeErr := hexutil.Decode(hexData); decodeErr == nil {
+				if reason, unpackErr := abi.UnpackRevert(data); unpackErr == nil {
+					return reason
+				}
+			}
+		}
+	}
+	// the node message usually reads "execution reverted: <reason>"
+	return err.Error()
 }
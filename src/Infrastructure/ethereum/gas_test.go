@@ -0,0 +1,56 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNewTransferTxGasLimit(t *testing.T) {
+	estimate := func(gas string, err error) rpcHandler {
+		return func([]json.RawMessage) (any, error) { return gas, err }
+	}
+	tests := []struct {
+		name       string
+		multiplier float64
+		estimate   rpcHandler
+		wantGas    uint64
+		wantErr    error
+	}{
+		{name: "default margin", estimate: estimate("0x5208", nil), wantGas: 25200},
+		{name: "configured margin", multiplier: 1.5, estimate: estimate("0x5208", nil), wantGas: 31500},
+		{name: "multiplier below one ignored", multiplier: 0.5, estimate: estimate("0x5208", nil), wantGas: 25200},
+		// a send that would revert is never built, let alone broadcast
+		{name: "estimate reverts", estimate: estimate("", errors.New("execution reverted")), wantErr: ErrEstimateGas},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := newTestClient(t, Config{Network: "test"}, map[string]rpcHandler{
+				"eth_getTransactionCount": func([]json.RawMessage) (any, error) { return "0x7", nil },
+				"eth_estimateGas":         tt.estimate,
+				"eth_gasPrice":            func([]json.RawMessage) (any, error) { return "0x3b9aca00", nil },
+			})
+			WithLegacyGas(true)(ec)
+			WithGasLimitMultiplier(tt.multiplier)(ec)
+
+			tx, err := ec.newTransferTx(context.Background(), common.HexToAddress("0x1"), big.NewInt(1))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tx.Gas() != tt.wantGas {
+				t.Errorf("gas limit = %d, want %d", tx.Gas(), tt.wantGas)
+			}
+			if tx.Nonce() != 7 {
+				t.Errorf("nonce = %d, want 7", tx.Nonce())
+			}
+		})
+	}
+}
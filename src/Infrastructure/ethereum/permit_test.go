@@ -0,0 +1,107 @@
+package ethereum
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestVerifyPermitSignature(t *testing.T) {
+	owner, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.HexToECDSA("8da4ef21b864d2cc526dbdb2a120bd2874c36c9d0a1fb7f8c63d7f7a8b41de8f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerAddr := crypto.PubkeyToAddress(owner.PublicKey)
+	token := common.HexToAddress("0x2222222222222222222222222222222222222222")
+	spender := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	domainSeparator := common.HexToHash("0xabcdef")
+	amount, deadline := big.NewInt(1_000_000), big.NewInt(1_900_000_000)
+	// secp256k1 order, s above half of it is the malleable twin of a valid signature
+	curveN, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+	// sign has key sign the permit digest at the given owner nonce
+	sign := func(key *ecdsa.PrivateKey, nonce int64) func(p *Params) {
+		return func(p *Params) {
+			digest := PermitDigest(domainSeparator, ownerAddr, spender, amount, big.NewInt(nonce), deadline)
+			sig, err := crypto.Sign(digest.Bytes(), key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Signature.R = common.BytesToHash(sig[:32])
+			p.Signature.S = common.BytesToHash(sig[32:64])
+			p.Signature.V = sig[64] + 27
+		}
+	}
+
+	tests := []struct {
+		name         string
+		nonce        int64 // the token's current nonce for the owner
+		separatorErr error
+		sign         func(p *Params)
+		wantErr      error
+	}{
+		{name: "valid", sign: sign(owner, 0)},
+		{name: "valid at a later nonce", nonce: 3, sign: sign(owner, 3)},
+		{name: "stale nonce", nonce: 1, sign: sign(owner, 0), wantErr: ErrInvalidSignature},
+		{name: "other signer", sign: sign(other, 0), wantErr: ErrInvalidSignature},
+		{name: "malleable s", sign: func(p *Params) {
+			sign(owner, 0)(p)
+			p.Signature.S = common.BigToHash(new(big.Int).Sub(curveN, p.Signature.S.Big()))
+			p.Signature.V ^= 1
+		}, wantErr: ErrInvalidSignature},
+		{name: "zero signature", sign: func(p *Params) {}, wantErr: ErrInvalidSignature},
+		{name: "token without permit", separatorErr: errors.New("execution reverted"), sign: sign(owner, 0), wantErr: ErrContractCall},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := newTestClient(t, Config{Network: "test", PhoenixContract: spender.Hex()}, map[string]rpcHandler{
+				"eth_call": func(params []json.RawMessage) (any, error) {
+					var call struct {
+						Data  string `json:"data"`
+						Input string `json:"input"`
+					}
+					if err := json.Unmarshal(params[0], &call); err != nil {
+						return nil, err
+					}
+					data := call.Input + call.Data
+					switch {
+					case strings.HasPrefix(data, "0x3644e515"): // DOMAIN_SEPARATOR()
+						if tt.separatorErr != nil {
+							return nil, tt.separatorErr
+						}
+						return domainSeparator.Hex(), nil
+					case strings.HasPrefix(data, "0x7ecebe00"): // nonces(address)
+						return hexutil.Encode(common.BigToHash(big.NewInt(tt.nonce)).Bytes()), nil
+					}
+					return nil, errors.New("execution reverted")
+				},
+			})
+			erc20, err := abi.JSON(strings.NewReader(erc20ABI))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ec.abi = map[string]abi.ABI{"erc20": erc20}
+			p := Params{TokenAddress: token, UserAddress: ownerAddr, Amount: amount, Deadline: deadline}
+			tt.sign(&p)
+
+			err = ec.VerifyPermitSignature(context.Background(), p)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
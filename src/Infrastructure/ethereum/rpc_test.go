@@ -0,0 +1,50 @@
+package ethereum
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// rpcHandler answers one JSON-RPC method with its result, or with an error when it returns one
+type rpcHandler func(params []json.RawMessage) (any, error)
+
+// newTestClient returns an EthereumClient talking to a fake node serving methods; an
+// unknown method fails the test.
+func newTestClient(t *testing.T, config Config, methods map[string]rpcHandler) *EthereumClient {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode rpc request: %v", err)
+			return
+		}
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		handler, ok := methods[req.Method]
+		if !ok {
+			t.Errorf("unexpected rpc method %s", req.Method)
+			resp["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]any{"code": -32000, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatalf("dial fake node: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return &EthereumClient{client: client, config: config, gasLimitMultiplier: DefaultGasLimitMultiplier}
+}
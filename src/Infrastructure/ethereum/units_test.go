@@ -0,0 +1,49 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestToUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		amount  string
+		want    string
+		wantErr error
+	}{
+		{name: "usdt", token: "USDT", amount: "1.5", want: "1500000"},
+		{name: "usdt smallest unit", token: "usdt", amount: "0.000001", want: "1"},
+		{name: "usdt below precision truncated", token: "USDT", amount: "2.0000019", want: "2000001"},
+		{name: "usdt dust only", token: "USDT", amount: "0.0000009", want: "0"},
+		{name: "18 decimals", token: "DAI", amount: "1.5", want: "1500000000000000000"},
+		{name: "18 decimals smallest unit", token: "DAI", amount: "0.000000000000000001", want: "1"},
+		{name: "18 decimals below precision truncated", token: "DAI", amount: "0.0000000000000000019", want: "1"},
+		{name: "eth", token: "ETH", amount: "250", want: "250000000000000000000"},
+		{name: "zero", token: "USDT", amount: "0", want: "0"},
+		{name: "negative", token: "USDT", amount: "-1", wantErr: ErrInvalidAmount},
+		// never seeded and no contract to ask
+		{name: "unknown token", token: "XYZ", amount: "1", wantErr: ErrUnsupportedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ec := &EthereumClient{decimals: map[string]uint8{"ETH": 18, "USDT": 6, "DAI": 18}}
+
+			got, err := ec.ToUnits(context.Background(), tt.token, decimal.RequireFromString(tt.amount))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.String() != tt.want {
+				t.Errorf("units = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
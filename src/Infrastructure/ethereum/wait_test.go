@@ -0,0 +1,135 @@
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestWaitMined(t *testing.T) {
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1)})
+	receipt := func(status string) rpcHandler {
+		return func([]json.RawMessage) (any, error) {
+			return map[string]any{
+				"transactionHash":   tx.Hash().Hex(),
+				"blockHash":         common.Hash{1}.Hex(),
+				"blockNumber":       "0xa",
+				"transactionIndex":  "0x0",
+				"status":            status,
+				"cumulativeGasUsed": "0x5208",
+				"gasUsed":           "0x5208",
+				"logs":              []any{},
+				"logsBloom":         "0x" + strings.Repeat("0", 512),
+				"type":              "0x0",
+			}, nil
+		}
+	}
+	notMined := func([]json.RawMessage) (any, error) { return nil, nil }
+	head := func(n string) rpcHandler {
+		return func([]json.RawMessage) (any, error) { return n, nil }
+	}
+
+	tests := []struct {
+		name          string
+		confirmations int
+		noTimeout     bool // leave MineTimeout unset, so only ctx stops the wait
+		cancelled     bool
+		methods       map[string]rpcHandler
+		wantErr       error
+		wantStatus    uint64
+	}{
+		{
+			name:       "mined",
+			methods:    map[string]rpcHandler{"eth_getTransactionReceipt": receipt("0x1")},
+			wantStatus: types.ReceiptStatusSuccessful,
+		},
+		{
+			name:       "reverted is a receipt, not an error",
+			methods:    map[string]rpcHandler{"eth_getTransactionReceipt": receipt("0x0")},
+			wantStatus: types.ReceiptStatusFailed,
+		},
+		{
+			name:    "never mined is pending, not failed",
+			methods: map[string]rpcHandler{"eth_getTransactionReceipt": notMined},
+			wantErr: ErrTxPending,
+		},
+		{
+			name:          "confirmed",
+			confirmations: 3,
+			methods: map[string]rpcHandler{
+				"eth_getTransactionReceipt": receipt("0x1"),
+				"eth_blockNumber":           head("0xc"),
+			},
+			wantStatus: types.ReceiptStatusSuccessful,
+		},
+		{
+			name:          "short of confirmations is pending",
+			confirmations: 3,
+			methods: map[string]rpcHandler{
+				"eth_getTransactionReceipt": receipt("0x1"),
+				"eth_blockNumber":           head("0xb"),
+			},
+			wantErr: ErrTxPending,
+		},
+		{
+			name:      "cancelled while never mined",
+			noTimeout: true,
+			cancelled: true,
+			methods:   map[string]rpcHandler{"eth_getTransactionReceipt": notMined},
+			wantErr:   ErrTxPending,
+		},
+		{
+			name:          "cancelled while confirming",
+			confirmations: 3,
+			noTimeout:     true,
+			cancelled:     true,
+			methods: map[string]rpcHandler{
+				"eth_getTransactionReceipt": receipt("0x1"),
+				"eth_blockNumber":           head("0xb"),
+			},
+			wantErr: ErrTxPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Network: "test", Confirmations: tt.confirmations, MineTimeout: 300 * time.Millisecond}
+			ctx := context.Background()
+			if tt.noTimeout {
+				cfg.MineTimeout = 0
+			}
+			if tt.cancelled {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, 300*time.Millisecond)
+				defer cancel()
+			}
+			ec := newTestClient(t, cfg, tt.methods)
+			var sent common.Hash
+			got, err := ec.waitMined(ctx, tx, func(h common.Hash) { sent = h })
+			if sent != tx.Hash() {
+				t.Errorf("onSent got %s, want %s", sent.Hex(), tx.Hash().Hex())
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if errors.Is(err, ErrMineTransaction) {
+					t.Errorf("an unknown outcome must not read as a failed tx: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
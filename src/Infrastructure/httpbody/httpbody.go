@@ -0,0 +1,66 @@
+// Package httpbody reads compressed exchange API responses. The exchange clients ask for
+// compression themselves, which turns off the transport's transparent gzip handling, so
+// they decode here; that way deflate is covered too.
+package httpbody
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AcceptEncoding is the Accept-Encoding the exchange clients send
+const AcceptEncoding = "gzip, deflate"
+
+// Read reads resp.Body, decoding it by its Content-Encoding, and marks resp uncompressed
+// the way the transport does, so later readers of resp don't decode twice. Unknown
+// encodings are returned as they are. The caller still closes the body.
+func Read(resp *http.Response) ([]byte, error) {
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case "deflate":
+		// deflate should be zlib wrapped, but some servers send raw deflate
+		br := bufio.NewReader(resp.Body)
+		if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, fmt.Errorf("deflate: %w", err)
+			}
+			defer zr.Close()
+			r = zr
+		} else {
+			fr := flate.NewReader(br)
+			defer fr.Close()
+			r = fr
+		}
+	default:
+		return io.ReadAll(resp.Body)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return b, nil
+}
+
+// isZlibHeader reports whether b starts a zlib stream: deflate method and a valid check
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
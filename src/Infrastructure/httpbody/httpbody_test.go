@@ -0,0 +1,75 @@
+package httpbody
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"testing"
+)
+
+const body = `{"markets":[{"symbol":"BTCUSDT"},{"symbol":"ETHUSDT"}]}`
+
+func compress(t *testing.T, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	gz := compress(t, func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil })
+	zl := compress(t, func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil })
+	raw := compress(t, func(w io.Writer) (io.WriteCloser, error) { return flate.NewWriter(w, flate.DefaultCompression) })
+	tests := []struct {
+		name        string
+		encoding    string
+		payload     []byte
+		wantDecoded bool
+		wantErr     bool
+	}{
+		{name: "plain", payload: []byte(body)},
+		{name: "gzip", encoding: "gzip", payload: gz, wantDecoded: true},
+		{name: "x-gzip, mixed case", encoding: " X-GZIP", payload: gz, wantDecoded: true},
+		{name: "zlib deflate", encoding: "deflate", payload: zl, wantDecoded: true},
+		{name: "raw deflate", encoding: "deflate", payload: raw, wantDecoded: true},
+		{name: "unknown encoding passed through", encoding: "identity", payload: []byte(body)},
+		{name: "corrupt gzip", encoding: "gzip", payload: []byte(body), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(tt.payload)), ContentLength: int64(len(tt.payload))}
+			if tt.encoding != "" {
+				resp.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			got, err := Read(resp)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if string(got) != body {
+				t.Errorf("body = %q, want %q", got, body)
+			}
+			// a decoded response reads as uncompressed to whoever looks at it next
+			if tt.wantDecoded && (resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed || resp.ContentLength != -1) {
+				t.Errorf("decoded response still marked compressed: %v, uncompressed %v, length %d",
+					resp.Header, resp.Uncompressed, resp.ContentLength)
+			}
+		})
+	}
+}
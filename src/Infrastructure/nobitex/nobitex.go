@@ -0,0 +1,448 @@
+// Package nobitex implements a strongly-typed HTTP client for the Nobitex REST API.
+//
+// Coverage: Implements the market data and trading endpoints we route through:
+// - All markets listing (via market stats)
+// - Order book depth
+// - Market order placement
+//
+// Notes:
+// - API responses carry a top-level {status, message, code} envelope next to the payload
+// - When status != "ok", this client returns an error enriched with the code/message
+// - Authenticated endpoints require an "Authorization: Token <token>" header
+// - Markets are addressed as "<src>-<dst>" in lower case (e.g. "btc-usdt")
+package nobitex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/MMN3003/mega/src/Infrastructure/redact"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/tracing"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Default HTTP timeouts tuned for server-side usage
+var (
+	DefaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// NewClient constructs a new API client. base should be like "https://api.nobitex.ir".
+func NewClient(baseUrl string, opts ...Option) (*Client, error) {
+	if baseUrl == "" {
+		return nil, errors.New("base url is required")
+	}
+
+	u, err := url.Parse(strings.TrimRight(baseUrl, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %w", err)
+	}
+
+	c := &Client{
+		BaseURL:   u,
+		HTTP:      DefaultHTTPClient,
+		UserAgent: "TraderBot/nobitex-go",
+		Logger:    log.Logger,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Option functional options
+type Option func(*Client)
+
+func WithAuthToken(token string) Option    { return func(c *Client) { c.AuthToken = token } }
+func WithHTTPClient(h *http.Client) Option { return func(c *Client) { c.HTTP = h } }
+func WithUserAgent(ua string) Option       { return func(c *Client) { c.UserAgent = ua } }
+func WithLogger(l zerolog.Logger) Option   { return func(c *Client) { c.Logger = l } }
+
+// WithStrictDecode logs a warning whenever a response does not match the expected schema
+func WithStrictDecode(strict bool) Option { return func(c *Client) { c.StrictDecode = strict } }
+
+// WithResponseLogging logs response bodies, with tokens, secrets and personal data masked
+func WithResponseLogging(enabled bool) Option { return func(c *Client) { c.LogResponses = enabled } }
+
+type Client struct {
+	BaseURL   *url.URL
+	HTTP      *http.Client
+	AuthToken string
+	UserAgent string
+	Logger    zerolog.Logger
+	// StrictDecode reports schema drift in responses, decoding stays lenient
+	StrictDecode bool
+	// LogResponses adds the redacted response body to the request log
+	LogResponses bool
+}
+
+// ResponseEnvelope is the status part shared by every Nobitex response.
+// The payload lives in sibling top-level fields, so it is decoded separately.
+type ResponseEnvelope struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// MarketStats is the 24h statistics block returned per market by /market/stats
+type MarketStats struct {
+	IsClosed  bool            `json:"isClosed"`
+	BestSell  decimal.Decimal `json:"bestSell"`
+	BestBuy   decimal.Decimal `json:"bestBuy"`
+	VolumeSrc decimal.Decimal `json:"volumeSrc"`
+	VolumeDst decimal.Decimal `json:"volumeDst"`
+	Latest    decimal.Decimal `json:"latest"`
+	DayLow    decimal.Decimal `json:"dayLow"`
+	DayHigh   decimal.Decimal `json:"dayHigh"`
+	DayOpen   decimal.Decimal `json:"dayOpen"`
+	DayClose  decimal.Decimal `json:"dayClose"`
+	DayChange decimal.Decimal `json:"dayChange"`
+}
+
+// Market is a tradable Nobitex pair, built from the stats map key and value
+type Market struct {
+	Symbol      string // "btc-usdt"
+	SrcCurrency string // "btc"
+	DstCurrency string // "usdt"
+	Stats       MarketStats
+}
+
+// OrderBookEntry represents a single price level in the order book.
+// Nobitex encodes levels as ["price", "amount"] string pairs.
+type OrderBookEntry struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+func (e *OrderBookEntry) UnmarshalJSON(b []byte) error {
+	var pair []decimal.Decimal
+	if err := json.Unmarshal(b, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid order book level: %s", string(b))
+	}
+	e.Price, e.Quantity = pair[0], pair[1]
+	return nil
+}
+
+// OrderBook represents the depth of a market
+type OrderBook struct {
+	LastUpdate int64            `json:"lastUpdate"`
+	Asks       []OrderBookEntry `json:"asks"`
+	Bids       []OrderBookEntry `json:"bids"`
+}
+
+// --- Market Data Endpoints ---
+
+// GetAllMarkets retrieves the list of all available markets
+func (c *Client) GetAllMarkets(ctx context.Context) ([]Market, error) {
+	result, err := doJSON[struct {
+		Stats map[string]MarketStats `json:"stats"`
+	}](c, ctx, http.MethodGet, "/market/stats", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	markets := make([]Market, 0, len(result.Stats))
+	for symbol, stats := range result.Stats {
+		src, dst, err := splitSymbol(symbol)
+		if err != nil {
+			continue
+		}
+		markets = append(markets, Market{
+			Symbol:      symbol,
+			SrcCurrency: src,
+			DstCurrency: dst,
+			Stats:       stats,
+		})
+	}
+	return markets, nil
+}
+
+// GetMarketDepth retrieves the order book depth for a specific market
+// symbol: The market symbol (e.g., "btc-usdt")
+func (c *Client) GetMarketDepth(ctx context.Context, symbol string) (*OrderBook, error) {
+	src, dst, err := splitSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	p := "/v3/orderbook/" + strings.ToUpper(src+dst)
+
+	result, err := doJSON[OrderBook](c, ctx, http.MethodGet, p, nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// --- Orders ---
+
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "buy"
+	OrderSideSell OrderSide = "sell"
+)
+
+type PlaceMarketOrderRequest struct {
+	Type        OrderSide       `json:"type"`        // "buy" or "sell"
+	Execution   string          `json:"execution"`   // "market"
+	SrcCurrency string          `json:"srcCurrency"` // e.g. "btc"
+	DstCurrency string          `json:"dstCurrency"` // e.g. "usdt"
+	Amount      decimal.Decimal `json:"amount"`      // amount of src currency
+}
+
+type Order struct {
+	ID            int64           `json:"id"`
+	Type          OrderSide       `json:"type"`
+	Execution     string          `json:"execution"`
+	SrcCurrency   string          `json:"srcCurrency"`
+	DstCurrency   string          `json:"dstCurrency"`
+	Price         string          `json:"price"`
+	Amount        decimal.Decimal `json:"amount"`
+	MatchedAmount decimal.Decimal `json:"matchedAmount"`
+	AveragePrice  decimal.Decimal `json:"averagePrice"`
+	Fee           decimal.Decimal `json:"fee"`
+	Status        string          `json:"status"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+// PlaceMarketOrder submits a market order for the given market symbol (e.g. "btc-usdt")
+func (c *Client) PlaceMarketOrder(ctx context.Context, symbol string, side OrderSide, amount decimal.Decimal) (*Order, error) {
+	src, dst, err := splitSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if side != OrderSideBuy && side != OrderSideSell {
+		return nil, errors.New("side must be 'buy' or 'sell'")
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return nil, errors.New("amount is required for market orders")
+	}
+
+	orderRequest := PlaceMarketOrderRequest{
+		Type:        side,
+		Execution:   "market",
+		SrcCurrency: src,
+		DstCurrency: dst,
+		Amount:      amount,
+	}
+
+	result, err := doJSON[struct {
+		Order Order `json:"order"`
+	}](c, ctx, http.MethodPost, "/market/orders/add", nil, orderRequest, "application/json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to place market order: %w", err)
+	}
+	return &result.Order, nil
+}
+
+func (c *Client) do(
+	ctx context.Context,
+	method, p string,
+	q url.Values,
+	body any,
+	out any,
+	contentType string,
+) (err error) {
+	u := *c.BaseURL
+	u.Path = path.Join(u.Path, p)
+	u.RawQuery = q.Encode()
+
+	ctx, span := tracing.StartClient(ctx, "nobitex "+method,
+		attribute.String("http.request.method", method),
+		attribute.String("url.path", u.Path),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// --- Build request body ---
+	var r io.Reader
+	if body != nil {
+		switch b := body.(type) {
+		case io.Reader:
+			r = b
+		case []byte:
+			r = bytes.NewReader(b)
+		default:
+			buf, err := json.Marshal(b)
+			if err != nil {
+				return fmt.Errorf("marshal body: %w", err)
+			}
+			r = bytes.NewReader(buf)
+			if contentType == "" {
+				contentType = "application/json"
+			}
+		}
+	}
+
+	// --- Build request ---
+	// don't start a call the caller already gave up on
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	// Set required headers
+	if c.AuthToken != "" {
+		req.Header.Set("Authorization", "Token "+c.AuthToken)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	// --- Execute request ---
+	start := time.Now()
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		// a cancelled caller is not an exchange failure; hand back ctx.Err() as is
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		metrics.ObserveExchangeRequest("nobitex", time.Since(start), 0, err)
+		return fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+	metrics.ObserveExchangeRequest("nobitex", time.Since(start), resp.StatusCode, nil)
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return fmt.Errorf("read body: %w", err)
+	}
+	if dst, ok := ctx.Value(rawResponseKey{}).(*[]byte); ok {
+		*dst = b
+	}
+
+	// --- Logging response ---
+	ev := c.Logger.Info().
+		Str("request_id", logger.RequestID(ctx)).
+		Str("method", method).
+		Str("url", u.String()).
+		Int("status", resp.StatusCode).
+		Str("duration", time.Since(start).String())
+	if c.LogResponses {
+		ev = ev.RawJSON("response", redact.JSON(b, 2048))
+	}
+	ev.Msg("http response")
+
+	// --- Status check ---
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http error %d: %s", resp.StatusCode, string(b))
+	}
+
+	// --- Decode output ---
+	if out == nil {
+		return nil
+	}
+
+	// Decode envelope first to check status
+	var env ResponseEnvelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		return fmt.Errorf("unmarshal envelope: %w", err)
+	}
+	if err := apiError(env); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("unmarshal result: %w", err)
+	}
+	if c.StrictDecode {
+		c.checkSchema(u.Path, b, out)
+	}
+	return nil
+}
+
+// doJSON decodes the response body into T after the envelope check
+func doJSON[T any](
+	c *Client,
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	body any,
+	contentType string,
+) (T, error) {
+	var out T
+	err := c.do(ctx, method, path, query, body, &out, contentType)
+	return out, err
+}
+
+// --- Helpers ---
+
+type rawResponseKey struct{}
+
+// WithRawResponse returns a context under which the client copies the raw body of
+// every response into dst, for debugging what the venue actually returned.
+func WithRawResponse(ctx context.Context, dst *[]byte) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, dst)
+}
+
+func apiError(env ResponseEnvelope) error {
+	if strings.EqualFold(env.Status, "ok") {
+		return nil
+	}
+	return fmt.Errorf("nobitex api error: status=%s code=%s message=%s", env.Status, env.Code, env.Message)
+}
+
+// splitSymbol splits "btc-usdt" into ("btc", "usdt")
+func splitSymbol(symbol string) (string, string, error) {
+	parts := strings.Split(strings.ToLower(symbol), "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid nobitex market symbol: %q", symbol)
+	}
+	return parts[0], parts[1], nil
+}
+
+// checkSchema re-decodes b strictly into a fresh value of out's type and logs a warning
+// when the nobitex response carries fields or types the client does not expect. The lenient
+// decode has already succeeded, so drift is reported and never fails the call.
+func (c *Client) checkSchema(p string, b []byte, out any) {
+	t := reflect.TypeOf(out)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return
+	}
+	// the envelope fields sit next to the payload, they are not drift
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err == nil {
+		delete(fields, "status")
+		delete(fields, "message")
+		delete(fields, "code")
+		if stripped, err := json.Marshal(fields); err == nil {
+			b = stripped
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(reflect.New(t.Elem()).Interface()); err != nil {
+		c.Logger.Warn().
+			Str("path", p).
+			Err(err).
+			RawJSON("response", redact.JSON(b, 2048)).
+			Msg("nobitex response schema drift")
+	}
+}
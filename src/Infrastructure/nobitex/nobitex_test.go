@@ -0,0 +1,288 @@
+package nobitex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// newTestClient returns a client of a fake Nobitex answering with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, append([]Option{WithAuthToken("tok"), WithHTTPClient(srv.Client()), WithLogger(zerolog.Nop())}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		wantErr bool
+	}{
+		{name: "valid", baseURL: "https://api.nobitex.ir"},
+		{name: "trailing slash", baseURL: "https://api.nobitex.ir/"},
+		{name: "empty", wantErr: true},
+		{name: "unparsable", baseURL: "://nobitex", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClient(tt.baseURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetAllMarkets(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    map[string]string // symbol -> latest
+		wantErr bool
+	}{
+		{
+			name:   "markets",
+			status: http.StatusOK,
+			body:   `{"status":"ok","stats":{"btc-usdt":{"latest":"65000.5"},"usdt-rls":{"latest":"600000"},"broken":{}}}`,
+			want:   map[string]string{"btc-usdt": "65000.5", "usdt-rls": "600000"},
+		},
+		{name: "api error", status: http.StatusOK, body: `{"status":"failed","code":"InvalidSymbol","message":"bad"}`, wantErr: true},
+		{name: "http error", status: http.StatusBadGateway, body: `upstream down`, wantErr: true},
+		{name: "malformed", status: http.StatusOK, body: `{"status":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/market/stats" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			})
+
+			markets, err := c.GetAllMarkets(context.Background())
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(markets) != len(tt.want) {
+				t.Fatalf("got %d markets, want %d", len(markets), len(tt.want))
+			}
+			for _, m := range markets {
+				if latest := tt.want[m.Symbol]; !m.Stats.Latest.Equal(decimal.RequireFromString(latest)) {
+					t.Errorf("%s latest = %s, want %s", m.Symbol, m.Stats.Latest, latest)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMarketDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbol   string
+		body     string
+		wantPath string
+		wantAsk  string
+		wantErr  bool
+	}{
+		{
+			name:     "order book",
+			symbol:   "btc-usdt",
+			body:     `{"status":"ok","lastUpdate":1,"asks":[["65001","0.5"]],"bids":[["64999","1.2"]]}`,
+			wantPath: "/v3/orderbook/BTCUSDT",
+			wantAsk:  "65001",
+		},
+		{name: "bad level", symbol: "btc-usdt", body: `{"status":"ok","asks":[["65001"]]}`, wantPath: "/v3/orderbook/BTCUSDT", wantErr: true},
+		{name: "bad symbol", symbol: "btcusdt", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath {
+					t.Errorf("path = %s, want %s", r.URL.Path, tt.wantPath)
+				}
+				_, _ = io.WriteString(w, tt.body)
+			})
+
+			book, err := c.GetMarketDepth(context.Background(), tt.symbol)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !book.Asks[0].Price.Equal(decimal.RequireFromString(tt.wantAsk)) {
+				t.Errorf("best ask = %s, want %s", book.Asks[0].Price, tt.wantAsk)
+			}
+		})
+	}
+}
+
+func TestPlaceMarketOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		symbol  string
+		side    OrderSide
+		amount  string
+		wantErr bool
+		called  bool
+	}{
+		{name: "buy", symbol: "btc-usdt", side: OrderSideBuy, amount: "0.01", called: true},
+		{name: "sell", symbol: "BTC-USDT", side: OrderSideSell, amount: "0.01", called: true},
+		{name: "bad side", symbol: "btc-usdt", side: "hold", amount: "0.01", wantErr: true},
+		{name: "zero amount", symbol: "btc-usdt", side: OrderSideBuy, amount: "0", wantErr: true},
+		{name: "bad symbol", symbol: "btc", side: OrderSideBuy, amount: "0.01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				if r.Method != http.MethodPost || r.URL.Path != "/market/orders/add" {
+					t.Errorf("%s %s", r.Method, r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); auth != "Token tok" {
+					t.Errorf("Authorization = %q", auth)
+				}
+				var req PlaceMarketOrderRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				if req.Type != tt.side || req.Execution != "market" || req.SrcCurrency != "btc" || req.DstCurrency != "usdt" {
+					t.Errorf("request = %+v", req)
+				}
+				_, _ = io.WriteString(w, `{"status":"ok","order":{"id":42,"status":"Done"}}`)
+			})
+
+			order, err := c.PlaceMarketOrder(context.Background(), tt.symbol, tt.side, decimal.RequireFromString(tt.amount))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if called != tt.called {
+				t.Errorf("venue called = %v, want %v", called, tt.called)
+			}
+			if err == nil && order.ID != 42 {
+				t.Errorf("order id = %d, want 42", order.ID)
+			}
+		})
+	}
+}
+
+func TestStrictDecode(t *testing.T) {
+	const book = `{"status":"ok","lastUpdate":1,"asks":[["100","1"]],"bids":[["99","2"]]`
+	tests := []struct {
+		name     string
+		strict   bool
+		body     string
+		wantWarn bool
+	}{
+		// the envelope's status sits next to the payload and is not drift
+		{name: "expected schema", strict: true, body: book + `}`},
+		{name: "drift in strict mode", strict: true, body: book + `,"lastTradePrice":"100"}`, wantWarn: true},
+		{name: "drift in lenient mode", body: book + `,"lastTradePrice":"100"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, tt.body)
+			}, WithLogger(zerolog.New(&logs)), WithStrictDecode(tt.strict))
+
+			got, err := c.GetMarketDepth(context.Background(), "BTC-USDT")
+
+			// drift is only reported, the call still succeeds
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Asks) != 1 || len(got.Bids) != 1 {
+				t.Errorf("order book = %+v", got)
+			}
+			warned := strings.Contains(logs.String(), `"level":"warn"`)
+			if warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v, logs: %s", warned, tt.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+// TestCancellation calls a Nobitex that never answers; the call must end with the caller's
+// context, long before any client timeout
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		wantErr  error
+		wantSent bool
+	}{
+		{
+			name: "cancelled before the call",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "cancelled in flight",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: context.Canceled, wantSent: true,
+		},
+		{
+			name: "deadline in flight",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded, wantSent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent atomic.Bool
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				sent.Store(true)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			})
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+
+			_, err := c.GetAllMarkets(ctx)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("returned after %s", elapsed)
+			}
+			if sent.Load() != tt.wantSent {
+				t.Errorf("request sent = %v, want %v", sent.Load(), tt.wantSent)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 // Package ompfinex implements a strongly-typed HTTP client for the OMPFinex REST API.
 //
 // Coverage: Implements the major resources exposed in https://docs.ompfinex.com/
-// including auth, user, markets, orders, wallets, deposits/withdrawals (rial & crypto),
+// including auth, user, markets, candles, trades, orders, wallets, deposits/withdrawals (rial & crypto),
 // verification (KYC/bank card), alerts, sessions, 2FA, currencies, and favorites.
 //
 // Notes:
@@ -17,7 +17,7 @@
 //   - Circuit breaker / rate-limiting client side
 //   - Structured metrics and tracing hooks
 //   - Timeouts per operation and context propagation
-//   - Token refresh flow if/when introduced by the API
+//   - Token refresh is opt-in via WithTokenRefresher: a 401 refreshes the token once and replays the request
 package ompfinex
 
 import (
@@ -31,12 +31,20 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/MMN3003/mega/src/Infrastructure/httpbody"
+	"github.com/MMN3003/mega/src/Infrastructure/redact"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/tracing"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Default HTTP timeouts tuned for server-side usage.
@@ -46,6 +54,9 @@ var (
 
 // NewClient constructs a new API client. base should be like "https://api.ompfinex.com".
 func NewClient(base string, opts ...Option) (*Client, error) {
+	if base == "" {
+		return nil, errors.New("base url is required")
+	}
 	u, err := url.Parse(strings.TrimRight(base, "/"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid base url: %w", err)
@@ -69,12 +80,63 @@ func WithHTTPClient(h *http.Client) Option { return func(c *Client) { c.HTTP = h
 func WithAuthToken(token string) Option    { return func(c *Client) { c.AuthToken = token } }
 func WithUserAgent(ua string) Option       { return func(c *Client) { c.UserAgent = ua } }
 
+// WithStrictDecode logs a warning whenever a response does not match the expected schema
+func WithStrictDecode(strict bool) Option { return func(c *Client) { c.StrictDecode = strict } }
+
+// WithResponseLogging logs response bodies, with tokens, secrets and personal data masked
+func WithResponseLogging(enabled bool) Option { return func(c *Client) { c.LogResponses = enabled } }
+
+// RequestInterceptor sees every request right before it is sent, after the client set its
+// headers, e.g. to add headers or a signature; an error aborts the call without sending.
+type RequestInterceptor func(*http.Request) error
+
+// ResponseInterceptor sees every response; its body is already read and can be read again.
+// An error fails the call.
+type ResponseInterceptor func(*http.Response) error
+
+// WithRequestInterceptor adds f to the interceptors run, in the order added, on every request
+func WithRequestInterceptor(f RequestInterceptor) Option {
+	return func(c *Client) {
+		if f != nil {
+			c.requestInterceptors = append(c.requestInterceptors, f)
+		}
+	}
+}
+
+// WithResponseInterceptor adds f to the interceptors run, in the order added, on every response
+func WithResponseInterceptor(f ResponseInterceptor) Option {
+	return func(c *Client) {
+		if f != nil {
+			c.responseInterceptors = append(c.responseInterceptors, f)
+		}
+	}
+}
+
+// TokenRefresher obtains a fresh auth token, e.g. by signing in again.
+type TokenRefresher func(ctx context.Context) (string, error)
+
+// WithTokenRefresher makes the client refresh its token and replay the request once
+// when the API answers 401, so long-running loops survive a token expiry.
+func WithTokenRefresher(f TokenRefresher) Option {
+	return func(c *Client) { c.refreshToken = f }
+}
+
 type Client struct {
 	BaseURL   *url.URL
 	HTTP      *http.Client
 	AuthToken string
 	UserAgent string
 	Logger    zerolog.Logger // structured logger
+	// StrictDecode reports schema drift in responses, decoding stays lenient
+	StrictDecode bool
+	// LogResponses adds the redacted response body to the request log
+	LogResponses bool
+
+	refreshToken TokenRefresher
+	refreshMu    sync.Mutex
+
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
 }
 
 // WithLogger allows plugging in structured logger
@@ -90,35 +152,97 @@ func (c *Client) do(
 	body any,
 	out any,
 	contentType string,
-) error {
+) (err error) {
 	u := *c.BaseURL
 	u.Path = path.Join(u.Path, p)
 	u.RawQuery = q.Encode()
 
+	ctx, span := tracing.StartClient(ctx, "ompfinex "+method,
+		attribute.String("http.request.method", method),
+		attribute.String("url.path", u.Path),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	// --- Build request body ---
-	var r io.Reader
+	// buffered so the request can be replayed after a token refresh
+	var payload []byte
 	if body != nil {
 		switch b := body.(type) {
 		case io.Reader:
-			r = b
+			buf, err := io.ReadAll(b)
+			if err != nil {
+				return fmt.Errorf("read body: %w", err)
+			}
+			payload = buf
 		case []byte:
-			r = bytes.NewReader(b)
+			payload = b
 		default:
 			buf, err := json.Marshal(b)
 			if err != nil {
 				return fmt.Errorf("marshal body: %w", err)
 			}
-			r = bytes.NewReader(buf)
+			payload = buf
 			if contentType == "" {
 				contentType = "application/json"
 			}
 		}
 	}
 
+	token := c.AuthToken
+	status, b, err := c.send(ctx, method, u.String(), payload, contentType, token)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusUnauthorized && c.refreshToken != nil {
+		if err := c.refreshAuth(ctx, token); err != nil {
+			return fmt.Errorf("refresh token: %w", err)
+		}
+		if status, b, err = c.send(ctx, method, u.String(), payload, contentType, c.AuthToken); err != nil {
+			return err
+		}
+	}
+
+	// --- Status check ---
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("http error %d: %s", status, string(b))
+	}
+
+	// --- Decode output ---
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	if c.StrictDecode {
+		c.checkSchema(u.Path, b, out)
+	}
+
+	// --- Envelope check ---
+	switch v := out.(type) {
+	case *ResponseEnvelope[json.RawMessage]:
+		if err := apiError(v.Status, v.Message, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send executes a single request with the given token and returns the status and body.
+func (c *Client) send(ctx context.Context, method, rawURL string, payload []byte, contentType, token string) (int, []byte, error) {
+	var r io.Reader
+	if payload != nil {
+		r = bytes.NewReader(payload)
+	}
+
 	// --- Build request ---
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	// don't start a call the caller already gave up on
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, r)
 	if err != nil {
-		return fmt.Errorf("new request: %w", err)
+		return 0, nil, fmt.Errorf("new request: %w", err)
 	}
 	if c.UserAgent != "" {
 		req.Header.Set("User-Agent", c.UserAgent)
@@ -126,50 +250,75 @@ func (c *Client) do(
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
-	c.setAuth(req)
+	// asking explicitly turns off the transport's transparent gzip; httpbody.Read decodes
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, intercept := range c.requestInterceptors {
+		if err := intercept(req); err != nil {
+			return 0, nil, fmt.Errorf("request interceptor: %w", err)
+		}
+	}
 
 	// --- Execute request ---
 	start := time.Now()
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
-		return fmt.Errorf("http do: %w", err)
+		// a cancelled caller is not an exchange failure; hand back ctx.Err() as is
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, nil, ctxErr
+		}
+		metrics.ObserveExchangeRequest("ompfinex", time.Since(start), 0, err)
+		return 0, nil, fmt.Errorf("http do: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.ObserveExchangeRequest("ompfinex", time.Since(start), resp.StatusCode, nil)
 
-	b, err := io.ReadAll(resp.Body)
+	b, err := httpbody.Read(resp)
 	if err != nil {
-		return fmt.Errorf("read body: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, nil, ctxErr
+		}
+		return 0, nil, fmt.Errorf("read body: %w", err)
+	}
+	if dst, ok := ctx.Value(rawResponseKey{}).(*[]byte); ok {
+		*dst = b
+	}
+	for _, intercept := range c.responseInterceptors {
+		resp.Body = io.NopCloser(bytes.NewReader(b))
+		if err := intercept(resp); err != nil {
+			return 0, nil, fmt.Errorf("response interceptor: %w", err)
+		}
 	}
 
 	// --- Logging response ---
-	c.Logger.Info().
+	ev := c.Logger.Info().
+		Str("request_id", logger.RequestID(ctx)).
 		Str("method", method).
-		Str("url", u.String()).
+		Str("url", rawURL).
 		Int("status", resp.StatusCode).
-		Str("duration", time.Since(start).String()).
-		RawJSON("response", truncateJSON(b, 2048)). // safe logging
-		Msg("http response")
-
-	// --- Status check ---
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("http error %d: %s", resp.StatusCode, string(b))
+		Str("duration", time.Since(start).String())
+	if c.LogResponses {
+		ev = ev.RawJSON("response", redact.JSON(b, 2048))
 	}
+	ev.Msg("http response")
 
-	// --- Decode output ---
-	if out == nil {
+	return resp.StatusCode, b, nil
+}
+
+// refreshAuth swaps in a new token unless a concurrent request already replaced expired.
+func (c *Client) refreshAuth(ctx context.Context, expired string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.AuthToken != expired {
 		return nil
 	}
-	if err := json.Unmarshal(b, out); err != nil {
-		return fmt.Errorf("unmarshal response: %w", err)
-	}
-
-	// --- Envelope check ---
-	switch v := out.(type) {
-	case *ResponseEnvelope[json.RawMessage]:
-		if err := apiError(v.Status, v.Message, b); err != nil {
-			return err
-		}
+	token, err := c.refreshToken(ctx)
+	if err != nil {
+		return err
 	}
+	c.AuthToken = token
 	return nil
 }
 
@@ -186,11 +335,13 @@ func apiError(status, message string, body []byte) error {
 }
 
 // --- Helpers ---
-func truncateJSON(b []byte, max int) []byte {
-	if len(b) > max {
-		return b[:max]
-	}
-	return b
+
+type rawResponseKey struct{}
+
+// WithRawResponse returns a context under which the client copies the raw body of
+// every response into dst, for debugging what the venue actually returned.
+func WithRawResponse(ctx context.Context, dst *[]byte) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, dst)
 }
 
 func truncateString(s string, max int) string {
@@ -200,14 +351,6 @@ func truncateString(s string, max int) string {
 	return s
 }
 
-// setAuth sets Authorization header if token present.
-func (c *Client) setAuth(req *http.Request) {
-	if c.AuthToken == "" {
-		return
-	}
-	req.Header.Set("Authorization", "Bearer "+c.AuthToken)
-}
-
 // --- Common response envelopes & pagination ---
 
 type ResponseEnvelope[T any] struct {
@@ -468,6 +611,71 @@ func (c *Client) ListFavoriteMarkets(ctx context.Context) ([]Market, error) {
 	return doJSON[[]Market](c, ctx, http.MethodGet, "/v1/market/favorite", nil, nil, "")
 }
 
+// --- Candles ---
+
+// candleResolutions are the chart resolutions the API accepts: minutes, or D(ay) and W(eek)
+var candleResolutions = map[string]bool{
+	"1": true, "5": true, "15": true, "30": true, "60": true, "240": true, "D": true, "W": true,
+}
+
+// Candle is one OHLC bar; Time is when the bar opens.
+type Candle struct {
+	Time   time.Time
+	Open   decimal.Decimal
+	High   decimal.Decimal
+	Low    decimal.Decimal
+	Close  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+// chartHistory is the TradingView UDF shape of the chart endpoint: one array per field,
+// index i of every array describing bar i.
+type chartHistory struct {
+	Time   []int64           `json:"t"`
+	Open   []decimal.Decimal `json:"o"`
+	High   []decimal.Decimal `json:"h"`
+	Low    []decimal.Decimal `json:"l"`
+	Close  []decimal.Decimal `json:"c"`
+	Volume []decimal.Decimal `json:"v"`
+}
+
+// GetCandles returns the bars of a market between from and to, oldest first. resolution
+// is minutes ("1", "5", "15", "30", "60", "240") or "D" or "W".
+func (c *Client) GetCandles(ctx context.Context, marketID int64, resolution string, from, to time.Time) ([]Candle, error) {
+	if !candleResolutions[resolution] {
+		return nil, fmt.Errorf("unsupported candle resolution %q", resolution)
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+	q := url.Values{
+		"resolution": {resolution},
+		"from":       {fmt.Sprint(from.Unix())},
+		"to":         {fmt.Sprint(to.Unix())},
+	}
+	p := fmt.Sprintf("/v1/market/%d/chart", marketID)
+	h, err := doJSON[chartHistory](c, ctx, http.MethodGet, p, q, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	n := len(h.Time)
+	if len(h.Open) != n || len(h.High) != n || len(h.Low) != n || len(h.Close) != n || len(h.Volume) != n {
+		return nil, fmt.Errorf("ompfinex chart: mismatched series lengths for market %d", marketID)
+	}
+	candles := make([]Candle, n)
+	for i := range candles {
+		candles[i] = Candle{
+			Time:   time.Unix(h.Time[i], 0).UTC(),
+			Open:   h.Open[i],
+			High:   h.High[i],
+			Low:    h.Low[i],
+			Close:  h.Close[i],
+			Volume: h.Volume[i],
+		}
+	}
+	return candles, nil
+}
+
 // --- Orders ---
 
 type OrderSide string
@@ -480,8 +688,9 @@ const (
 type OrderType string
 
 const (
-	OrderLimit  OrderType = "limit"
-	OrderMarket OrderType = "market"
+	OrderLimit     OrderType = "limit"
+	OrderMarket    OrderType = "market"
+	OrderStopLimit OrderType = "stop_limit"
 )
 
 type PlaceOrderRequest struct {
@@ -490,6 +699,8 @@ type PlaceOrderRequest struct {
 	Type     OrderType        `json:"type"`
 	Price    *decimal.Decimal `json:"price,omitempty"`
 	Amount   decimal.Decimal  `json:"amount"`
+	// StopPrice triggers a stop_limit order
+	StopPrice *decimal.Decimal `json:"stop_price,omitempty"`
 }
 
 type Order struct {
@@ -781,6 +992,26 @@ func (c *Client) GetMarketOrders(ctx context.Context, marketID int64) ([]MarketO
 	return doJSON[[]MarketOrder](c, ctx, http.MethodGet, path, nil, nil, "")
 }
 
+// Trade is one executed trade of a market
+type Trade struct {
+	Price  decimal.Decimal `json:"price"`
+	Amount decimal.Decimal `json:"amount"`
+	// Side is the taker's side
+	Side      OrderSide `json:"type"`
+	Timestamp string    `json:"created_at"`
+}
+
+// GetRecentTrades returns the latest trades of a market, newest first; a non-positive
+// limit leaves the page size to the API.
+func (c *Client) GetRecentTrades(ctx context.Context, marketID int64, limit int) ([]Trade, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", fmt.Sprint(limit))
+	}
+	p := fmt.Sprintf("/v1/market/%d/trade", marketID)
+	return doJSON[[]Trade](c, ctx, http.MethodGet, p, q, nil, "")
+}
+
 type OrderBookEntry struct {
 	Amount decimal.Decimal `json:"amount"`
 	Price  decimal.Decimal `json:"price"`
@@ -817,3 +1048,22 @@ func String(s string) *string { return &s }
 
 // Int64 returns a pointer to i.
 func Int64(i int64) *int64 { return &i }
+
+// checkSchema re-decodes b strictly into a fresh value of out's type and logs a warning
+// when the ompfinex response carries fields or types the client does not expect. The lenient
+// decode has already succeeded, so drift is reported and never fails the call.
+func (c *Client) checkSchema(p string, b []byte, out any) {
+	t := reflect.TypeOf(out)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(reflect.New(t.Elem()).Interface()); err != nil {
+		c.Logger.Warn().
+			Str("path", p).
+			Err(err).
+			RawJSON("response", redact.JSON(b, 2048)).
+			Msg("ompfinex response schema drift")
+	}
+}
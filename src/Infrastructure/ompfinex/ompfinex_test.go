@@ -0,0 +1,241 @@
+package ompfinex
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// newTestClient returns a client of a fake OMPFinex answering with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	opts = append([]Option{WithHTTPClient(srv.Client()), WithLogger(zerolog.Nop())}, opts...)
+	c, err := NewClient(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+// TestTokenExpiresBetweenPages pages through the user orders with a token the API stops
+// accepting after the first page
+func TestTokenExpiresBetweenPages(t *testing.T) {
+	tests := []struct {
+		name          string
+		refresher     bool
+		refreshErr    error
+		newToken      string
+		wantPages     int
+		wantErr       string
+		wantRefreshes int
+	}{
+		{name: "refreshed and continued", refresher: true, newToken: "fresh", wantPages: 3, wantRefreshes: 1},
+		{name: "no refresher", wantPages: 1, wantErr: "http error 401"},
+		{name: "refresh fails", refresher: true, refreshErr: errors.New("sign in failed"), wantPages: 1, wantErr: "refresh token: sign in failed", wantRefreshes: 1},
+		// the replay is not retried again, a bad refresher can't loop forever
+		{name: "refreshed token rejected", refresher: true, newToken: "also-expired", wantPages: 1, wantErr: "http error 401", wantRefreshes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := 0
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+				// the first token expires once a page was served with it
+				if (token == "initial" && served > 0) || (token != "initial" && token != "fresh") {
+					http.Error(w, `{"status":"FAILED","message":"token expired"}`, http.StatusUnauthorized)
+					return
+				}
+				served++
+				fmt.Fprintf(w, `{"status":"OK","data":[{"id":%s}],"pagination":{"page":%s,"total_pages":3}}`,
+					r.URL.Query().Get("page"), r.URL.Query().Get("page"))
+			}
+			refreshes := 0
+			opts := []Option{WithAuthToken("initial")}
+			if tt.refresher {
+				opts = append(opts, WithTokenRefresher(func(ctx context.Context) (string, error) {
+					refreshes++
+					return tt.newToken, tt.refreshErr
+				}))
+			}
+			c := newTestClient(t, handler, opts...)
+
+			var (
+				pages int
+				err   error
+			)
+			for page := 1; page <= 3; page++ {
+				var orders []Order
+				if orders, _, err = c.ListUserOrders(context.Background(), nil, page, 10); err != nil {
+					break
+				}
+				if len(orders) != 1 || orders[0].ID != int64(page) {
+					t.Fatalf("page %d orders = %+v", page, orders)
+				}
+				pages++
+			}
+
+			if pages != tt.wantPages {
+				t.Errorf("pages = %d, want %d", pages, tt.wantPages)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+			if refreshes != tt.wantRefreshes {
+				t.Errorf("refreshes = %d, want %d", refreshes, tt.wantRefreshes)
+			}
+		})
+	}
+}
+
+// TestResponseLogging signs in and checks the auth token never reaches the request log
+func TestResponseLogging(t *testing.T) {
+	const token = "tok-8f1c2e"
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantBody bool
+	}{
+		{name: "default leaves bodies out"},
+		{name: "enabled logs redacted bodies", opts: []Option{WithResponseLogging(true)}, wantBody: true},
+		{name: "disabled", opts: []Option{WithResponseLogging(false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs strings.Builder
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"status":"OK","token":%q,"data":{"uid":42,"email":"a@b.c"}}`, token)
+			}, append(tt.opts, WithLogger(zerolog.New(&logs)))...)
+
+			data, err := c.SignIn(context.Background(), SignInRequest{Email: "a@b.c", Password: "pw"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.UID != 42 || c.AuthToken != token {
+				t.Errorf("uid = %d, token = %q", data.UID, c.AuthToken)
+			}
+			if strings.Contains(logs.String(), token) {
+				t.Errorf("token leaked into the logs: %s", logs.String())
+			}
+			if got := strings.Contains(logs.String(), `"uid":42`); got != tt.wantBody {
+				t.Errorf("body logged = %v, want %v, logs: %s", got, tt.wantBody, logs.String())
+			}
+		})
+	}
+}
+
+// TestGzipResponse serves the market list compressed, as OMPFinex does for large payloads
+func TestGzipResponse(t *testing.T) {
+	const markets = `{"status":"OK","data":[{"id":12,"name":"BTCUSDT"},{"id":13,"name":"ETHUSDT"}]}`
+	tests := []struct {
+		name string
+		gzip bool
+	}{
+		{name: "gzip encoded", gzip: true},
+		{name: "uncompressed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acceptEncoding string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding = r.Header.Get("Accept-Encoding")
+				if !tt.gzip {
+					_, _ = io.WriteString(w, markets)
+					return
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				zw := gzip.NewWriter(w)
+				_, _ = io.WriteString(zw, markets)
+				_ = zw.Close()
+			})
+
+			got, err := c.ListMarkets(context.Background())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(acceptEncoding, "gzip") {
+				t.Errorf("Accept-Encoding = %q, want gzip", acceptEncoding)
+			}
+			if len(got) != 2 || got[0].ID != 12 || got[1].ID != 13 {
+				t.Errorf("markets = %+v", got)
+			}
+		})
+	}
+}
+
+// TestCancellation calls a OMPFinex that never answers; the call must end with the caller's
+// context, long before any client timeout
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		wantErr  error
+		wantSent bool
+	}{
+		{
+			name: "cancelled before the call",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "cancelled in flight",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: context.Canceled, wantSent: true,
+		},
+		{
+			name: "deadline in flight",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded, wantSent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent atomic.Bool
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				sent.Store(true)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			})
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+
+			_, err := c.ListMarkets(ctx)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("returned after %s", elapsed)
+			}
+			if sent.Load() != tt.wantSent {
+				t.Errorf("request sent = %v, want %v", sent.Load(), tt.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		max  int
+		want string
+	}{
+		{name: "nothing sensitive", body: `{"uid":7,"price":"101.5"}`, want: `{"price":"101.5","uid":7}`},
+		{name: "top-level token", body: `{"status":"success","token":"abc"}`, want: `{"status":"success","token":"REDACTED"}`},
+		{
+			name: "nested keys matched by part",
+			body: `{"data":{"access_token":"abc","national_id":"0012345678","card_number":"6037","email":"a@b.c"}}`,
+			want: `{"data":{"access_token":"REDACTED","card_number":"REDACTED","email":"a@b.c","national_id":"REDACTED"}}`,
+		},
+		{name: "case insensitive", body: `{"API_Secret":"abc","QR_Code":"png"}`, want: `{"API_Secret":"REDACTED","QR_Code":"REDACTED"}`},
+		{name: "objects in arrays", body: `[{"password":"p"},{"amount":1}]`, want: `[{"password":"REDACTED"},{"amount":1}]`},
+		{name: "sensitive object masked whole", body: `{"card":{"number":"6037","cvv2":"123"}}`, want: `{"card":"REDACTED"}`},
+		{name: "numbers kept exact", body: `{"amount":0.123456789012345678901}`, want: `{"amount":0.123456789012345678901}`},
+		{name: "non-JSON", body: `<html>bad gateway</html>`, want: `"non-JSON body omitted (24 bytes)"`},
+		{name: "truncated", body: `{"a":"xxxxxxxxxx"}`, max: 8, want: `"{\"a\":\"xx..."`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := JSON([]byte(tt.body), tt.max)
+
+			if string(got) != tt.want {
+				t.Errorf("JSON = %s, want %s", got, tt.want)
+			}
+			// zerolog's RawJSON needs valid JSON
+			if !json.Valid(got) {
+				t.Errorf("JSON = %s is not valid JSON", got)
+			}
+		})
+	}
+}
+
+func TestJSONNeverLeaksSecrets(t *testing.T) {
+	const secret = "s3cr3t-value"
+	for _, key := range sensitiveKeyParts {
+		t.Run(key, func(t *testing.T) {
+			got := JSON([]byte(`{"data":[{"`+key+`":"`+secret+`"}]}`), 2048)
+
+			if strings.Contains(string(got), secret) {
+				t.Errorf("JSON = %s leaks the %s value", got, key)
+			}
+		})
+	}
+}
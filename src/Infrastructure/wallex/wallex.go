@@ -3,6 +3,7 @@
 // Coverage: Implements market data endpoints including:
 // - All markets listing
 // - Order book depth
+// - Klines (chart history) and 24h market stats
 //
 // Notes:
 // - API responses follow a {result, message, success} envelope pattern
@@ -21,11 +22,18 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"reflect"
 	"time"
 
+	"github.com/MMN3003/mega/src/Infrastructure/httpbody"
+	"github.com/MMN3003/mega/src/Infrastructure/redact"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/tracing"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Default HTTP timeouts tuned for server-side usage
@@ -66,12 +74,51 @@ func WithHTTPClient(h *http.Client) Option { return func(c *Client) { c.HTTP = h
 func WithUserAgent(ua string) Option       { return func(c *Client) { c.UserAgent = ua } }
 func WithLogger(l zerolog.Logger) Option   { return func(c *Client) { c.Logger = l } }
 
+// WithStrictDecode logs a warning whenever a response does not match the expected schema
+func WithStrictDecode(strict bool) Option { return func(c *Client) { c.StrictDecode = strict } }
+
+// WithResponseLogging logs response bodies, with tokens, secrets and personal data masked
+func WithResponseLogging(enabled bool) Option { return func(c *Client) { c.LogResponses = enabled } }
+
+// RequestInterceptor sees every request right before it is sent, after the client set its
+// headers, e.g. to add headers or a signature; an error aborts the call without sending.
+type RequestInterceptor func(*http.Request) error
+
+// ResponseInterceptor sees every response; its body is already read and can be read again.
+// An error fails the call.
+type ResponseInterceptor func(*http.Response) error
+
+// WithRequestInterceptor adds f to the interceptors run, in the order added, on every request
+func WithRequestInterceptor(f RequestInterceptor) Option {
+	return func(c *Client) {
+		if f != nil {
+			c.requestInterceptors = append(c.requestInterceptors, f)
+		}
+	}
+}
+
+// WithResponseInterceptor adds f to the interceptors run, in the order added, on every response
+func WithResponseInterceptor(f ResponseInterceptor) Option {
+	return func(c *Client) {
+		if f != nil {
+			c.responseInterceptors = append(c.responseInterceptors, f)
+		}
+	}
+}
+
 type Client struct {
 	BaseURL   *url.URL
 	HTTP      *http.Client
 	APIKey    string
 	UserAgent string
 	Logger    zerolog.Logger
+	// StrictDecode reports schema drift in responses, decoding stays lenient
+	StrictDecode bool
+	// LogResponses adds the redacted response body to the request log
+	LogResponses bool
+
+	requestInterceptors  []RequestInterceptor
+	responseInterceptors []ResponseInterceptor
 }
 
 // ResponseEnvelope is the standard response structure from Wallex API
@@ -81,6 +128,8 @@ type ResponseEnvelope[T any] struct {
 	Success bool   `json:"success"`
 }
 
+// Market is a Wallex pair. Numeric fields are decimals, never floats, so none of them
+// can leak binary rounding into price or fee math.
 type Market struct {
 	Symbol             string          `json:"symbol"`
 	BaseAsset          string          `json:"base_asset"`
@@ -91,9 +140,9 @@ type Market struct {
 	EnQuoteAsset       string          `json:"en_quote_asset"`
 	Categories         []int           `json:"categories"` // Changed from []string to []int based on response
 	Price              decimal.Decimal `json:"price"`
-	Change24h          float64         `json:"change_24h"`
+	Change24h          decimal.Decimal `json:"change_24h"`
 	Volume24h          decimal.Decimal `json:"volume_24h"`
-	Change7D           float64         `json:"change_7D"`
+	Change7D           decimal.Decimal `json:"change_7D"`
 	QuoteVolume24h     decimal.Decimal `json:"quote_volume_24h"`
 	SpotIsNew          bool            `json:"spot_is_new"`
 	OtcIsNew           bool            `json:"otc_is_new"`
@@ -104,8 +153,8 @@ type Market struct {
 	IsTmnBased         bool            `json:"is_tmn_based"`
 	IsUsdtBased        bool            `json:"is_usdt_based"`
 	IsZeroFee          bool            `json:"is_zero_fee"`
-	LeverageStep       float64         `json:"leverage_step"`
-	MaxLeverage        float64         `json:"max_leverage"`
+	LeverageStep       decimal.Decimal `json:"leverage_step"`
+	MaxLeverage        decimal.Decimal `json:"max_leverage"`
 	CreatedAt          string          `json:"created_at"`
 	AmountPrecision    int             `json:"amount_precision"`
 	PricePrecision     int             `json:"price_precision"`
@@ -164,6 +213,109 @@ func (c *Client) GetMarketDepth(ctx context.Context, symbol string) (*OrderBook,
 	return &result, nil
 }
 
+// klineResolutions are the resolutions the chart history accepts: minutes, or D(ay) and W(eek)
+var klineResolutions = map[string]bool{
+	"1": true, "60": true, "180": true, "360": true, "720": true, "D": true, "W": true,
+}
+
+// Kline is one OHLC bar; Time is when the bar opens.
+type Kline struct {
+	Time   time.Time
+	Open   decimal.Decimal
+	High   decimal.Decimal
+	Low    decimal.Decimal
+	Close  decimal.Decimal
+	Volume decimal.Decimal
+}
+
+// udfHistory is the TradingView UDF shape of the chart history: one array per field,
+// index i of every array describing bar i.
+type udfHistory struct {
+	Time   []int64           `json:"t"`
+	Open   []decimal.Decimal `json:"o"`
+	High   []decimal.Decimal `json:"h"`
+	Low    []decimal.Decimal `json:"l"`
+	Close  []decimal.Decimal `json:"c"`
+	Volume []decimal.Decimal `json:"v"`
+}
+
+// GetKlines retrieves the bars of a market between the unix seconds from and to, oldest
+// first. resolution is minutes ("1", "60", "180", "360", "720") or "D" or "W".
+func (c *Client) GetKlines(ctx context.Context, symbol, resolution string, from, to int64) ([]Kline, error) {
+	if symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	if !klineResolutions[resolution] {
+		return nil, fmt.Errorf("unsupported kline resolution %q", resolution)
+	}
+	if from >= to {
+		return nil, errors.New("from must be before to")
+	}
+
+	query := url.Values{}
+	query.Set("symbol", symbol)
+	query.Set("resolution", resolution)
+	query.Set("from", fmt.Sprint(from))
+	query.Set("to", fmt.Sprint(to))
+
+	h, err := doJSON[udfHistory](c, ctx, http.MethodGet, "/v1/udf/history", query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	n := len(h.Time)
+	if len(h.Open) != n || len(h.High) != n || len(h.Low) != n || len(h.Close) != n || len(h.Volume) != n {
+		return nil, fmt.Errorf("wallex klines: mismatched series lengths for %s", symbol)
+	}
+	klines := make([]Kline, n)
+	for i := range klines {
+		klines[i] = Kline{
+			Time:   time.Unix(h.Time[i], 0).UTC(),
+			Open:   h.Open[i],
+			High:   h.High[i],
+			Low:    h.Low[i],
+			Close:  h.Close[i],
+			Volume: h.Volume[i],
+		}
+	}
+	return klines, nil
+}
+
+// MarketStats is the rolling 24h ticker of a market
+type MarketStats struct {
+	BidPrice       decimal.Decimal `json:"bidPrice"`
+	AskPrice       decimal.Decimal `json:"askPrice"`
+	LastPrice      decimal.Decimal `json:"lastPrice"`
+	LastQty        decimal.Decimal `json:"lastQty"`
+	HighPrice24h   decimal.Decimal `json:"24h_highPrice"`
+	LowPrice24h    decimal.Decimal `json:"24h_lowPrice"`
+	Change24h      decimal.Decimal `json:"24h_ch"`
+	Volume24h      decimal.Decimal `json:"24h_volume"`
+	QuoteVolume24h decimal.Decimal `json:"24h_quoteVolume"`
+	Change7D       decimal.Decimal `json:"7d_ch"`
+	Volume7D       decimal.Decimal `json:"7d_volume"`
+}
+
+// GetMarketStats retrieves the 24h stats of one market. Wallex only serves them for all
+// markets at once, so this fetches the full ticker and picks symbol out of it.
+func (c *Client) GetMarketStats(ctx context.Context, symbol string) (*MarketStats, error) {
+	if symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	result, err := doJSON[struct {
+		Symbols map[string]struct {
+			Stats MarketStats `json:"stats"`
+		} `json:"symbols"`
+	}](c, ctx, http.MethodGet, "/v1/markets", nil, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	m, ok := result.Symbols[symbol]
+	if !ok {
+		return nil, fmt.Errorf("wallex market %s not found", symbol)
+	}
+	return &m.Stats, nil
+}
+
 func (c *Client) do(
 	ctx context.Context,
 	method, p string,
@@ -171,11 +323,17 @@ func (c *Client) do(
 	body any,
 	out any,
 	contentType string,
-) error {
+) (err error) {
 	u := *c.BaseURL
 	u.Path = path.Join(u.Path, p)
 	u.RawQuery = q.Encode()
 
+	ctx, span := tracing.StartClient(ctx, "wallex "+method,
+		attribute.String("http.request.method", method),
+		attribute.String("url.path", u.Path),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	// --- Build request body ---
 	var r io.Reader
 	if body != nil {
@@ -197,6 +355,10 @@ func (c *Client) do(
 	}
 
 	// --- Build request ---
+	// don't start a call the caller already gave up on
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
@@ -212,28 +374,56 @@ func (c *Client) do(
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
 	}
+	// asking explicitly turns off the transport's transparent gzip; httpbody.Read decodes
+	req.Header.Set("Accept-Encoding", httpbody.AcceptEncoding)
+	for _, intercept := range c.requestInterceptors {
+		if err := intercept(req); err != nil {
+			return fmt.Errorf("request interceptor: %w", err)
+		}
+	}
 
 	// --- Execute request ---
 	start := time.Now()
 	resp, err := c.HTTP.Do(req)
 	if err != nil {
+		// a cancelled caller is not an exchange failure; hand back ctx.Err() as is
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		metrics.ObserveExchangeRequest("wallex", time.Since(start), 0, err)
 		return fmt.Errorf("http do: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.ObserveExchangeRequest("wallex", time.Since(start), resp.StatusCode, nil)
 
-	b, err := io.ReadAll(resp.Body)
+	b, err := httpbody.Read(resp)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		return fmt.Errorf("read body: %w", err)
 	}
+	if dst, ok := ctx.Value(rawResponseKey{}).(*[]byte); ok {
+		*dst = b
+	}
+	for _, intercept := range c.responseInterceptors {
+		resp.Body = io.NopCloser(bytes.NewReader(b))
+		if err := intercept(resp); err != nil {
+			return fmt.Errorf("response interceptor: %w", err)
+		}
+	}
 
 	// --- Logging response ---
-	c.Logger.Info().
+	ev := c.Logger.Info().
+		Str("request_id", logger.RequestID(ctx)).
 		Str("method", method).
 		Str("url", u.String()).
 		Int("status", resp.StatusCode).
-		Str("duration", time.Since(start).String()).
-		RawJSON("response", truncateJSON(b, 2048)). // safe logging
-		Msg("http response")
+		Str("duration", time.Since(start).String())
+	if c.LogResponses {
+		ev = ev.RawJSON("response", redact.JSON(b, 2048))
+	}
+	ev.Msg("http response")
 
 	// --- Status check ---
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
@@ -259,6 +449,9 @@ func (c *Client) do(
 	if err := json.Unmarshal(env.Result, out); err != nil {
 		return fmt.Errorf("unmarshal result: %w", err)
 	}
+	if c.StrictDecode {
+		c.checkSchema(u.Path, env.Result, out)
+	}
 
 	return nil
 }
@@ -278,11 +471,13 @@ func doJSON[T any](
 }
 
 // --- Helpers ---
-func truncateJSON(b []byte, max int) []byte {
-	if len(b) > max {
-		return b[:max]
-	}
-	return b
+
+type rawResponseKey struct{}
+
+// WithRawResponse returns a context under which the client copies the raw body of
+// every response into dst, for debugging what the venue actually returned.
+func WithRawResponse(ctx context.Context, dst *[]byte) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, dst)
 }
 
 type OrderResponse struct {
@@ -359,3 +554,73 @@ func (c *Client) PlaceMarketOrder(ctx context.Context, symbol string, side Order
 
 	return &response, nil
 }
+
+// OrderType of a spot order
+type OrderType string
+
+const (
+	OrderTypeLimit  OrderType = "LIMIT"
+	OrderTypeMarket OrderType = "MARKET"
+)
+
+// TimeInForce of a spot limit order
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC"
+	TimeInForceIOC TimeInForce = "IOC"
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+// PlaceOrderRequest is a spot order on the wallex book, for order types the OTC
+// easy-trade endpoint can't express
+type PlaceOrderRequest struct {
+	Symbol      string           `json:"symbol"`
+	Type        OrderType        `json:"type"`
+	Side        OrderSide        `json:"side"`
+	Price       *decimal.Decimal `json:"price,omitempty"`
+	Quantity    decimal.Decimal  `json:"quantity"`
+	TimeInForce TimeInForce      `json:"timeInForce,omitempty"`
+	PostOnly    bool             `json:"postOnly,omitempty"`
+}
+
+// PlaceOrder submits a spot order
+func (c *Client) PlaceOrder(ctx context.Context, in PlaceOrderRequest) (*OrderResponse, error) {
+	if in.Symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	if in.Side != OrderSideBuy && in.Side != OrderSideSell {
+		return nil, errors.New("side must be 'buy' or 'sell'")
+	}
+	if !in.Quantity.IsPositive() {
+		return nil, errors.New("quantity is required")
+	}
+	if in.Type == OrderTypeLimit && (in.Price == nil || !in.Price.IsPositive()) {
+		return nil, errors.New("price is required for limit orders")
+	}
+
+	response, err := doJSON[OrderResponse](c, ctx, http.MethodPost, "/v1/account/orders", nil, in, "application/json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to place order: %w", err)
+	}
+	return &response, nil
+}
+
+// checkSchema re-decodes b strictly into a fresh value of out's type and logs a warning
+// when the wallex response carries fields or types the client does not expect. The lenient
+// decode has already succeeded, so drift is reported and never fails the call.
+func (c *Client) checkSchema(p string, b []byte, out any) {
+	t := reflect.TypeOf(out)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(reflect.New(t.Elem()).Interface()); err != nil {
+		c.Logger.Warn().
+			Str("path", p).
+			Err(err).
+			RawJSON("response", redact.JSON(b, 2048)).
+			Msg("wallex response schema drift")
+	}
+}
@@ -0,0 +1,232 @@
+package wallex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+)
+
+// newTestClient returns a client of a fake Wallex answering with handler
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(srv.URL, append([]Option{WithHTTPClient(srv.Client()), WithLogger(zerolog.Nop())}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+// TestNoFloatFields keeps floats out of every type carrying exchange amounts, prices or fees
+func TestNoFloatFields(t *testing.T) {
+	types := []any{
+		Market{}, OrderBookEntry{}, OrderBook{}, Kline{}, MarketStats{},
+		OrderResponse{}, Fill{}, PlaceMarketOrderRequest{}, PlaceOrderRequest{},
+	}
+	for _, v := range types {
+		typ := reflect.TypeOf(v)
+		t.Run(typ.Name(), func(t *testing.T) {
+			for i := 0; i < typ.NumField(); i++ {
+				f := typ.Field(i)
+				kind := f.Type.Kind()
+				if kind == reflect.Slice || kind == reflect.Ptr {
+					kind = f.Type.Elem().Kind()
+				}
+				if kind == reflect.Float32 || kind == reflect.Float64 {
+					t.Errorf("%s.%s is a %s", typ.Name(), f.Name, f.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAllMarketsDecimals(t *testing.T) {
+	tests := []struct {
+		name       string
+		change24h  string // as sent by the API
+		wantChange string
+	}{
+		{name: "string", change24h: `"0.1"`, wantChange: "0.1"},
+		// 0.1 + 0.2 is 0.30000000000000004 as floats
+		{name: "number", change24h: `0.3`, wantChange: "0.3"},
+		{name: "many digits", change24h: `"-12.123456789012345678"`, wantChange: "-12.123456789012345678"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"success":true,"result":{"markets":[{"symbol":"BTCUSDT","change_24h":`+tt.change24h+
+					`,"change_7D":"1.5","leverage_step":"0.5","max_leverage":"10"}]}}`)
+			})
+
+			markets, err := c.GetAllMarkets(context.Background())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(markets) != 1 {
+				t.Fatalf("markets = %+v", markets)
+			}
+			m := markets[0]
+			if !m.Change24h.Equal(decimal.RequireFromString(tt.wantChange)) {
+				t.Errorf("change 24h = %s, want %s", m.Change24h, tt.wantChange)
+			}
+			if !m.Change7D.Equal(decimal.RequireFromString("1.5")) || !m.LeverageStep.Equal(decimal.RequireFromString("0.5")) ||
+				!m.MaxLeverage.Equal(decimal.NewFromInt(10)) {
+				t.Errorf("market = %+v", m)
+			}
+		})
+	}
+}
+
+func TestStrictDecode(t *testing.T) {
+	const market = `{"symbol":"BTCUSDT","categories":[1]`
+	tests := []struct {
+		name     string
+		strict   bool
+		market   string
+		wantWarn bool
+	}{
+		{name: "expected schema", strict: true, market: market + `}`},
+		{name: "drift in strict mode", strict: true, market: market + `,"funding_rate":"0.01"}`, wantWarn: true},
+		{name: "drift in lenient mode", market: market + `,"funding_rate":"0.01"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"success":true,"result":{"markets":[`+tt.market+`]}}`)
+			}, WithLogger(zerolog.New(&logs)), WithStrictDecode(tt.strict))
+
+			markets, err := c.GetAllMarkets(context.Background())
+
+			// drift is only reported, the call still succeeds
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(markets) != 1 || markets[0].Symbol != "BTCUSDT" {
+				t.Errorf("markets = %+v", markets)
+			}
+			warned := strings.Contains(logs.String(), `"level":"warn"`) && strings.Contains(logs.String(), "funding_rate")
+			if warned != tt.wantWarn {
+				t.Errorf("warned = %v, want %v, logs: %s", warned, tt.wantWarn, logs.String())
+			}
+		})
+	}
+}
+
+// TestGzipResponse serves the market list compressed, as Wallex does for large payloads
+func TestGzipResponse(t *testing.T) {
+	const markets = `{"success":true,"result":{"markets":[{"symbol":"BTCUSDT"},{"symbol":"ETHUSDT"}]}}`
+	tests := []struct {
+		name string
+		gzip bool
+	}{
+		{name: "gzip encoded", gzip: true},
+		{name: "uncompressed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var acceptEncoding string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				acceptEncoding = r.Header.Get("Accept-Encoding")
+				if !tt.gzip {
+					_, _ = io.WriteString(w, markets)
+					return
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				zw := gzip.NewWriter(w)
+				_, _ = io.WriteString(zw, markets)
+				_ = zw.Close()
+			})
+
+			got, err := c.GetAllMarkets(context.Background())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(acceptEncoding, "gzip") {
+				t.Errorf("Accept-Encoding = %q, want gzip", acceptEncoding)
+			}
+			if len(got) != 2 || got[0].Symbol != "BTCUSDT" || got[1].Symbol != "ETHUSDT" {
+				t.Errorf("markets = %+v", got)
+			}
+		})
+	}
+}
+
+// TestCancellation calls a Wallex that never answers; the call must end with the caller's
+// context, long before any client timeout
+func TestCancellation(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      func() (context.Context, context.CancelFunc)
+		wantErr  error
+		wantSent bool
+	}{
+		{
+			name: "cancelled before the call",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "cancelled in flight",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: context.Canceled, wantSent: true,
+		},
+		{
+			name: "deadline in flight",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded, wantSent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent atomic.Bool
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				sent.Store(true)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			})
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+
+			_, err := c.GetAllMarkets(ctx)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("returned after %s", elapsed)
+			}
+			if sent.Load() != tt.wantSent {
+				t.Errorf("request sent = %v, want %v", sent.Load(), tt.wantSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package apierror gives every HTTP error response the same shape, so clients can branch
+// on a stable code instead of parsing messages.
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes; clients branch on these, so they never change meaning
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeUnauthorized   = "unauthorized"
+	CodeNotFound       = "not_found"
+	CodeConflict       = "conflict"
+	CodeTooLarge       = "request_too_large"
+	CodeUnprocessable  = "unprocessable"
+	CodeRateLimited    = "rate_limited"
+	CodeInternal       = "internal_error"
+	CodeUpstreamFailed = "upstream_failed"
+	CodeUnavailable    = "unavailable"
+)
+
+// internalMessage replaces the message of every 500
+const internalMessage = "internal server error"
+
+// APIError is the body of every error response
+// swagger:model APIError
+type APIError struct {
+	Code    string `json:"code" example:"not_found"`
+	Message string `json:"message" example:"order not found"`
+	// RequestID is the X-Request-ID of the request, to quote when reporting the failure
+	RequestID string `json:"request_id,omitempty" example:"0b6f5a6e-3c4d-4f7e-9a55-3e1f8f0d2c11"`
+	// Details carries structured context for some codes, e.g. the failed prerequisites of an order
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Respond aborts the request with status and an APIError built from err. The message of a
+// 500 is always generic, so internal errors never leak to clients; log them instead.
+func Respond(c *gin.Context, status int, code string, err error) {
+	RespondWithDetails(c, status, code, err, nil)
+}
+
+// RespondWithDetails is Respond with structured details attached
+func RespondWithDetails(c *gin.Context, status int, code string, err error, details interface{}) {
+	message := internalMessage
+	if status != http.StatusInternalServerError && err != nil {
+		message = err.Error()
+	}
+	c.AbortWithStatusJSON(status, APIError{
+		Code:      code,
+		Message:   message,
+		RequestID: logger.RequestID(c.Request.Context()),
+		Details:   details,
+	})
+}
+
+// Internal answers 500 with a generic message
+func Internal(c *gin.Context) {
+	Respond(c, http.StatusInternalServerError, CodeInternal, nil)
+}
@@ -1,37 +1,221 @@
 package config
 
 import (
+	"errors"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
+	"github.com/shopspring/decimal"
 )
 
 type Config struct {
 	ListenAddr  string
 	Env         string
+	LogLevel    string // minimum level logged; empty means debug in dev and info elsewhere
 	QuoteTTL    time.Duration
 	DatabaseURL string
 	OMP         OMPConfig
 	Wallex      WallexConfig
+	Nobitex     NobitexConfig
 	Ethereum    EthereumConfig
+	Oracle      OracleConfig
+	// AdminToken guards the /admin endpoints; empty disables them
+	AdminToken string
+	// RateLimit limits how fast one caller may hit the public routes
+	RateLimit RateLimitConfig
+	// CORS lets browser clients on other origins call the API
+	CORS CORSConfig
+	// Server tunes the HTTP server timeouts and graceful shutdown
+	Server ServerConfig
+	// DB sizes the database connection pool
+	DB DBConfig
+	// QuoteSigningSecret signs quote ids; empty uses a random key, so quotes die with the process
+	QuoteSigningSecret string
+	// UserJWTSecret verifies the HS256 bearer tokens of the order routes; empty only when UserAuthDisabled
+	UserJWTSecret string
+	// UserAuthDisabled turns user authentication off, for development only
+	UserAuthDisabled bool
+	// MaxOrderRetries is how many times a failed market order is retried before refunding, and a
+	// failed refund before dead-lettering it
+	MaxOrderRetries int
+	// OrderClaimBatchSize is the most orders each cron processor claims per run
+	OrderClaimBatchSize int
+	// OrderMaxConcurrency is how many claimed orders each cron processor works on at once
+	OrderMaxConcurrency int
+	// OrderMaxLifetime is how long a debited order may wait for its market order before it is refunded
+	OrderMaxLifetime time.Duration
+	// OrderArchiveEnabled moves terminal orders older than OrderArchiveRetention to orders_archive
+	OrderArchiveEnabled   bool
+	OrderArchiveRetention time.Duration
+	// OTelEndpoint is the OTLP/HTTP collector URL spans are exported to; empty disables tracing
+	OTelEndpoint    string
+	OTelServiceName string
+	// ReadyzCheckExchanges makes /readyz also require every exchange to answer, not just the database
+	ReadyzCheckExchanges bool
+	// ReadyzTimeout bounds each /readyz dependency check
+	ReadyzTimeout time.Duration
+	// Cron schedules the order pipeline jobs and the market refresh
+	Cron CronConfig
+	// CronLockTTL is how long a job lock may go unrefreshed before it is treated as abandoned
+	// and reclaimed; running jobs refresh theirs every third of it
+	CronLockTTL time.Duration
+	// ExchangesRetryAfter is the Retry-After sent with 503s while every exchange is down
+	ExchangesRetryAfter time.Duration
+	// IndicativeProbeVolume is the size indicative prices are computed at for MegaMarkets without their own
+	IndicativeProbeVolume decimal.Decimal
+	// LogRedactQueryKeys are query params whose values the request log replaces with REDACTED
+	LogRedactQueryKeys []string
+	// LogMaxPathLength truncates longer request paths in the request log
+	LogMaxPathLength int
+}
+
+// CronConfig holds the schedule of every background job, as cron specs with a leading
+// seconds field or descriptors like "@every 10m". The defaults are staggered so the jobs
+// don't all hit the cron lock table in the same second.
+type CronConfig struct {
+	PendingOrders                string
+	SuccessDebitOrders           string
+	ReturnUserOrders             string
+	MarketUserOrderSuccessOrders string
+	MarketUserOrderFailedOrders  string
+	StaleOrdersRefund            string
+	OrderArchive                 string
+	StaleQuotesExpiry            string
+	// MarketRefresh re-fetches the exchange market lists that GET /markets serves from the database
+	MarketRefresh string
+}
+
+// ServerConfig holds the timeouts of the HTTP server
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds the graceful shutdown: draining requests and the running cron jobs
+	ShutdownTimeout time.Duration
+}
+
+// DBConfig sizes the database connection pool; MaxIdleConns never exceeds MaxOpenConns
+type DBConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// CORSConfig configures which browser origins may call the API
+type CORSConfig struct {
+	// AllowedOrigins are exact origins such as https://app.example.com, or "*" for any
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight answer
+	MaxAge time.Duration
+}
+
+// RateLimit is a per-caller token bucket: Rate requests per second on average and Burst
+// at once. A zero Rate disables it.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig holds the rate limit of each public route group
+type RateLimitConfig struct {
+	Market RateLimit // market data and best-price, each request fans out to the exchanges
+	Order  RateLimit
+}
+
+// OracleConfig configures the external reference price check; empty Source disables it.
+type OracleConfig struct {
+	Source  string // "binance"
+	BaseURL string
+	// Band is the max allowed relative deviation from the reference price (0.05 = 5%)
+	Band decimal.Decimal
 }
 type EthereumConfig struct {
+	// Chains lists every EVM chain we settle on; the first one is the default
+	Chains []ChainConfig
+	// LegacyGas sends pre-EIP-1559 transactions, for chains without a base fee
+	LegacyGas bool
+	// GasLimitMultiplier pads every gas estimate (1.2 = 20% headroom)
+	GasLimitMultiplier decimal.Decimal
+	// Confirmations is how many blocks a tx needs before it counts as final
+	Confirmations int
+	// MineTimeout bounds the wait for a tx to be mined and confirmed
+	MineTimeout time.Duration
+}
+
+// ChainConfig is the connection and contracts of one EVM chain
+type ChainConfig struct {
+	Network                string // e.g. "sepolia", also the prefix of its env vars
 	RPCURL                 string
+	ChainID                int64
 	AdminKey               string
-	TreasuryKey            string
 	PhoenixContractAddress string
 	USDTContractAddress    string
 }
+
 type OMPConfig struct {
 	BaseURL string
 	Token   string
+	// MaxConcurrentOrders caps market orders in flight to this venue at once
+	MaxConcurrentOrders int
+	HTTP                HTTPClientConfig
+	// SlippagePercentage overrides the MegaMarket tolerance on this venue; zero keeps the default
+	SlippagePercentage decimal.Decimal
+	// StrictDecode logs a warning when a response drifts from the expected schema
+	StrictDecode bool
+	// LogResponses adds redacted response bodies to the exchange request log
+	LogResponses bool
 }
 
 type WallexConfig struct {
-	BaseURL string
-	APIKey  string
+	BaseURL             string
+	APIKey              string
+	MaxConcurrentOrders int
+	HTTP                HTTPClientConfig
+	SlippagePercentage  decimal.Decimal
+	StrictDecode        bool
+	LogResponses        bool
+}
+
+type NobitexConfig struct {
+	BaseURL             string
+	Token               string
+	MaxConcurrentOrders int
+	HTTP                HTTPClientConfig
+	SlippagePercentage  decimal.Decimal
+	StrictDecode        bool
+	LogResponses        bool
+}
+
+// HTTPClientConfig tunes the HTTP client of a single exchange, since each venue has its
+// own latency and rate-limit profile.
+type HTTPClientConfig struct {
+	Timeout             time.Duration
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	// ProxyURL routes the exchange's traffic through a proxy; nil uses the environment proxy
+	ProxyURL *url.URL
+}
+
+// Client builds an http.Client with its own transport, so connection pools are not shared
+// between exchanges.
+func (h HTTPClientConfig) Client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = h.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = h.MaxConnsPerHost
+	if h.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(h.ProxyURL)
+	}
+	return &http.Client{Timeout: h.Timeout, Transport: transport}
 }
 
 // LoadFromEnv reads configuration from environment variables with fallback defaults.
@@ -55,33 +239,197 @@ func LoadFromEnv() *Config {
 	if err != nil {
 		log.Fatalf("[FATAL] Invalid QUOTE_TTL duration: %v", err)
 	}
-	sepoliaRPCURL := os.Getenv("SEPOLIA_RPC_URL")
-	adminPrivateKey := os.Getenv("SEPOLIA_ADMIN_PRIVATE_KEY")
-	contractAddress := os.Getenv("SEPOLIA_PHOENIX_CONTRACT_ADDRESS")
-	usdtContractAddress := os.Getenv("SEPOLIA_USDT_CONTRACT_ADDRESS")
-	treasuryKey := os.Getenv("SEPOLIA_TREASURY_PRIVATE_KEY")
 
 	return &Config{
 		ListenAddr:  listenAddr,
 		Env:         env,
+		LogLevel:    getEnv("LOG_LEVEL", ""),
 		QuoteTTL:    ttl,
 		DatabaseURL: databaseURL,
 		OMP: OMPConfig{
-			BaseURL: getEnv("OMP_BASE_URL", "https://api.ompfinex.com"),
-			Token:   getEnv("OMP_TOKEN", ""),
+			BaseURL:             getEnv("OMP_BASE_URL", "https://api.ompfinex.com"),
+			Token:               getEnv("OMP_TOKEN", ""),
+			MaxConcurrentOrders: getEnvInt("OMP_MAX_CONCURRENT_ORDERS", 4),
+			HTTP:                getHTTPClientConfig("OMP"),
+			SlippagePercentage:  getEnvDecimal("OMP_SLIPPAGE_PERCENTAGE", decimal.Zero),
+			StrictDecode:        getEnvBool("OMP_STRICT_DECODE", false),
+			LogResponses:        getEnvBool("OMP_LOG_RESPONSES", false),
 		},
 		Wallex: WallexConfig{
-			BaseURL: getEnv("WALLEX_BASE_URL", "https://api.wallex.ir"),
-			APIKey:  getEnv("WALLEX_API_KEY", ""),
+			BaseURL:             getEnv("WALLEX_BASE_URL", "https://api.wallex.ir"),
+			APIKey:              getEnv("WALLEX_API_KEY", ""),
+			MaxConcurrentOrders: getEnvInt("WALLEX_MAX_CONCURRENT_ORDERS", 4),
+			HTTP:                getHTTPClientConfig("WALLEX"),
+			SlippagePercentage:  getEnvDecimal("WALLEX_SLIPPAGE_PERCENTAGE", decimal.Zero),
+			StrictDecode:        getEnvBool("WALLEX_STRICT_DECODE", false),
+			LogResponses:        getEnvBool("WALLEX_LOG_RESPONSES", false),
+		},
+		Nobitex: NobitexConfig{
+			BaseURL:             getEnv("NOBITEX_BASE_URL", "https://api.nobitex.ir"),
+			Token:               getEnv("NOBITEX_TOKEN", ""),
+			MaxConcurrentOrders: getEnvInt("NOBITEX_MAX_CONCURRENT_ORDERS", 4),
+			HTTP:                getHTTPClientConfig("NOBITEX"),
+			SlippagePercentage:  getEnvDecimal("NOBITEX_SLIPPAGE_PERCENTAGE", decimal.Zero),
+			StrictDecode:        getEnvBool("NOBITEX_STRICT_DECODE", false),
+			LogResponses:        getEnvBool("NOBITEX_LOG_RESPONSES", false),
 		},
 		Ethereum: EthereumConfig{
-			RPCURL:                 sepoliaRPCURL,
-			AdminKey:               adminPrivateKey,
-			TreasuryKey:            treasuryKey,
-			PhoenixContractAddress: contractAddress,
-			USDTContractAddress:    usdtContractAddress,
+			Chains:             getChainConfigs(),
+			LegacyGas:          getEnvBool("ETH_LEGACY_GAS", false),
+			GasLimitMultiplier: getEnvDecimal("ETH_GAS_LIMIT_MULTIPLIER", decimal.NewFromFloat(1.2)),
+			Confirmations:      getEnvInt("ETH_CONFIRMATIONS", 1),
+			MineTimeout:        getEnvDuration("ETH_MINE_TIMEOUT", 5*time.Minute),
+		},
+		Oracle: OracleConfig{
+			Source:  getEnv("PRICE_ORACLE_SOURCE", ""),
+			BaseURL: getEnv("PRICE_ORACLE_BASE_URL", "https://api.binance.com"),
+			Band:    getEnvDecimal("PRICE_ORACLE_BAND", decimal.NewFromFloat(0.05)),
+		},
+		MaxOrderRetries:       getEnvInt("MAX_ORDER_RETRIES", 5),
+		OrderClaimBatchSize:   getEnvInt("ORDER_CLAIM_BATCH_SIZE", 100),
+		OrderMaxConcurrency:   getEnvInt("ORDER_MAX_CONCURRENCY", 8),
+		OrderMaxLifetime:      getEnvDuration("ORDER_MAX_LIFETIME", 24*time.Hour),
+		AdminToken:            getEnv("ADMIN_API_TOKEN", ""),
+		UserJWTSecret:         getUserJWTSecret(),
+		UserAuthDisabled:      getEnvBool("USER_AUTH_DISABLED", false),
+		QuoteSigningSecret:    getEnv("QUOTE_SIGNING_SECRET", ""),
+		OrderArchiveEnabled:   getEnvBool("ORDER_ARCHIVE_ENABLED", false),
+		OrderArchiveRetention: getEnvDuration("ORDER_ARCHIVE_RETENTION", 90*24*time.Hour),
+		CORS: CORSConfig{
+			// any origin while developing, none elsewhere unless listed
+			AllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS", defaultCORSOrigins(env)),
+			AllowedMethods: getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "OPTIONS"}),
+			AllowedHeaders: getEnvList("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "X-Request-ID", "Idempotency-Key", "traceparent"}),
+			MaxAge:         getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Market: RateLimit{
+				Rate:  getEnvDecimal("RATE_LIMIT_MARKET_RPS", decimal.NewFromInt(5)).InexactFloat64(),
+				Burst: getEnvInt("RATE_LIMIT_MARKET_BURST", 10),
+			},
+			Order: RateLimit{
+				Rate:  getEnvDecimal("RATE_LIMIT_ORDER_RPS", decimal.NewFromInt(2)).InexactFloat64(),
+				Burst: getEnvInt("RATE_LIMIT_ORDER_BURST", 5),
+			},
 		},
+		Server: ServerConfig{
+			ReadTimeout:       getEnvDuration("SERVER_READ_TIMEOUT", 5*time.Second),
+			ReadHeaderTimeout: getEnvDuration("SERVER_READ_HEADER_TIMEOUT", 2*time.Second),
+			WriteTimeout:      getEnvDuration("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       getEnvDuration("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			ShutdownTimeout:   getEnvDuration("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+		},
+		DB: getDBConfig(),
+		Cron: CronConfig{
+			PendingOrders:                getEnvCron("CRON_PENDING_ORDERS", "0 * * * * *"),
+			SuccessDebitOrders:           getEnvCron("CRON_SUCCESS_DEBIT_ORDERS", "10 * * * * *"),
+			ReturnUserOrders:             getEnvCron("CRON_RETURN_USER_ORDERS", "20 * * * * *"),
+			MarketUserOrderSuccessOrders: getEnvCron("CRON_MARKET_USER_ORDER_SUCCESS_ORDERS", "30 * * * * *"),
+			MarketUserOrderFailedOrders:  getEnvCron("CRON_MARKET_USER_ORDER_FAILED_ORDERS", "40 * * * * *"),
+			StaleOrdersRefund:            getEnvCron("CRON_STALE_ORDERS_REFUND", "50 * * * * *"),
+			OrderArchive:                 getEnvCron("CRON_ORDER_ARCHIVE", "5 17 * * * *"),
+			StaleQuotesExpiry:            getEnvCron("CRON_STALE_QUOTES_EXPIRY", "@every 10m"),
+			MarketRefresh:                getEnvCron("CRON_MARKET_REFRESH", "@every 5m"),
+		},
+		OTelEndpoint:          getEnv("OTEL_EXPORTER_ENDPOINT", ""),
+		OTelServiceName:       getEnv("OTEL_SERVICE_NAME", "mega"),
+		ReadyzCheckExchanges:  getEnvBool("READYZ_CHECK_EXCHANGES", false),
+		ReadyzTimeout:         getEnvDuration("READYZ_TIMEOUT", 2*time.Second),
+		CronLockTTL:           getEnvDuration("CRON_LOCK_TTL", 10*time.Minute),
+		ExchangesRetryAfter:   getEnvDuration("EXCHANGES_RETRY_AFTER", 30*time.Second),
+		IndicativeProbeVolume: getEnvDecimal("INDICATIVE_PROBE_VOLUME", decimal.NewFromInt(1)),
+		LogRedactQueryKeys:    getEnvList("LOG_REDACT_QUERY_KEYS", []string{"token", "api_key", "apikey", "key", "secret", "password", "signature", "auth"}),
+		LogMaxPathLength:      getEnvInt("LOG_MAX_PATH_LENGTH", 200),
+	}
+}
+
+// getDBConfig reads the DB_* pool settings, failing fast when more connections may idle
+// than may be open, which database/sql would silently clamp
+func getDBConfig() DBConfig {
+	db := DBConfig{
+		MaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 20),
+		MaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 10*time.Minute),
 	}
+	if db.MaxIdleConns > db.MaxOpenConns {
+		log.Fatalf("[FATAL] Invalid DB_MAX_IDLE_CONNS: %d exceeds DB_MAX_OPEN_CONNS %d", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	return db
+}
+
+// getUserJWTSecret reads USER_JWT_SECRET, failing fast when it is empty so a missing
+// secret can't leave the order routes open; USER_AUTH_DISABLED=true opts out explicitly
+func getUserJWTSecret() string {
+	secret := getEnv("USER_JWT_SECRET", "")
+	if err := validateUserAuth(secret, getEnvBool("USER_AUTH_DISABLED", false)); err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+	return secret
+}
+
+// validateUserAuth rejects an empty user JWT secret unless user auth is explicitly disabled
+func validateUserAuth(secret string, disabled bool) error {
+	if secret == "" && !disabled {
+		return errors.New("USER_JWT_SECRET is required; set USER_AUTH_DISABLED=true to run without user authentication (development only)")
+	}
+	return nil
+}
+
+// defaultCORSOrigins allows any origin in dev and denies every origin elsewhere
+func defaultCORSOrigins(env string) []string {
+	if env == "dev" {
+		return []string{"*"}
+	}
+	return nil
+}
+
+// knownChainIDs lets well-known networks omit <NETWORK>_CHAIN_ID
+var knownChainIDs = map[string]int64{
+	"mainnet": 1,
+	"sepolia": 11155111,
+	"polygon": 137,
+	"mumbai":  80001,
+	"amoy":    80002,
+}
+
+// getChainConfigs reads the chains named in ETH_NETWORKS (comma separated, default
+// "sepolia"), each from its own <NETWORK>_RPC_URL, <NETWORK>_CHAIN_ID,
+// <NETWORK>_ADMIN_PRIVATE_KEY, <NETWORK>_PHOENIX_CONTRACT_ADDRESS and
+// <NETWORK>_USDT_CONTRACT_ADDRESS. The admin key signs every transaction, treasury payouts
+// included, since they are withdrawn through the Phoenix contract.
+func getChainConfigs() []ChainConfig {
+	var chains []ChainConfig
+	for _, network := range strings.Split(getEnv("ETH_NETWORKS", "sepolia"), ",") {
+		network = strings.ToLower(strings.TrimSpace(network))
+		if network == "" {
+			continue
+		}
+		prefix := strings.ToUpper(network)
+		chainID := int64(getEnvInt(prefix+"_CHAIN_ID", 0))
+		if chainID == 0 {
+			chainID = knownChainIDs[network]
+		}
+		if chainID == 0 {
+			log.Fatalf("[FATAL] %s_CHAIN_ID is required for network %s", prefix, network)
+		}
+		rpcURL := os.Getenv(prefix + "_RPC_URL")
+		if rpcURL == "" {
+			log.Fatalf("[FATAL] %s_RPC_URL is required for network %s", prefix, network)
+		}
+		adminKey := os.Getenv(prefix + "_ADMIN_PRIVATE_KEY")
+		if adminKey == "" {
+			log.Fatalf("[FATAL] %s_ADMIN_PRIVATE_KEY is required for network %s", prefix, network)
+		}
+		chains = append(chains, ChainConfig{
+			Network:                network,
+			RPCURL:                 rpcURL,
+			ChainID:                chainID,
+			AdminKey:               adminKey,
+			PhoenixContractAddress: os.Getenv(prefix + "_PHOENIX_CONTRACT_ADDRESS"),
+			USDTContractAddress:    os.Getenv(prefix + "_USDT_CONTRACT_ADDRESS"),
+		})
+	}
+	return chains
 }
 
 // helper to get env with default fallback
@@ -91,3 +439,99 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// helper to get a positive int env with default fallback
+func getEnvInt(key string, fallback int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		log.Fatalf("[FATAL] Invalid %s: must be a positive integer, got %q", key, val)
+	}
+	return n
+}
+
+// helper to read <PREFIX>_HTTP_* settings of an exchange client
+func getHTTPClientConfig(prefix string) HTTPClientConfig {
+	h := HTTPClientConfig{
+		Timeout:             getEnvDuration(prefix+"_HTTP_TIMEOUT", 30*time.Second),
+		MaxIdleConnsPerHost: getEnvInt(prefix+"_HTTP_MAX_IDLE_CONNS_PER_HOST", 10),
+		MaxConnsPerHost:     getEnvInt(prefix+"_HTTP_MAX_CONNS_PER_HOST", 50),
+	}
+	if raw := getEnv(prefix+"_HTTP_PROXY", ""); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			log.Fatalf("[FATAL] Invalid %s_HTTP_PROXY: %q", prefix, raw)
+		}
+		h.ProxyURL = u
+	}
+	return h
+}
+
+// helper to get a bool env with default fallback
+func getEnvBool(key string, fallback bool) bool {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Fatalf("[FATAL] Invalid %s: must be a boolean, got %q", key, val)
+	}
+	return b
+}
+
+// helper to get a comma separated env list with default fallback
+func getEnvList(key string, fallback []string) []string {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// helper to get a positive duration env with default fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Fatalf("[FATAL] Invalid %s: must be a positive duration, got %q", key, val)
+	}
+	return d
+}
+
+// cronParser matches the cron.WithSeconds() scheduler the jobs run on
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// helper to get a cron spec env with default fallback, failing fast on a spec the scheduler would reject
+func getEnvCron(key, fallback string) string {
+	val := getEnv(key, fallback)
+	if _, err := cronParser.Parse(val); err != nil {
+		log.Fatalf("[FATAL] Invalid %s: %q is not a cron spec: %v", key, val, err)
+	}
+	return val
+}
+
+// helper to get a non-negative decimal env with default fallback
+func getEnvDecimal(key string, fallback decimal.Decimal) decimal.Decimal {
+	val, ok := os.LookupEnv(key)
+	if !ok || val == "" {
+		return fallback
+	}
+	d, err := decimal.NewFromString(val)
+	if err != nil || d.IsNegative() {
+		log.Fatalf("[FATAL] Invalid %s: must be a non-negative decimal, got %q", key, val)
+	}
+	return d
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestValidateUserAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		disabled bool
+		wantErr  bool
+	}{
+		{name: "secret set", secret: "s3cret"},
+		{name: "empty secret", wantErr: true},
+		{name: "empty secret, auth disabled", disabled: true},
+		{name: "secret set, auth disabled", secret: "s3cret", disabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateUserAuth(tt.secret, tt.disabled)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetChainConfigs(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []ChainConfig
+	}{
+		{
+			name: "known chain id",
+			env: map[string]string{
+				"ETH_NETWORKS":              "sepolia",
+				"SEPOLIA_RPC_URL":           "https://sepolia.example",
+				"SEPOLIA_ADMIN_PRIVATE_KEY": "0xabc",
+			},
+			want: []ChainConfig{{Network: "sepolia", RPCURL: "https://sepolia.example", ChainID: 11155111, AdminKey: "0xabc"}},
+		},
+		{
+			name: "several chains, explicit id",
+			env: map[string]string{
+				"ETH_NETWORKS":                   " Sepolia, local ,",
+				"SEPOLIA_RPC_URL":                "https://sepolia.example",
+				"SEPOLIA_ADMIN_PRIVATE_KEY":      "0xabc",
+				"SEPOLIA_USDT_CONTRACT_ADDRESS":  "0xusdt",
+				"LOCAL_CHAIN_ID":                 "31337",
+				"LOCAL_RPC_URL":                  "http://localhost:8545",
+				"LOCAL_ADMIN_PRIVATE_KEY":        "0xdef",
+				"LOCAL_PHOENIX_CONTRACT_ADDRESS": "0xphoenix",
+			},
+			want: []ChainConfig{
+				{Network: "sepolia", RPCURL: "https://sepolia.example", ChainID: 11155111, AdminKey: "0xabc", USDTContractAddress: "0xusdt"},
+				{Network: "local", RPCURL: "http://localhost:8545", ChainID: 31337, AdminKey: "0xdef", PhoenixContractAddress: "0xphoenix"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got := getChainConfigs()
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d chains, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("chain %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPClientConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		env         map[string]string
+		wantTimeout time.Duration
+		wantIdle    int
+		wantConns   int
+		wantProxy   string
+	}{
+		{name: "defaults", prefix: "OMP", wantTimeout: 30 * time.Second, wantIdle: 10, wantConns: 50},
+		{
+			name: "tuned", prefix: "WALLEX",
+			env: map[string]string{
+				"WALLEX_HTTP_TIMEOUT":                  "5s",
+				"WALLEX_HTTP_MAX_IDLE_CONNS_PER_HOST":  "2",
+				"WALLEX_HTTP_MAX_CONNS_PER_HOST":       "4",
+				"WALLEX_HTTP_PROXY":                    "http://proxy.internal:3128",
+				"NOBITEX_HTTP_TIMEOUT":                 "1s", // another exchange's setting
+				"OMP_HTTP_MAX_IDLE_CONNS_PER_HOST":     "99",
+				"NOBITEX_HTTP_MAX_IDLE_CONNS_PER_HOST": "99",
+			},
+			wantTimeout: 5 * time.Second, wantIdle: 2, wantConns: 4, wantProxy: "http://proxy.internal:3128",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			client := getHTTPClientConfig(tt.prefix).Client()
+
+			if client.Timeout != tt.wantTimeout {
+				t.Errorf("timeout = %s, want %s", client.Timeout, tt.wantTimeout)
+			}
+			transport := client.Transport.(*http.Transport)
+			if transport == http.DefaultTransport {
+				t.Error("client shares the default transport")
+			}
+			if transport.MaxIdleConnsPerHost != tt.wantIdle || transport.MaxConnsPerHost != tt.wantConns {
+				t.Errorf("pool = %d idle / %d conns, want %d / %d",
+					transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost, tt.wantIdle, tt.wantConns)
+			}
+			if tt.wantProxy == "" {
+				return
+			}
+			req, _ := http.NewRequest(http.MethodGet, "https://api.wallex.ir/v1/depth", nil)
+			proxy, err := transport.Proxy(req)
+			if err != nil || proxy == nil || proxy.String() != tt.wantProxy {
+				t.Errorf("proxy = %v (err %v), want %s", proxy, err, tt.wantProxy)
+			}
+		})
+	}
+}
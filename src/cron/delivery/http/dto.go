@@ -0,0 +1,42 @@
+package http
+
+import (
+	"time"
+
+	"github.com/MMN3003/mega/src/cron/domain"
+	"github.com/google/uuid"
+)
+
+// CronJobDto is a background job with its last run
+// swagger:model CronJobDto
+type CronJobDto struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name" example:"pending_orders"`
+	Schedule       string     `json:"schedule" example:"1 * * * * *"`
+	Running        bool       `json:"running" example:"false"`
+	LastStartedAt  *time.Time `json:"last_started_at"`
+	LastFinishedAt *time.Time `json:"last_finished_at"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// ListCronJobsResponse lists every registered job
+// swagger:model ListCronJobsResponse
+type ListCronJobsResponse struct {
+	Crons []CronJobDto `json:"crons"`
+}
+
+func ListCronJobsResponseFromDomain(jobs []domain.CronJob) ListCronJobsResponse {
+	dtos := make([]CronJobDto, len(jobs))
+	for i, j := range jobs {
+		dtos[i] = CronJobDto{
+			ID:             j.ID,
+			Name:           j.Name,
+			Schedule:       j.Schedule,
+			Running:        j.Running,
+			LastStartedAt:  j.LastStartedAt,
+			LastFinishedAt: j.LastFinishedAt,
+			LastError:      j.LastError,
+		}
+	}
+	return ListCronJobsResponse{Crons: dtos}
+}
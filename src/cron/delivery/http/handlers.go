@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/MMN3003/mega/src/apierror"
+	"github.com/MMN3003/mega/src/cron/usecase"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler binds usecase + logger
+type Handler struct {
+	service *usecase.Service
+	logger  *logger.Logger
+}
+
+func NewHandler(s *usecase.Service, l *logger.Logger) *Handler {
+	return &Handler{service: s, logger: l}
+}
+
+// RegisterAdminRoutes mounts the operator endpoints on an already authenticated group
+func (h *Handler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/crons", h.ListCrons)
+}
+
+// ListCrons godoc
+//
+//	@Summary		List background jobs
+//	@Description	Schedule, running state, last run and last error of every pipeline job
+//	@Tags			admin
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Success		200				{object}	ListCronJobsResponse
+//	@Failure		401				{object}	apierror.APIError
+//	@Failure		500				{object}	apierror.APIError
+//	@Router			/admin/crons [get]
+func (h *Handler) ListCrons(c *gin.Context) {
+	jobs, err := h.service.ListJobs(c.Request.Context())
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("ListCrons err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	c.JSON(http.StatusOK, ListCronJobsResponseFromDomain(jobs))
+}
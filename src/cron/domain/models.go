@@ -1,7 +1,22 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Cron struct {
 	ID uuid.UUID `json:"id"`
 }
+
+// CronJob is the bookkeeping of a scheduled pipeline job and its last run
+type CronJob struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Running        bool       `json:"running"`
+	LastStartedAt  *time.Time `json:"last_started_at"`
+	LastFinishedAt *time.Time `json:"last_finished_at"`
+	LastError      string     `json:"last_error,omitempty"`
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,9 +10,24 @@ import (
 type CronRepository interface {
 	SaveCron(ctx context.Context, c *Cron) (*Cron, error)
 	DeleteCron(ctx context.Context, id uuid.UUID) error
+	// ReclaimStaleCron takes over a lock created before staleBefore and reports whether it did
+	ReclaimStaleCron(ctx context.Context, id uuid.UUID, staleBefore, now time.Time) (bool, error)
+	// RefreshCron restamps a held lock so it doesn't look abandoned
+	RefreshCron(ctx context.Context, id uuid.UUID, now time.Time) error
+	UpsertJob(ctx context.Context, id uuid.UUID, name, schedule string) error
+	MarkJobStarted(ctx context.Context, id uuid.UUID, at time.Time) error
+	MarkJobFinished(ctx context.Context, id uuid.UUID, at time.Time, lastError string) error
+	ListJobs(ctx context.Context) ([]CronJob, error)
 }
 
 type CronUseCase interface {
-	CreateCron(ctx context.Context, id uuid.UUID) error
+	// CreateCron takes the job lock and reports whether it was reclaimed from a crashed run
+	CreateCron(ctx context.Context, id uuid.UUID) (stale bool, err error)
 	DeleteCron(ctx context.Context, id uuid.UUID) error
+	// KeepCron refreshes a held lock until the returned stop is called
+	KeepCron(ctx context.Context, id uuid.UUID) (stop func())
+	RegisterJob(ctx context.Context, id uuid.UUID, name, schedule string) error
+	StartRun(ctx context.Context, id uuid.UUID) error
+	FinishRun(ctx context.Context, id uuid.UUID, runErr error) error
+	ListJobs(ctx context.Context) ([]CronJob, error)
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/MMN3003/mega/src/logger"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var _ domain.CronRepository = (*CronRepo)(nil)
@@ -26,6 +27,18 @@ type Cron struct {
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
+// CronJob keeps the schedule and last run of every pipeline job
+type CronJob struct {
+	ID             uuid.UUID `gorm:"primarykey"`
+	Name           string    `gorm:"not null"`
+	Schedule       string    `gorm:"not null"`
+	Running        bool      `gorm:"not null;default:false"`
+	LastStartedAt  *time.Time
+	LastFinishedAt *time.Time
+	LastError      string
+	UpdatedAt      time.Time
+}
+
 // ---------- REPO ----------
 
 type CronRepo struct {
@@ -34,7 +47,7 @@ type CronRepo struct {
 }
 
 func NewCronRepo(db *gorm.DB, log *logger.Logger) *CronRepo {
-	if err := db.AutoMigrate(&Cron{}); err != nil {
+	if err := db.AutoMigrate(&Cron{}, &CronJob{}); err != nil {
 		log.Fatalf("failed to migrate schema: %v", err)
 	}
 	return &CronRepo{db: db, log: log}
@@ -67,6 +80,67 @@ func (r *CronRepo) DeleteCron(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Unscoped().Delete(&Cron{}, id).Error
 }
 
+// ReclaimStaleCron restamps the lock row if it is older than staleBefore. Only one caller
+// can win the conditional update, so two instances never reclaim the same lock.
+func (r *CronRepo) ReclaimStaleCron(ctx context.Context, id uuid.UUID, staleBefore, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Unscoped().Model(&Cron{}).
+		Where("id = ? AND created_at < ?", id, staleBefore).
+		Updates(map[string]any{"created_at": now, "updated_at": now, "deleted_at": nil})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// RefreshCron restamps the lock row while its run is still going.
+func (r *CronRepo) RefreshCron(ctx context.Context, id uuid.UUID, now time.Time) error {
+	return r.db.WithContext(ctx).Model(&Cron{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"created_at": now, "updated_at": now}).Error
+}
+
+// ---------- JOBS ----------
+
+// UpsertJob registers a job, refreshing its name and schedule if it already exists.
+func (r *CronRepo) UpsertJob(ctx context.Context, id uuid.UUID, name, schedule string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "schedule", "updated_at"}),
+	}).Create(&CronJob{ID: id, Name: name, Schedule: schedule}).Error
+}
+
+func (r *CronRepo) MarkJobStarted(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&CronJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"running": true, "last_started_at": at}).Error
+}
+
+func (r *CronRepo) MarkJobFinished(ctx context.Context, id uuid.UUID, at time.Time, lastError string) error {
+	return r.db.WithContext(ctx).Model(&CronJob{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"running": false, "last_finished_at": at, "last_error": lastError}).Error
+}
+
+func (r *CronRepo) ListJobs(ctx context.Context) ([]domain.CronJob, error) {
+	var models []CronJob
+	if err := r.db.WithContext(ctx).Order("name").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	jobs := make([]domain.CronJob, len(models))
+	for i, m := range models {
+		jobs[i] = domain.CronJob{
+			ID:             m.ID,
+			Name:           m.Name,
+			Schedule:       m.Schedule,
+			Running:        m.Running,
+			LastStartedAt:  m.LastStartedAt,
+			LastFinishedAt: m.LastFinishedAt,
+			LastError:      m.LastError,
+		}
+	}
+	return jobs, nil
+}
+
 // ---------- HELPERS ----------
 
 func (r *CronRepo) toDomainCron(c *Cron) *domain.Cron {
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"time"
 
 	"github.com/MMN3003/mega/src/cron/domain"
 	"github.com/MMN3003/mega/src/logger"
@@ -10,22 +11,102 @@ import (
 
 var _ domain.CronUseCase = (*Service)(nil)
 
+// defaultLockTTL is used when no WithLockTTL option is given
+const defaultLockTTL = 10 * time.Minute
+
 type Service struct {
 	cronRepo domain.CronRepository
 	logger   *logger.Logger
+	// lockTTL is how old a job lock must be before it is considered abandoned by a crashed run
+	lockTTL time.Duration
+}
+
+// Option configures optional Service behaviour
+type Option func(*Service)
+
+// WithLockTTL sets after how long a held job lock is reclaimed
+func WithLockTTL(d time.Duration) Option {
+	return func(s *Service) {
+		if d > 0 {
+			s.lockTTL = d
+		}
+	}
 }
 
-func NewService(cronRepo domain.CronRepository, logg *logger.Logger) *Service {
+func NewService(cronRepo domain.CronRepository, logg *logger.Logger, opts ...Option) *Service {
 	s := &Service{
 		cronRepo: cronRepo,
 		logger:   logg,
+		lockTTL:  defaultLockTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	return s
 }
-func (s *Service) CreateCron(ctx context.Context, id uuid.UUID) error {
-	_, err := s.cronRepo.SaveCron(ctx, &domain.Cron{ID: id})
-	return err
+
+// CreateCron takes the job lock. A lock older than the lock TTL was left behind by a run
+// that crashed before DeleteCron, so it is reclaimed instead of blocking the job forever,
+// and stale reports it.
+func (s *Service) CreateCron(ctx context.Context, id uuid.UUID) (stale bool, err error) {
+	_, err = s.cronRepo.SaveCron(ctx, &domain.Cron{ID: id})
+	if err == nil {
+		return false, nil
+	}
+	now := time.Now()
+	reclaimed, reclaimErr := s.cronRepo.ReclaimStaleCron(ctx, id, now.Add(-s.lockTTL), now)
+	if reclaimErr != nil || !reclaimed {
+		return false, err
+	}
+	return true, nil
 }
+
+// KeepCron refreshes the lock every third of the lock TTL until stop is called, so a run
+// that outlasts the TTL is never taken for a crashed one and reclaimed by another instance.
+func (s *Service) KeepCron(ctx context.Context, id uuid.UUID) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := s.cronRepo.RefreshCron(ctx, id, now); err != nil {
+					s.logger.Errorf("cron %s: refresh lock: %v", id, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
 func (s *Service) DeleteCron(ctx context.Context, id uuid.UUID) error {
 	return s.cronRepo.DeleteCron(ctx, id)
 }
+func (s *Service) RegisterJob(ctx context.Context, id uuid.UUID, name, schedule string) error {
+	return s.cronRepo.UpsertJob(ctx, id, name, schedule)
+}
+func (s *Service) StartRun(ctx context.Context, id uuid.UUID) error {
+	return s.cronRepo.MarkJobStarted(ctx, id, time.Now())
+}
+
+// FinishRun records the end of a run; a nil runErr clears the last error.
+func (s *Service) FinishRun(ctx context.Context, id uuid.UUID, runErr error) error {
+	lastError := ""
+	if runErr != nil {
+		lastError = runErr.Error()
+	}
+	return s.cronRepo.MarkJobFinished(ctx, id, time.Now(), lastError)
+}
+func (s *Service) ListJobs(ctx context.Context) ([]domain.CronJob, error) {
+	return s.cronRepo.ListJobs(ctx)
+}
@@ -0,0 +1,58 @@
+// Package health serves the readiness probe: unlike the /healthz liveness probe it checks
+// that the dependencies a request needs are actually reachable.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check probes one dependency; a nil error means it is usable
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Readiness runs every check concurrently, each bounded by timeout, and answers 200 when
+// all pass or 503 otherwise. The body reports "ok" or the error of every check.
+func Readiness(timeout time.Duration, checks ...Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			results = make(map[string]string, len(checks))
+			healthy = true
+		)
+		for _, check := range checks {
+			check := check
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				status := "ok"
+				if err := check.Run(ctx); err != nil {
+					status = err.Error()
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				results[check.Name] = status
+				if status != "ok" {
+					healthy = false
+				}
+			}()
+		}
+		wg.Wait()
+
+		if !healthy {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": results})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": results})
+	}
+}
@@ -1,12 +1,30 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// requestIDKey stores the request id in a context
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request id that WithContext logs
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id carried by ctx, or "" when there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
 type Logger struct {
 	env string
 	log zerolog.Logger
@@ -33,6 +51,20 @@ func New(env string) *Logger {
 	}
 }
 
+// SetLevel sets the minimum level logged ("debug", "info", "warn", "error"...) by every
+// Logger. An empty level keeps the current one.
+func (l *Logger) SetLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	zerolog.SetGlobalLevel(lvl)
+	return nil
+}
+
 func (l *Logger) Infof(format string, args ...interface{}) {
 	l.log.Info().Msgf(format, args...)
 }
@@ -56,6 +88,19 @@ func (l *Logger) WithField(key string, value interface{}) *Logger {
 	}
 }
 
+// WithContext adds the request_id and the trace_id of the span in ctx, when present, so log
+// lines can be matched to their request and trace.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	zctx := l.log.With()
+	if id := RequestID(ctx); id != "" {
+		zctx = zctx.Str("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		zctx = zctx.Str("trace_id", sc.TraceID().String())
+	}
+	return &Logger{env: l.env, log: zctx.Logger()}
+}
+
 func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	ctx := l.log.With()
 	for k, v := range fields {
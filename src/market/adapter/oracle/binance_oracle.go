@@ -0,0 +1,56 @@
+package oracle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+var _ domain.PriceOracle = (*BinanceOracle)(nil)
+
+// BinanceOracle reads the last traded price from Binance's public ticker endpoint.
+type BinanceOracle struct {
+	baseURL string
+	http    *http.Client
+}
+
+func NewBinanceOracle(baseURL string) *BinanceOracle {
+	return &BinanceOracle{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (o *BinanceOracle) ReferencePrice(ctx context.Context, megaMarket *domain.MegaMarket) (decimal.Decimal, error) {
+	symbol := strings.ToUpper(megaMarket.SourceTokenSymbol + megaMarket.DestinationTokenSymbol)
+	u := o.baseURL + "/api/v3/ticker/price?" + url.Values{"symbol": {symbol}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("new request: %w", err)
+	}
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("http do: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decimal.Zero, fmt.Errorf("binance ticker %s: http %d", symbol, resp.StatusCode)
+	}
+
+	var ticker struct {
+		Symbol string          `json:"symbol"`
+		Price  decimal.Decimal `json:"price"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ticker); err != nil {
+		return decimal.Zero, fmt.Errorf("decode ticker: %w", err)
+	}
+	return ticker.Price, nil
+}
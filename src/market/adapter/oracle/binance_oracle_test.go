@@ -0,0 +1,48 @@
+package oracle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestBinanceOracleReferencePrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "ticker", status: http.StatusOK, body: `{"symbol":"BTCUSDT","price":"65000.12"}`, want: "65000.12"},
+		{name: "unknown symbol", status: http.StatusBadRequest, body: `{"code":-1121,"msg":"Invalid symbol."}`, wantErr: true},
+		{name: "malformed", status: http.StatusOK, body: `{"price":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v3/ticker/price" || r.URL.Query().Get("symbol") != "BTCUSDT" {
+					t.Errorf("request %s", r.URL)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+
+			price, err := NewBinanceOracle(srv.URL+"/").ReferencePrice(context.Background(),
+				&domain.MegaMarket{SourceTokenSymbol: "btc", DestinationTokenSymbol: "usdt"})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !price.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("price = %s, want %s", price, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+func TestBestPriceExchangesOverride(t *testing.T) {
+	books := map[string]string{
+		"/v1/depth":             `{"success":true,"result":{"ask":[{"price":"101","quantity":"5"}],"bid":[{"price":"99","quantity":"5"}]}}`,
+		"/v3/orderbook/BTCUSDT": `{"status":"ok","lastUpdate":1,"asks":[["100","5"]],"bids":[["98","5"]]}`,
+	}
+	tests := []struct {
+		name         string
+		query        string
+		wantStatus   int
+		wantExchange string
+		wantPrice    string
+	}{
+		{name: "every mapped exchange", wantStatus: http.StatusOK, wantExchange: "nobitex", wantPrice: "100"},
+		// nobitex is cheaper, restricting to wallex must leave it out
+		{name: "only wallex", query: "?exchanges=wallex", wantStatus: http.StatusOK, wantExchange: "wallex", wantPrice: "101"},
+		{name: "only nobitex", query: "?exchanges=nobitex", wantStatus: http.StatusOK, wantExchange: "nobitex", wantPrice: "100"},
+		{name: "spaces and empties ignored", query: "?exchanges=%20wallex%20,", wantStatus: http.StatusOK, wantExchange: "wallex", wantPrice: "101"},
+		{name: "exchange not mapped", query: "?exchanges=ompfinex", wantStatus: http.StatusBadRequest},
+		{name: "one of them not mapped", query: "?exchanges=wallex,ompfinex", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				hits []string
+			)
+			venue := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				hits = append(hits, r.URL.Path)
+				mu.Unlock()
+				_, _ = io.WriteString(w, books[r.URL.Path])
+			})
+			markets := &stubMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 1, ExchangeName: "nobitex", ExchangeMarketIdentifier: "BTC-USDT", IsActive: true},
+			}}
+			megaMarkets := &stubMegaMarketRepo{megaMarket: &domain.MegaMarket{ID: 1, IsActive: true}}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			newTestHandler(t, venue, markets, megaMarkets).RegisterRoutes(r)
+			req := httptest.NewRequest(http.MethodPut, "/market/best-price"+tt.query,
+				strings.NewReader(`{"mega_market_id":1,"volume":"1","is_buy":true}`))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(hits) > 0 {
+					t.Errorf("exchanges called for a rejected request: %v", hits)
+				}
+				return
+			}
+			var got GetBestExchangePriceByVolumeResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Market.ExchangeName != tt.wantExchange || !got.Price.Equal(decimal.RequireFromString(tt.wantPrice)) {
+				t.Errorf("best = %s at %s, want %s at %s", got.Market.ExchangeName, got.Price, tt.wantExchange, tt.wantPrice)
+			}
+			if tt.query != "" && len(hits) != 1 {
+				t.Errorf("venues called = %v, want only %s", hits, tt.wantExchange)
+			}
+		})
+	}
+}
+
+// TestBestPriceRequestFields checks the handler reads the mega market, volume and side from
+// the body and answers with the venue the service picked
+func TestBestPriceRequestFields(t *testing.T) {
+	books := map[string]string{
+		"/v1/depth":             `{"success":true,"result":{"ask":[{"price":"101","quantity":"5"}],"bid":[{"price":"99","quantity":"5"}]}}`,
+		"/v3/orderbook/BTCUSDT": `{"status":"ok","lastUpdate":1,"asks":[["100","5"]],"bids":[["98","5"]]}`,
+	}
+	tests := []struct {
+		name         string
+		body         string
+		unknown      bool
+		wantStatus   int
+		wantExchange string
+		wantPrice    string
+	}{
+		{name: "buy", body: `{"mega_market_id":7,"volume":"1","is_buy":true}`, wantStatus: http.StatusOK, wantExchange: "nobitex", wantPrice: "100"},
+		{name: "sell", body: `{"mega_market_id":7,"volume":"1","is_buy":false}`, wantStatus: http.StatusOK, wantExchange: "wallex", wantPrice: "99"},
+		{name: "side defaults to sell", body: `{"mega_market_id":7,"volume":"1"}`, wantStatus: http.StatusOK, wantExchange: "wallex", wantPrice: "99"},
+		{name: "malformed body", body: `{"mega_market_id":"seven"}`, wantStatus: http.StatusBadRequest},
+		{name: "volume not a number", body: `{"mega_market_id":7,"volume":"lots","is_buy":true}`, wantStatus: http.StatusBadRequest},
+		{name: "zero volume", body: `{"mega_market_id":7,"volume":"0","is_buy":true}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown mega market", body: `{"mega_market_id":8,"volume":"1","is_buy":true}`, unknown: true, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			venue := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, books[r.URL.Path])
+			})
+			markets := &stubMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 7, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 7, ExchangeName: "nobitex", ExchangeMarketIdentifier: "BTC-USDT", IsActive: true},
+			}}
+			megaMarkets := &stubMegaMarketRepo{megaMarket: &domain.MegaMarket{ID: 7, IsActive: true}}
+			if tt.unknown {
+				megaMarkets.megaMarket = nil
+			}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			newTestHandler(t, venue, markets, megaMarkets).RegisterRoutes(r)
+			req := httptest.NewRequest(http.MethodPut, "/market/best-price", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got GetBestExchangePriceByVolumeResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Market.ExchangeName != tt.wantExchange || !got.Price.Equal(decimal.RequireFromString(tt.wantPrice)) {
+				t.Errorf("best = %s at %s, want %s at %s", got.Market.ExchangeName, got.Price, tt.wantExchange, tt.wantPrice)
+			}
+			if got.Market.MegaMarket.ID != 7 {
+				t.Errorf("mega market = %d, want 7", got.Market.MegaMarket.ID)
+			}
+		})
+	}
+}
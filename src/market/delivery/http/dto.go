@@ -15,6 +15,8 @@
 package http
 
 import (
+	"encoding/json"
+
 	"github.com/MMN3003/mega/src/market/domain"
 	"github.com/shopspring/decimal"
 )
@@ -37,6 +39,17 @@ type MegaMarketDto struct {
 	FeePercentage          decimal.Decimal `json:"fee_percentage" example:"0.01"`
 	SourceTokenSymbol      string          `json:"source_token_symbol" example:"BTC"`
 	DestinationTokenSymbol string          `json:"destination_token_symbol" example:"USDT"`
+	// IndicativePrice is omitted when the mega market could not be priced
+	IndicativePrice *IndicativePriceDto `json:"indicative_price,omitempty"`
+}
+
+// IndicativePriceDto is the best price at the mega market's probe volume. It is for
+// comparing pairs; an order of another size executes at a different price.
+// swagger:model IndicativePriceDto
+type IndicativePriceDto struct {
+	ProbeVolume decimal.Decimal `json:"probe_volume" example:"0.01"`
+	Buy         decimal.Decimal `json:"buy" example:"65010.2"`
+	Sell        decimal.Decimal `json:"sell" example:"64990.7"`
 }
 
 type MarketAndMegaMarketDto struct {
@@ -82,10 +95,13 @@ func MarketAndMegaMarketDtoFromDomain(m domain.Market, megaMarket domain.MegaMar
 }
 
 // fromDomain converts a slice of domain.Market into FetchAndUpdateMarketsResponse
-func FetchAndUpdateMarketsResponseFromDomain(markets []domain.Market, megaMarketMap map[uint]domain.MegaMarket) FetchAndUpdateMarketsResponse {
+func FetchAndUpdateMarketsResponseFromDomain(markets []domain.Market, megaMarketMap map[uint]domain.MegaMarket, prices map[uint]domain.IndicativePrice) FetchAndUpdateMarketsResponse {
 	dtos := make([]MarketAndMegaMarketDto, len(markets))
 	for i, m := range markets {
 		dtos[i] = MarketAndMegaMarketDtoFromDomain(m, megaMarketMap[m.MegaMarketID])
+		if p, ok := prices[m.MegaMarketID]; ok {
+			dtos[i].MegaMarket.IndicativePrice = &IndicativePriceDto{ProbeVolume: p.ProbeVolume, Buy: p.Buy, Sell: p.Sell}
+		}
 	}
 	return FetchAndUpdateMarketsResponse{Markets: dtos}
 }
@@ -106,13 +122,92 @@ type GetBestExchangePriceByVolumeRequestBody struct {
 // CreateQuoteResponseBody returns a quote
 // swagger:model CreateQuoteResponseBody
 type GetBestExchangePriceByVolumeResponse struct {
-	Price  decimal.Decimal        `json:"price" example:"100.0"`
-	Market MarketAndMegaMarketDto `json:"market"`
+	Price decimal.Decimal `json:"price" example:"100.0"`
+	// PriceImpact is how far Price is from the venue's best level, relative to it
+	PriceImpact decimal.Decimal        `json:"price_impact" example:"0.0012"`
+	Market      MarketAndMegaMarketDto `json:"market"`
 }
 
-func GetBestExchangePriceByVolumeResponseFromDomain(m *domain.Market, mm *domain.MegaMarket, price decimal.Decimal) GetBestExchangePriceByVolumeResponse {
+func GetBestExchangePriceByVolumeResponseFromDomain(best *domain.BestPrice) GetBestExchangePriceByVolumeResponse {
 	return GetBestExchangePriceByVolumeResponse{
-		Price:  price,
-		Market: MarketAndMegaMarketDtoFromDomain(*m, *mm),
+		Price:       best.Price,
+		PriceImpact: best.PriceImpact,
+		Market:      MarketAndMegaMarketDtoFromDomain(best.Market, best.MegaMarket),
+	}
+}
+
+// BookLevelDto is a single normalized order book level
+// swagger:model BookLevelDto
+type BookLevelDto struct {
+	Price    decimal.Decimal `json:"price" example:"65000.5"`
+	Quantity decimal.Decimal `json:"quantity" example:"0.25"`
+}
+
+// ParsedDepthDto is the book as the service understood it
+type ParsedDepthDto struct {
+	Asks []BookLevelDto `json:"asks"`
+	Bids []BookLevelDto `json:"bids"`
+}
+
+// DebugDepthResponse shows the exchange response next to the normalized book
+// swagger:model DebugDepthResponse
+type DebugDepthResponse struct {
+	ExchangeName             string          `json:"exchange_name" example:"wallex"`
+	ExchangeMarketIdentifier string          `json:"exchange_market_identifier" example:"BTCUSDT"`
+	Raw                      json.RawMessage `json:"raw" swaggertype:"object"`
+	Parsed                   ParsedDepthDto  `json:"parsed"`
+}
+
+func DebugDepthResponseFromDomain(d *domain.DepthSnapshot) DebugDepthResponse {
+	raw := json.RawMessage(d.Raw)
+	if !json.Valid(d.Raw) {
+		// venue returned something that is not JSON, hand it back as a string
+		raw, _ = json.Marshal(string(d.Raw))
+	}
+	return DebugDepthResponse{
+		ExchangeName:             d.ExchangeName,
+		ExchangeMarketIdentifier: d.ExchangeMarketIdentifier,
+		Raw:                      raw,
+		Parsed: ParsedDepthDto{
+			Asks: bookLevelDtosFromDomain(d.Asks),
+			Bids: bookLevelDtosFromDomain(d.Bids),
+		},
+	}
+}
+
+func bookLevelDtosFromDomain(levels []domain.BookLevel) []BookLevelDto {
+	out := make([]BookLevelDto, len(levels))
+	for i, l := range levels {
+		out[i] = BookLevelDto{Price: l.Price, Quantity: l.Quantity}
+	}
+	return out
+}
+
+// VenueVolumeDto is the 24h volume of one exchange market
+type VenueVolumeDto struct {
+	MarketID     uint            `json:"market_id" example:"1"`
+	ExchangeName string          `json:"exchange_name" example:"wallex"`
+	Volume24h    decimal.Decimal `json:"volume_24h" example:"12.5"`
+}
+
+// MegaMarketVolumeResponse is the 24h volume of a MegaMarket across its venues
+// swagger:model MegaMarketVolumeResponse
+type MegaMarketVolumeResponse struct {
+	MegaMarketID uint             `json:"mega_market_id" example:"1"`
+	Currency     string           `json:"currency" example:"BTC"`
+	Volume24h    decimal.Decimal  `json:"volume_24h" example:"37.5"`
+	Venues       []VenueVolumeDto `json:"venues"`
+}
+
+func MegaMarketVolumeResponseFromDomain(v *domain.MegaMarketVolume) MegaMarketVolumeResponse {
+	venues := make([]VenueVolumeDto, len(v.Venues))
+	for i, m := range v.Venues {
+		venues[i] = VenueVolumeDto{MarketID: m.ID, ExchangeName: m.ExchangeName, Volume24h: m.Volume24h}
+	}
+	return MegaMarketVolumeResponse{
+		MegaMarketID: v.MegaMarketID,
+		Currency:     v.Currency,
+		Volume24h:    v.Volume24h,
+		Venues:       venues,
 	}
 }
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestFetchAndUpdateMarketsResponseIndicativePrice(t *testing.T) {
+	markets := []domain.Market{{ID: 1, MegaMarketID: 1}, {ID: 2, MegaMarketID: 1}, {ID: 3, MegaMarketID: 2}}
+	megaMarkets := map[uint]domain.MegaMarket{1: {ID: 1}, 2: {ID: 2}}
+	prices := map[uint]domain.IndicativePrice{
+		1: {MegaMarketID: 1, ProbeVolume: decimal.RequireFromString("0.01"), Buy: decimal.NewFromInt(101), Sell: decimal.NewFromInt(99)},
+	}
+	tests := []struct {
+		name      string
+		index     int
+		wantPrice bool
+	}{
+		{name: "priced", index: 0, wantPrice: true},
+		{name: "every market of the mega market", index: 1, wantPrice: true},
+		{name: "not priced", index: 2},
+	}
+	resp := FetchAndUpdateMarketsResponseFromDomain(markets, megaMarkets, prices)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resp.Markets[tt.index].MegaMarket.IndicativePrice
+			if (got != nil) != tt.wantPrice {
+				t.Fatalf("indicative price = %+v, want one: %v", got, tt.wantPrice)
+			}
+			if got == nil {
+				return
+			}
+			if !got.ProbeVolume.Equal(decimal.RequireFromString("0.01")) || !got.Buy.Equal(decimal.NewFromInt(101)) ||
+				!got.Sell.Equal(decimal.NewFromInt(99)) {
+				t.Errorf("indicative price = %+v", got)
+			}
+		})
+	}
+}
@@ -1,74 +1,171 @@
 package http
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/MMN3003/mega/src/apierror"
 	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/market/domain"
 	"github.com/MMN3003/mega/src/market/usecase"
 	"github.com/shopspring/decimal"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultRetryAfter is used when no WithRetryAfter option is given
+const defaultRetryAfter = 30 * time.Second
+
 // Handler binds usecase + logger
 type Handler struct {
 	service *usecase.MarketService
 	logger  *logger.Logger
+	// retryAfter is sent with 503s so clients back off while every exchange is down
+	retryAfter time.Duration
+	// rateLimit guards the public routes, which fan out to the exchanges
+	rateLimit gin.HandlerFunc
+}
+
+// HandlerOption configures optional Handler behaviour
+type HandlerOption func(*Handler)
+
+// WithRetryAfter sets the Retry-After of 503 responses sent while every exchange is down
+func WithRetryAfter(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if d > 0 {
+			h.retryAfter = d
+		}
+	}
 }
 
-func NewHandler(s *usecase.MarketService, l *logger.Logger) *Handler {
-	return &Handler{service: s, logger: l}
+// WithRateLimit limits the public routes with limit, see middleware.RateLimit
+func WithRateLimit(limit gin.HandlerFunc) HandlerOption {
+	return func(h *Handler) {
+		h.rateLimit = limit
+	}
+}
+
+func NewHandler(s *usecase.MarketService, l *logger.Logger, opts ...HandlerOption) *Handler {
+	h := &Handler{service: s, logger: l, retryAfter: defaultRetryAfter, rateLimit: func(c *gin.Context) { c.Next() }}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// exchangesUnavailable answers 503 with a Retry-After header when err means every exchange
+// the request needs is down, and reports whether it did.
+func (h *Handler) exchangesUnavailable(c *gin.Context, err error) bool {
+	if !errors.Is(err, domain.ErrNoExchangesAvailable) {
+		return false
+	}
+	seconds := int((h.retryAfter + time.Second - 1) / time.Second) // Retry-After is in whole seconds
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	apierror.RespondWithDetails(c, http.StatusServiceUnavailable, apierror.CodeUnavailable,
+		errors.New("exchanges are unavailable, retry later"), gin.H{"retry_after": seconds})
+	return true
 }
 
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
-	r.GET("/markets", h.ListPairs)
-	r.PUT("/market/best-price", h.GetBestExchangePriceByVolume)
+	r.GET("/markets", h.rateLimit, h.ListPairs)
+	r.GET("/markets/:id/volume", h.rateLimit, h.GetMegaMarketVolume)
+	r.PUT("/market/best-price", h.rateLimit, h.GetBestExchangePriceByVolume)
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 }
 
+// RegisterAdminRoutes mounts the operator endpoints on an already authenticated group
+func (h *Handler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/debug/depth", h.DebugDepth)
+	r.POST("/markets/refresh", h.RefreshMarkets)
+}
+
 // ListPairs godoc
 //
 //	@Summary		List available market
-//	@Description	Get all available market
+//	@Description	Get all available market, as stored by the last market refresh; the exchanges are not contacted.
+//	@Description	Each mega market carries an indicative buy and sell price computed at its probe volume: it is
+//	@Description	comparable across pairs, but not the price an order of another size executes at.
 //	@Tags			market
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	http.FetchAndUpdateMarketsResponse
-//	@Failure		500	{object}	object{error=string}
+//	@Failure		429	{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500	{object}	apierror.APIError
 //	@Router			/markets [get]
 func (h *Handler) ListPairs(c *gin.Context) {
+	ctx := c.Request.Context()
+	markets, megaMarketMap, err := h.service.ListMarkets(ctx)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("ListPairs err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+
+	prices := h.service.GetIndicativePrices(ctx, megaMarketMap)
+	c.JSON(http.StatusOK, FetchAndUpdateMarketsResponseFromDomain(markets, megaMarketMap, prices))
+}
+
+// RefreshMarkets godoc
+//
+//	@Summary		Refresh markets from the exchanges
+//	@Description	Fetch every exchange's market list, persist the changes and return the stored markets. This also
+//	@Description	runs on the CRON_MARKET_REFRESH schedule; call it to pick up a new listing without waiting.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Success		200				{object}	http.FetchAndUpdateMarketsResponse
+//	@Failure		401				{object}	apierror.APIError
+//	@Failure		500				{object}	apierror.APIError
+//	@Failure		503				{object}	apierror.APIError	"every exchange is down, see Retry-After"
+//	@Router			/admin/markets/refresh [post]
+func (h *Handler) RefreshMarkets(c *gin.Context) {
 	ctx := c.Request.Context()
 	markets, megaMarketMap, err := h.service.FetchAndUpdateMarkets(ctx)
+	if h.exchangesUnavailable(c, err) {
+		return
+	}
 	if err != nil {
-		h.logger.Errorf("ListPairs err: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(c.Request.Context()).Errorf("RefreshMarkets err: %v", err)
+		apierror.Internal(c)
 		return
 	}
 
-	c.JSON(http.StatusOK, FetchAndUpdateMarketsResponseFromDomain(markets, megaMarketMap))
+	prices := h.service.GetIndicativePrices(ctx, megaMarketMap)
+	c.JSON(http.StatusOK, FetchAndUpdateMarketsResponseFromDomain(markets, megaMarketMap, prices))
 }
 
 // GetBestExchangePriceByVolume godoc
 //
 //	@Summary		Get best exchange price by volume
-//	@Description	Get the best exchange price for a given market and volume
+//	@Description	Get the best exchange price for a given market and volume. exchanges restricts the
+//	@Description	comparison to a subset of the mega market's exchanges, to price one venue in isolation.
+//	@Description	price_impact is how far the average price is from the venue's best level (0.01 = 1%); a volume
+//	@Description	whose impact exceeds the mega market's slippage is rejected with 422.
 //	@Tags			market
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		GetBestExchangePriceByVolumeRequestBody	true	"Request body"
+//	@Param			request		body		GetBestExchangePriceByVolumeRequestBody	true	"Request body"
+//	@Param			exchanges	query		string									false	"Comma separated exchange names"	example(wallex,nobitex)
 //	@Success		200	{object}	GetBestExchangePriceByVolumeResponse
-//	@Failure		400	{object}	object{error=string}
-//	@Failure		500	{object}	object{error=string}
+//	@Failure		400	{object}	apierror.APIError
+//	@Failure		404	{object}	apierror.APIError
+//	@Failure		422	{object}	apierror.APIError	"price impact above the mega market's slippage, or price outside the reference band"
+//	@Failure		429	{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500	{object}	apierror.APIError
+//	@Failure		503	{object}	apierror.APIError	"every exchange is down, see Retry-After"
 //	@Router			/market/best-price [put]
 func (h *Handler) GetBestExchangePriceByVolume(c *gin.Context) {
 	ctx := c.Request.Context()
 	// get data from body
 	var req GetBestExchangePriceByVolumeRequestBody
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Errorf("GetBestExchangePriceByVolume err: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		h.logger.WithContext(c.Request.Context()).Errorf("GetBestExchangePriceByVolume err: %v", err)
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid request"))
 		return
 	}
 	megaMarketId := req.MegaMarketID
@@ -76,16 +173,102 @@ func (h *Handler) GetBestExchangePriceByVolume(c *gin.Context) {
 
 	volume, err := decimal.NewFromString(volumeStr)
 	if err != nil {
-		h.logger.Errorf("GetBestExchangePriceByVolume err: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid volume"})
+		h.logger.WithContext(c.Request.Context()).Errorf("GetBestExchangePriceByVolume err: %v", err)
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid volume"))
+		return
+	}
+
+	var exchanges []string
+	for _, e := range strings.Split(c.Query("exchanges"), ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			exchanges = append(exchanges, e)
+		}
+	}
+
+	best, err := h.service.GetBestExchangePriceOn(ctx, megaMarketId, volume, req.IsBuy, exchanges)
+	switch {
+	case errors.Is(err, domain.ErrInvalidVolume), errors.Is(err, domain.ErrExchangeNotMapped):
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err)
+		return
+	case errors.Is(err, domain.ErrPriceImpactTooHigh), errors.Is(err, domain.ErrPriceOutOfBand):
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeUnprocessable, err)
+		return
+	case errors.Is(err, domain.ErrMegaMarketNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, err)
+		return
+	}
+	if h.exchangesUnavailable(c, err) {
+		return
+	}
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("GetBestExchangePriceByVolume err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	c.JSON(http.StatusOK, GetBestExchangePriceByVolumeResponseFromDomain(best))
+}
+
+// GetMegaMarketVolume godoc
+//
+//	@Summary		MegaMarket 24h volume
+//	@Description	24h traded volume of a MegaMarket summed over its active exchange markets, in the source token
+//	@Tags			market
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"MegaMarket id"
+//	@Success		200	{object}	MegaMarketVolumeResponse
+//	@Failure		400	{object}	apierror.APIError
+//	@Failure		404	{object}	apierror.APIError
+//	@Failure		429	{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500	{object}	apierror.APIError
+//	@Router			/markets/{id}/volume [get]
+func (h *Handler) GetMegaMarketVolume(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid id"))
+		return
+	}
+	volume, err := h.service.GetMegaMarketVolume(ctx, uint(id))
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("GetMegaMarketVolume err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	if volume == nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, errors.New("mega market not found"))
+		return
+	}
+	c.JSON(http.StatusOK, MegaMarketVolumeResponseFromDomain(volume))
+}
+
+// DebugDepth godoc
+//
+//	@Summary		Raw exchange depth
+//	@Description	Fetch the order book of one exchange market and return the raw venue response next to the parsed book
+//	@Tags			admin
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Param			exchange		query		string	true	"Exchange name"	Enums(ompfinex, wallex, nobitex)
+//	@Param			identifier		query		string	true	"Exchange market identifier"
+//	@Success		200				{object}	DebugDepthResponse
+//	@Failure		400				{object}	apierror.APIError
+//	@Failure		401				{object}	apierror.APIError
+//	@Failure		502				{object}	apierror.APIError
+//	@Router			/admin/debug/depth [get]
+func (h *Handler) DebugDepth(c *gin.Context) {
+	exchange := c.Query("exchange")
+	identifier := c.Query("identifier")
+	if exchange == "" || identifier == "" {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("exchange and identifier are required"))
 		return
 	}
 
-	price, market, megaMarket, err := h.service.GetBestExchangePriceByVolume(ctx, megaMarketId, volume, req.IsBuy)
+	snapshot, err := h.service.DebugMarketDepth(c.Request.Context(), exchange, identifier)
 	if err != nil {
-		h.logger.Errorf("GetBestExchangePriceByVolume err: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(c.Request.Context()).Errorf("DebugDepth exchange=%s identifier=%s err: %v", exchange, identifier, err)
+		apierror.Respond(c, http.StatusBadGateway, apierror.CodeUpstreamFailed, err)
 		return
 	}
-	c.JSON(http.StatusOK, GetBestExchangePriceByVolumeResponseFromDomain(market, megaMarket, price))
+	c.JSON(http.StatusOK, DebugDepthResponseFromDomain(snapshot))
 }
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/config"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/market/usecase"
+	"github.com/MMN3003/mega/src/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestHandler returns a handler on the given repos whose exchanges are all served by venue
+func newTestHandler(t *testing.T, venue http.Handler, markets domain.MarketRepository, megaMarkets domain.MegaMarketRepository, opts ...HandlerOption) *Handler {
+	t.Helper()
+	srv := httptest.NewServer(venue)
+	t.Cleanup(srv.Close)
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	cfg := &config.Config{
+		OMP:     config.OMPConfig{BaseURL: srv.URL},
+		Wallex:  config.WallexConfig{BaseURL: srv.URL},
+		Nobitex: config.NobitexConfig{BaseURL: srv.URL},
+	}
+	svc, err := usecase.NewService(markets, megaMarkets, l, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(svc, l, opts...)
+}
+
+// newTestRouter mounts the admin routes of a handler whose exchanges are all served by venue
+func newTestRouter(t *testing.T, venue http.Handler) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	newTestHandler(t, venue, nil, nil).RegisterAdminRoutes(r.Group("/admin", middleware.AdminAuth("secret")))
+	return r
+}
+
+func TestDebugDepth(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"2"}],"bid":[{"price":"99","quantity":"3"}]}}`
+	tests := []struct {
+		name       string
+		query      string
+		token      string
+		venueDown  bool
+		wantStatus int
+	}{
+		{name: "raw and parsed", query: "?exchange=wallex&identifier=BTCUSDT", token: "secret", wantStatus: http.StatusOK},
+		{name: "no admin token", query: "?exchange=wallex&identifier=BTCUSDT", wantStatus: http.StatusUnauthorized},
+		{name: "missing identifier", query: "?exchange=wallex", token: "secret", wantStatus: http.StatusBadRequest},
+		{name: "venue down", query: "?exchange=wallex&identifier=BTCUSDT", token: "secret", venueDown: true, wantStatus: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRouter(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.venueDown {
+					http.Error(w, "maintenance", http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = io.WriteString(w, wallexBook)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/admin/debug/depth"+tt.query, nil)
+			req.Header.Set(middleware.AdminHeader, tt.token)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got DebugDepthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if string(got.Raw) != wallexBook {
+				t.Errorf("raw = %s, want %s", got.Raw, wallexBook)
+			}
+			if len(got.Parsed.Asks) != 1 || got.Parsed.Asks[0].Price.String() != "101" ||
+				len(got.Parsed.Bids) != 1 || got.Parsed.Bids[0].Quantity.String() != "3" {
+				t.Errorf("parsed = %+v", got.Parsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// TestListPairs goes through the whole handler: stored markets, their mega markets and the
+// indicative prices all end up in the response
+func TestListPairs(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"5"}],"bid":[{"price":"99","quantity":"5"}]}}`
+	btc := domain.Market{ID: 1, MegaMarketID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", MarketName: "BTC/USDT", IsActive: true}
+	tests := []struct {
+		name        string
+		markets     []domain.Market
+		megaMarket  *domain.MegaMarket
+		repoErr     error
+		wantStatus  int
+		wantMarkets int
+		wantPrice   bool
+	}{
+		{
+			name:    "markets with their mega market",
+			markets: []domain.Market{btc},
+			megaMarket: &domain.MegaMarket{ID: 1, IsActive: true, SourceTokenSymbol: "BTC", DestinationTokenSymbol: "USDT",
+				ProbeVolume: decimal.NewFromInt(1)},
+			wantStatus: http.StatusOK, wantMarkets: 1, wantPrice: true,
+		},
+		{name: "nothing listed", wantStatus: http.StatusOK},
+		{name: "mega markets unreadable", markets: []domain.Market{btc}, repoErr: errors.New("db down"), wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			venue := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, wallexBook)
+			})
+			markets := &stubMarketRepo{markets: tt.markets}
+			megaMarkets := &stubMegaMarketRepo{megaMarket: tt.megaMarket, err: tt.repoErr}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			newTestHandler(t, venue, markets, megaMarkets).RegisterRoutes(r)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/markets", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got FetchAndUpdateMarketsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Markets) != tt.wantMarkets {
+				t.Fatalf("markets = %+v, want %d", got.Markets, tt.wantMarkets)
+			}
+			if tt.wantMarkets == 0 {
+				return
+			}
+			m := got.Markets[0]
+			if m.ID != 1 || m.ExchangeName != "wallex" || m.MegaMarket.ID != 1 || m.MegaMarket.SourceTokenSymbol != "BTC" {
+				t.Errorf("market = %+v", m)
+			}
+			if p := m.MegaMarket.IndicativePrice; (p != nil) != tt.wantPrice ||
+				p != nil && (!p.Buy.Equal(decimal.NewFromInt(101)) || !p.Sell.Equal(decimal.NewFromInt(99))) {
+				t.Errorf("indicative price = %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/apierror"
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// stubMarketRepo serves the markets of one mega market; other methods panic
+type stubMarketRepo struct {
+	domain.MarketRepository
+	markets []domain.Market
+}
+
+func (r *stubMarketRepo) GetMarketsByMegaMarketId(ctx context.Context, megaMarketId uint) ([]domain.Market, error) {
+	return r.markets, nil
+}
+
+func (r *stubMarketRepo) GetAllActiveMarkets(ctx context.Context) ([]domain.Market, error) {
+	return r.markets, nil
+}
+
+// stubMegaMarketRepo serves one mega market, or fails with err
+type stubMegaMarketRepo struct {
+	domain.MegaMarketRepository
+	megaMarket *domain.MegaMarket
+	err        error
+}
+
+func (r *stubMegaMarketRepo) GetActiveMegaMarketByID(ctx context.Context, id uint) (*domain.MegaMarket, error) {
+	return r.megaMarket, r.err
+}
+
+func (r *stubMegaMarketRepo) GetAllActiveMegaMarkets(ctx context.Context) ([]domain.MegaMarket, error) {
+	if r.err != nil || r.megaMarket == nil {
+		return nil, r.err
+	}
+	return []domain.MegaMarket{*r.megaMarket}, nil
+}
+
+func TestBestPriceExchangesDown(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"2"}],"bid":[{"price":"99","quantity":"2"}]}}`
+	tests := []struct {
+		name           string
+		venueDown      bool
+		repoErr        error
+		wantStatus     int
+		wantCode       string
+		wantRetryAfter string
+	}{
+		{name: "priced", wantStatus: http.StatusOK},
+		// 4.5s is rounded up, Retry-After is in whole seconds
+		{name: "every exchange down", venueDown: true, wantStatus: http.StatusServiceUnavailable,
+			wantCode: apierror.CodeUnavailable, wantRetryAfter: "5"},
+		{name: "genuine error", repoErr: errors.New("connection refused"), wantStatus: http.StatusInternalServerError,
+			wantCode: apierror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			venue := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.venueDown {
+					http.Error(w, "maintenance", http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = io.WriteString(w, wallexBook)
+			})
+			markets := &stubMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 1, ExchangeName: "nobitex", ExchangeMarketIdentifier: "btc-usdt", IsActive: true},
+			}}
+			megaMarkets := &stubMegaMarketRepo{megaMarket: &domain.MegaMarket{ID: 1, IsActive: true}, err: tt.repoErr}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			newTestHandler(t, venue, markets, megaMarkets, WithRetryAfter(4500*time.Millisecond)).RegisterRoutes(r)
+			req := httptest.NewRequest(http.MethodPut, "/market/best-price",
+				strings.NewReader(`{"mega_market_id":1,"volume":"1","is_buy":true}`))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body apierror.APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", body.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrPriceOutOfBand is returned when the computed price deviates too far from the reference oracle
+	ErrPriceOutOfBand = errors.New("price outside reference band")
+	// ErrBelowMinimumSize is returned when an order volume is under what the exchange market accepts
+	ErrBelowMinimumSize = errors.New("order below market minimum size")
+	// ErrPriceOutOfRange is returned when an order price is outside the exchange market's price limits
+	ErrPriceOutOfRange = errors.New("order price outside market limits")
+	// ErrInvalidVolume is returned when a requested volume is not positive
+	ErrInvalidVolume = errors.New("volume must be positive")
+	// ErrMegaMarketNotFound is returned when no active mega market exists for the given id
+	ErrMegaMarketNotFound = errors.New("no active mega market found")
+	// ErrNoExchangesAvailable is returned when every exchange a request depends on failed to answer
+	ErrNoExchangesAvailable = errors.New("no exchange available")
+	// ErrExchangeNotMapped is returned when a requested exchange has no market for the mega market
+	ErrExchangeNotMapped = errors.New("exchange not mapped for mega market")
+	// ErrPriceImpactTooHigh is returned when filling a volume moves the price further than the mega market's slippage allows
+	ErrPriceImpactTooHigh = errors.New("price impact exceeds mega market slippage")
+)
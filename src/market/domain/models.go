@@ -1,6 +1,10 @@
 package domain
 
-import "github.com/shopspring/decimal"
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
 
 type Market struct {
 	ID                          uint
@@ -10,6 +14,48 @@ type Market struct {
 	MegaMarketID                uint
 	IsActive                    bool
 	ExchangeMarketFeePercentage decimal.Decimal
+	// AmountPrecision and PricePrecision are the decimals the exchange accepts; nil when unknown
+	AmountPrecision *int32
+	PricePrecision  *int32
+	// MinAmount is the smallest order volume the exchange accepts; zero when unknown
+	MinAmount decimal.Decimal
+	// MinPrice and MaxPrice bound the price the exchange accepts; zero when unbounded
+	MinPrice decimal.Decimal
+	MaxPrice decimal.Decimal
+	// Volume24h is the traded base asset volume of the last 24h, refreshed on every sync
+	Volume24h decimal.Decimal
+}
+
+// ValidateOrder checks an order's volume and price against the exchange limits, so
+// it is rejected with a clear reason instead of an opaque exchange error.
+func (m Market) ValidateOrder(volume, price decimal.Decimal) error {
+	if volume.LessThan(m.MinAmount) {
+		return fmt.Errorf("%w: volume %s on %s %s (min %s)",
+			ErrBelowMinimumSize, volume, m.ExchangeName, m.MarketName, m.MinAmount)
+	}
+	if m.MinPrice.IsPositive() && price.LessThan(m.MinPrice) {
+		return fmt.Errorf("%w: price %s on %s %s (min %s)",
+			ErrPriceOutOfRange, price, m.ExchangeName, m.MarketName, m.MinPrice)
+	}
+	if m.MaxPrice.IsPositive() && price.GreaterThan(m.MaxPrice) {
+		return fmt.Errorf("%w: price %s on %s %s (max %s)",
+			ErrPriceOutOfRange, price, m.ExchangeName, m.MarketName, m.MaxPrice)
+	}
+	return nil
+}
+
+// RoundAmount rounds volume down to the exchange's amount precision and rejects it with
+// ErrBelowMinimumSize when nothing tradable is left.
+func (m Market) RoundAmount(volume decimal.Decimal) (decimal.Decimal, error) {
+	rounded := volume
+	if m.AmountPrecision != nil {
+		rounded = volume.RoundFloor(*m.AmountPrecision)
+	}
+	if !rounded.IsPositive() || rounded.LessThan(m.MinAmount) {
+		return decimal.Zero, fmt.Errorf("%w: %s on %s %s (min %s)",
+			ErrBelowMinimumSize, volume, m.ExchangeName, m.MarketName, m.MinAmount)
+	}
+	return rounded, nil
 }
 
 type MegaMarket struct {
@@ -20,4 +66,67 @@ type MegaMarket struct {
 	SourceTokenSymbol      string
 	DestinationTokenSymbol string
 	SlipagePercentage      decimal.Decimal
+	// ProbeVolume is the reference size, in SourceTokenSymbol, indicative prices are
+	// computed at; zero falls back to the configured default
+	ProbeVolume decimal.Decimal
+}
+
+// IndicativePrice is the best buy and sell price of a MegaMarket at its probe volume. It
+// keeps prices comparable across pairs and over time, but it is not the price an order of
+// another size would execute at; that needs the best price at the order's own volume.
+type IndicativePrice struct {
+	MegaMarketID uint
+	ProbeVolume  decimal.Decimal
+	Buy          decimal.Decimal
+	Sell         decimal.Decimal
+}
+
+// BestPrice is the best average price to fill a volume of a MegaMarket, and the exchange
+// market offering it
+type BestPrice struct {
+	Price decimal.Decimal
+	// TopOfBook is the price of the venue's best level
+	TopOfBook decimal.Decimal
+	// PriceImpact is how far Price is from TopOfBook, relative to it (0.01 = 1%)
+	PriceImpact decimal.Decimal
+	Market      Market
+	MegaMarket  MegaMarket
+}
+
+// MegaMarketVolume is the 24h volume of a MegaMarket summed over its exchange markets,
+// in SourceTokenSymbol units.
+type MegaMarketVolume struct {
+	MegaMarketID uint
+	Currency     string
+	Volume24h    decimal.Decimal
+	Venues       []Market
+}
+
+// ExecutionAllocation is the share of an order routed to a single exchange market
+type ExecutionAllocation struct {
+	Market   Market
+	Volume   decimal.Decimal
+	AvgPrice decimal.Decimal
+}
+
+// ExecutionPlan splits an order across exchanges, cheapest levels first
+type ExecutionPlan struct {
+	Volume      decimal.Decimal
+	AvgPrice    decimal.Decimal // volume-weighted across all allocations
+	Allocations []ExecutionAllocation
+}
+
+// BookLevel is a single normalized order book level
+type BookLevel struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// DepthSnapshot pairs the body an exchange returned for a depth call with the book parsed from it
+type DepthSnapshot struct {
+	ExchangeName             string
+	ExchangeMarketIdentifier string
+	Raw                      []byte
+	Asks                     []BookLevel
+	Bids                     []BookLevel
 }
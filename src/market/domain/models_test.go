@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundAmount(t *testing.T) {
+	precision := func(p int32) *int32 { return &p }
+	tests := []struct {
+		name      string
+		precision *int32
+		minAmount string
+		volume    string
+		want      string
+		wantErr   error
+	}{
+		// wallex BTCUSDT trades 6 amount decimals
+		{name: "within precision", precision: precision(6), minAmount: "0.000001", volume: "0.123456", want: "0.123456"},
+		{name: "rounded down", precision: precision(6), minAmount: "0.000001", volume: "0.1234569", want: "0.123456"},
+		// wallex SHIBTMN trades whole units only
+		{name: "whole units", precision: precision(0), volume: "1500.99", want: "1500"},
+		{name: "nothing left after rounding", precision: precision(0), volume: "0.9", wantErr: ErrBelowMinimumSize},
+		{name: "below the minimum", precision: precision(6), minAmount: "0.0001", volume: "0.00005", wantErr: ErrBelowMinimumSize},
+		{name: "unknown precision kept", volume: "0.123456789", want: "0.123456789"},
+		{name: "zero volume", precision: precision(6), volume: "0", wantErr: ErrBelowMinimumSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Market{ExchangeName: "wallex", MarketName: "BTC/USDT", AmountPrecision: tt.precision}
+			if tt.minAmount != "" {
+				m.MinAmount = decimal.RequireFromString(tt.minAmount)
+			}
+
+			got, err := m.RoundAmount(decimal.RequireFromString(tt.volume))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("amount = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarketValidateOrder(t *testing.T) {
+	m := Market{ExchangeName: "ompfinex", MarketName: "BTC/IRT", MinAmount: decimal.RequireFromString("0.001"),
+		MinPrice: decimal.NewFromInt(1000), MaxPrice: decimal.NewFromInt(100000)}
+	tests := []struct {
+		name    string
+		market  Market
+		volume  string
+		price   string
+		wantErr error
+	}{
+		{name: "within limits", market: m, volume: "0.5", price: "50000"},
+		{name: "at the limits", market: m, volume: "0.001", price: "100000"},
+		{name: "below min size", market: m, volume: "0.0009", price: "50000", wantErr: ErrBelowMinimumSize},
+		{name: "below min price", market: m, volume: "0.5", price: "999", wantErr: ErrPriceOutOfRange},
+		{name: "above max price", market: m, volume: "0.5", price: "100001", wantErr: ErrPriceOutOfRange},
+		{name: "no limits known", volume: "0.0000001", price: "1000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.market.ValidateOrder(decimal.RequireFromString(tt.volume), decimal.RequireFromString(tt.price))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -13,10 +13,12 @@ type MarketRepository interface {
 	UpdateMarket(ctx context.Context, m *Market) error
 	SoftDelete(ctx context.Context, id uint) error
 	SoftDeleteAll(ctx context.Context) error
+	SoftDeleteByIds(ctx context.Context, ids []uint) error
 
 	GetMarketsByExchangeName(ctx context.Context, exchangeName string) ([]Market, error)
 	GetMarketsByMarketName(ctx context.Context, marketName string) ([]Market, error)
 	UpsertMarketsForExchange(ctx context.Context, markets []Market) error
+	// GetMarketsByMegaMarketId returns the active markets mapped to the mega market
 	GetMarketsByMegaMarketId(ctx context.Context, megaMarketId uint) ([]Market, error)
 	GetAllActiveMarkets(ctx context.Context) ([]Market, error)
 }
@@ -31,13 +33,27 @@ type MegaMarketRepository interface {
 	GetAllActiveMegaMarkets(ctx context.Context) ([]MegaMarket, error)
 }
 
+// PriceOracle supplies an independent reference price for a MegaMarket,
+// quoted as DestinationTokenSymbol per SourceTokenSymbol.
+type PriceOracle interface {
+	ReferencePrice(ctx context.Context, megaMarket *MegaMarket) (decimal.Decimal, error)
+}
+
 type MarketUseCase interface {
 	// Market lifecycle
 	UpsertMarketPairs(ctx context.Context, exchangeName string, markets []string) error
 	FetchAndUpdateMarkets(ctx context.Context) ([]Market, map[uint]MegaMarket, error)
+	// ListMarkets returns the stored active markets without contacting the exchanges
+	ListMarkets(ctx context.Context) ([]Market, map[uint]MegaMarket, error)
 	GetMarketByID(ctx context.Context, id uint) (*Market, error)
 	GetMegaMarketByID(ctx context.Context, id uint) (*MegaMarket, error)
+	GetMarketsByMegaMarketID(ctx context.Context, megaMarketId uint) ([]Market, error)
+	GetMegaMarketVolume(ctx context.Context, megaMarketId uint) (*MegaMarketVolume, error)
 
 	// Pricing logic
 	GetBestExchangePriceByVolume(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (decimal.Decimal, *Market, *MegaMarket, error)
+	// GetBestExchangePriceOn only considers the given exchanges, which must all be mapped for the mega market
+	GetBestExchangePriceOn(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool, exchanges []string) (*BestPrice, error)
+	GetBestExecutionPlan(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (*ExecutionPlan, error)
+	GetIndicativePrices(ctx context.Context, megaMarkets map[uint]MegaMarket) map[uint]IndicativePrice
 }
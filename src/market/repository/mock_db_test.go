@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MMN3003/mega/src/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newMockRepo returns a market Repo on a sqlmock connection; every expectation set on the
+// mock must be met by the end of the test.
+func newMockRepo(t *testing.T) (*Repo, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	return &Repo{db: gdb, log: l}, mock
+}
+
+// marketColumns are the markets table columns returned by the mocked selects
+var marketColumns = []string{"id", "exchange_market_identifier", "exchange_name", "mega_market_id", "market_name", "is_active", "exchange_market_fee_percentage"}
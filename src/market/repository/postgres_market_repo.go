@@ -19,6 +19,10 @@ var _ domain.MarketRepository = (*Repo)(nil)
 // CreatedAt time.Time
 // UpdatedAt time.Time
 // DeletedAt gorm.DeletedAt `gorm:"index"`
+//
+// uidx_exchange_market deliberately spans soft-deleted rows too: an exchange market keeps
+// one row, and so one id, for its whole life. UpsertMarketsForExchange revives a
+// soft-deleted row by clearing deleted_at instead of inserting a duplicate next to it.
 type Market struct {
 	gorm.Model
 
@@ -28,6 +32,12 @@ type Market struct {
 	MarketName                  string `gorm:"not null;index:idx_market"`
 	IsActive                    bool   `gorm:"not null;default:true"`
 	ExchangeMarketFeePercentage decimal.Decimal
+	AmountPrecision             *int32
+	PricePrecision              *int32
+	MinAmount                   decimal.Decimal `gorm:"type:numeric;not null;default:0"`
+	MinPrice                    decimal.Decimal `gorm:"type:numeric;not null;default:0"`
+	MaxPrice                    decimal.Decimal `gorm:"type:numeric;not null;default:0"`
+	Volume24h                   decimal.Decimal `gorm:"column:volume_24h;type:numeric;not null;default:0"`
 }
 
 // ---------- REPO ----------
@@ -52,6 +62,7 @@ func (r *Repo) SaveMarket(ctx context.Context, m *domain.Market) error {
 		ExchangeName:                m.ExchangeName,
 		MarketName:                  m.MarketName,
 		IsActive:                    m.IsActive,
+		MegaMarketID:                m.MegaMarketID,
 		ExchangeMarketFeePercentage: m.ExchangeMarketFeePercentage,
 	}
 	return r.db.WithContext(ctx).Create(&model).Error
@@ -76,6 +87,7 @@ func (r *Repo) UpdateMarket(ctx context.Context, m *domain.Market) error {
 			ExchangeName:                m.ExchangeName,
 			MarketName:                  m.MarketName,
 			IsActive:                    m.IsActive,
+			MegaMarketID:                m.MegaMarketID,
 			ExchangeMarketFeePercentage: m.ExchangeMarketFeePercentage,
 		}).Error
 }
@@ -83,6 +95,9 @@ func (r *Repo) UpdateMarket(ctx context.Context, m *domain.Market) error {
 func (r *Repo) SoftDelete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&Market{}, id).Error
 }
+
+// SoftDeleteAll soft deletes every market. Markets upserted afterwards revive their old
+// rows, see Market.
 func (r *Repo) SoftDeleteAll(ctx context.Context) error {
 	return r.db.
 		WithContext(ctx).
@@ -90,6 +105,13 @@ func (r *Repo) SoftDeleteAll(ctx context.Context) error {
 		Delete(&Market{}).Error
 }
 
+func (r *Repo) SoftDeleteByIds(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Delete(&Market{}, ids).Error
+}
+
 // Indexed fetch: by ExchangeName
 func (r *Repo) GetMarketsByExchangeName(ctx context.Context, exchangeName string) ([]domain.Market, error) {
 	var models []Market
@@ -112,11 +134,12 @@ func (r *Repo) GetMarketsByMarketName(ctx context.Context, marketName string) ([
 	return r.toDomainMarkets(models), nil
 }
 
-// Indexed fetch: by MegaMarketID
+// Indexed fetch: the active markets of a MegaMarket. Inactive venues must never be priced
+// or traded on.
 func (r *Repo) GetMarketsByMegaMarketId(ctx context.Context, megaMarketId uint) ([]domain.Market, error) {
 	var models []Market
 	if err := r.db.WithContext(ctx).
-		Where("mega_market_id = ?", megaMarketId).
+		Where("mega_market_id = ? AND is_active = ?", megaMarketId, true).
 		Find(&models).Error; err != nil {
 		return nil, err
 	}
@@ -134,16 +157,24 @@ func (r *Repo) UpsertMarketsForExchange(ctx context.Context, markets []domain.Ma
 			IsActive:                    m.IsActive,
 			MegaMarketID:                m.MegaMarketID,
 			ExchangeMarketFeePercentage: m.ExchangeMarketFeePercentage,
+			AmountPrecision:             m.AmountPrecision,
+			PricePrecision:              m.PricePrecision,
+			MinAmount:                   m.MinAmount,
+			MinPrice:                    m.MinPrice,
+			MaxPrice:                    m.MaxPrice,
+			Volume24h:                   m.Volume24h,
 		})
 	}
 
 	// Use GORM upsert with PostgreSQL ON CONFLICT
-	// conflict target: exchange_identifier + market_name (you should define a unique index on these two columns!)
+	// conflict target: uidx_exchange_market (exchange_market_identifier + exchange_name). The
+	// index includes soft-deleted rows, so a relisted market conflicts with its old row and
+	// resetting deleted_at brings it back with its id.
 	if err := r.db.WithContext(ctx).
 		Clauses(
 			clause.OnConflict{
 				Columns:   []clause.Column{{Name: "exchange_market_identifier"}, {Name: "exchange_name"}},
-				DoUpdates: clause.AssignmentColumns([]string{"exchange_name", "is_active", "market_name", "updated_at", "deleted_at", "exchange_market_fee_percentage"}),
+				DoUpdates: clause.AssignmentColumns([]string{"exchange_name", "is_active", "market_name", "mega_market_id", "updated_at", "deleted_at", "exchange_market_fee_percentage", "amount_precision", "price_precision", "min_amount", "min_price", "max_price", "volume_24h"}),
 			},
 		).
 		Create(&models).Error; err != nil {
@@ -175,6 +206,12 @@ func (r *Repo) toDomainMarket(m *Market) *domain.Market {
 		IsActive:                    m.IsActive,
 		MegaMarketID:                m.MegaMarketID,
 		ExchangeMarketFeePercentage: m.ExchangeMarketFeePercentage,
+		AmountPrecision:             m.AmountPrecision,
+		PricePrecision:              m.PricePrecision,
+		MinAmount:                   m.MinAmount,
+		MinPrice:                    m.MinPrice,
+		MaxPrice:                    m.MaxPrice,
+		Volume24h:                   m.Volume24h,
 	}
 }
 func (r *Repo) toDomainMarkets(ms []Market) []domain.Market {
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetMarketsByMegaMarketId(t *testing.T) {
+	const query = `SELECT \* FROM "markets" WHERE \(mega_market_id = \$1 AND is_active = \$2\) AND "markets"."deleted_at" IS NULL`
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		err     error
+		wantIDs []uint
+		wantErr bool
+	}{
+		{
+			name: "active markets",
+			rows: sqlmock.NewRows(marketColumns).
+				AddRow(1, "BTC-USDT", "nobitex", 7, "BTCUSDT", true, "0.002").
+				AddRow(2, "btcusdt", "wallex", 7, "BTCUSDT", true, "0.001"),
+			wantIDs: []uint{1, 2},
+		},
+		{name: "none", rows: sqlmock.NewRows(marketColumns)},
+		{name: "query fails", err: errors.New("connection reset"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockRepo(t)
+			// only active, non-deleted rows of the mega market are asked for
+			exp := mock.ExpectQuery(query).WithArgs(7, true)
+			if tt.err != nil {
+				exp.WillReturnError(tt.err)
+			} else {
+				exp.WillReturnRows(tt.rows)
+			}
+
+			got, err := r.GetMarketsByMegaMarketId(context.Background(), 7)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d markets, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, m := range got {
+				if m.ID != tt.wantIDs[i] || m.MegaMarketID != 7 || !m.IsActive {
+					t.Errorf("market %d = %+v", i, m)
+				}
+			}
+		})
+	}
+}
+
+// captureArg matches any value and keeps it, so a test can serve back what was written
+type captureArg struct{ v any }
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.v = v
+	return true
+}
+
+// TestMarketWritesKeepMegaMarket writes a market linked to mega market 7 through each write
+// path and reads it back with the link and fee that were stored
+func TestMarketWritesKeepMegaMarket(t *testing.T) {
+	const insert = `INSERT INTO "markets" \("created_at","updated_at","deleted_at","exchange_market_identifier","exchange_name","mega_market_id","market_name","is_active","exchange_market_fee_percentage",`
+	m := domain.Market{
+		ID:                          3,
+		ExchangeMarketIdentifier:    "btcusdt",
+		ExchangeName:                "wallex",
+		MarketName:                  "BTCUSDT",
+		IsActive:                    true,
+		MegaMarketID:                7,
+		ExchangeMarketFeePercentage: decimal.RequireFromString("0.0025"),
+	}
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock, megaID, fee *captureArg)
+		write  func(r *Repo) error
+	}{
+		{
+			name: "save",
+			expect: func(mock sqlmock.Sqlmock, megaID, fee *captureArg) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(insert).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "btcusdt", "wallex", megaID, "BTCUSDT", true, fee,
+						nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectCommit()
+			},
+			write: func(r *Repo) error { return r.SaveMarket(context.Background(), &m) },
+		},
+		{
+			name: "upsert",
+			expect: func(mock sqlmock.Sqlmock, megaID, fee *captureArg) {
+				mock.ExpectBegin()
+				// a refresh must overwrite the link and fee of an existing row too
+				mock.ExpectQuery(insert+`.* ON CONFLICT .* DO UPDATE SET .*"mega_market_id"="excluded"."mega_market_id",.*"exchange_market_fee_percentage"="excluded"."exchange_market_fee_percentage"`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "btcusdt", "wallex", megaID, "BTCUSDT", true, fee,
+						nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectCommit()
+			},
+			write: func(r *Repo) error { return r.UpsertMarketsForExchange(context.Background(), []domain.Market{m}) },
+		},
+		{
+			name: "update",
+			expect: func(mock sqlmock.Sqlmock, megaID, fee *captureArg) {
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "markets" SET "updated_at"=\$1,"exchange_market_identifier"=\$2,"exchange_name"=\$3,"mega_market_id"=\$4,"market_name"=\$5,"is_active"=\$6,"exchange_market_fee_percentage"=\$7 WHERE id = \$8`).
+					WithArgs(sqlmock.AnyArg(), "btcusdt", "wallex", megaID, "BTCUSDT", true, fee, 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			write: func(r *Repo) error { return r.UpdateMarket(context.Background(), &m) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockRepo(t)
+			var megaID, fee captureArg
+			tt.expect(mock, &megaID, &fee)
+			if err := tt.write(r); err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectQuery(`SELECT \* FROM "markets" WHERE "markets"."id" = \$1`).
+				WithArgs(3, 1).
+				WillReturnRows(sqlmock.NewRows(marketColumns).
+					AddRow(3, "btcusdt", "wallex", megaID.v, "BTCUSDT", true, fee.v))
+
+			got, err := r.GetMarketByID(context.Background(), 3)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.MegaMarketID != 7 {
+				t.Errorf("MegaMarketID = %d, want 7", got.MegaMarketID)
+			}
+			if !got.ExchangeMarketFeePercentage.Equal(m.ExchangeMarketFeePercentage) {
+				t.Errorf("ExchangeMarketFeePercentage = %s, want %s", got.ExchangeMarketFeePercentage, m.ExchangeMarketFeePercentage)
+			}
+		})
+	}
+}
@@ -26,6 +26,7 @@ type MegaMarket struct {
 	SourceTokenSymbol      string
 	DestinationTokenSymbol string
 	SlipagePercentage      decimal.Decimal
+	ProbeVolume            decimal.Decimal `gorm:"type:numeric;not null;default:0"`
 }
 
 // ---------- REPO ----------
@@ -55,6 +56,7 @@ func (r *MegaMarketRepo) Seed(ctx context.Context) error {
 			SourceTokenSymbol:      "BTC",
 			DestinationTokenSymbol: "USDT",
 			SlipagePercentage:      decimal.NewFromFloat(0.02),
+			ProbeVolume:            decimal.NewFromFloat(0.01),
 		},
 		{
 			ExchangeMarketNames:    `["DOGE/USDT","Dogecoin/Tether"]`,
@@ -63,6 +65,7 @@ func (r *MegaMarketRepo) Seed(ctx context.Context) error {
 			SourceTokenSymbol:      "DOGE",
 			DestinationTokenSymbol: "USDT",
 			SlipagePercentage:      decimal.NewFromFloat(0.02),
+			ProbeVolume:            decimal.NewFromInt(1000),
 		},
 		{
 			ExchangeMarketNames:    `["ETH/USDT","Ethereum/Tether"]`,
@@ -71,6 +74,7 @@ func (r *MegaMarketRepo) Seed(ctx context.Context) error {
 			SourceTokenSymbol:      "ETH",
 			DestinationTokenSymbol: "USDT",
 			SlipagePercentage:      decimal.NewFromFloat(0.02),
+			ProbeVolume:            decimal.NewFromFloat(0.1),
 		},
 	}
 
@@ -102,6 +106,7 @@ func (r *MegaMarketRepo) SaveMegaMarket(ctx context.Context, m *domain.MegaMarke
 		SourceTokenSymbol:      m.SourceTokenSymbol,
 		DestinationTokenSymbol: m.DestinationTokenSymbol,
 		SlipagePercentage:      m.SlipagePercentage,
+		ProbeVolume:            m.ProbeVolume,
 	}
 	return r.db.WithContext(ctx).Create(&model).Error
 }
@@ -171,5 +176,6 @@ func (r *MegaMarketRepo) toDomainMegaMarket(m *MegaMarket) *domain.MegaMarket {
 		SourceTokenSymbol:      m.SourceTokenSymbol,
 		DestinationTokenSymbol: m.DestinationTokenSymbol,
 		SlipagePercentage:      m.SlipagePercentage,
+		ProbeVolume:            m.ProbeVolume,
 	}
 }
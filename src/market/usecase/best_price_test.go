@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetBestExchangePriceByVolumeValidation(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"2"}],"bid":[{"price":"99","quantity":"2"}]}}`
+	tests := []struct {
+		name       string
+		megaMarket uint
+		volume     string
+		wantErr    error
+		wantCalls  bool
+	}{
+		{name: "zero volume", megaMarket: 1, volume: "0", wantErr: domain.ErrInvalidVolume},
+		{name: "negative volume", megaMarket: 1, volume: "-1", wantErr: domain.ErrInvalidVolume},
+		{name: "unknown mega market", megaMarket: 9, volume: "1", wantErr: domain.ErrMegaMarketNotFound},
+		{name: "inactive mega market", megaMarket: 2, volume: "1", wantErr: domain.ErrMegaMarketNotFound},
+		{name: "valid", megaMarket: 1, volume: "1", wantCalls: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			wlx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				_, _ = io.WriteString(w, wallexBook)
+			}))
+			t.Cleanup(wlx.Close)
+			markets := &fakeMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 2, ExchangeName: "wallex", ExchangeMarketIdentifier: "ETHUSDT", IsActive: true},
+			}}
+			megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{
+				1: {ID: 1, IsActive: true},
+				2: {ID: 2, IsActive: false},
+			}}
+			svc := newTestMarketService(t, markets, megaMarkets, nil, wlx, nil)
+
+			_, _, _, err := svc.GetBestExchangePriceByVolume(context.Background(), tt.megaMarket, decimal.RequireFromString(tt.volume), true)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if n := calls.Load(); (n > 0) != tt.wantCalls {
+				t.Errorf("%d exchange calls, want calls: %v", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestGetBestExchangePriceByVolumeSide prices both sides across Wallex and Nobitex: buys
+// go to the lowest average price, sells to the highest
+func TestGetBestExchangePriceByVolumeSide(t *testing.T) {
+	wallexBook := func(asks, bids string) string {
+		return `{"success":true,"result":{"ask":[` + asks + `],"bid":[` + bids + `]}}`
+	}
+	nobitexBook := func(asks, bids string) string {
+		return `{"status":"ok","lastUpdate":1,"asks":[` + asks + `],"bids":[` + bids + `]}`
+	}
+	// wallex has the best top of book on both sides, but its second ask level is far off
+	wallexDeep := wallexBook(`{"price":"100","quantity":"1"},{"price":"104","quantity":"5"}`, `{"price":"99","quantity":"5"}`)
+	nobitexFlat := nobitexBook(`["101","5"]`, `["98","5"]`)
+	tests := []struct {
+		name         string
+		wallex       string
+		nobitex      string
+		volume       string
+		isBuy        bool
+		wantExchange string
+		wantPrice    string
+	}{
+		{name: "buy at the top of book", wallex: wallexDeep, nobitex: nobitexFlat, volume: "1", isBuy: true, wantExchange: "wallex", wantPrice: "100"},
+		// wallex fills 2 at (100 + 104) / 2 = 102
+		{name: "buy through a thin level", wallex: wallexDeep, nobitex: nobitexFlat, volume: "2", isBuy: true, wantExchange: "nobitex", wantPrice: "101"},
+		{name: "sell", wallex: wallexDeep, nobitex: nobitexFlat, volume: "2", isBuy: false, wantExchange: "wallex", wantPrice: "99"},
+		{
+			name:    "sell where nobitex pays more",
+			wallex:  wallexBook(`{"price":"101","quantity":"5"}`, `{"price":"99","quantity":"1"},{"price":"95","quantity":"5"}`),
+			nobitex: nobitexFlat, volume: "2", isBuy: false, wantExchange: "nobitex", wantPrice: "98",
+		},
+		{
+			// ties go to the lowest market id, wallex's
+			name:    "tie",
+			wallex:  wallexBook(`{"price":"101","quantity":"5"}`, `{"price":"98","quantity":"5"}`),
+			nobitex: nobitexFlat, volume: "1", isBuy: false, wantExchange: "wallex", wantPrice: "98",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wlx := newExchangeStub(t, map[string]string{"/v1/depth": tt.wallex})
+			nbx := newExchangeStub(t, map[string]string{"/v3/orderbook/BTCUSDT": tt.nobitex})
+			markets := &fakeMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 1, ExchangeName: "nobitex", ExchangeMarketIdentifier: "BTC-USDT", IsActive: true},
+			}}
+			megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{1: {ID: 1, IsActive: true}}}
+			svc := newTestMarketService(t, markets, megaMarkets, nil, wlx, nbx)
+
+			price, market, _, err := svc.GetBestExchangePriceByVolume(context.Background(), 1, decimal.RequireFromString(tt.volume), tt.isBuy)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if market.ExchangeName != tt.wantExchange || !price.Equal(decimal.RequireFromString(tt.wantPrice)) {
+				t.Errorf("best = %s at %s, want %s at %s", market.ExchangeName, price, tt.wantExchange, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestBetterPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  int64
+		isBuy bool
+		want  bool
+	}{
+		{name: "buy, cheaper", a: 99, b: 100, isBuy: true, want: true},
+		{name: "buy, dearer", a: 101, b: 100, isBuy: true},
+		{name: "sell, higher", a: 101, b: 100, want: true},
+		{name: "sell, lower", a: 99, b: 100},
+		{name: "buy, equal", a: 100, b: 100, isBuy: true},
+		{name: "sell, equal", a: 100, b: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := betterPrice(decimal.NewFromInt(tt.a), decimal.NewFromInt(tt.b), tt.isBuy); got != tt.want {
+				t.Errorf("betterPrice(%d, %d, %v) = %v, want %v", tt.a, tt.b, tt.isBuy, got, tt.want)
+			}
+		})
+	}
+}
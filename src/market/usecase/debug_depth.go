@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/MMN3003/mega/src/Infrastructure/nobitex"
+	"github.com/MMN3003/mega/src/Infrastructure/ompfinex"
+	"github.com/MMN3003/mega/src/Infrastructure/wallex"
+	"github.com/MMN3003/mega/src/market/domain"
+)
+
+// DebugMarketDepth fetches the exchange book once and returns the raw response body
+// next to the normalized levels, so a misbehaving pair can be inspected end to end.
+func (s *MarketService) DebugMarketDepth(ctx context.Context, exchangeName, exchangeMarketID string) (*domain.DepthSnapshot, error) {
+	var raw []byte
+	ctx = ompfinex.WithRawResponse(ctx, &raw)
+	ctx = wallex.WithRawResponse(ctx, &raw)
+	ctx = nobitex.WithRawResponse(ctx, &raw)
+
+	asks, bids, err := s.fetchBook(ctx, exchangeName, exchangeMarketID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.DepthSnapshot{
+		ExchangeName:             exchangeName,
+		ExchangeMarketIdentifier: exchangeMarketID,
+		Raw:                      raw,
+		Asks:                     toDomainLevels(asks),
+		Bids:                     toDomainLevels(bids),
+	}, nil
+}
+
+func toDomainLevels(levels []bookLevel) []domain.BookLevel {
+	out := make([]domain.BookLevel, len(levels))
+	for i, l := range levels {
+		out[i] = domain.BookLevel{Price: l.Price, Quantity: l.Quantity}
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+	"golang.org/x/sync/errgroup"
+)
+
+// bookLevel is a normalized order book level: price per unit of base and base quantity
+type bookLevel struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// GetBestExecutionPlan merges the books of every exchange mapped to the MegaMarket into
+// a single ladder and fills the volume from the best levels, splitting across venues.
+func (s *MarketService) GetBestExecutionPlan(
+	ctx context.Context,
+	megaMarketId uint,
+	volume decimal.Decimal,
+	isBuy bool,
+) (*domain.ExecutionPlan, error) {
+	if volume.LessThanOrEqual(decimal.Zero) {
+		return nil, errors.New("volume must be positive")
+	}
+	megaMarket, err := s.megaMarketRepo.GetActiveMegaMarketByID(ctx, megaMarketId)
+	if err != nil {
+		s.logger.Errorf("get active mega market by id failed: %v", err)
+		return nil, err
+	}
+	if megaMarket == nil {
+		return nil, errors.New("no active mega market found for id")
+	}
+	markets, err := s.marketsRepo.GetMarketsByMegaMarketId(ctx, megaMarketId)
+	if err != nil {
+		s.logger.Errorf("get markets by mega market id failed: %v", err)
+		return nil, err
+	}
+
+	type ladderLevel struct {
+		bookLevel
+		market int // index into markets
+	}
+
+	var (
+		ladder []ladderLevel
+		mu     sync.Mutex
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, m := range markets {
+		i, m := i, m
+		g.Go(func() error {
+			levels, err := s.fetchBookLevels(gctx, m.ExchangeName, m.ExchangeMarketIdentifier, isBuy)
+			if err != nil {
+				// Log, but don’t fail the whole plan
+				s.logger.Errorf("[%s] fetch book failed: %v", m.ExchangeName, err)
+				return nil
+			}
+			mu.Lock()
+			for _, l := range levels {
+				ladder = append(ladder, ladderLevel{bookLevel: l, market: i})
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if len(ladder) == 0 && len(markets) > 0 {
+		return nil, fmt.Errorf("%w: no order book could be fetched", domain.ErrNoExchangesAvailable)
+	}
+
+	// best levels first: cheapest asks when buying, richest bids when selling
+	sort.SliceStable(ladder, func(a, b int) bool {
+		if isBuy {
+			return ladder[a].Price.LessThan(ladder[b].Price)
+		}
+		return ladder[a].Price.GreaterThan(ladder[b].Price)
+	})
+
+	filled := make(map[int]decimal.Decimal)
+	costs := make(map[int]decimal.Decimal)
+	totalVolume, totalCost := decimal.Zero, decimal.Zero
+	for _, l := range ladder {
+		if totalVolume.GreaterThanOrEqual(volume) {
+			break
+		}
+		consumed := decimal.Min(volume.Sub(totalVolume), l.Quantity)
+		filled[l.market] = filled[l.market].Add(consumed)
+		costs[l.market] = costs[l.market].Add(consumed.Mul(l.Price))
+		totalVolume = totalVolume.Add(consumed)
+		totalCost = totalCost.Add(consumed.Mul(l.Price))
+	}
+	if totalVolume.LessThan(volume) {
+		return nil, fmt.Errorf(
+			"not enough liquidity across exchanges (available=%s, requested=%s)",
+			totalVolume, volume,
+		)
+	}
+
+	plan := &domain.ExecutionPlan{
+		Volume:   volume,
+		AvgPrice: totalCost.Div(volume),
+	}
+	for i, m := range markets {
+		v, ok := filled[i]
+		if !ok || v.IsZero() {
+			continue
+		}
+		plan.Allocations = append(plan.Allocations, domain.ExecutionAllocation{
+			Market:   m,
+			Volume:   v,
+			AvgPrice: costs[i].Div(v),
+		})
+	}
+	return plan, nil
+}
+
+// fetchBookLevels returns the side of the book an order would consume, best level first
+func (s *MarketService) fetchBookLevels(
+	ctx context.Context,
+	exchangeName string,
+	exchangeMarketID string,
+	isBuy bool,
+) ([]bookLevel, error) {
+	asks, bids, err := s.fetchBook(ctx, exchangeName, exchangeMarketID)
+	if err != nil {
+		return nil, err
+	}
+	if isBuy {
+		return asks, nil
+	}
+	return bids, nil
+}
+
+// fetchBook returns both sides of the exchange book normalized to bookLevels,
+// dropping unparsable and non-positive levels
+func (s *MarketService) fetchBook(
+	ctx context.Context,
+	exchangeName string,
+	exchangeMarketID string,
+) ([]bookLevel, []bookLevel, error) {
+	var asks, bids []bookLevel
+	switch exchangeName {
+	case "ompfinex":
+		depth, err := s.ompfinexClient.GetMarketDepth(ctx, exchangeMarketID)
+		if err != nil {
+			return nil, nil, err
+		}
+		parse := func(side [][]string) []bookLevel {
+			var levels []bookLevel
+			for _, l := range side {
+				if len(l) != 2 {
+					continue
+				}
+				price, err1 := decimal.NewFromString(l[0])
+				qty, err2 := decimal.NewFromString(l[1])
+				if err1 != nil || err2 != nil {
+					continue
+				}
+				levels = append(levels, bookLevel{Price: price, Quantity: qty})
+			}
+			return levels
+		}
+		asks, bids = parse(depth.Asks), parse(depth.Bids)
+
+	case "wallex":
+		depth, err := s.wallexClient.GetMarketDepth(ctx, exchangeMarketID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, l := range depth.Asks {
+			asks = append(asks, bookLevel{Price: l.Price, Quantity: l.Quantity})
+		}
+		for _, l := range depth.Bids {
+			bids = append(bids, bookLevel{Price: l.Price, Quantity: l.Quantity})
+		}
+
+	case "nobitex":
+		depth, err := s.nobitexClient.GetMarketDepth(ctx, exchangeMarketID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, l := range depth.Asks {
+			asks = append(asks, bookLevel{Price: l.Price, Quantity: l.Quantity})
+		}
+		for _, l := range depth.Bids {
+			bids = append(bids, bookLevel{Price: l.Price, Quantity: l.Quantity})
+		}
+
+	default:
+		return nil, nil, errors.New("unsupported exchange: " + exchangeName)
+	}
+
+	return positiveLevels(asks), positiveLevels(bids), nil
+}
+
+func positiveLevels(levels []bookLevel) []bookLevel {
+	valid := levels[:0]
+	for _, l := range levels {
+		if l.Price.GreaterThan(decimal.Zero) && l.Quantity.GreaterThan(decimal.Zero) {
+			valid = append(valid, l)
+		}
+	}
+	return valid
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetBestExecutionPlan(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"2"},{"price":"104","quantity":"5"}],"bid":[{"price":"99","quantity":"2"},{"price":"96","quantity":"5"}]}}`
+	nobitexBook := `{"status":"ok","asks":[["100","1"],["103","5"]],"bids":[["98","1"],["97","5"]]}`
+	tests := []struct {
+		name       string
+		volume     string
+		isBuy      bool
+		nobitexOff bool
+		anyErr     bool
+		wantAvg    string
+		wantSplit  map[string]string // exchange -> volume
+	}{
+		{name: "fits the best level", volume: "1", isBuy: true, wantAvg: "100", wantSplit: map[string]string{"nobitex": "1"}},
+		{
+			name: "buy split across venues", volume: "4", isBuy: true,
+			// 1@100 nobitex, 2@101 wallex, 1@103 nobitex
+			wantAvg: "101.25", wantSplit: map[string]string{"nobitex": "2", "wallex": "2"},
+		},
+		{
+			name: "sell takes the richest bids", volume: "4",
+			// 2@99 wallex, 1@98 nobitex, 1@97 nobitex
+			wantAvg: "98.25", wantSplit: map[string]string{"wallex": "2", "nobitex": "2"},
+		},
+		{name: "one venue down", volume: "3", isBuy: true, nobitexOff: true, wantAvg: "102", wantSplit: map[string]string{"wallex": "3"}},
+		{name: "not enough liquidity", volume: "100", isBuy: true, anyErr: true},
+		{name: "zero volume", volume: "0", isBuy: true, anyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markets := &fakeMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 7, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 7, ExchangeName: "nobitex", ExchangeMarketIdentifier: "btc-usdt", IsActive: true},
+			}}
+			megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{7: {ID: 7, IsActive: true}}}
+			var nbx *httptest.Server
+			if !tt.nobitexOff {
+				nbx = newExchangeStub(t, map[string]string{"/v3/orderbook/BTCUSDT": nobitexBook})
+			}
+			wlx := newExchangeStub(t, map[string]string{"/v1/depth": wallexBook})
+			svc := newTestMarketService(t, markets, megaMarkets, nil, wlx, nbx)
+
+			plan, err := svc.GetBestExecutionPlan(context.Background(), 7, decimal.RequireFromString(tt.volume), tt.isBuy)
+
+			if tt.anyErr {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !plan.AvgPrice.Equal(decimal.RequireFromString(tt.wantAvg)) {
+				t.Errorf("avg price = %s, want %s", plan.AvgPrice, tt.wantAvg)
+			}
+			if len(plan.Allocations) != len(tt.wantSplit) {
+				t.Fatalf("allocations = %+v, want %v", plan.Allocations, tt.wantSplit)
+			}
+			for _, a := range plan.Allocations {
+				if want := tt.wantSplit[a.Market.ExchangeName]; !a.Volume.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("%s volume = %s, want %s", a.Market.ExchangeName, a.Volume, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetBestExecutionPlanNoBooks(t *testing.T) {
+	markets := &fakeMarketRepo{markets: []domain.Market{
+		{ID: 1, MegaMarketID: 7, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+	}}
+	megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{7: {ID: 7, IsActive: true}}}
+	svc := newTestMarketService(t, markets, megaMarkets, nil, nil, nil)
+
+	_, err := svc.GetBestExecutionPlan(context.Background(), 7, decimal.NewFromInt(1), true)
+
+	if !errors.Is(err, domain.ErrNoExchangesAvailable) {
+		t.Fatalf("err = %v, want %v", err, domain.ErrNoExchangesAvailable)
+	}
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/nobitex"
+	"github.com/MMN3003/mega/src/Infrastructure/ompfinex"
+	"github.com/MMN3003/mega/src/Infrastructure/wallex"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/rs/zerolog"
+)
+
+// fakeMarketRepo serves markets from memory. Methods the tests don't need fall through to
+// the nil embedded interface and panic.
+type fakeMarketRepo struct {
+	domain.MarketRepository
+	markets []domain.Market
+	// deleted holds soft-deleted markets; an upsert of the same exchange market revives them
+	deleted []domain.Market
+}
+
+func (r *fakeMarketRepo) GetMarketByID(ctx context.Context, id uint) (*domain.Market, error) {
+	for i := range r.markets {
+		if r.markets[i].ID == id {
+			m := r.markets[i]
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeMarketRepo) GetMarketsByMegaMarketId(ctx context.Context, megaMarketId uint) ([]domain.Market, error) {
+	var out []domain.Market
+	for _, m := range r.markets {
+		if m.MegaMarketID == megaMarketId && m.IsActive {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeMarketRepo) GetAllActiveMarkets(ctx context.Context) ([]domain.Market, error) {
+	var out []domain.Market
+	for _, m := range r.markets {
+		if m.IsActive {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+// UpsertMarketsForExchange updates the market with the same exchange and identifier, soft
+// deleted or not, keeping its id, and inserts the rest
+func (r *fakeMarketRepo) UpsertMarketsForExchange(ctx context.Context, markets []domain.Market) error {
+	same := func(a, b domain.Market) bool {
+		return a.ExchangeName == b.ExchangeName && a.ExchangeMarketIdentifier == b.ExchangeMarketIdentifier
+	}
+next:
+	for _, m := range markets {
+		for i := range r.markets {
+			if same(r.markets[i], m) {
+				m.ID = r.markets[i].ID
+				r.markets[i] = m
+				continue next
+			}
+		}
+		for i := range r.deleted {
+			if same(r.deleted[i], m) {
+				m.ID = r.deleted[i].ID
+				r.deleted = append(r.deleted[:i], r.deleted[i+1:]...)
+				r.markets = append(r.markets, m)
+				continue next
+			}
+		}
+		m.ID = uint(len(r.markets) + len(r.deleted) + 1)
+		r.markets = append(r.markets, m)
+	}
+	return nil
+}
+
+func (r *fakeMarketRepo) SoftDeleteByIds(ctx context.Context, ids []uint) error {
+	for _, id := range ids {
+		for i := range r.markets {
+			if r.markets[i].ID == id {
+				r.deleted = append(r.deleted, r.markets[i])
+				r.markets = append(r.markets[:i], r.markets[i+1:]...)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// fakeMegaMarketRepo serves mega markets from memory
+type fakeMegaMarketRepo struct {
+	domain.MegaMarketRepository
+	megaMarkets map[uint]*domain.MegaMarket
+}
+
+func (r *fakeMegaMarketRepo) GetMegaMarketByID(ctx context.Context, id uint) (*domain.MegaMarket, error) {
+	return r.megaMarkets[id], nil
+}
+
+func (r *fakeMegaMarketRepo) GetActiveMegaMarketByID(ctx context.Context, id uint) (*domain.MegaMarket, error) {
+	if m := r.megaMarkets[id]; m != nil && m.IsActive {
+		return m, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeMegaMarketRepo) GetAllActiveMegaMarkets(ctx context.Context) ([]domain.MegaMarket, error) {
+	var out []domain.MegaMarket
+	for _, m := range r.megaMarkets {
+		if m.IsActive {
+			out = append(out, *m)
+		}
+	}
+	return out, nil
+}
+
+// newExchangeStub serves the response bodies by request path; other paths get a 500
+func newExchangeStub(t *testing.T, bodies map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.Error(w, "no stub for "+r.URL.Path, http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestMarketService returns a MarketService on the given repos whose exchange clients
+// talk to the stubs; a nil stub leaves its exchange unreachable
+func newTestMarketService(t *testing.T, markets *fakeMarketRepo, megaMarkets *fakeMegaMarketRepo, omp, wlx, nbx *httptest.Server) *MarketService {
+	t.Helper()
+	url := func(srv *httptest.Server) string {
+		if srv == nil {
+			return "http://127.0.0.1:1"
+		}
+		return srv.URL
+	}
+	ompClient, err := ompfinex.NewClient(url(omp), ompfinex.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallexClient, err := wallex.NewClient(url(wlx), wallex.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nobitexClient, err := nobitex.NewClient(url(nbx), nobitex.WithLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	return &MarketService{
+		marketsRepo:    markets,
+		megaMarketRepo: megaMarkets,
+		logger:         l,
+		ompfinexClient: ompClient,
+		wallexClient:   wallexClient,
+		nobitexClient:  nobitexClient,
+	}
+}
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetIndicativePrices(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"2"},{"price":"104","quantity":"5"}],"bid":[{"price":"99","quantity":"2"},{"price":"96","quantity":"5"}]}}`
+	markets := &fakeMarketRepo{markets: []domain.Market{
+		{ID: 1, MegaMarketID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+		{ID: 2, MegaMarketID: 2, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+		{ID: 3, MegaMarketID: 3, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+		{ID: 4, MegaMarketID: 4, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+	}}
+	megaMarkets := map[uint]domain.MegaMarket{
+		1: {ID: 1, IsActive: true, ProbeVolume: decimal.NewFromInt(4)},
+		2: {ID: 2, IsActive: true},
+		3: {ID: 3, IsActive: false, ProbeVolume: decimal.NewFromInt(1)},
+		// deeper than the book, it can't be priced
+		4: {ID: 4, IsActive: true, ProbeVolume: decimal.NewFromInt(100)},
+	}
+	megaMarketRepo := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{}}
+	for id, mm := range megaMarkets {
+		mm := mm
+		megaMarketRepo.megaMarkets[id] = &mm
+	}
+	svc := newTestMarketService(t, markets, megaMarketRepo, nil, newExchangeStub(t, map[string]string{"/v1/depth": wallexBook}), nil)
+	svc.defaultProbeVolume = decimal.NewFromInt(1)
+
+	prices := svc.GetIndicativePrices(context.Background(), megaMarkets)
+
+	tests := []struct {
+		name       string
+		megaMarket uint
+		wantListed bool
+		wantProbe  string
+		wantBuy    string
+		wantSell   string
+	}{
+		{name: "own probe volume walks the book", megaMarket: 1, wantListed: true, wantProbe: "4", wantBuy: "102.5", wantSell: "97.5"},
+		{name: "default probe volume", megaMarket: 2, wantListed: true, wantProbe: "1", wantBuy: "101", wantSell: "99"},
+		{name: "inactive left out", megaMarket: 3},
+		{name: "unpriceable left out", megaMarket: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := prices[tt.megaMarket]
+			if ok != tt.wantListed {
+				t.Fatalf("listed = %v, want %v: %+v", ok, tt.wantListed, got)
+			}
+			if !ok {
+				return
+			}
+			if !got.ProbeVolume.Equal(decimal.RequireFromString(tt.wantProbe)) {
+				t.Errorf("probe volume = %s, want %s", got.ProbeVolume, tt.wantProbe)
+			}
+			if !got.Buy.Equal(decimal.RequireFromString(tt.wantBuy)) || !got.Sell.Equal(decimal.RequireFromString(tt.wantSell)) {
+				t.Errorf("buy/sell = %s/%s, want %s/%s", got.Buy, got.Sell, tt.wantBuy, tt.wantSell)
+			}
+		})
+	}
+}
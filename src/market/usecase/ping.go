@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+)
+
+// PingExchange lists the markets of the exchange as a cheap reachability probe
+func (s *MarketService) PingExchange(ctx context.Context, exchangeName string) error {
+	var err error
+	switch exchangeName {
+	case "ompfinex":
+		_, err = s.ompfinexClient.ListMarkets(ctx)
+	case "wallex":
+		_, err = s.wallexClient.GetAllMarkets(ctx)
+	case "nobitex":
+		_, err = s.nobitexClient.GetAllMarkets(ctx)
+	default:
+		return fmt.Errorf("unknown exchange %q", exchangeName)
+	}
+	return err
+}
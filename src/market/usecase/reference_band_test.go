@@ -0,0 +1,51 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+// fakeOracle quotes a fixed reference price, or fails with err
+type fakeOracle struct {
+	price decimal.Decimal
+	err   error
+}
+
+func (o fakeOracle) ReferencePrice(ctx context.Context, megaMarket *domain.MegaMarket) (decimal.Decimal, error) {
+	return o.price, o.err
+}
+
+func TestCheckReferenceBand(t *testing.T) {
+	tests := []struct {
+		name    string
+		oracle  domain.PriceOracle
+		price   string
+		wantErr error
+	}{
+		{name: "no oracle", price: "1000"},
+		{name: "inside band", oracle: fakeOracle{price: decimal.NewFromInt(100)}, price: "104"},
+		{name: "on the band edge", oracle: fakeOracle{price: decimal.NewFromInt(100)}, price: "95"},
+		{name: "above band", oracle: fakeOracle{price: decimal.NewFromInt(100)}, price: "106", wantErr: domain.ErrPriceOutOfBand},
+		{name: "below band", oracle: fakeOracle{price: decimal.NewFromInt(100)}, price: "94", wantErr: domain.ErrPriceOutOfBand},
+		{name: "oracle down fails open", oracle: fakeOracle{err: errors.New("timeout")}, price: "1000"},
+		{name: "no reference price", oracle: fakeOracle{}, price: "1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestMarketService(t, &fakeMarketRepo{}, &fakeMegaMarketRepo{}, nil, nil, nil)
+			if tt.oracle != nil {
+				svc.SetPriceOracle(tt.oracle, decimal.NewFromFloat(0.05))
+			}
+
+			err := svc.checkReferenceBand(context.Background(), &domain.MegaMarket{ID: 1}, decimal.RequireFromString(tt.price))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
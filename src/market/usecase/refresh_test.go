@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+)
+
+// TestFetchAndUpdateMarketsFullRefresh checks a refresh leaves exactly the markets the
+// exchanges currently list, and that a relisted market comes back with its old id
+func TestFetchAndUpdateMarketsFullRefresh(t *testing.T) {
+	wallexMarket := func(id uint, symbol string) domain.Market {
+		return domain.Market{ID: id, ExchangeName: "wallex", ExchangeMarketIdentifier: symbol,
+			MarketName: strings.TrimSuffix(symbol, "USDT") + "/USDT", MegaMarketID: 1, IsActive: true}
+	}
+	listing := func(symbols ...string) string {
+		var markets []string
+		for _, s := range symbols {
+			markets = append(markets, `{"symbol":"`+s+`","en_base_asset":"`+strings.TrimSuffix(s, "USDT")+`","en_quote_asset":"USDT"}`)
+		}
+		return `{"success":true,"result":{"markets":[` + strings.Join(markets, ",") + `]}}`
+	}
+	ompMarket := domain.Market{ID: 9, ExchangeName: "ompfinex", ExchangeMarketIdentifier: "12", MarketName: "BTC/USDT", MegaMarketID: 1, IsActive: true}
+	tests := []struct {
+		name    string
+		stored  []domain.Market
+		deleted []domain.Market
+		listed  []string
+		want    []string // exchange|identifier#id of the active set
+	}{
+		{name: "first sync", listed: []string{"BTCUSDT", "ETHUSDT"}, want: []string{"wallex|BTCUSDT#1", "wallex|ETHUSDT#2"}},
+		{
+			name:   "delisted market removed",
+			stored: []domain.Market{wallexMarket(1, "BTCUSDT"), wallexMarket(2, "ETHUSDT")},
+			listed: []string{"BTCUSDT"},
+			want:   []string{"wallex|BTCUSDT#1"},
+		},
+		{
+			name:    "relisted market revived",
+			stored:  []domain.Market{wallexMarket(1, "BTCUSDT")},
+			deleted: []domain.Market{wallexMarket(2, "ETHUSDT")},
+			listed:  []string{"BTCUSDT", "ETHUSDT"},
+			want:    []string{"wallex|BTCUSDT#1", "wallex|ETHUSDT#2"},
+		},
+		{
+			name:   "unmapped listing ignored",
+			stored: []domain.Market{wallexMarket(1, "BTCUSDT")},
+			listed: []string{"BTCUSDT", "DOGEUSDT"},
+			want:   []string{"wallex|BTCUSDT#1"},
+		},
+		{
+			// ompfinex doesn't answer, so its markets can't be judged delisted
+			name:   "unreachable exchange keeps its markets",
+			stored: []domain.Market{wallexMarket(1, "BTCUSDT"), ompMarket},
+			listed: []string{"BTCUSDT"},
+			want:   []string{"ompfinex|12#9", "wallex|BTCUSDT#1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markets := &fakeMarketRepo{markets: tt.stored, deleted: tt.deleted}
+			megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{
+				1: {ID: 1, IsActive: true, ExchangeMarketNames: `["BTC/USDT","ETH/USDT"]`},
+			}}
+			wlx := newExchangeStub(t, map[string]string{"/hector/web/v1/markets": listing(tt.listed...)})
+			svc := newTestMarketService(t, markets, megaMarkets, nil, wlx, nil)
+
+			got, _, err := svc.FetchAndUpdateMarkets(context.Background())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			var active []string
+			for _, m := range got {
+				active = append(active, m.ExchangeName+"|"+m.ExchangeMarketIdentifier+"#"+strconv.FormatUint(uint64(m.ID), 10))
+			}
+			sort.Strings(active)
+			if !equalStrings(active, tt.want) {
+				t.Errorf("active markets = %v, want %v", active, tt.want)
+			}
+		})
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/MMN3003/mega/src/Infrastructure/nobitex"
 	"github.com/MMN3003/mega/src/Infrastructure/ompfinex"
 	"github.com/MMN3003/mega/src/Infrastructure/wallex"
 	"github.com/MMN3003/mega/src/config"
@@ -23,23 +25,58 @@ type MarketService struct {
 	logger         *logger.Logger
 	ompfinexClient *ompfinex.Client
 	wallexClient   *wallex.Client
+	nobitexClient  *nobitex.Client
+	priceOracle    domain.PriceOracle
+	oracleBand     decimal.Decimal
+	// defaultProbeVolume prices MegaMarkets that have no probe volume of their own
+	defaultProbeVolume decimal.Decimal
 }
 
-func NewService(m domain.MarketRepository, megaMarketRepo domain.MegaMarketRepository, logg *logger.Logger, cfg *config.Config) *MarketService {
-	ompfinexClient, _ := ompfinex.NewClient(cfg.OMP.BaseURL,
+func NewService(m domain.MarketRepository, megaMarketRepo domain.MegaMarketRepository, logg *logger.Logger, cfg *config.Config) (*MarketService, error) {
+	ompfinexClient, err := ompfinex.NewClient(cfg.OMP.BaseURL,
 		ompfinex.WithAuthToken(cfg.OMP.Token),
+		ompfinex.WithHTTPClient(cfg.OMP.HTTP.Client()),
+		ompfinex.WithStrictDecode(cfg.OMP.StrictDecode),
+		ompfinex.WithResponseLogging(cfg.OMP.LogResponses),
 	)
-	wallexClient, _ := wallex.NewClient(cfg.Wallex.BaseURL,
+	if err != nil {
+		return nil, fmt.Errorf("ompfinex client: %w", err)
+	}
+	wallexClient, err := wallex.NewClient(cfg.Wallex.BaseURL,
 		wallex.WithAPIKey(cfg.Wallex.APIKey),
+		wallex.WithHTTPClient(cfg.Wallex.HTTP.Client()),
+		wallex.WithStrictDecode(cfg.Wallex.StrictDecode),
+		wallex.WithResponseLogging(cfg.Wallex.LogResponses),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("wallex client: %w", err)
+	}
+	nobitexClient, err := nobitex.NewClient(cfg.Nobitex.BaseURL,
+		nobitex.WithAuthToken(cfg.Nobitex.Token),
+		nobitex.WithHTTPClient(cfg.Nobitex.HTTP.Client()),
+		nobitex.WithStrictDecode(cfg.Nobitex.StrictDecode),
+		nobitex.WithResponseLogging(cfg.Nobitex.LogResponses),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("nobitex client: %w", err)
+	}
 	s := &MarketService{
 		marketsRepo:    m,
 		megaMarketRepo: megaMarketRepo,
 		logger:         logg,
 		ompfinexClient: ompfinexClient,
 		wallexClient:   wallexClient,
+		nobitexClient:  nobitexClient,
+
+		defaultProbeVolume: cfg.IndicativeProbeVolume,
 	}
-	return s
+	return s, nil
+}
+
+// SetPriceOracle enables the reference-price sanity check with the given relative band.
+func (s *MarketService) SetPriceOracle(o domain.PriceOracle, band decimal.Decimal) {
+	s.priceOracle = o
+	s.oracleBand = band
 }
 
 func (s *MarketService) UpsertMarketPairs(ctx context.Context, exchangeName string, markets []string) error {
@@ -56,6 +93,24 @@ func (s *MarketService) UpsertMarketPairs(ctx context.Context, exchangeName stri
 	return s.marketsRepo.UpsertMarketsForExchange(ctx, marketList)
 }
 
+// ListMarkets returns the stored active markets and the active mega markets they map to.
+// It only reads the database; FetchAndUpdateMarkets keeps it in sync with the exchanges.
+func (s *MarketService) ListMarkets(ctx context.Context) ([]domain.Market, map[uint]domain.MegaMarket, error) {
+	megaMarkets, err := s.megaMarketRepo.GetAllActiveMegaMarkets(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	megaMarketMap := make(map[uint]domain.MegaMarket, len(megaMarkets))
+	for _, megaMarket := range megaMarkets {
+		megaMarketMap[megaMarket.ID] = megaMarket
+	}
+	markets, err := s.marketsRepo.GetAllActiveMarkets(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return markets, megaMarketMap, nil
+}
+
 func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Market, map[uint]domain.MegaMarket, error) {
 	// --- Step 1: Load MegaMarkets
 	megaMarkets, err := s.megaMarketRepo.GetAllActiveMegaMarkets(ctx)
@@ -101,6 +156,11 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 				if err != nil {
 					return nil, err
 				}
+				// 24h volume only comes with the order books; markets sync without it if they fail
+				books, err := s.ompfinexClient.GetMarketOrderBook(ctx)
+				if err != nil {
+					s.logger.Errorf("[ompfinex] fetch 24h volumes failed: %v", err)
+				}
 				mapped := make([]domain.Market, 0, len(raw))
 				for _, m := range raw {
 					if megaMarketID, ok := marketNamesMap[m.BaseCurrency.ID+"/"+m.QuoteCurrency.ID]; ok {
@@ -111,6 +171,9 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 							IsActive:                 true,
 							ExchangeMarketIdentifier: strconv.FormatInt(m.ID, 10),
 							MegaMarketID:             megaMarketID,
+							MinPrice:                 m.MinPrice,
+							MaxPrice:                 m.MaxPrice,
+							Volume24h:                ompfinexVolume24h(books, m),
 						})
 					}
 				}
@@ -134,6 +197,34 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 							IsActive:                 true,
 							ExchangeMarketIdentifier: m.Symbol,
 							MegaMarketID:             megaMarketID,
+							AmountPrecision:          int32Ptr(int32(m.AmountPrecision)),
+							PricePrecision:           int32Ptr(int32(m.PricePrecision)),
+							Volume24h:                m.Volume24h,
+						})
+					}
+				}
+				return mapped, nil
+			},
+		},
+		{
+			name: "nobitex",
+			fetch: func(ctx context.Context) ([]domain.Market, error) {
+				raw, err := s.nobitexClient.GetAllMarkets(ctx)
+				if err != nil {
+					return nil, err
+				}
+				mapped := make([]domain.Market, 0, len(raw))
+				for _, m := range raw {
+					marketName := strings.ToUpper(m.SrcCurrency) + "/" + strings.ToUpper(m.DstCurrency)
+					if megaMarketID, ok := marketNamesMap[marketName]; ok {
+						s.logger.Infof("[nobitex] fetched market: %+v", m)
+						mapped = append(mapped, domain.Market{
+							ExchangeName:             "nobitex",
+							MarketName:               marketName,
+							IsActive:                 !m.Stats.IsClosed,
+							ExchangeMarketIdentifier: m.Symbol,
+							MegaMarketID:             megaMarketID,
+							Volume24h:                m.Stats.VolumeSrc,
 						})
 					}
 				}
@@ -142,7 +233,11 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 		},
 	}
 
-	resultsCh := make(chan []domain.Market, len(fetchers))
+	type fetchResult struct {
+		exchange string
+		markets  []domain.Market
+	}
+	resultsCh := make(chan fetchResult, len(fetchers))
 	errorsCh := make(chan error, len(fetchers))
 
 	for _, f := range fetchers {
@@ -155,7 +250,7 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 				errorsCh <- err
 				return
 			}
-			resultsCh <- markets
+			resultsCh <- fetchResult{exchange: name, markets: markets}
 		}(f.fetch, f.name)
 	}
 
@@ -163,23 +258,42 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 	close(resultsCh)
 	close(errorsCh)
 
-	for markets := range resultsCh {
+	// only exchanges that answered may have markets removed
+	fetchedExchanges := make(map[string]bool, len(fetchers))
+	for res := range resultsCh {
 		allMarketsMu.Lock()
-		allMarkets = append(allMarkets, markets...)
+		allMarkets = append(allMarkets, res.markets...)
+		fetchedExchanges[res.exchange] = true
 		allMarketsMu.Unlock()
 	}
 
 	// --- Step 3: Decide if we fail or continue
 	if len(allMarkets) == 0 {
-		return nil, nil, errors.New("failed to fetch markets from all exchanges")
+		return nil, nil, fmt.Errorf("%w: failed to fetch markets from all exchanges", domain.ErrNoExchangesAvailable)
 	}
-	s.marketsRepo.SoftDeleteAll(ctx)
 
-	// --- Step 4: Persist
-	if err := s.marketsRepo.UpsertMarketsForExchange(ctx, allMarkets); err != nil {
-		s.logger.Errorf("failed to upsert markets: %v", err)
+	// --- Step 4: Diff against the stored active set and persist only the changes
+	existing, err := s.marketsRepo.GetAllActiveMarkets(ctx)
+	if err != nil {
+		s.logger.Errorf("failed to get active markets: %v", err)
 		return nil, nil, err
 	}
+	changed, removed := diffMarkets(existing, allMarkets, fetchedExchanges)
+
+	if len(changed) > 0 {
+		if err := s.marketsRepo.UpsertMarketsForExchange(ctx, changed); err != nil {
+			s.logger.Errorf("failed to upsert markets: %v", err)
+			return nil, nil, err
+		}
+	}
+	if len(removed) > 0 {
+		if err := s.marketsRepo.SoftDeleteByIds(ctx, removed); err != nil {
+			s.logger.Errorf("failed to soft delete markets: %v", err)
+			return nil, nil, err
+		}
+	}
+	s.logger.Infof("market sync: %d upserted, %d removed, %d unchanged",
+		len(changed), len(removed), len(allMarkets)-len(changed))
 
 	storedMarkets, err := s.marketsRepo.GetAllActiveMarkets(ctx)
 	if err != nil {
@@ -190,30 +304,148 @@ func (s *MarketService) FetchAndUpdateMarkets(ctx context.Context) ([]domain.Mar
 	return storedMarkets, megaMarketMap, nil
 }
 
+// diffMarkets compares freshly fetched markets with the stored active set. It returns the
+// fetched markets that are new or differ from their stored row, and the ids of stored
+// markets of successfully fetched exchanges that are no longer listed.
+func diffMarkets(existing, fetched []domain.Market, fetchedExchanges map[string]bool) ([]domain.Market, []uint) {
+	key := func(m domain.Market) string { return m.ExchangeName + "|" + m.ExchangeMarketIdentifier }
+
+	stored := make(map[string]domain.Market, len(existing))
+	for _, m := range existing {
+		stored[key(m)] = m
+	}
+
+	var changed []domain.Market
+	seen := make(map[string]bool, len(fetched))
+	for _, m := range fetched {
+		k := key(m)
+		seen[k] = true
+		old, ok := stored[k]
+		if ok &&
+			old.MarketName == m.MarketName &&
+			old.MegaMarketID == m.MegaMarketID &&
+			old.IsActive == m.IsActive &&
+			old.ExchangeMarketFeePercentage.Equal(m.ExchangeMarketFeePercentage) &&
+			equalInt32Ptr(old.AmountPrecision, m.AmountPrecision) &&
+			equalInt32Ptr(old.PricePrecision, m.PricePrecision) &&
+			old.MinAmount.Equal(m.MinAmount) &&
+			old.MinPrice.Equal(m.MinPrice) &&
+			old.MaxPrice.Equal(m.MaxPrice) &&
+			old.Volume24h.Equal(m.Volume24h) {
+			continue
+		}
+		changed = append(changed, m)
+	}
+
+	var removed []uint
+	for k, m := range stored {
+		if !seen[k] && fetchedExchanges[m.ExchangeName] {
+			removed = append(removed, m.ID)
+		}
+	}
+	return changed, removed
+}
+
+// ompfinexVolume24h finds a market's 24h volume in the order book snapshot, keyed by
+// market id or name depending on the endpoint version.
+func ompfinexVolume24h(books map[string]ompfinex.MarketOrderBook, m ompfinex.Market) decimal.Decimal {
+	book, ok := books[strconv.FormatInt(m.ID, 10)]
+	if !ok {
+		book, ok = books[m.Name]
+	}
+	if !ok {
+		return decimal.Zero
+	}
+	v, err := decimal.NewFromString(book.Volume24h)
+	if err != nil {
+		return decimal.Zero
+	}
+	return v
+}
+
+// restrictToExchanges keeps the markets of the given exchanges, failing if one of them
+// has no market in the list.
+func restrictToExchanges(markets []domain.Market, exchanges []string) ([]domain.Market, error) {
+	wanted := make(map[string]bool, len(exchanges))
+	for _, e := range exchanges {
+		wanted[e] = true
+	}
+	var kept []domain.Market
+	for _, m := range markets {
+		if wanted[m.ExchangeName] {
+			kept = append(kept, m)
+			delete(wanted, m.ExchangeName)
+		}
+	}
+	for _, e := range exchanges {
+		if wanted[e] {
+			return nil, fmt.Errorf("%w: %s", domain.ErrExchangeNotMapped, e)
+		}
+	}
+	return kept, nil
+}
+
+func int32Ptr(v int32) *int32 { return &v }
+
+func equalInt32Ptr(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func (s *MarketService) GetBestExchangePriceByVolume(
 	ctx context.Context,
 	megaMarketId uint,
 	volume decimal.Decimal,
 	isBuy bool,
 ) (decimal.Decimal, *domain.Market, *domain.MegaMarket, error) {
+	best, err := s.GetBestExchangePriceOn(ctx, megaMarketId, volume, isBuy, nil)
+	if err != nil {
+		return decimal.Zero, nil, nil, err
+	}
+	return best.Price, &best.Market, &best.MegaMarket, nil
+}
+
+// GetBestExchangePriceOn is GetBestExchangePriceByVolume restricted to exchanges, so one venue
+// can be priced in isolation. An empty exchanges considers every mapped exchange. It also
+// reports the price impact of the volume on the chosen venue, and rejects it with
+// ErrPriceImpactTooHigh when that exceeds the mega market's slippage.
+func (s *MarketService) GetBestExchangePriceOn(
+	ctx context.Context,
+	megaMarketId uint,
+	volume decimal.Decimal,
+	isBuy bool,
+	exchanges []string,
+) (*domain.BestPrice, error) {
 	// TODO: add fee of transaction
+	// reject before touching the db or any exchange
+	if !volume.IsPositive() {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidVolume, volume)
+	}
 	// --- Fetch candidate markets
 	megaMarket, err := s.megaMarketRepo.GetActiveMegaMarketByID(ctx, megaMarketId)
 	if err != nil {
 		s.logger.Errorf("get active mega market by id failed: %v", err)
-		return decimal.Zero, nil, nil, err
+		return nil, err
 	}
 	if megaMarket == nil {
-		return decimal.Zero, nil, nil, errors.New("no active mega market found for id")
+		return nil, fmt.Errorf("%w: id %d", domain.ErrMegaMarketNotFound, megaMarketId)
 	}
 	markets, err := s.marketsRepo.GetMarketsByMegaMarketId(ctx, megaMarketId)
 	if err != nil {
 		s.logger.Errorf("get markets by mega market id failed: %v", err)
-		return decimal.Zero, nil, nil, err
+		return nil, err
+	}
+	if len(exchanges) > 0 {
+		if markets, err = restrictToExchanges(markets, exchanges); err != nil {
+			return nil, err
+		}
 	}
 
 	type result struct {
 		price        decimal.Decimal
+		topOfBook    decimal.Decimal
 		exchangeName string
 		market       domain.Market
 	}
@@ -223,73 +455,196 @@ func (s *MarketService) GetBestExchangePriceByVolume(
 		mu      sync.Mutex
 	)
 
-	// --- Run each market check concurrently
-	g, ctx := errgroup.WithContext(ctx)
-	for _, m := range markets {
-		m := m // capture range variable
+	// --- Single venue: nothing to compare, price it directly
+	if len(markets) == 1 {
+		m := markets[0]
+		price, topOfBook, err := s.fetchAndCalculatePrice(ctx, m.ExchangeName, m.ExchangeMarketIdentifier, volume, isBuy)
+		if err != nil {
+			s.logger.Errorf("[%s] price calculation failed: %v", m.ExchangeName, err)
+			return nil, fmt.Errorf("%w: could not determine best price", domain.ErrNoExchangesAvailable)
+		}
+		results = append(results, result{price: price, topOfBook: topOfBook, exchangeName: m.ExchangeName, market: m})
+	} else {
+		// --- Run each market check concurrently
+		g, ctx := errgroup.WithContext(ctx)
+		for _, m := range markets {
+			m := m // capture range variable
 
-		g.Go(func() error {
-			price, err := s.fetchAndCalculatePrice(ctx, m.ExchangeName, m.ExchangeMarketIdentifier, volume, isBuy)
-			if err != nil {
-				// Log, but don’t fail the whole group
-				s.logger.Errorf("[%s] price calculation failed: %v", m.ExchangeName, err)
+			g.Go(func() error {
+				price, topOfBook, err := s.fetchAndCalculatePrice(ctx, m.ExchangeName, m.ExchangeMarketIdentifier, volume, isBuy)
+				if err != nil {
+					// Log, but don’t fail the whole group
+					s.logger.Errorf("[%s] price calculation failed: %v", m.ExchangeName, err)
+					return nil
+				}
+
+				mu.Lock()
+				results = append(results, result{price: price, topOfBook: topOfBook, exchangeName: m.ExchangeName, market: m})
+				mu.Unlock()
 				return nil
-			}
+			})
+		}
 
-			mu.Lock()
-			results = append(results, result{price: price, exchangeName: m.ExchangeName, market: m})
-			mu.Unlock()
-			return nil
-		})
+		_ = g.Wait() // we ignore returned error since we log & skip per exchange
 	}
 
-	_ = g.Wait() // we ignore returned error since we log & skip per exchange
-
-	// --- Pick the lowest price
+	// --- Pick the best price for the side; ties go to the lowest market id, so concurrent
+	// fetches finishing in any order pick the same venue
 	if len(results) == 0 {
-		return decimal.Zero, nil, nil, errors.New("could not determine best price")
+		return nil, fmt.Errorf("%w: could not determine best price", domain.ErrNoExchangesAvailable)
 	}
 
 	best := results[0]
 	for _, r := range results[1:] {
-		if r.price.LessThan(best.price) {
+		if betterPrice(r.price, best.price, isBuy) || (r.price.Equal(best.price) && r.market.ID < best.market.ID) {
 			best = r
 		}
 	}
 
-	return best.price, &best.market, megaMarket, nil
+	if err := s.checkReferenceBand(ctx, megaMarket, best.price); err != nil {
+		return nil, err
+	}
+
+	impact := priceImpact(best.price, best.topOfBook)
+	if megaMarket.SlipagePercentage.IsPositive() && impact.GreaterThan(megaMarket.SlipagePercentage) {
+		return nil, fmt.Errorf("%w: filling %s on %s moves the price from %s to %s, impact %s above the allowed %s",
+			domain.ErrPriceImpactTooHigh, volume, best.exchangeName, best.topOfBook, best.price,
+			impact.StringFixed(4), megaMarket.SlipagePercentage)
+	}
+
+	return &domain.BestPrice{
+		Price:       best.price,
+		TopOfBook:   best.topOfBook,
+		PriceImpact: impact,
+		Market:      best.market,
+		MegaMarket:  *megaMarket,
+	}, nil
+}
+
+// betterPrice reports whether average price a beats b for the side: a buyer wants to pay
+// the least, a seller to receive the most
+func betterPrice(a, b decimal.Decimal, isBuy bool) bool {
+	if isBuy {
+		return a.LessThan(b)
+	}
+	return a.GreaterThan(b)
+}
+
+// priceImpact is how far the average fill price is from the best level, relative to the
+// best level. It is a cost, so it is positive for buys and sells alike.
+func priceImpact(avg, topOfBook decimal.Decimal) decimal.Decimal {
+	if !topOfBook.IsPositive() {
+		return decimal.Zero
+	}
+	return avg.Sub(topOfBook).Abs().Div(topOfBook)
+}
+
+// probeVolume returns the size indicative prices of the MegaMarket are computed at
+func (s *MarketService) probeVolume(megaMarket *domain.MegaMarket) decimal.Decimal {
+	if megaMarket.ProbeVolume.IsPositive() {
+		return megaMarket.ProbeVolume
+	}
+	return s.defaultProbeVolume
+}
+
+// GetIndicativePrices prices every active MegaMarket of megaMarkets at its probe volume, so
+// listed prices are comparable. MegaMarkets that can't be priced are logged and left out.
+func (s *MarketService) GetIndicativePrices(ctx context.Context, megaMarkets map[uint]domain.MegaMarket) map[uint]domain.IndicativePrice {
+	var (
+		prices = make(map[uint]domain.IndicativePrice, len(megaMarkets))
+		mu     sync.Mutex
+		g      errgroup.Group
+	)
+	for _, mm := range megaMarkets {
+		if !mm.IsActive {
+			continue
+		}
+		mm := mm
+		g.Go(func() error {
+			probe := s.probeVolume(&mm)
+			buy, _, _, err := s.GetBestExchangePriceByVolume(ctx, mm.ID, probe, true)
+			if err != nil {
+				s.logger.Errorf("indicative buy price of mega market %d failed: %v", mm.ID, err)
+				return nil
+			}
+			sell, _, _, err := s.GetBestExchangePriceByVolume(ctx, mm.ID, probe, false)
+			if err != nil {
+				s.logger.Errorf("indicative sell price of mega market %d failed: %v", mm.ID, err)
+				return nil
+			}
+			mu.Lock()
+			prices[mm.ID] = domain.IndicativePrice{MegaMarketID: mm.ID, ProbeVolume: probe, Buy: buy, Sell: sell}
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return prices
+}
+
+// checkReferenceBand rejects prices that deviate from the oracle by more than the band,
+// guarding against trading on a thin or manipulated book. Oracle outages fail open.
+func (s *MarketService) checkReferenceBand(ctx context.Context, megaMarket *domain.MegaMarket, price decimal.Decimal) error {
+	if s.priceOracle == nil {
+		return nil
+	}
+	ref, err := s.priceOracle.ReferencePrice(ctx, megaMarket)
+	if err != nil {
+		s.logger.Errorf("reference price for mega market %d unavailable, skipping band check: %v", megaMarket.ID, err)
+		return nil
+	}
+	if ref.LessThanOrEqual(decimal.Zero) {
+		return nil
+	}
+	deviation := price.Sub(ref).Abs().Div(ref)
+	if deviation.GreaterThan(s.oracleBand) {
+		return fmt.Errorf("%w: price=%s reference=%s deviation=%s band=%s",
+			domain.ErrPriceOutOfBand, price, ref, deviation.StringFixed(4), s.oracleBand)
+	}
+	return nil
 }
+
+// fetchAndCalculatePrice returns the average price to fill volume on one exchange market,
+// and the price of its best level.
 func (s *MarketService) fetchAndCalculatePrice(
 	ctx context.Context,
 	exchangeName string,
 	exchangeMarketID string,
 	volume decimal.Decimal,
 	isBuy bool,
-) (decimal.Decimal, error) {
+) (avg, best decimal.Decimal, err error) {
 	switch exchangeName {
 	case "ompfinex":
 		depth, err := s.ompfinexClient.GetMarketDepth(ctx, exchangeMarketID)
 		if err != nil {
-			return decimal.Zero, err
+			return decimal.Zero, decimal.Zero, err
 		}
 		return s.calculateOmpfinexPrice(depth, volume, isBuy)
 
 	case "wallex":
 		depth, err := s.wallexClient.GetMarketDepth(ctx, exchangeMarketID)
 		if err != nil {
-			return decimal.Zero, err
+			return decimal.Zero, decimal.Zero, err
 		}
 		return s.calculateWallexPrice(depth, volume, isBuy)
 
+	case "nobitex":
+		depth, err := s.nobitexClient.GetMarketDepth(ctx, exchangeMarketID)
+		if err != nil {
+			return decimal.Zero, decimal.Zero, err
+		}
+		return s.calculateNobitexPrice(depth, volume, isBuy)
+
 	default:
-		return decimal.Zero, errors.New("unsupported exchange: " + exchangeName)
+		return decimal.Zero, decimal.Zero, errors.New("unsupported exchange: " + exchangeName)
 	}
 }
 
-// calculateOmpfinexPrice calculates the price to buy the requested volume
-func (s *MarketService) calculateOmpfinexPrice(depth ompfinex.OrderBook, volume decimal.Decimal, isBuy bool) (decimal.Decimal, error) {
+// calculateOmpfinexPrice calculates the price to buy the requested volume, and returns the
+// best level's price next to it
+func (s *MarketService) calculateOmpfinexPrice(depth ompfinex.OrderBook, volume decimal.Decimal, isBuy bool) (avg, best decimal.Decimal, err error) {
 	if volume.LessThanOrEqual(decimal.Zero) {
-		return decimal.Zero, errors.New("volume must be positive")
+		return decimal.Zero, decimal.Zero, errors.New("volume must be positive")
 	}
 
 	var (
@@ -309,6 +664,9 @@ func (s *MarketService) calculateOmpfinexPrice(depth ompfinex.OrderBook, volume
 			if err1 != nil || err2 != nil || price.LessThanOrEqual(decimal.Zero) || vol.LessThanOrEqual(decimal.Zero) {
 				continue
 			}
+			if best.IsZero() {
+				best = price
+			}
 
 			remaining := volume.Sub(totalVolume)
 			available := vol
@@ -317,13 +675,13 @@ func (s *MarketService) calculateOmpfinexPrice(depth ompfinex.OrderBook, volume
 			totalCost = totalCost.Add(price.Mul(consumed))
 			totalVolume = totalVolume.Add(consumed)
 
-			fmt.Printf("[OMP BUY] Level=%d Price=%s Avail=%s Consumed=%s TotalCost=%s TotalVol=%s\n",
+			s.logger.Debugf("[OMP BUY] Level=%d Price=%s Avail=%s Consumed=%s TotalCost=%s TotalVol=%s",
 				i, price, available, consumed, totalCost, totalVolume)
 
 			if totalVolume.GreaterThanOrEqual(volume) {
-				avg := totalCost.Div(volume)
-				fmt.Printf("[OMP BUY COMPLETE] AvgPrice=%s\n", avg)
-				return avg, nil
+				avg = totalCost.Div(volume)
+				s.logger.Debugf("[OMP BUY COMPLETE] AvgPrice=%s", avg)
+				return avg, best, nil
 			}
 		}
 	} else {
@@ -338,6 +696,9 @@ func (s *MarketService) calculateOmpfinexPrice(depth ompfinex.OrderBook, volume
 			if err1 != nil || err2 != nil || price.LessThanOrEqual(decimal.Zero) || vol.LessThanOrEqual(decimal.Zero) {
 				continue
 			}
+			if best.IsZero() {
+				best = price
+			}
 
 			remaining := volume.Sub(totalVolume)
 			available := vol
@@ -346,18 +707,18 @@ func (s *MarketService) calculateOmpfinexPrice(depth ompfinex.OrderBook, volume
 			totalCost = totalCost.Add(price.Mul(consumed))
 			totalVolume = totalVolume.Add(consumed)
 
-			fmt.Printf("[OMP SELL] Level=%d Price=%s Avail=%s Consumed=%s TotalCost=%s TotalVol=%s\n",
+			s.logger.Debugf("[OMP SELL] Level=%d Price=%s Avail=%s Consumed=%s TotalCost=%s TotalVol=%s",
 				i, price, available, consumed, totalCost, totalVolume)
 
 			if totalVolume.GreaterThanOrEqual(volume) {
-				avg := totalCost.Div(volume)
-				fmt.Printf("[OMP SELL COMPLETE] AvgPrice=%s\n", avg)
-				return avg, nil
+				avg = totalCost.Div(volume)
+				s.logger.Debugf("[OMP SELL COMPLETE] AvgPrice=%s", avg)
+				return avg, best, nil
 			}
 		}
 	}
 
-	return decimal.Zero, fmt.Errorf(
+	return decimal.Zero, decimal.Zero, fmt.Errorf(
 		"not enough liquidity in order book (available=%s, requested=%s)",
 		totalVolume, volume,
 	)
@@ -370,12 +731,45 @@ func (s *MarketService) GetMegaMarketByID(ctx context.Context, id uint) (*domain
 	return s.megaMarketRepo.GetActiveMegaMarketByID(ctx, id)
 }
 
-// calculateWallexPrice calculates the minimum average price to buy the specified volume
-// by consuming asks from the order book starting from the best (lowest) price.
-// Returns the weighted average price or error if not enough volume available.
-func (s *MarketService) calculateWallexPrice(depth *wallex.OrderBook, volume decimal.Decimal, isBuy bool) (decimal.Decimal, error) {
+// GetMegaMarketVolume sums the 24h volume of the MegaMarket's active exchange markets.
+// Every venue reports it in the base asset, so the total is in SourceTokenSymbol.
+func (s *MarketService) GetMegaMarketVolume(ctx context.Context, megaMarketId uint) (*domain.MegaMarketVolume, error) {
+	megaMarket, err := s.megaMarketRepo.GetActiveMegaMarketByID(ctx, megaMarketId)
+	if err != nil {
+		return nil, err
+	}
+	if megaMarket == nil {
+		return nil, nil
+	}
+	markets, err := s.marketsRepo.GetMarketsByMegaMarketId(ctx, megaMarketId)
+	if err != nil {
+		return nil, err
+	}
+	v := &domain.MegaMarketVolume{
+		MegaMarketID: megaMarketId,
+		Currency:     megaMarket.SourceTokenSymbol,
+		Volume24h:    decimal.Zero,
+	}
+	for _, m := range markets {
+		if !m.IsActive {
+			continue
+		}
+		v.Volume24h = v.Volume24h.Add(m.Volume24h)
+		v.Venues = append(v.Venues, m)
+	}
+	return v, nil
+}
+
+func (s *MarketService) GetMarketsByMegaMarketID(ctx context.Context, megaMarketId uint) ([]domain.Market, error) {
+	return s.marketsRepo.GetMarketsByMegaMarketId(ctx, megaMarketId)
+}
+
+// calculateWallexPrice calculates the average price to fill the requested base volume by
+// walking asks (buy) or bids (sell) from the best level outward, and the best level's price.
+// Returns an error if not enough volume is available.
+func (s *MarketService) calculateWallexPrice(depth *wallex.OrderBook, volume decimal.Decimal, isBuy bool) (avg, best decimal.Decimal, err error) {
 	if volume.LessThanOrEqual(decimal.Zero) {
-		return decimal.Zero, errors.New("volume must be positive")
+		return decimal.Zero, decimal.Zero, errors.New("volume must be positive")
 	}
 
 	var (
@@ -389,22 +783,25 @@ func (s *MarketService) calculateWallexPrice(depth *wallex.OrderBook, volume dec
 			if ask.Price.LessThanOrEqual(decimal.Zero) || ask.Quantity.LessThanOrEqual(decimal.Zero) {
 				continue
 			}
+			if best.IsZero() {
+				best = ask.Price
+			}
 
-			remaining := volume.Sub(totalCost)
-			available := ask.Quantity.Mul(ask.Price)
+			remaining := volume.Sub(totalVolume)
+			available := ask.Quantity
 			consumed := decimal.Min(remaining, available)
 
 			// accumulate totals
-			totalCost = totalCost.Add(consumed)
-			totalVolume = totalVolume.Add(consumed.Div(ask.Price))
+			totalCost = totalCost.Add(ask.Price.Mul(consumed))
+			totalVolume = totalVolume.Add(consumed)
 
-			fmt.Printf("[BUY] Level=%d Price=%s Available=%s Consumed=%s TotalCost=%s TotalVolume=%s\n",
+			s.logger.Debugf("[BUY] Level=%d Price=%s Available=%s Consumed=%s TotalCost=%s TotalVolume=%s",
 				i, ask.Price, available, consumed, totalCost, totalVolume)
 
-			if totalCost.GreaterThanOrEqual(volume) {
-				avg := totalCost.Div(totalVolume)
-				fmt.Printf("[BUY COMPLETE] AvgPrice=%s\n", avg)
-				return avg, nil
+			if totalVolume.GreaterThanOrEqual(volume) {
+				avg = totalCost.Div(volume)
+				s.logger.Debugf("[BUY COMPLETE] AvgPrice=%s", avg)
+				return avg, best, nil
 			}
 		}
 	} else {
@@ -413,6 +810,9 @@ func (s *MarketService) calculateWallexPrice(depth *wallex.OrderBook, volume dec
 			if bid.Price.LessThanOrEqual(decimal.Zero) || bid.Quantity.LessThanOrEqual(decimal.Zero) {
 				continue
 			}
+			if best.IsZero() {
+				best = bid.Price
+			}
 
 			remaining := volume.Sub(totalVolume)
 			available := bid.Quantity
@@ -422,19 +822,67 @@ func (s *MarketService) calculateWallexPrice(depth *wallex.OrderBook, volume dec
 			totalCost = totalCost.Add(bid.Price.Mul(consumed))
 			totalVolume = totalVolume.Add(consumed)
 
-			fmt.Printf("[SELL] Level=%d Price=%s Available=%s Consumed=%s TotalCost=%s TotalVolume=%s\n",
+			s.logger.Debugf("[SELL] Level=%d Price=%s Available=%s Consumed=%s TotalCost=%s TotalVolume=%s",
 				i, bid.Price, available, consumed, totalCost, totalVolume)
 
 			if totalVolume.GreaterThanOrEqual(volume) {
-				avg := totalCost.Div(volume)
-				fmt.Printf("[SELL COMPLETE] AvgPrice=%s\n", avg)
-				return avg, nil
+				avg = totalCost.Div(volume)
+				s.logger.Debugf("[SELL COMPLETE] AvgPrice=%s", avg)
+				return avg, best, nil
 			}
 		}
 	}
 
 	// Not enough liquidity
-	return decimal.Zero, fmt.Errorf(
+	return decimal.Zero, decimal.Zero, fmt.Errorf(
+		"not enough liquidity in order book (available=%s, requested=%s)",
+		totalVolume, volume,
+	)
+}
+
+// calculateNobitexPrice calculates the average price to fill the requested base volume
+// by walking asks (buy) or bids (sell) from the best level outward, and the best level's price.
+func (s *MarketService) calculateNobitexPrice(depth *nobitex.OrderBook, volume decimal.Decimal, isBuy bool) (avg, best decimal.Decimal, err error) {
+	if volume.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero, decimal.Zero, errors.New("volume must be positive")
+	}
+
+	var (
+		totalVolume = decimal.Zero
+		totalCost   = decimal.Zero
+	)
+
+	levels := depth.Bids
+	if isBuy {
+		// Buying → consume from Asks (lowest prices first)
+		levels = depth.Asks
+	}
+
+	for i, level := range levels {
+		if level.Price.LessThanOrEqual(decimal.Zero) || level.Quantity.LessThanOrEqual(decimal.Zero) {
+			continue
+		}
+		if best.IsZero() {
+			best = level.Price
+		}
+
+		remaining := volume.Sub(totalVolume)
+		consumed := decimal.Min(remaining, level.Quantity)
+
+		totalCost = totalCost.Add(level.Price.Mul(consumed))
+		totalVolume = totalVolume.Add(consumed)
+
+		s.logger.Debugf("[NOBITEX] IsBuy=%t Level=%d Price=%s Available=%s Consumed=%s TotalCost=%s TotalVolume=%s",
+			isBuy, i, level.Price, level.Quantity, consumed, totalCost, totalVolume)
+
+		if totalVolume.GreaterThanOrEqual(volume) {
+			avg = totalCost.Div(volume)
+			s.logger.Debugf("[NOBITEX COMPLETE] AvgPrice=%s", avg)
+			return avg, best, nil
+		}
+	}
+
+	return decimal.Zero, decimal.Zero, fmt.Errorf(
 		"not enough liquidity in order book (available=%s, requested=%s)",
 		totalVolume, volume,
 	)
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+// TestSingleVenueFastPath prices mega market 7, mapped to wallex only, against mega market 8,
+// mapped to the same wallex book plus an unreachable nobitex market so it takes the
+// comparison path; both must agree
+func TestSingleVenueFastPath(t *testing.T) {
+	wallexBook := `{"success":true,"result":{"ask":[{"price":"101","quantity":"2"},{"price":"104","quantity":"5"}],"bid":[{"price":"99","quantity":"2"},{"price":"96","quantity":"5"}]}}`
+	tests := []struct {
+		name      string
+		volume    string
+		isBuy     bool
+		wallexOff bool
+		wantPrice string
+		wantErr   error
+	}{
+		{name: "buy at the best level", volume: "1", isBuy: true, wantPrice: "101"},
+		{name: "buy walks the book", volume: "4", isBuy: true, wantPrice: "102.5"},
+		{name: "sell at the best level", volume: "2", wantPrice: "99"},
+		{name: "sell walks the book", volume: "4", wantPrice: "97.5"},
+		{name: "venue down", volume: "1", isBuy: true, wallexOff: true, wantErr: domain.ErrNoExchangesAvailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markets := &fakeMarketRepo{markets: []domain.Market{
+				{ID: 1, MegaMarketID: 7, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 2, MegaMarketID: 8, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+				{ID: 3, MegaMarketID: 8, ExchangeName: "nobitex", ExchangeMarketIdentifier: "btc-usdt", IsActive: true},
+			}}
+			megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{
+				7: {ID: 7, IsActive: true},
+				8: {ID: 8, IsActive: true},
+			}}
+			bodies := map[string]string{"/v1/depth": wallexBook}
+			if tt.wallexOff {
+				bodies = nil
+			}
+			svc := newTestMarketService(t, markets, megaMarkets, nil, newExchangeStub(t, bodies), nil)
+			volume := decimal.RequireFromString(tt.volume)
+
+			single, err := svc.GetBestExchangePriceOn(context.Background(), 7, volume, tt.isBuy, nil)
+			general, generalErr := svc.GetBestExchangePriceOn(context.Background(), 8, volume, tt.isBuy, nil)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(generalErr, tt.wantErr) {
+					t.Fatalf("errs = %v / %v, want %v", err, generalErr, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || generalErr != nil {
+				t.Fatalf("errs = %v / %v", err, generalErr)
+			}
+			if !single.Price.Equal(decimal.RequireFromString(tt.wantPrice)) {
+				t.Errorf("single venue price = %s, want %s", single.Price, tt.wantPrice)
+			}
+			if !single.Price.Equal(general.Price) || !single.TopOfBook.Equal(general.TopOfBook) ||
+				!single.PriceImpact.Equal(general.PriceImpact) {
+				t.Errorf("single venue %+v differs from general path %+v", single, general)
+			}
+			if single.Market.ExchangeName != general.Market.ExchangeName {
+				t.Errorf("venue = %s, general path picked %s", single.Market.ExchangeName, general.Market.ExchangeName)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestDiffMarkets(t *testing.T) {
+	btc := domain.Market{ID: 1, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", MarketName: "BTC/USDT",
+		MegaMarketID: 3, IsActive: true, ExchangeMarketFeePercentage: decimal.NewFromFloat(0.002)}
+	eth := domain.Market{ID: 2, ExchangeName: "wallex", ExchangeMarketIdentifier: "ETHUSDT", MarketName: "ETH/USDT", IsActive: true}
+	omp := domain.Market{ID: 3, ExchangeName: "ompfinex", ExchangeMarketIdentifier: "7", MarketName: "BTC/IRT", IsActive: true}
+	with := func(m domain.Market, f func(*domain.Market)) domain.Market {
+		f(&m)
+		return m
+	}
+	tests := []struct {
+		name        string
+		existing    []domain.Market
+		fetched     []domain.Market
+		fetchedFrom []string
+		wantChanged []string // exchange market identifiers
+		wantRemoved []uint
+	}{
+		{name: "first sync", fetched: []domain.Market{btc, eth}, fetchedFrom: []string{"wallex"}, wantChanged: []string{"BTCUSDT", "ETHUSDT"}},
+		{name: "nothing changed", existing: []domain.Market{btc, eth}, fetched: []domain.Market{btc, eth}, fetchedFrom: []string{"wallex"}},
+		{
+			name:     "fee changed",
+			existing: []domain.Market{btc, eth},
+			fetched: []domain.Market{with(btc, func(m *domain.Market) { m.ExchangeMarketFeePercentage = decimal.NewFromFloat(0.001) }),
+				eth},
+			fetchedFrom: []string{"wallex"},
+			wantChanged: []string{"BTCUSDT"},
+		},
+		{
+			name:        "24h volume moved",
+			existing:    []domain.Market{btc},
+			fetched:     []domain.Market{with(btc, func(m *domain.Market) { m.Volume24h = decimal.NewFromInt(12) })},
+			fetchedFrom: []string{"wallex"},
+			wantChanged: []string{"BTCUSDT"},
+		},
+		{name: "delisted", existing: []domain.Market{btc, eth}, fetched: []domain.Market{btc}, fetchedFrom: []string{"wallex"}, wantRemoved: []uint{2}},
+		{
+			// an exchange that failed to answer keeps its markets
+			name:        "exchange down",
+			existing:    []domain.Market{btc, omp},
+			fetched:     []domain.Market{btc},
+			fetchedFrom: []string{"wallex"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetchedExchanges := map[string]bool{}
+			for _, e := range tt.fetchedFrom {
+				fetchedExchanges[e] = true
+			}
+
+			changed, removed := diffMarkets(tt.existing, tt.fetched, fetchedExchanges)
+
+			var gotChanged []string
+			for _, m := range changed {
+				gotChanged = append(gotChanged, m.ExchangeMarketIdentifier)
+			}
+			sort.Strings(gotChanged)
+			if !equalStrings(gotChanged, tt.wantChanged) {
+				t.Errorf("changed = %v, want %v", gotChanged, tt.wantChanged)
+			}
+			sort.Slice(removed, func(i, j int) bool { return removed[i] < removed[j] })
+			if len(removed) != len(tt.wantRemoved) {
+				t.Fatalf("removed = %v, want %v", removed, tt.wantRemoved)
+			}
+			for i := range removed {
+				if removed[i] != tt.wantRemoved[i] {
+					t.Errorf("removed = %v, want %v", removed, tt.wantRemoved)
+				}
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ompfinex"
+	"github.com/MMN3003/mega/src/market/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestGetMegaMarketVolume(t *testing.T) {
+	markets := &fakeMarketRepo{markets: []domain.Market{
+		{ID: 1, MegaMarketID: 1, ExchangeName: "ompfinex", IsActive: true, Volume24h: decimal.RequireFromString("1.5")},
+		{ID: 2, MegaMarketID: 1, ExchangeName: "wallex", IsActive: true, Volume24h: decimal.RequireFromString("2.25")},
+		{ID: 3, MegaMarketID: 1, ExchangeName: "nobitex", IsActive: false, Volume24h: decimal.NewFromInt(100)},
+		{ID: 4, MegaMarketID: 2, ExchangeName: "wallex", IsActive: true, Volume24h: decimal.NewFromInt(7)},
+	}}
+	megaMarkets := &fakeMegaMarketRepo{megaMarkets: map[uint]*domain.MegaMarket{
+		1: {ID: 1, IsActive: true, SourceTokenSymbol: "BTC"},
+		2: {ID: 2, IsActive: true, SourceTokenSymbol: "ETH"},
+		3: {ID: 3, IsActive: true, SourceTokenSymbol: "TRX"},
+		4: {ID: 4, IsActive: false, SourceTokenSymbol: "DOGE"},
+	}}
+	tests := []struct {
+		name       string
+		megaMarket uint
+		wantNil    bool
+		want       string
+		currency   string
+		venues     int
+	}{
+		// the delisted nobitex market doesn't count
+		{name: "sums mapped venues", megaMarket: 1, want: "3.75", currency: "BTC", venues: 2},
+		{name: "single venue", megaMarket: 2, want: "7", currency: "ETH", venues: 1},
+		{name: "no venues", megaMarket: 3, want: "0", currency: "TRX"},
+		{name: "inactive mega market", megaMarket: 4, wantNil: true},
+		{name: "unknown mega market", megaMarket: 9, wantNil: true},
+	}
+	svc := newTestMarketService(t, markets, megaMarkets, nil, nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.GetMegaMarketVolume(context.Background(), tt.megaMarket)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("volume = %+v, want nil", got)
+				}
+				return
+			}
+			if !got.Volume24h.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("volume = %s, want %s", got.Volume24h, tt.want)
+			}
+			if got.Currency != tt.currency {
+				t.Errorf("currency = %s, want %s", got.Currency, tt.currency)
+			}
+			if len(got.Venues) != tt.venues {
+				t.Errorf("%d venues, want %d", len(got.Venues), tt.venues)
+			}
+		})
+	}
+}
+
+func TestOmpfinexVolume24h(t *testing.T) {
+	m := ompfinex.Market{ID: 12, Name: "BTCUSDT"}
+	tests := []struct {
+		name  string
+		books map[string]ompfinex.MarketOrderBook
+		want  string
+	}{
+		{name: "keyed by id", books: map[string]ompfinex.MarketOrderBook{"12": {Volume24h: "4.2"}}, want: "4.2"},
+		{name: "keyed by name", books: map[string]ompfinex.MarketOrderBook{"BTCUSDT": {Volume24h: "0.5"}}, want: "0.5"},
+		{name: "missing", books: map[string]ompfinex.MarketOrderBook{"13": {Volume24h: "9"}}, want: "0"},
+		{name: "order books unavailable", want: "0"},
+		{name: "malformed", books: map[string]ompfinex.MarketOrderBook{"12": {Volume24h: "n/a"}}, want: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ompfinexVolume24h(tt.books, m); !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("volume = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
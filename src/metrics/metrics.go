@@ -0,0 +1,170 @@
+// Package metrics holds the Prometheus collectors exposed on /metrics.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mega"
+
+var (
+	// OrderStepLatency observes how long an order stayed in a status before leaving it.
+	OrderStepLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "order",
+		Name:      "step_latency_seconds",
+		Help:      "Time an order spent in a status before transitioning out of it.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 14), // 0.5s .. ~68m
+	}, []string{"status"})
+
+	// PayoutDust accumulates the amount kept back by rounding treasury payouts down.
+	PayoutDust = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "treasury",
+		Name:      "payout_dust_total",
+		Help:      "Token amount withheld by rounding payouts down to the smallest unit.",
+	}, []string{"token"})
+
+	// TreasuryPaused is 1 while payouts of a token are paused for lack of treasury balance.
+	TreasuryPaused = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "treasury",
+		Name:      "paused",
+		Help:      "1 while orders paying out token are paused because the treasury ran out of it.",
+	}, []string{"token"})
+
+	// OrderTransitions counts status changes of orders.
+	OrderTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "order",
+		Name:      "transitions_total",
+		Help:      "Order status changes, by the status left and the status entered.",
+	}, []string{"from", "to"})
+
+	// OrdersDeadLettered counts orders given up on and parked for an operator.
+	OrdersDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "order",
+		Name:      "dead_lettered_total",
+		Help:      "Orders moved to dead letter, by the status they failed in.",
+	}, []string{"status"})
+
+	// ExchangeRequestDuration observes the round trip of every exchange API call.
+	ExchangeRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of exchange API requests.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10), // 50ms .. ~25s
+	}, []string{"exchange"})
+
+	// ExchangeRequestErrors counts exchange API calls that failed in transport or with a non-2xx status.
+	ExchangeRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exchange",
+		Name:      "request_errors_total",
+		Help:      "Failed exchange API requests; code is the HTTP status, or transport when none came back.",
+	}, []string{"exchange", "code"})
+
+	// EthereumTransactions counts the outcome of every transaction we send.
+	EthereumTransactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ethereum",
+		Name:      "transactions_total",
+		Help:      "Sent transactions by result: send_failed, unconfirmed, reverted or success.",
+	}, []string{"network", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(OrderStepLatency, PayoutDust, TreasuryPaused, OrderTransitions,
+		OrdersDeadLettered, ExchangeRequestDuration, ExchangeRequestErrors, EthereumTransactions)
+}
+
+// Handler serves the default registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// AddPayoutDust records dust withheld from a payout of token.
+func AddPayoutDust(token string, dust float64) {
+	PayoutDust.WithLabelValues(token).Add(dust)
+}
+
+// SetTreasuryPaused flags whether payouts of token are paused.
+func SetTreasuryPaused(token string, paused bool) {
+	v := 0.0
+	if paused {
+		v = 1
+	}
+	TreasuryPaused.WithLabelValues(token).Set(v)
+}
+
+// ObserveOrderStepLatency records the time spent in status before the order left it.
+func ObserveOrderStepLatency(status string, d time.Duration) {
+	OrderStepLatency.WithLabelValues(status).Observe(d.Seconds())
+}
+
+// IncOrderTransition records an order moving from one status to another.
+func IncOrderTransition(from, to string) {
+	OrderTransitions.WithLabelValues(from, to).Inc()
+}
+
+// IncOrderDeadLettered records an order moved to dead letter out of status.
+func IncOrderDeadLettered(status string) {
+	OrdersDeadLettered.WithLabelValues(status).Inc()
+}
+
+// ObserveExchangeRequest records one exchange API call. status is 0 when err is a
+// transport error and no response came back.
+func ObserveExchangeRequest(exchange string, d time.Duration, status int, err error) {
+	ExchangeRequestDuration.WithLabelValues(exchange).Observe(d.Seconds())
+	switch {
+	case err != nil:
+		ExchangeRequestErrors.WithLabelValues(exchange, "transport").Inc()
+	case status < 200 || status >= 300:
+		ExchangeRequestErrors.WithLabelValues(exchange, strconv.Itoa(status)).Inc()
+	}
+}
+
+// IncEthereumTransaction records the result of a transaction sent on network.
+func IncEthereumTransaction(network, result string) {
+	EthereumTransactions.WithLabelValues(network, result).Inc()
+}
+
+// orderStatusCollector reads the number of orders per status from the database on every
+// scrape, so every instance reports the same, current numbers.
+type orderStatusCollector struct {
+	desc  *prometheus.Desc
+	count func(ctx context.Context) (map[string]int64, error)
+}
+
+func (c *orderStatusCollector) Describe(ch chan<- *prometheus.Desc) { ch <- c.desc }
+
+func (c *orderStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	counts, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), status)
+	}
+}
+
+// RegisterOrderStatusCounts exposes mega_order_status_count, the number of orders in each
+// status as reported by count.
+func RegisterOrderStatusCounts(count func(ctx context.Context) (map[string]int64, error)) {
+	prometheus.MustRegister(&orderStatusCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "order", "status_count"),
+			"Orders currently in each status.", []string{"status"}, nil),
+		count: count,
+	})
+}
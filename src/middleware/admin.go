@@ -0,0 +1,31 @@
+// Package middleware holds gin middleware shared by the delivery layers.
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/MMN3003/mega/src/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHeader carries the operator token on /admin requests
+const AdminHeader = "X-Admin-Token"
+
+// AdminAuth rejects requests whose X-Admin-Token does not match token.
+// An empty token disables the admin endpoints entirely.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, errors.New("not found"))
+			return
+		}
+		given := c.GetHeader(AdminHeader)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			apierror.Respond(c, http.StatusUnauthorized, apierror.CodeUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS answers cross-origin requests from allowedOrigins, where "*" allows any origin,
+// and short-circuits their OPTIONS preflights with 204. Requests from other origins get
+// no CORS headers, so browsers refuse them; an empty allowedOrigins denies every origin.
+// Same-origin and non-browser requests are not affected.
+func CORS(allowedOrigins, allowedMethods, allowedHeaders []string, maxAge time.Duration) gin.HandlerFunc {
+	allowAny := false
+	origins := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		if o == "*" {
+			allowAny = true
+		}
+		origins[strings.TrimSuffix(o, "/")] = true
+	}
+	methods := strings.Join(allowedMethods, ", ")
+	headers := strings.Join(allowedHeaders, ", ")
+	// exposed so browser clients can read them off responses
+	exposed := strings.Join([]string{RequestIDHeader, "Retry-After"}, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		if !allowAny && !origins[origin] {
+			if isPreflight(c) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Expose-Headers", exposed)
+		if isPreflight(c) {
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			if maxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+func isPreflight(c *gin.Context) bool {
+	return c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MMN3003/mega/src/apierror"
+	"github.com/gin-gonic/gin"
+)
+
+// sweepInterval is how often buckets that refilled completely are dropped
+const sweepInterval = time.Minute
+
+var errRateLimited = errors.New("too many requests, retry later")
+
+// RateLimit limits each caller to a token bucket of rate requests per second and burst
+// requests at once. Callers are told apart by the user id UserAuth authenticated, or by
+// client IP before auth or without it, so it belongs after UserAuth. Rejected requests
+// get a 429 with a Retry-After. A non-positive rate disables the limit.
+func RateLimit(rate float64, burst int) gin.HandlerFunc {
+	if rate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l := &limiter{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket)}
+	return func(c *gin.Context) {
+		key, ok := UserID(c.Request.Context())
+		if !ok {
+			key = "ip:" + c.ClientIP()
+		}
+		allowed, wait := l.allow(key, time.Now())
+		if !allowed {
+			seconds := int(math.Ceil(wait.Seconds())) // Retry-After is in whole seconds
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			apierror.RespondWithDetails(c, http.StatusTooManyRequests, apierror.CodeRateLimited, errRateLimited,
+				gin.H{"retry_after": seconds})
+			return
+		}
+		c.Next()
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type limiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// allow takes a token from key's bucket, or reports how long until one is available
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that are full again: they behave exactly like new ones, and keeping
+// them would let the map grow with every IP ever seen
+func (l *limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
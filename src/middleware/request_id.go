@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request id in and out
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength caps a client supplied id, longer ones are replaced
+const maxRequestIDLength = 128
+
+// RequestID keeps the X-Request-ID of the request, or generates one, stores it on the
+// request context for logger.WithContext and echoes it in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty printable ASCII ids short enough to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
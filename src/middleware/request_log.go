@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// redacted replaces the value of a sensitive query param in logs
+const redacted = "REDACTED"
+
+// RequestLogger logs method, path with query, status and duration of every request. Query
+// params named in sensitiveKeys (case-insensitive) are redacted and paths longer than
+// maxPathLen runes are truncated; maxPathLen <= 0 never truncates.
+func RequestLogger(logg *logger.Logger, sensitiveKeys []string, maxPathLen int) gin.HandlerFunc {
+	sensitive := make(map[string]bool, len(sensitiveKeys))
+	for _, k := range sensitiveKeys {
+		sensitive[strings.ToLower(k)] = true
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		logg.WithContext(c.Request.Context()).Infof("%s %s status:%d duration:%s",
+			c.Request.Method,
+			RedactURL(c.Request.URL, sensitive, maxPathLen),
+			c.Writer.Status(),
+			time.Since(start),
+		)
+	}
+}
+
+// RedactURL renders the path and query of u safe for logging
+func RedactURL(u *url.URL, sensitive map[string]bool, maxPathLen int) string {
+	path := u.Path
+	if runes := []rune(path); maxPathLen > 0 && len(runes) > maxPathLen {
+		path = string(runes[:maxPathLen]) + "..."
+	}
+	if u.RawQuery == "" {
+		return path
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		// a query we can't parse may still carry secrets, leave it out
+		return path + "?" + redacted
+	}
+	for key := range query {
+		if sensitive[strings.ToLower(key)] {
+			query[key] = []string{redacted}
+		}
+	}
+	return path + "?" + query.Encode()
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactURL(t *testing.T) {
+	sensitive := map[string]bool{"token": true, "api_key": true}
+	tests := []struct {
+		name       string
+		url        string
+		maxPathLen int
+		want       string
+	}{
+		{name: "no query", url: "/orders/1", want: "/orders/1"},
+		{name: "harmless query kept", url: "/orders?id=7", want: "/orders?id=7"},
+		{name: "sensitive param redacted", url: "/orders?id=7&token=s3cret", want: "/orders?id=7&token=REDACTED"},
+		{name: "key match ignores case", url: "/orders?API_KEY=s3cret", want: "/orders?API_KEY=REDACTED"},
+		{name: "every value redacted", url: "/orders?token=a&token=b", want: "/orders?token=REDACTED"},
+		{name: "unparsable query left out", url: "/orders?token=%zz", want: "/orders?REDACTED"},
+		{name: "long path truncated", url: "/markets/abcdefgh", maxPathLen: 10, want: "/markets/a..."},
+		{name: "short path kept", url: "/markets/1", maxPathLen: 10, want: "/markets/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := RedactURL(u, sensitive, tt.maxPathLen); got != tt.want {
+				t.Errorf("RedactURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestLoggerRedacts(t *testing.T) {
+	// the logger writes to stdout, capture it for the one request
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	logg := logger.New("prod")
+	os.Stdout = stdout
+	_ = logg.SetLevel("info")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestLogger(logg, []string{"token"}, 0))
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders?id=7&token=s3cret", nil))
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := string(out)
+	if strings.Contains(line, "s3cret") {
+		t.Errorf("log line leaks the token: %s", line)
+	}
+	if !strings.Contains(line, "GET /orders?id=7&token=REDACTED status:200") {
+		t.Errorf("log line = %s", line)
+	}
+}
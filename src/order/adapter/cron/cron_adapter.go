@@ -8,8 +8,12 @@ import (
 )
 
 type CronAdapter interface {
-	CreateCron(ctx context.Context, id uuid.UUID) error
+	CreateCron(ctx context.Context, id uuid.UUID) (stale bool, err error)
 	DeleteCron(ctx context.Context, id uuid.UUID) error
+	KeepCron(ctx context.Context, id uuid.UUID) (stop func())
+	RegisterJob(ctx context.Context, id uuid.UUID, name, schedule string) error
+	StartRun(ctx context.Context, id uuid.UUID) error
+	FinishRun(ctx context.Context, id uuid.UUID, runErr error) error
 }
 
 var _ CronAdapter = (*CronPort)(nil)
@@ -23,10 +27,26 @@ type CronPort struct {
 	cronService domain.CronUseCase
 }
 
-func (m *CronPort) CreateCron(ctx context.Context, id uuid.UUID) error {
+func (m *CronPort) CreateCron(ctx context.Context, id uuid.UUID) (bool, error) {
 	return m.cronService.CreateCron(ctx, id)
 }
 
 func (m *CronPort) DeleteCron(ctx context.Context, id uuid.UUID) error {
 	return m.cronService.DeleteCron(ctx, id)
 }
+
+func (m *CronPort) KeepCron(ctx context.Context, id uuid.UUID) (stop func()) {
+	return m.cronService.KeepCron(ctx, id)
+}
+
+func (m *CronPort) RegisterJob(ctx context.Context, id uuid.UUID, name, schedule string) error {
+	return m.cronService.RegisterJob(ctx, id, name, schedule)
+}
+
+func (m *CronPort) StartRun(ctx context.Context, id uuid.UUID) error {
+	return m.cronService.StartRun(ctx, id)
+}
+
+func (m *CronPort) FinishRun(ctx context.Context, id uuid.UUID, runErr error) error {
+	return m.cronService.FinishRun(ctx, id, runErr)
+}
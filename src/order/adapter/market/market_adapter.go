@@ -10,7 +10,10 @@ import (
 type MarketAdapter interface {
 	GetMarketByID(ctx context.Context, id uint) (*domain.Market, error)
 	GetMegaMarketByID(ctx context.Context, id uint) (*domain.MegaMarket, error)
+	GetMarketsByMegaMarketID(ctx context.Context, megaMarketId uint) ([]domain.Market, error)
 	GetBestExchangePriceByVolume(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (decimal.Decimal, *domain.Market, *domain.MegaMarket, error)
+	// GetBestPrice is GetBestExchangePriceByVolume with the top of book and price impact
+	GetBestPrice(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (*domain.BestPrice, error)
 }
 
 var _ MarketAdapter = (*MarketPort)(nil)
@@ -32,6 +35,14 @@ func (m *MarketPort) GetMegaMarketByID(ctx context.Context, id uint) (*domain.Me
 	return m.marketService.GetMegaMarketByID(ctx, id)
 }
 
+func (m *MarketPort) GetMarketsByMegaMarketID(ctx context.Context, megaMarketId uint) ([]domain.Market, error) {
+	return m.marketService.GetMarketsByMegaMarketID(ctx, megaMarketId)
+}
+
 func (m *MarketPort) GetBestExchangePriceByVolume(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (decimal.Decimal, *domain.Market, *domain.MegaMarket, error) {
 	return m.marketService.GetBestExchangePriceByVolume(ctx, megaMarketId, volume, isBuy)
 }
+
+func (m *MarketPort) GetBestPrice(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (*domain.BestPrice, error) {
+	return m.marketService.GetBestExchangePriceOn(ctx, megaMarketId, volume, isBuy, nil)
+}
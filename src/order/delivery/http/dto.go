@@ -15,6 +15,7 @@
 package http
 
 import (
+	"errors"
 	"time"
 
 	"github.com/MMN3003/mega/src/order/domain"
@@ -28,6 +29,42 @@ type OrderSignaturePayload struct {
 	S string `json:"s"`
 }
 
+// ExchangeOrderParamsDto asks for an exchange-native order instead of a market order
+// swagger:model ExchangeOrderParamsDto
+type ExchangeOrderParamsDto struct {
+	Type        domain.ExchangeOrderType `json:"type" example:"LIMIT" enums:"MARKET,LIMIT,STOP_LIMIT"`
+	Price       *decimal.Decimal         `json:"price,omitempty" example:"61000"`
+	StopPrice   *decimal.Decimal         `json:"stop_price,omitempty" example:"60500"`
+	TimeInForce domain.TimeInForce       `json:"time_in_force,omitempty" example:"IOC" enums:"GTC,IOC,FOK"`
+	PostOnly    bool                     `json:"post_only,omitempty" example:"false"`
+}
+
+func (p *ExchangeOrderParamsDto) toDomain() *domain.ExchangeOrderParams {
+	if p == nil {
+		return nil
+	}
+	return &domain.ExchangeOrderParams{
+		Type:        p.Type,
+		Price:       p.Price,
+		StopPrice:   p.StopPrice,
+		TimeInForce: p.TimeInForce,
+		PostOnly:    p.PostOnly,
+	}
+}
+
+func exchangeOrderParamsDtoFromDomain(p *domain.ExchangeOrderParams) *ExchangeOrderParamsDto {
+	if p == nil {
+		return nil
+	}
+	return &ExchangeOrderParamsDto{
+		Type:        p.Type,
+		Price:       p.Price,
+		StopPrice:   p.StopPrice,
+		TimeInForce: p.TimeInForce,
+		PostOnly:    p.PostOnly,
+	}
+}
+
 // SubmitOrderRequestBody is the payload to submit a new order
 // swagger:model SubmitOrderRequestBody
 type SubmitOrderRequestBody struct {
@@ -43,6 +80,8 @@ type SubmitOrderRequestBody struct {
 	TokenAddress       string                `json:"token_address"`
 	Signature          OrderSignaturePayload `json:"signature"`
 	UserId             string                `json:"user_id"`
+	// ExchangeOrderParams is for advanced users; omit it for a market order
+	ExchangeOrderParams *ExchangeOrderParamsDto `json:"exchange_order_params,omitempty"`
 }
 
 func (c SubmitOrderRequestBody) ToOrder() *domain.Order {
@@ -62,39 +101,97 @@ func (c SubmitOrderRequestBody) ToOrder() *domain.Order {
 			R: common.HexToHash(c.Signature.R),
 			S: common.HexToHash(c.Signature.S),
 		},
-		UserId: c.UserId,
+		UserId:              c.UserId,
+		ExchangeOrderParams: c.ExchangeOrderParams.toDomain(),
 	}
 }
 
 // SubmitOrderResponse is the response to submit a new order
 // swagger:model SubmitOrderResponse
 type SubmitOrderResponse struct {
-	ID                     uint                  `json:"id"`
-	Status                 domain.OrderStatus    `json:"status"`
-	CreatedAt              time.Time             `json:"created_at"`
-	UpdatedAt              time.Time             `json:"updated_at"`
-	Volume                 decimal.Decimal       `json:"volume"`
-	Price                  decimal.Decimal       `json:"price"`
-	FromNetwork            string                `json:"from_network"`
-	ToNetwork              string                `json:"to_network"`
-	UserAddress            string                `json:"user_address"`
-	MarketID               uint                  `json:"market_id"`
-	MegaMarketID           uint                  `json:"mega_market_id"`
-	SlipagePercentage      decimal.Decimal       `json:"slipage_percentage"`
-	IsBuy                  bool                  `json:"is_buy"`
-	ContractAddress        string                `json:"contract_address"`
-	Deadline               int64                 `json:"deadline"`
-	DestinationAddress     *string               `json:"destination_address"`
-	TokenAddress           string                `json:"token_address"`
-	Signature              OrderSignaturePayload `json:"signature"`
-	DepositTxHash          *string               `json:"deposit_tx_hash"`
-	ReleaseTxHash          *string               `json:"release_tx_hash"`
-	UserId                 string                `json:"user_id"`
-	DestinationTokenSymbol string                `json:"destination_token_symbol"`
-	SourceTokenSymbol      string                `json:"source_token_symbol"`
+	ID                     uint                    `json:"id"`
+	Status                 domain.OrderStatus      `json:"status"`
+	CreatedAt              time.Time               `json:"created_at"`
+	UpdatedAt              time.Time               `json:"updated_at"`
+	Volume                 decimal.Decimal         `json:"volume"`
+	Price                  decimal.Decimal         `json:"price"`
+	FromNetwork            string                  `json:"from_network"`
+	ToNetwork              string                  `json:"to_network"`
+	UserAddress            string                  `json:"user_address"`
+	MarketID               uint                    `json:"market_id"`
+	MegaMarketID           uint                    `json:"mega_market_id"`
+	SlipagePercentage      decimal.Decimal         `json:"slipage_percentage"`
+	IsBuy                  bool                    `json:"is_buy"`
+	ContractAddress        string                  `json:"contract_address"`
+	Deadline               int64                   `json:"deadline"`
+	DestinationAddress     *string                 `json:"destination_address"`
+	TokenAddress           string                  `json:"token_address"`
+	Signature              OrderSignaturePayload   `json:"signature"`
+	DepositTxHash          *string                 `json:"deposit_tx_hash"`
+	ReleaseTxHash          *string                 `json:"release_tx_hash"`
+	UserId                 string                  `json:"user_id"`
+	DestinationTokenSymbol string                  `json:"destination_token_symbol"`
+	SourceTokenSymbol      string                  `json:"source_token_symbol"`
+	RefundReason           domain.RefundReason     `json:"refund_reason,omitempty" example:"SLIPPAGE_EXCEEDED"`
+	ExchangeOrderID        *string                 `json:"exchange_order_id"`
+	RetryCount             int                     `json:"retry_count"`
+	CollectedFee           decimal.Decimal         `json:"collected_fee" example:"0.98"`
+	ExchangeOrderParams    *ExchangeOrderParamsDto `json:"exchange_order_params,omitempty"`
+	FeeBreakdown           *FeeBreakdownDto        `json:"fee_breakdown,omitempty"`
+	History                []OrderStatusHistoryDto `json:"history,omitempty"`
+}
+
+// FeeBreakdownDto itemizes fees, all in the same currency
+// swagger:model FeeBreakdownDto
+type FeeBreakdownDto struct {
+	Currency     string          `json:"currency" example:"USDT"`
+	Gross        decimal.Decimal `json:"gross" example:"100"`
+	ExchangeFee  decimal.Decimal `json:"exchange_fee" example:"0.2"`
+	PlatformFee  decimal.Decimal `json:"platform_fee" example:"1"`
+	EstimatedGas decimal.Decimal `json:"estimated_gas" example:"0"`
+	Net          decimal.Decimal `json:"net" example:"98.8"`
+}
+
+func feeBreakdownDtoFromDomain(b *domain.FeeBreakdown) *FeeBreakdownDto {
+	if b == nil {
+		return nil
+	}
+	return &FeeBreakdownDto{
+		Currency:     b.Currency,
+		Gross:        b.Gross,
+		ExchangeFee:  b.ExchangeFee,
+		PlatformFee:  b.PlatformFee,
+		EstimatedGas: b.EstimatedGas,
+		Net:          b.Net,
+	}
+}
+
+// OrderStatusHistoryDto is a single audited status change
+// swagger:model OrderStatusHistoryDto
+type OrderStatusHistoryDto struct {
+	FromStatus domain.OrderStatus `json:"from_status" example:"USER_DEBIT_IN_PROGRESS"`
+	ToStatus   domain.OrderStatus `json:"to_status" example:"USER_DEBIT_SUCCESS"`
+	Reason     string             `json:"reason,omitempty" example:"SLIPPAGE_EXCEEDED"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func orderStatusHistoryDtosFromDomain(history []domain.OrderStatusHistory) []OrderStatusHistoryDto {
+	out := make([]OrderStatusHistoryDto, len(history))
+	for i, h := range history {
+		out[i] = OrderStatusHistoryDto{
+			FromStatus: h.FromStatus,
+			ToStatus:   h.ToStatus,
+			Reason:     h.Reason,
+			CreatedAt:  h.CreatedAt,
+		}
+	}
+	return out
 }
 
 func fromOrderDomain(order *domain.Order) SubmitOrderResponse {
+	if order == nil {
+		return SubmitOrderResponse{}
+	}
 	return SubmitOrderResponse{
 		ID:                 order.ID,
 		Status:             order.Status,
@@ -123,9 +220,117 @@ func fromOrderDomain(order *domain.Order) SubmitOrderResponse {
 		UserId:                 order.UserId,
 		DestinationTokenSymbol: order.DestinationTokenSymbol,
 		SourceTokenSymbol:      order.SourceTokenSymbol,
+		RefundReason:           order.RefundReason,
+		ExchangeOrderID:        order.ExchangeOrderID,
+		RetryCount:             order.RetryCount,
+		CollectedFee:           order.CollectedFee,
+		ExchangeOrderParams:    exchangeOrderParamsDtoFromDomain(order.ExchangeOrderParams),
+		FeeBreakdown:           feeBreakdownDtoFromDomain(order.FeeBreakdown),
 	}
 }
 
+// BulkOrderResultDto is the outcome of one order of a bulk submission, at the same index
+// as the order in the request
+// swagger:model BulkOrderResultDto
+type BulkOrderResultDto struct {
+	Index               int                  `json:"index" example:"0"`
+	Order               *SubmitOrderResponse `json:"order,omitempty"`
+	Error               string               `json:"error,omitempty"`
+	FailedPrerequisites []string             `json:"failed_prerequisites,omitempty"`
+}
+
+// SubmitOrdersResponse lists the outcome of every order of a bulk submission
+// swagger:model SubmitOrdersResponse
+type SubmitOrdersResponse struct {
+	Created int                  `json:"created" example:"2"`
+	Results []BulkOrderResultDto `json:"results"`
+}
+
+func SubmitOrdersResponseFromDomain(results []domain.BulkOrderResult) SubmitOrdersResponse {
+	resp := SubmitOrdersResponse{Results: make([]BulkOrderResultDto, len(results))}
+	for i, r := range results {
+		dto := BulkOrderResultDto{Index: i}
+		if r.Order != nil {
+			order := fromOrderDomain(r.Order)
+			dto.Order = &order
+			resp.Created++
+		}
+		if r.Err != nil {
+			dto.Error = r.Err.Error()
+			var preflightErr *domain.PreflightError
+			if errors.As(r.Err, &preflightErr) {
+				dto.Error = domain.ErrInvalidOrder.Error()
+				dto.FailedPrerequisites = preflightErr.Failures
+			}
+		}
+		resp.Results[i] = dto
+	}
+	return resp
+}
+
+// PaginationDto describes the returned page
+// swagger:model PaginationDto
+type PaginationDto struct {
+	TotalRecords int `json:"total_records" example:"230"`
+	PerPage      int `json:"per_page" example:"50"`
+	Page         int `json:"page" example:"1"`
+	TotalPages   int `json:"total_pages" example:"5"`
+}
+
+// ListOrdersResponse is a page of orders
+// swagger:model ListOrdersResponse
+type ListOrdersResponse struct {
+	Orders     []SubmitOrderResponse `json:"orders"`
+	Pagination PaginationDto         `json:"pagination"`
+}
+
+func ListOrdersResponseFromDomain(orders []domain.Order, p *domain.Pagination) ListOrdersResponse {
+	dtos := make([]SubmitOrderResponse, len(orders))
+	for i := range orders {
+		dtos[i] = fromOrderDomain(&orders[i])
+	}
+	return ListOrdersResponse{
+		Orders: dtos,
+		Pagination: PaginationDto{
+			TotalRecords: p.TotalRecords,
+			PerPage:      p.PerPage,
+			Page:         p.Page,
+			TotalPages:   p.TotalPages,
+		},
+	}
+}
+
+// StepLatencyDto is the latency of a single pipeline step, in seconds
+// swagger:model StepLatencyDto
+type StepLatencyDto struct {
+	Status     domain.OrderStatus `json:"status" example:"TREASURY_CREDIT_IN_PROGRESS"`
+	Count      int64              `json:"count" example:"42"`
+	P50Seconds float64            `json:"p50_seconds" example:"12.5"`
+	P90Seconds float64            `json:"p90_seconds" example:"30.1"`
+	P99Seconds float64            `json:"p99_seconds" example:"58.7"`
+}
+
+// StepLatencyResponse lists per-step latency ordered from slowest to fastest median
+// swagger:model StepLatencyResponse
+type StepLatencyResponse struct {
+	Since time.Time        `json:"since"`
+	Steps []StepLatencyDto `json:"steps"`
+}
+
+func StepLatencyResponseFromDomain(since time.Time, steps []domain.StepLatency) StepLatencyResponse {
+	dtos := make([]StepLatencyDto, len(steps))
+	for i, st := range steps {
+		dtos[i] = StepLatencyDto{
+			Status:     st.Status,
+			Count:      st.Count,
+			P50Seconds: st.P50.Seconds(),
+			P90Seconds: st.P90.Seconds(),
+			P99Seconds: st.P99.Seconds(),
+		}
+	}
+	return StepLatencyResponse{Since: since, Steps: dtos}
+}
+
 // PairDTO describes a tradable pair
 // swagger:model PairDTO
 type PairDTO struct {
@@ -152,12 +357,11 @@ type ListPairsResponseBody struct {
 // CreateQuoteRequestBody is the payload to request a quote
 // swagger:model CreateQuoteRequestBody
 type CreateQuoteRequestBody struct {
-	FromNetwork string `json:"from_network" example:"sepolia"`
-	FromToken   string `json:"from_token" example:"USDT"`
-	ToNetwork   string `json:"to_network" example:"mumbai"`
-	ToToken     string `json:"to_token" example:"MATIC"`
-	AmountIn    string `json:"amount_in" example:"100.0"` // decimal string
-	UserAddress string `json:"user_address" example:"0xabc..."`
+	MegaMarketID uint   `json:"mega_market_id" binding:"required" example:"1"`
+	Volume       string `json:"volume" binding:"required" example:"0.5"` // decimal string
+	IsBuy        bool   `json:"is_buy" example:"true"`
+	// ValidateOnly prices the quote without saving it; the response has no quote_id
+	ValidateOnly bool `json:"validate_only" example:"false"`
 }
 
 // CreateQuoteRequest wrapper for swagger param
@@ -170,14 +374,45 @@ type CreateQuoteRequest struct {
 // CreateQuoteResponseBody returns a quote
 // swagger:model CreateQuoteResponseBody
 type CreateQuoteResponseBody struct {
-	QuoteID     string          `json:"quote_id" example:"b9f..."`
-	AmountIn    decimal.Decimal `json:"amount_in" example:"100.0"`
-	AmountOut   decimal.Decimal `json:"amount_out" example:"98.5"`
-	ExpiresAt   time.Time       `json:"expires_at"`
-	FromNetwork string          `json:"from_network"`
-	FromToken   string          `json:"from_token"`
-	ToNetwork   string          `json:"to_network"`
-	ToToken     string          `json:"to_token"`
+	QuoteID      string `json:"quote_id,omitempty" example:"b9f0c1d2-6f1e-4c55-9a7b-3d2e1f0a9b8c.Xk3..."` // empty for validate_only previews
+	MegaMarketID uint   `json:"mega_market_id" example:"1"`
+	MarketID     uint   `json:"market_id" example:"12"`
+	ExchangeName string `json:"exchange_name" example:"wallex"`
+	IsBuy        bool   `json:"is_buy" example:"true"`
+	FromToken    string `json:"from_token" example:"USDT"`
+	ToToken      string `json:"to_token" example:"BTC"`
+	// Volume is the quoted size, AmountOut what it costs (buy) or pays (sell) after fees
+	Volume    decimal.Decimal `json:"volume" example:"0.5"`
+	AmountOut decimal.Decimal `json:"amount_out" example:"30650"`
+	// Price is the exchange's average fill price, EffectivePrice the same after fees
+	Price          decimal.Decimal `json:"price" example:"61000"`
+	EffectivePrice decimal.Decimal `json:"effective_price" example:"61300"`
+	PriceImpact    decimal.Decimal `json:"price_impact" example:"0.0012"`
+	ExpiresAt      time.Time       `json:"expires_at"`
+	// FeeBreakdown itemizes the difference between the exchange price and the effective price
+	FeeBreakdown *FeeBreakdownDto `json:"fee_breakdown,omitempty"`
+}
+
+func CreateQuoteResponseBodyFromDomain(q *domain.Quote) CreateQuoteResponseBody {
+	resp := CreateQuoteResponseBody{
+		MegaMarketID:   q.MegaMarketID,
+		MarketID:       q.MarketID,
+		ExchangeName:   q.ExchangeName,
+		IsBuy:          q.IsBuy,
+		FromToken:      q.FromToken,
+		ToToken:        q.ToToken,
+		Volume:         q.AmountIn,
+		AmountOut:      q.AmountOut,
+		Price:          q.Price,
+		EffectivePrice: q.EffectivePrice,
+		PriceImpact:    q.PriceImpact,
+		ExpiresAt:      q.ExpiresAt,
+		FeeBreakdown:   feeBreakdownDtoFromDomain(q.FeeBreakdown),
+	}
+	if q.ID != "" {
+		resp.QuoteID = q.SignedID()
+	}
+	return resp
 }
 
 // CreateQuoteResponse wrapper for swagger response
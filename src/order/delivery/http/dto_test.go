@@ -0,0 +1,82 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestFromOrderDomainRetryCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryCount int
+	}{
+		{name: "never retried"},
+		{name: "retried", retryCount: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(fromOrderDomain(&domain.Order{ID: 1, RetryCount: tt.retryCount}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				RetryCount *int `json:"retry_count"`
+			}
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatal(err)
+			}
+			// zero is reported too, so clients can tell it from a missing field
+			if got.RetryCount == nil || *got.RetryCount != tt.retryCount {
+				t.Errorf("retry_count = %v in %s, want %d", got.RetryCount, body, tt.retryCount)
+			}
+		})
+	}
+}
+
+func TestSubmitOrdersResponseFromDomain(t *testing.T) {
+	results := []domain.BulkOrderResult{
+		{Order: &domain.Order{ID: 7}},
+		{Err: &domain.PreflightError{Failures: []string{"market 9 not found"}}},
+		{Err: domain.ErrBulkAborted},
+	}
+	tests := []struct {
+		name      string
+		index     int
+		wantOrder uint
+		wantError string
+		wantFails []string
+	}{
+		{name: "created", index: 0, wantOrder: 7},
+		{name: "failed preflight", index: 1, wantError: domain.ErrInvalidOrder.Error(), wantFails: []string{"market 9 not found"}},
+		{name: "aborted", index: 2, wantError: domain.ErrBulkAborted.Error()},
+	}
+	resp := SubmitOrdersResponseFromDomain(results)
+	if resp.Created != 1 || len(resp.Results) != len(results) {
+		t.Fatalf("response = %+v, want 1 of %d created", resp, len(results))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resp.Results[tt.index]
+			if got.Index != tt.index {
+				t.Errorf("index = %d, want %d", got.Index, tt.index)
+			}
+			if tt.wantOrder != 0 && (got.Order == nil || got.Order.ID != tt.wantOrder) {
+				t.Errorf("order = %+v, want id %d", got.Order, tt.wantOrder)
+			}
+			if got.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", got.Error, tt.wantError)
+			}
+			if len(got.FailedPrerequisites) != len(tt.wantFails) {
+				t.Errorf("failed prerequisites = %q, want %q", got.FailedPrerequisites, tt.wantFails)
+			}
+		})
+	}
+}
+
+func TestFromOrderDomainNil(t *testing.T) {
+	if got := fromOrderDomain(nil); got.ID != 0 || got.Status != "" {
+		t.Errorf("fromOrderDomain(nil) = %+v, want an empty response", got)
+	}
+}
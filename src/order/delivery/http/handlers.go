@@ -2,26 +2,80 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/MMN3003/mega/src/apierror"
 	"github.com/MMN3003/mega/src/logger"
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/middleware"
+	"github.com/MMN3003/mega/src/order/domain"
 	"github.com/MMN3003/mega/src/order/usecase"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// maxBulkOrders caps how many orders one bulk submission may carry
+	maxBulkOrders = 100
+	// maxBulkBodyBytes caps the size of a bulk submission body
+	maxBulkBodyBytes = 1 << 20
+	// idempotencyKeyHeader lets a client retry a submission without creating a second order
+	idempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength caps the stored key
+	maxIdempotencyKeyLength = 255
 )
 
 // Handler binds usecase + logger
 type Handler struct {
 	service *usecase.Service
 	logger  *logger.Logger
+	// userAuth guards every order route
+	userAuth gin.HandlerFunc
+	// rateLimit runs after userAuth, so authenticated callers are limited per user
+	rateLimit gin.HandlerFunc
+}
+
+// HandlerOption configures optional Handler behaviour
+type HandlerOption func(*Handler)
+
+// WithUserAuth authenticates the caller of order submission and listing with auth, which
+// must put the trusted user id on the request context, see middleware.UserAuth
+func WithUserAuth(auth gin.HandlerFunc) HandlerOption {
+	return func(h *Handler) {
+		h.userAuth = auth
+	}
+}
+
+// WithRateLimit limits every order route with limit, see middleware.RateLimit
+func WithRateLimit(limit gin.HandlerFunc) HandlerOption {
+	return func(h *Handler) {
+		h.rateLimit = limit
+	}
 }
 
-func NewHandler(s *usecase.Service, l *logger.Logger) *Handler {
-	return &Handler{service: s, logger: l}
+func NewHandler(s *usecase.Service, l *logger.Logger, opts ...HandlerOption) *Handler {
+	next := func(c *gin.Context) { c.Next() }
+	h := &Handler{service: s, logger: l, userAuth: next, rateLimit: next}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
-	r.GET("/:id", h.GetOrderById)
-	r.POST("/submit", h.SubmitOrder)
+	r.GET("/orders", h.userAuth, h.rateLimit, h.ListOrders)
+	r.POST("/orders/bulk", h.userAuth, h.rateLimit, h.SubmitOrders)
+
+	// single order routes live under /order so :id can't swallow other top-level paths
+	order := r.Group("/order")
+	order.GET("/:id", h.userAuth, h.rateLimit, h.GetOrderById)
+	order.POST("/submit", h.userAuth, h.rateLimit, h.SubmitOrder)
+	order.POST("/:id/cancel", h.userAuth, h.rateLimit, h.CancelOrder)
+
+	r.POST("/quote", h.userAuth, h.rateLimit, h.CreateQuote)
 	// r.GET("/health", func(c *gin.Context) {
 	// 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	// })
@@ -36,106 +90,378 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 // GetOrderById godoc
 //
 //	@Summary		Get order by id
-//	@Description	Get order by id
+//	@Description	Get order by id. Only the order's user may read it; another user's order is
+//	@Description	reported as not found.
 //	@Tags			order
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	http.FetchAndUpdateMarketsResponse
-//	@Failure		500	{object}	object{error=string}
-//	@Router			/order/:id [get]
+//	@Param			Authorization	header		string	true	"Bearer token"
+//	@Param			id				path		int		true	"Order id"
+//	@Success		200				{object}	SubmitOrderResponse
+//	@Failure		400				{object}	apierror.APIError
+//	@Failure		401				{object}	apierror.APIError
+//	@Failure		404				{object}	apierror.APIError
+//	@Failure		429				{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500				{object}	apierror.APIError
+//	@Router			/order/{id} [get]
 func (h *Handler) GetOrderById(c *gin.Context) {
 	ctx := c.Request.Context()
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		h.logger.Errorf("GetOrderById err: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid id"))
 		return
 	}
-	order, err := h.service.GetOrderById(ctx, uint(id))
+	userID, _ := middleware.UserID(ctx)
+	order, err := h.service.GetOrderById(ctx, uint(id), userID)
 	if err != nil {
-		h.logger.Errorf("GetOrderById err: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(c.Request.Context()).Errorf("GetOrderById err: %v", err)
+		apierror.Internal(c)
 		return
 	}
-	c.JSON(http.StatusOK, fromOrderDomain(order))
+	if order == nil {
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, fmt.Errorf("%w: id %d", domain.ErrOrderNotFound, id))
+		return
+	}
+	history, err := h.service.GetOrderHistory(ctx, order.ID)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("GetOrderHistory err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	resp := fromOrderDomain(order)
+	resp.History = orderStatusHistoryDtosFromDomain(history)
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListOrders godoc
+//
+//	@Summary		List orders
+//	@Description	List orders filtered by user, status and market, oldest first. An authenticated caller only
+//	@Description	sees their own orders, whatever user_id says. Operators list any user's orders under /admin,
+//	@Description	e.g. status=DEAD_LETTER for the orders that failed for good.
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer token"
+//	@Param			user_id		query		string	false	"User id"
+//	@Param			status		query		string	false	"Order status"	example(PENDING)
+//	@Param			market_id	query		int		false	"Exchange market id"
+//	@Param			page		query		int		false	"Page number"	default(1)
+//	@Param			limit		query		int		false	"Page size"		default(50)
+//	@Success		200			{object}	ListOrdersResponse
+//	@Failure		400			{object}	apierror.APIError
+//	@Failure		401			{object}	apierror.APIError
+//	@Failure		429			{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500			{object}	apierror.APIError
+//	@Router			/orders [get]
+//	@Router			/admin/orders [get]
+func (h *Handler) ListOrders(c *gin.Context) {
+	ctx := c.Request.Context()
+	filter := domain.OrderFilter{
+		UserId: c.Query("user_id"),
+		Status: domain.OrderStatus(c.Query("status")),
+	}
+	for key, dst := range map[string]*int{"page": &filter.Page, "limit": &filter.Limit} {
+		if v := c.Query(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, fmt.Errorf("invalid %s", key))
+				return
+			}
+			*dst = n
+		}
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if userID, ok := middleware.UserID(ctx); ok {
+		filter.UserId = userID
+	}
+	if v := c.Query("market_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid market_id"))
+			return
+		}
+		filter.MarketID = uint(id)
+	}
+
+	orders, pagination, err := h.service.ListOrders(ctx, filter)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("ListOrders err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	c.JSON(http.StatusOK, ListOrdersResponseFromDomain(orders, pagination))
 }
 
 // SubmitOrder godoc
 //
 //	@Summary		Submit order
-//	@Description	Submit a new order
+//	@Description	Submit a new order. When authentication is on, the order belongs to the token's user and the
+//	@Description	body's user_id is ignored. A retry with the same Idempotency-Key returns the order the key
+//	@Description	first created instead of creating another one.
 //	@Tags			order
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		SubmitOrderRequestBody	true	"Request body"
+//	@Param			Authorization	header		string					true	"Bearer token"
+//	@Param			Idempotency-Key	header		string					false	"Client chosen key, unique per user, up to 255 characters"
+//	@Param			request			body		SubmitOrderRequestBody	true	"Request body"
 //	@Success		200	{object}	SubmitOrderResponse
-//	@Failure		400	{object}	object{error=string}
-//	@Failure		500	{object}	object{error=string}
+//	@Failure		400	{object}	apierror.APIError
+//	@Failure		401	{object}	apierror.APIError
+//	@Failure		429	{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500	{object}	apierror.APIError
 //	@Router			/order/submit [post]
 func (h *Handler) SubmitOrder(c *gin.Context) {
 	ctx := c.Request.Context()
 	// get data from body
 	var req SubmitOrderRequestBody
 	if err := c.ShouldBindJSON(&req); err != nil {
-		h.logger.Errorf("SubmitOrder err: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		h.logger.WithContext(c.Request.Context()).Errorf("SubmitOrder err: %v", err)
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid request"))
 		return
 	}
 
-	order, err := h.service.SubmitOrder(ctx, req.ToOrder())
+	order := req.ToOrder()
+	if userID, ok := middleware.UserID(ctx); ok {
+		order.UserId = userID // the token, not the body, says who is submitting
+	}
+	if key := c.GetHeader(idempotencyKeyHeader); key != "" {
+		if len(key) > maxIdempotencyKeyLength {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("idempotency key too long"))
+			return
+		}
+		order.IdempotencyKey = &key
+	}
+	order, err := h.service.SubmitOrder(ctx, order)
+	var preflightErr *domain.PreflightError
+	if errors.As(err, &preflightErr) {
+		apierror.RespondWithDetails(c, http.StatusBadRequest, apierror.CodeInvalidRequest, domain.ErrInvalidOrder, gin.H{"failed_prerequisites": preflightErr.Failures})
+		return
+	}
+	if errors.Is(err, domain.ErrInvalidOrder) {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err)
+		return
+	}
 	if err != nil {
-		h.logger.Errorf("SubmitOrder err: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		h.logger.WithContext(c.Request.Context()).Errorf("SubmitOrder err: %v", err)
+		apierror.Internal(c)
 		return
 	}
 	c.JSON(http.StatusOK, fromOrderDomain(order))
 }
 
-// // swagger:route POST /swap/quote swap createQuote
-// // Create a swap quote
-// //
-// // Responses:
-// //
-// //	200: CreateQuoteResponseBody
-// //	400: BadRequest
-// func (h *Handler) CreateQuote(w http.ResponseWriter, r *http.Request) {
-// 	var reqBody CreateQuoteRequestBody
-// 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-// 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
-// 		return
-// 	}
-// 	amount, err := decimal.NewFromString(reqBody.AmountIn)
-// 	if err != nil {
-// 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid amount"})
-// 		return
-// 	}
+// SubmitOrders godoc
+//
+//	@Summary		Submit orders in bulk
+//	@Description	Validate and submit up to 100 orders at once; created orders are saved in a single transaction.
+//	@Description	With atomic=true (the default) nothing is saved unless every order is valid, otherwise each invalid order only fails its own result.
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string						true	"Bearer token"
+//	@Param			atomic			query		bool						false	"All-or-nothing"	default(true)
+//	@Param			request			body		[]SubmitOrderRequestBody	true	"Orders"
+//	@Success		200		{object}	SubmitOrdersResponse
+//	@Failure		400		{object}	SubmitOrdersResponse
+//	@Failure		401		{object}	apierror.APIError
+//	@Failure		413		{object}	apierror.APIError
+//	@Failure		429		{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500		{object}	apierror.APIError
+//	@Router			/orders/bulk [post]
+func (h *Handler) SubmitOrders(c *gin.Context) {
+	ctx := c.Request.Context()
+	atomic := true
+	if v := c.Query("atomic"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid atomic"))
+			return
+		}
+		atomic = parsed
+	}
 
-// 	q, err := h.swapSvc.CreateQuote(context.Background(), usecase.CreateQuoteRequest{
-// 		FromNetwork: reqBody.FromNetwork,
-// 		FromToken:   reqBody.FromToken,
-// 		ToNetwork:   reqBody.ToNetwork,
-// 		ToToken:     reqBody.ToToken,
-// 		AmountIn:    amount,
-// 		UserAddress: reqBody.UserAddress,
-// 	})
-// 	if err != nil {
-// 		h.logger.Errorf("CreateQuote err: %v", err)
-// 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-// 		return
-// 	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkBodyBytes)
+	var req []SubmitOrderRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Respond(c, http.StatusRequestEntityTooLarge, apierror.CodeTooLarge, errors.New("request body too large"))
+			return
+		}
+		h.logger.WithContext(c.Request.Context()).Errorf("SubmitOrders err: %v", err)
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid request"))
+		return
+	}
+	if len(req) == 0 || len(req) > maxBulkOrders {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("between 1 and 100 orders are accepted"))
+		return
+	}
 
-// 	resp := CreateQuoteResponseBody{
-// 		QuoteID:     q.ID,
-// 		AmountIn:    q.AmountIn,
-// 		AmountOut:   q.AmountOut,
-// 		ExpiresAt:   q.ExpiresAt,
-// 		FromNetwork: q.FromNetwork,
-// 		FromToken:   q.FromToken,
-// 		ToNetwork:   q.ToNetwork,
-// 		ToToken:     q.ToToken,
-// 	}
-// 	writeJSON(w, http.StatusOK, resp)
-// }
+	orders := make([]*domain.Order, len(req))
+	for i := range req {
+		orders[i] = req[i].ToOrder()
+		if userID, ok := middleware.UserID(ctx); ok {
+			orders[i].UserId = userID
+		}
+	}
+	results, err := h.service.SubmitOrders(ctx, orders, atomic)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("SubmitOrders err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	resp := SubmitOrdersResponseFromDomain(results)
+	status := http.StatusOK
+	if atomic && resp.Created < len(results) {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, resp)
+}
+
+// CancelOrder godoc
+//
+//	@Summary		Cancel order
+//	@Description	Cancel an order that is still pending, before the user is debited. Only the order's
+//	@Description	user may cancel it; another user's order is reported as not found.
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string	true	"Bearer token"
+//	@Param			id				path		int		true	"Order id"
+//	@Success		200				{object}	SubmitOrderResponse
+//	@Failure		400				{object}	apierror.APIError
+//	@Failure		401				{object}	apierror.APIError
+//	@Failure		404				{object}	apierror.APIError
+//	@Failure		409				{object}	apierror.APIError
+//	@Failure		429				{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500				{object}	apierror.APIError
+//	@Router			/order/{id}/cancel [post]
+func (h *Handler) CancelOrder(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid id"))
+		return
+	}
+	userID, _ := middleware.UserID(ctx)
+	order, err := h.service.CancelOrder(ctx, uint(id), userID)
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, err)
+		return
+	case errors.Is(err, domain.ErrOrderNotCancellable):
+		apierror.Respond(c, http.StatusConflict, apierror.CodeConflict, err)
+		return
+	case err != nil:
+		h.logger.WithContext(c.Request.Context()).Errorf("CancelOrder err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	c.JSON(http.StatusOK, fromOrderDomain(order))
+}
+
+// RegisterAdminRoutes mounts the operator endpoints on an already authenticated group
+func (h *Handler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/orders", h.ListOrders)
+	r.GET("/orders/step-latency", h.GetStepLatencies)
+}
+
+// GetStepLatencies godoc
+//
+//	@Summary		Order pipeline step latency
+//	@Description	Latency percentiles of each order status, computed from order events
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string	true	"Admin token"
+//	@Param			window	query		string	false	"Look-back window (Go duration)"	default(24h)
+//	@Success		200		{object}	StepLatencyResponse
+//	@Failure		400		{object}	apierror.APIError
+//	@Failure		401		{object}	apierror.APIError
+//	@Failure		500		{object}	apierror.APIError
+//	@Router			/admin/orders/step-latency [get]
+func (h *Handler) GetStepLatencies(c *gin.Context) {
+	ctx := c.Request.Context()
+	window, err := time.ParseDuration(c.DefaultQuery("window", "24h"))
+	if err != nil || window <= 0 {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid window"))
+		return
+	}
+	since := time.Now().Add(-window)
+	steps, err := h.service.GetStepLatencies(ctx, since)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Errorf("GetStepLatencies err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	c.JSON(http.StatusOK, StepLatencyResponseFromDomain(since, steps))
+}
+
+// CreateQuote godoc
+//
+//	@Summary		Quote a mega market trade
+//	@Description	Price a volume of a mega market on its best exchange and return the fee-adjusted effective
+//	@Description	price. The quote is saved until expires_at and its quote_id can be executed at these terms;
+//	@Description	validate_only prices it without saving it. A volume whose price impact exceeds the mega
+//	@Description	market's slippage is rejected with 422.
+//	@Tags			order
+//	@Accept			json
+//	@Produce		json
+//	@Param			Authorization	header		string					true	"Bearer token"
+//	@Param			request			body		CreateQuoteRequestBody	true	"Request body"
+//	@Success		200	{object}	CreateQuoteResponseBody
+//	@Failure		400	{object}	apierror.APIError
+//	@Failure		401	{object}	apierror.APIError
+//	@Failure		404	{object}	apierror.APIError
+//	@Failure		422	{object}	apierror.APIError	"price impact above the mega market's slippage, or price outside the reference band"
+//	@Failure		429	{object}	apierror.APIError	"rate limited, see Retry-After"
+//	@Failure		500	{object}	apierror.APIError
+//	@Failure		503	{object}	apierror.APIError	"every exchange is down"
+//	@Router			/quote [post]
+func (h *Handler) CreateQuote(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req CreateQuoteRequestBody
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.WithContext(ctx).Errorf("CreateQuote err: %v", err)
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid request"))
+		return
+	}
+	volume, err := decimal.NewFromString(req.Volume)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, errors.New("invalid volume"))
+		return
+	}
+	userID, _ := middleware.UserID(ctx)
+
+	q, err := h.service.CreateQuote(ctx, domain.QuoteRequest{
+		MegaMarketID: req.MegaMarketID,
+		Volume:       volume,
+		IsBuy:        req.IsBuy,
+		UserID:       userID,
+		ValidateOnly: req.ValidateOnly,
+	})
+	switch {
+	case errors.Is(err, market_domain.ErrInvalidVolume):
+		apierror.Respond(c, http.StatusBadRequest, apierror.CodeInvalidRequest, err)
+		return
+	case errors.Is(err, market_domain.ErrMegaMarketNotFound):
+		apierror.Respond(c, http.StatusNotFound, apierror.CodeNotFound, err)
+		return
+	case errors.Is(err, market_domain.ErrPriceImpactTooHigh), errors.Is(err, market_domain.ErrPriceOutOfBand):
+		apierror.Respond(c, http.StatusUnprocessableEntity, apierror.CodeUnprocessable, err)
+		return
+	case errors.Is(err, market_domain.ErrNoExchangesAvailable):
+		apierror.Respond(c, http.StatusServiceUnavailable, apierror.CodeUnavailable, errors.New("exchanges are unavailable, retry later"))
+		return
+	case err != nil:
+		h.logger.WithContext(ctx).Errorf("CreateQuote err: %v", err)
+		apierror.Internal(c)
+		return
+	}
+	c.JSON(http.StatusOK, CreateQuoteResponseBodyFromDomain(q))
+}
 
 // // swagger:route POST /swap/execute swap executeQuote
 // // Execute an existing quote
@@ -157,7 +483,7 @@ func (h *Handler) SubmitOrder(c *gin.Context) {
 // 		RequestingUser: reqBody.RequestingUser,
 // 	})
 // 	if err != nil {
-// 		h.logger.Errorf("ExecuteQuote err: %v", err)
+// 		h.logger.WithContext(c.Request.Context()).Errorf("ExecuteQuote err: %v", err)
 // 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 // 		return
 // 	}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
+	"github.com/MMN3003/mega/src/apierror"
+	"github.com/MMN3003/mega/src/config"
+	"github.com/MMN3003/mega/src/logger"
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/adapter/market"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/MMN3003/mega/src/order/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// stubOrderRepo serves orders from memory, or fails with err; other methods panic
+type stubOrderRepo struct {
+	domain.OrderRepository
+	orders map[uint]*domain.Order
+	err    error
+}
+
+func (r *stubOrderRepo) GetOrderByID(ctx context.Context, id uint) (*domain.Order, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.orders[id], nil
+}
+
+// GetOrderByIdempotencyKey serves the stored order userId submitted with key
+func (r *stubOrderRepo) GetOrderByIdempotencyKey(ctx context.Context, userId, key string) (*domain.Order, error) {
+	for _, o := range r.orders {
+		if o.UserId == userId && o.IdempotencyKey != nil && *o.IdempotencyKey == key {
+			return o, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *stubOrderRepo) GetOrderHistory(ctx context.Context, id uint) ([]domain.OrderStatusHistory, error) {
+	return nil, nil
+}
+
+// stubMarketAdapter knows no markets; other methods panic
+type stubMarketAdapter struct {
+	market.MarketAdapter
+}
+
+func (stubMarketAdapter) GetMarketByID(ctx context.Context, id uint) (*market_domain.Market, error) {
+	return nil, nil
+}
+
+// newTestRouter mounts the order routes of a handler on repo; the exchanges are unreachable
+func newTestRouter(t *testing.T, repo domain.OrderRepository, opts ...HandlerOption) *gin.Engine {
+	t.Helper()
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	const unreachable = "http://127.0.0.1:1"
+	cfg := &config.Config{
+		OMP:     config.OMPConfig{BaseURL: unreachable},
+		Wallex:  config.WallexConfig{BaseURL: unreachable},
+		Nobitex: config.NobitexConfig{BaseURL: unreachable},
+	}
+	svc, err := usecase.NewService(repo, l, cfg, &ethereum.Chains{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetAdapters(context.Background(), stubMarketAdapter{}); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewHandler(svc, l, opts...).RegisterRoutes(r)
+	return r
+}
+
+func TestGetOrderByIdNotFound(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		repoErr    error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "found", path: "/order/1", wantStatus: http.StatusOK},
+		{name: "unknown id", path: "/order/2", wantStatus: http.StatusNotFound, wantCode: apierror.CodeNotFound},
+		{name: "id not a number", path: "/order/abc", wantStatus: http.StatusBadRequest, wantCode: apierror.CodeInvalidRequest},
+		{name: "repo fails", path: "/order/1", repoErr: errors.New("db down"), wantStatus: http.StatusInternalServerError, wantCode: apierror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubOrderRepo{orders: map[uint]*domain.Order{1: {ID: 1, Status: domain.OrderPending}}, err: tt.repoErr}
+			r := newTestRouter(t, repo)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var got SubmitOrderResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.ID != 1 {
+					t.Errorf("id = %d, want 1", got.ID)
+				}
+				return
+			}
+			var got apierror.APIError
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusNotFound && !strings.Contains(got.Message, domain.ErrOrderNotFound.Error()) {
+				t.Errorf("message = %q, want it to say %q", got.Message, domain.ErrOrderNotFound)
+			}
+		})
+	}
+}
+
+// TestSubmitOrderIdempotencyKey retries a submission; the stub repo can't save, so a
+// repeated key must be answered without creating an order
+func TestSubmitOrderIdempotencyKey(t *testing.T) {
+	key, atLimit := "retry-7", strings.Repeat("k", maxIdempotencyKeyLength)
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+		wantID     uint
+	}{
+		{name: "repeated key", key: key, wantStatus: http.StatusOK, wantID: 1},
+		{name: "key at the limit", key: atLimit, wantStatus: http.StatusOK, wantID: 2},
+		{name: "key too long", key: strings.Repeat("x", maxIdempotencyKeyLength+1), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubOrderRepo{orders: map[uint]*domain.Order{
+				1: {ID: 1, UserId: "alice", IdempotencyKey: &key, Status: domain.OrderPending},
+				2: {ID: 2, UserId: "alice", IdempotencyKey: &atLimit, Status: domain.OrderPending},
+			}}
+			r := newTestRouter(t, repo)
+			req := httptest.NewRequest(http.MethodPost, "/order/submit", strings.NewReader(`{"user_id":"alice","market_id":2,"volume":"1"}`))
+			req.Header.Set("Idempotency-Key", tt.key)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got SubmitOrderResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("id = %d, want %d", got.ID, tt.wantID)
+			}
+		})
+	}
+}
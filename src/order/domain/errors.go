@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidTransition is returned when an order is moved to a status its current status cannot reach
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	// ErrOrderNotFound is returned when no order exists for the given id
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrOrderNotCancellable is returned when the order already left PENDING and may be executing on-chain
+	ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+	// ErrInvalidOrder is returned when an order breaks the limits of the market it targets
+	ErrInvalidOrder = errors.New("invalid order")
+	// ErrUnsupportedOrderType is returned when the exchange can't place the requested order type
+	ErrUnsupportedOrderType = errors.New("order type not supported by the exchange")
+	// ErrBulkAborted marks a valid order left unsaved because another order of its all-or-nothing batch was invalid
+	ErrBulkAborted = errors.New("not submitted, another order in the batch is invalid")
+	// ErrQuoteNotFound is returned when no quote matches a quote id, or its signature does not verify
+	ErrQuoteNotFound = errors.New("quote not found")
+	// ErrQuoteExpired is returned when a quote is looked up after its expiry
+	ErrQuoteExpired = errors.New("quote expired")
+	// ErrQuoteUsed is returned when a quote was already executed
+	ErrQuoteUsed = errors.New("quote already used")
+)
+
+// PreflightError lists every prerequisite an order failed, so the caller can fix them all at once
+type PreflightError struct {
+	Failures []string
+}
+
+func (e *PreflightError) Error() string {
+	return "order preflight failed: " + strings.Join(e.Failures, "; ")
+}
+
+func (e *PreflightError) Unwrap() error { return ErrInvalidOrder }
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -23,8 +24,93 @@ const (
 	OrderRefundUserOrderFailed     OrderStatus = "REFUND_USER_ORDER_FAILED"
 	OrderTreasuryCreditInProgress  OrderStatus = "TREASURY_CREDIT_IN_PROGRESS"
 	OrderCompleted                 OrderStatus = "COMPLETED"
+	OrderCancelled                 OrderStatus = "CANCELLED"
+	OrderExpired                   OrderStatus = "EXPIRED"
+	// OrderDeadLetter holds orders that failed for good and need an operator
+	OrderDeadLetter OrderStatus = "DEAD_LETTER"
+	// OrderAwaitingReconciliation holds orders whose on-chain transfer or exchange order was
+	// sent but whose outcome is unknown; an operator settles them from the tx hashes
+	OrderAwaitingReconciliation OrderStatus = "AWAITING_RECONCILIATION"
 )
 
+// RefundReason explains why an order was routed to refund
+type RefundReason string
+
+const (
+	RefundReasonSlippageExceeded     RefundReason = "SLIPPAGE_EXCEEDED"
+	RefundReasonMarketOrderFailed    RefundReason = "MARKET_ORDER_FAILED"
+	RefundReasonExchangeBalance      RefundReason = "INSUFFICIENT_EXCHANGE_BALANCE"
+	RefundReasonTreasuryCreditFailed RefundReason = "TREASURY_CREDIT_FAILED"
+	RefundReasonDeadlineExpired      RefundReason = "DEADLINE_EXPIRED"
+	RefundReasonMaxLifetimeExceeded  RefundReason = "MAX_LIFETIME_EXCEEDED"
+)
+
+// ExchangeOrderType is the order type placed on the exchange
+type ExchangeOrderType string
+
+const (
+	ExchangeOrderMarket    ExchangeOrderType = "MARKET"
+	ExchangeOrderLimit     ExchangeOrderType = "LIMIT"
+	ExchangeOrderStopLimit ExchangeOrderType = "STOP_LIMIT"
+)
+
+// TimeInForce is how long a limit order stays on the exchange's book
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC" // good till cancelled
+	TimeInForceIOC TimeInForce = "IOC" // immediate or cancel
+	TimeInForceFOK TimeInForce = "FOK" // fill or kill
+)
+
+// ExchangeOrderParams asks for an exchange-native order instead of the default market
+// order. Whether the order's exchange supports them is checked when it is submitted.
+type ExchangeOrderParams struct {
+	Type ExchangeOrderType `json:"type"`
+	// Price is the limit price of LIMIT and STOP_LIMIT orders
+	Price *decimal.Decimal `json:"price,omitempty"`
+	// StopPrice triggers a STOP_LIMIT order
+	StopPrice *decimal.Decimal `json:"stop_price,omitempty"`
+	// TimeInForce defaults to GTC
+	TimeInForce TimeInForce `json:"time_in_force,omitempty"`
+	// PostOnly rejects the order instead of letting it take liquidity
+	PostOnly bool `json:"post_only,omitempty"`
+}
+
+// Validate checks the params are consistent on their own, whatever the exchange
+func (p *ExchangeOrderParams) Validate() error {
+	switch p.Type {
+	case ExchangeOrderMarket:
+		if p.Price != nil || p.StopPrice != nil || p.PostOnly || p.TimeInForce != "" {
+			return fmt.Errorf("%w: market orders take no price, stop price, time in force or post only", ErrInvalidOrder)
+		}
+		return nil
+	case ExchangeOrderLimit:
+		if p.StopPrice != nil {
+			return fmt.Errorf("%w: stop price needs a STOP_LIMIT order", ErrInvalidOrder)
+		}
+	case ExchangeOrderStopLimit:
+		if p.StopPrice == nil || !p.StopPrice.IsPositive() {
+			return fmt.Errorf("%w: STOP_LIMIT orders need a positive stop price", ErrInvalidOrder)
+		}
+	default:
+		return fmt.Errorf("%w: unknown exchange order type %q", ErrInvalidOrder, p.Type)
+	}
+	if p.Price == nil || !p.Price.IsPositive() {
+		return fmt.Errorf("%w: %s orders need a positive price", ErrInvalidOrder, p.Type)
+	}
+	switch p.TimeInForce {
+	case "", TimeInForceGTC:
+	case TimeInForceIOC, TimeInForceFOK:
+		if p.PostOnly {
+			return fmt.Errorf("%w: post only orders must rest on the book, not %s", ErrInvalidOrder, p.TimeInForce)
+		}
+	default:
+		return fmt.Errorf("%w: unknown time in force %q", ErrInvalidOrder, p.TimeInForce)
+	}
+	return nil
+}
+
 type OrderSignature struct {
 	V uint8       `json:"v"`
 	R common.Hash `json:"r"`
@@ -54,6 +140,88 @@ type Order struct {
 	UserId                 string          `json:"user_id"`
 	DestinationTokenSymbol string          `json:"destination_token_symbol"`
 	SourceTokenSymbol      string          `json:"source_token_symbol"`
+	RefundReason           RefundReason    `json:"refund_reason,omitempty"`
+	ExchangeOrderID        *string         `json:"exchange_order_id"`
+	RetryCount             int             `json:"retry_count"`
+	CollectedFee           decimal.Decimal `json:"collected_fee"`
+	// ExchangeOrderParams, when set, replaces the default market order on the exchange
+	ExchangeOrderParams *ExchangeOrderParams `json:"exchange_order_params,omitempty"`
+	// IdempotencyKey is the client's Idempotency-Key of the submission, unique per user
+	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+	// FeeBreakdown is computed on read, it is not persisted
+	FeeBreakdown *FeeBreakdown `json:"fee_breakdown,omitempty"`
+}
+
+// FeeBreakdown itemizes everything taken between the gross price and what the user
+// receives. All amounts are in Currency, the order's destination token.
+type FeeBreakdown struct {
+	Currency     string          `json:"currency"`
+	Gross        decimal.Decimal `json:"gross"`
+	ExchangeFee  decimal.Decimal `json:"exchange_fee"`
+	PlatformFee  decimal.Decimal `json:"platform_fee"`
+	EstimatedGas decimal.Decimal `json:"estimated_gas"`
+	Net          decimal.Decimal `json:"net"`
+}
+
+// BulkOrderResult is the outcome of one order of a bulk submission: the created order,
+// or the reason it was rejected.
+type BulkOrderResult struct {
+	Order *Order
+	Err   error
+}
+
+// OrderEvent records the moment an order entered a status
+type OrderEvent struct {
+	ID        uint        `json:"id"`
+	OrderID   uint        `json:"order_id"`
+	Status    OrderStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// OrderFilter narrows ListOrders; zero fields are ignored.
+// Page is 1-based and Limit falls back to a repository default when zero.
+type OrderFilter struct {
+	Status       OrderStatus
+	UserId       string
+	MarketID     uint
+	CreatedFrom  *time.Time
+	CreatedUntil *time.Time
+	Page         int
+	Limit        int
+}
+
+// Pagination describes the page returned by a list query
+type Pagination struct {
+	TotalRecords int `json:"total_records"`
+	PerPage      int `json:"per_page"`
+	Page         int `json:"page"`
+	TotalPages   int `json:"total_pages"`
+}
+
+// OrderStatusHistory is the audit record of a single status change
+type OrderStatusHistory struct {
+	ID         uint        `json:"id"`
+	OrderID    uint        `json:"order_id"`
+	FromStatus OrderStatus `json:"from_status"`
+	ToStatus   OrderStatus `json:"to_status"`
+	Reason     string      `json:"reason,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// TreasuryPause stops payouts in Token until the treasury on Network holds Needed of it again
+type TreasuryPause struct {
+	Token   string
+	Network string
+	Needed  decimal.Decimal
+}
+
+// StepLatency aggregates how long orders stay in a status before leaving it
+type StepLatency struct {
+	Status OrderStatus   `json:"status"`
+	Count  int64         `json:"count"`
+	P50    time.Duration `json:"p50"`
+	P90    time.Duration `json:"p90"`
+	P99    time.Duration `json:"p99"`
 }
 
 // Coin description
@@ -65,7 +233,8 @@ type Coin struct {
 	DisplayName  string `json:"display_name,omitempty" db:"display_name"`
 }
 
-// Quote entity
+// Quote entity. A market quote prices AmountIn, the volume, of a mega market on its best
+// exchange market; AmountOut is what the volume costs or pays after fees.
 type Quote struct {
 	ID          string          `json:"id" db:"id"`
 	FromNetwork string          `json:"from_network" db:"from_network"`
@@ -78,6 +247,33 @@ type Quote struct {
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
 	Used        bool            `json:"used" db:"used"`
 	UserAddress string          `json:"user_address" db:"user_address"`
+
+	MegaMarketID uint   `json:"mega_market_id" db:"mega_market_id"`
+	MarketID     uint   `json:"market_id" db:"market_id"`
+	ExchangeName string `json:"exchange_name" db:"exchange_name"`
+	IsBuy        bool   `json:"is_buy" db:"is_buy"`
+	// Price is the exchange's average fill price, EffectivePrice the same after fees
+	Price          decimal.Decimal `json:"price" db:"price"`
+	EffectivePrice decimal.Decimal `json:"effective_price" db:"effective_price"`
+	PriceImpact    decimal.Decimal `json:"price_impact" db:"price_impact"`
+	UserID         string          `json:"user_id" db:"user_id"`
+	// Signature binds the quote's terms to its ID; it is not stored but recomputed on lookup
+	Signature    string        `json:"-" db:"-"`
+	FeeBreakdown *FeeBreakdown `json:"fee_breakdown,omitempty" db:"-"`
+}
+
+// SignedID is the quote id handed to clients, which only resolves while the signature matches
+func (q *Quote) SignedID() string {
+	return q.ID + "." + q.Signature
+}
+
+// QuoteRequest asks for a market quote; ValidateOnly prices it without saving it
+type QuoteRequest struct {
+	MegaMarketID uint
+	Volume       decimal.Decimal
+	IsBuy        bool
+	UserID       string
+	ValidateOnly bool
 }
 
 const (
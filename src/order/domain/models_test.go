@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestExchangeOrderParamsValidate(t *testing.T) {
+	price := decimal.NewFromInt(100)
+	zero := decimal.Zero
+	tests := []struct {
+		name    string
+		params  ExchangeOrderParams
+		wantErr bool
+	}{
+		{name: "market", params: ExchangeOrderParams{Type: ExchangeOrderMarket}},
+		{name: "market with a price", params: ExchangeOrderParams{Type: ExchangeOrderMarket, Price: &price}, wantErr: true},
+		{name: "market with time in force", params: ExchangeOrderParams{Type: ExchangeOrderMarket, TimeInForce: TimeInForceIOC}, wantErr: true},
+		{name: "limit", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price}},
+		{name: "limit ioc", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price, TimeInForce: TimeInForceIOC}},
+		{name: "limit fok", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price, TimeInForce: TimeInForceFOK}},
+		{name: "limit post only", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price, PostOnly: true}},
+		{name: "limit without price", params: ExchangeOrderParams{Type: ExchangeOrderLimit}, wantErr: true},
+		{name: "limit at zero", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &zero}, wantErr: true},
+		{name: "limit with a stop price", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price, StopPrice: &price}, wantErr: true},
+		// post only must rest on the book, IOC and FOK never do
+		{name: "post only ioc", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price, TimeInForce: TimeInForceIOC, PostOnly: true}, wantErr: true},
+		{name: "unknown time in force", params: ExchangeOrderParams{Type: ExchangeOrderLimit, Price: &price, TimeInForce: "GTD"}, wantErr: true},
+		{name: "stop limit", params: ExchangeOrderParams{Type: ExchangeOrderStopLimit, Price: &price, StopPrice: &price}},
+		{name: "stop limit without stop price", params: ExchangeOrderParams{Type: ExchangeOrderStopLimit, Price: &price}, wantErr: true},
+		{name: "stop limit without price", params: ExchangeOrderParams{Type: ExchangeOrderStopLimit, StopPrice: &price}, wantErr: true},
+		{name: "unknown type", params: ExchangeOrderParams{Type: "ICEBERG"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidOrder) {
+				t.Errorf("err = %v, want it to wrap ErrInvalidOrder", err)
+			}
+		})
+	}
+}
@@ -2,28 +2,74 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
 
 type OrderUsecase interface {
 	PlaceMarketOrder(ctx context.Context, marketId uint, volume decimal.Decimal, isBuy bool) (string, error)
+	PlaceExchangeOrder(ctx context.Context, marketId uint, volume decimal.Decimal, isBuy bool, params *ExchangeOrderParams) (string, error)
 	SubmitOrder(ctx context.Context, o *Order) (*Order, error)
+	// SubmitOrders validates every order and saves the valid ones in one transaction; with
+	// atomic set nothing is saved unless all of them are valid
+	SubmitOrders(ctx context.Context, orders []*Order, atomic bool) ([]BulkOrderResult, error)
+	PreflightOrder(ctx context.Context, o *Order) error
+	// CancelOrder cancels userID's order; userID is empty only when authentication is off
+	CancelOrder(ctx context.Context, id uint, userID string) (*Order, error)
 	FetchPendingOrders(ctx context.Context) error
 	FetchSuccessDebitOrders(ctx context.Context) error
 	FetchReturnUserOrders(ctx context.Context) error
 	FetchMarketUserOrderSuccessOrders(ctx context.Context) error
 	FetchFailedMarketUserOrderOrders(ctx context.Context) error
+	RefundStaleOrders(ctx context.Context) error
+	ExpireStaleQuotes(ctx context.Context) error
+	CreateQuote(ctx context.Context, req QuoteRequest) (*Quote, error)
+	// GetQuote resolves a signed quote id to its quote while the quote can still be executed
+	GetQuote(ctx context.Context, signedID string) (*Quote, error)
+	ArchiveOrders(ctx context.Context) error
+	GetStepLatencies(ctx context.Context, since time.Time) ([]StepLatency, error)
+	GetOrderHistory(ctx context.Context, id uint) ([]OrderStatusHistory, error)
+	ListOrders(ctx context.Context, filter OrderFilter) ([]Order, *Pagination, error)
 }
 type OrderRepository interface {
 	SaveOrder(ctx context.Context, o *Order) (*Order, error)
+	// SaveOrders creates all orders in one transaction
+	SaveOrders(ctx context.Context, orders []*Order) ([]*Order, error)
 	GetOrderByID(ctx context.Context, id uint) (*Order, error)
+	// GetOrderByIdempotencyKey returns the order userId submitted with key, or nil when there is none
+	GetOrderByIdempotencyKey(ctx context.Context, userId, key string) (*Order, error)
 	UpdateOrder(ctx context.Context, o *Order) error
 	SoftDelete(ctx context.Context, id uint) error
 	SoftDeleteAll(ctx context.Context) error
 	GetOrdersByUserId(ctx context.Context, userId string) ([]Order, error)
 	GetOrdersByStatus(ctx context.Context, status OrderStatus) ([]Order, error)
+	ListOrders(ctx context.Context, filter OrderFilter) ([]Order, *Pagination, error)
 	ChangeStatusByIds(ctx context.Context, ids []uint, status OrderStatus) error
+	// ClaimOrders skips orders paying out in one of skipTokens
+	ClaimOrders(ctx context.Context, from, to OrderStatus, limit int, skipTokens []string) ([]Order, error)
+	RefundStaleOrders(ctx context.Context, statuses []OrderStatus, createdBefore time.Time, reason RefundReason, limit int) ([]Order, error)
+	RefundOrder(ctx context.Context, id uint, reason RefundReason) error
+	// ArchiveOrders moves orders in one of statuses last updated before updatedBefore to the
+	// archive table and returns how many it moved
+	ArchiveOrders(ctx context.Context, statuses []OrderStatus, updatedBefore time.Time, limit int) (int, error)
+	RetryOrder(ctx context.Context, id uint, status OrderStatus) error
+	// DeadLetterOrder parks the order in OrderDeadLetter, recording reason in its status history
+	DeadLetterOrder(ctx context.Context, id uint, reason string) error
+	// ReconcileOrder parks the order in OrderAwaitingReconciliation, recording reason in its status history
+	ReconcileOrder(ctx context.Context, id uint, reason string) error
+	SetExchangeOrderID(ctx context.Context, id uint, exchangeOrderID string) error
+	SetTxHashes(ctx context.Context, id uint, depositTxHash, releaseTxHash *string) error
+	SetCollectedFee(ctx context.Context, id uint, fee decimal.Decimal) error
+	GetStepLatencies(ctx context.Context, since time.Time) ([]StepLatency, error)
+	GetOrderHistory(ctx context.Context, id uint) ([]OrderStatusHistory, error)
+	CountOrdersByStatus(ctx context.Context) (map[OrderStatus]int64, error)
+	// PauseToken stores p, or raises the amount an existing pause of p.Token needs; it
+	// reports whether anything changed
+	PauseToken(ctx context.Context, p TreasuryPause) (bool, error)
+	// ListTreasuryPauses returns the paused tokens, sorted by token
+	ListTreasuryPauses(ctx context.Context) ([]TreasuryPause, error)
+	ResumeToken(ctx context.Context, token string) error
 }
 
 // QuoteRepository persistence port
@@ -32,6 +78,8 @@ type QuoteRepository interface {
 	GetByID(ctx context.Context, id string) (*Quote, error)
 	MarkUsed(ctx context.Context, id string) error
 	ListActive(ctx context.Context) ([]*Quote, error)
+	// ExpireStale deletes unused quotes that expired before the given time and returns how many
+	ExpireStale(ctx context.Context, before time.Time) (int, error)
 }
 
 // OnChainAdapter port for network adapter
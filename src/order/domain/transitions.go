@@ -0,0 +1,67 @@
+package domain
+
+// transitions lists, per status, the statuses an order may move to next.
+// Statuses missing as keys are terminal.
+var transitions = map[OrderStatus][]OrderStatus{
+	OrderPending: {OrderUserDebitInProgress, OrderCancelled, OrderExpired},
+	OrderUserDebitInProgress: {
+		OrderUserDebitSuccess,
+		OrderFailedUserDebit,
+		OrderExpired,
+		OrderPending, // parked undebited until the treasury can pay out
+		OrderAwaitingReconciliation,
+	},
+	OrderUserDebitSuccess: {OrderMarketUserOrderInProgress, OrderRefundUserOrder},
+	OrderMarketUserOrderInProgress: {
+		OrderMarketUserOrderSuccess,
+		OrderMarketUserOrderFailed,
+		OrderUserDebitSuccess, // retry after a failed market order
+		OrderRefundUserOrder,
+		OrderAwaitingReconciliation,
+	},
+	OrderMarketUserOrderFailed:  {OrderMarketUserOrderInProgress, OrderRefundUserOrder},
+	OrderMarketUserOrderSuccess: {OrderTreasuryCreditInProgress},
+	OrderTreasuryCreditInProgress: {
+		OrderCompleted,
+		OrderRefundUserOrder,
+		OrderMarketUserOrderSuccess, // wait for the treasury to be topped up
+		OrderAwaitingReconciliation,
+	},
+	OrderRefundUserOrder: {OrderRefundUserOrderInProgress},
+	OrderRefundUserOrderInProgress: {
+		OrderRefundUserOrderSuccess,
+		OrderRefundUserOrderFailed,
+		OrderRefundUserOrder, // retry the refund
+		OrderDeadLetter,
+		OrderAwaitingReconciliation,
+	},
+}
+
+// StaleRefundableStatuses are the statuses a debited order may be refunded from when it
+// outlives its max lifetime. Later statuses are past the point of no return: the exchange
+// order filled and the treasury credit may already be on its way. In-progress statuses are
+// left out too, a worker may be placing the exchange order right now.
+var StaleRefundableStatuses = []OrderStatus{
+	OrderUserDebitSuccess,
+	OrderMarketUserOrderFailed,
+}
+
+// ArchivableStatuses are the terminal statuses an order may be archived in once it is past
+// the retention period. Failed refunds are left in place, they still need an operator.
+var ArchivableStatuses = []OrderStatus{
+	OrderCompleted,
+	OrderCancelled,
+	OrderExpired,
+	OrderFailedUserDebit,
+	OrderRefundUserOrderSuccess,
+}
+
+// CanTransition reports whether an order in status from may move to status to
+func CanTransition(from, to OrderStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
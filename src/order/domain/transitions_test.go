@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to OrderStatus
+		want     bool
+	}{
+		// the happy path
+		{OrderPending, OrderUserDebitInProgress, true},
+		{OrderUserDebitInProgress, OrderUserDebitSuccess, true},
+		{OrderUserDebitSuccess, OrderMarketUserOrderInProgress, true},
+		{OrderMarketUserOrderInProgress, OrderMarketUserOrderSuccess, true},
+		{OrderMarketUserOrderSuccess, OrderTreasuryCreditInProgress, true},
+		{OrderTreasuryCreditInProgress, OrderCompleted, true},
+		// retries, refunds and parking
+		{OrderPending, OrderCancelled, true},
+		{OrderPending, OrderExpired, true},
+		{OrderUserDebitInProgress, OrderFailedUserDebit, true},
+		{OrderUserDebitInProgress, OrderPending, true},
+		{OrderMarketUserOrderInProgress, OrderUserDebitSuccess, true},
+		{OrderMarketUserOrderFailed, OrderRefundUserOrder, true},
+		{OrderTreasuryCreditInProgress, OrderMarketUserOrderSuccess, true},
+		{OrderRefundUserOrder, OrderRefundUserOrderInProgress, true},
+		{OrderRefundUserOrderInProgress, OrderRefundUserOrder, true},
+		{OrderRefundUserOrderInProgress, OrderDeadLetter, true},
+		{OrderTreasuryCreditInProgress, OrderAwaitingReconciliation, true},
+		// illegal jumps
+		{OrderCompleted, OrderPending, false},
+		{OrderPending, OrderCompleted, false},
+		{OrderPending, OrderUserDebitSuccess, false},
+		{OrderUserDebitSuccess, OrderCompleted, false},
+		{OrderMarketUserOrderSuccess, OrderRefundUserOrder, false},
+		{OrderRefundUserOrderSuccess, OrderRefundUserOrder, false},
+		{OrderDeadLetter, OrderPending, false},
+		// a debited order is refunded before it can be given up on
+		{OrderMarketUserOrderInProgress, OrderDeadLetter, false},
+		{OrderAwaitingReconciliation, OrderCompleted, false},
+		{OrderPending, OrderPending, false},
+		{"UNKNOWN", OrderPending, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// terminal statuses are left to an operator, nothing may move an order out of them
+func TestTerminalStatuses(t *testing.T) {
+	terminal := []OrderStatus{
+		OrderCompleted, OrderCancelled, OrderExpired, OrderFailedUserDebit,
+		OrderRefundUserOrderSuccess, OrderRefundUserOrderFailed, OrderDeadLetter, OrderAwaitingReconciliation,
+	}
+	for _, status := range terminal {
+		t.Run(string(status), func(t *testing.T) {
+			if next := transitions[status]; len(next) > 0 {
+				t.Errorf("%s may move to %v", status, next)
+			}
+		})
+	}
+}
+
+func TestStaleRefundableStatuses(t *testing.T) {
+	for _, status := range StaleRefundableStatuses {
+		t.Run(string(status), func(t *testing.T) {
+			if strings.HasSuffix(string(status), "_IN_PROGRESS") {
+				t.Errorf("%s is being worked on, the sweep would race the worker", status)
+			}
+			if !CanTransition(status, OrderRefundUserOrder) {
+				t.Errorf("%s cannot be refunded", status)
+			}
+		})
+	}
+}
+
+func TestArchivableStatuses(t *testing.T) {
+	for _, status := range ArchivableStatuses {
+		t.Run(string(status), func(t *testing.T) {
+			if next := transitions[status]; len(next) > 0 {
+				t.Errorf("%s may still move to %v, archiving it would strand it", status, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MMN3003/mega/src/logger"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newMockOrderRepo returns an OrderRepo on a sqlmock connection; every expectation set on
+// the mock must be met by the end of the test.
+func newMockOrderRepo(t *testing.T) (*OrderRepo, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	return &OrderRepo{db: gdb, log: l}, mock
+}
+
+// expectRecordStatus expects recordStatusTx appending the event and history rows of one
+// order that had no event yet
+func expectRecordStatus(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(`SELECT DISTINCT ON \(order_id\) \* FROM order_events`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "status", "created_at"}))
+	mock.ExpectQuery(`INSERT INTO "order_events"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "order_status_history"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+// captureArg matches any value and keeps it, so a test can check what was written
+type captureArg struct{ v any }
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.v = v
+	return true
+}
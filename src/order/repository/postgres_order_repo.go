@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"reflect"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/metrics"
 	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var _ domain.OrderRepository = (*OrderRepo)(nil)
@@ -35,7 +40,10 @@ type Order struct {
 	Deadline               int64           `json:"deadline"`
 	DestinationAddress     *string         `json:"destination_address"`
 	TokenAddress           string          `json:"token_address"`
-	Signature              *string         `json:"signature"`
+	SigV                   uint8           `json:"sig_v"`
+	SigR                   string          `json:"sig_r"`
+	SigS                   string          `json:"sig_s"`
+	Signature              *string         `json:"signature"` // legacy JSON, moved into SigV/SigR/SigS on startup
 	DepositTxHash          *string         `json:"deposit_tx_hash"`
 	ReleaseTxHash          *string         `json:"release_tx_hash"`
 	UserId                 string          `json:"user_id" gorm:"index"`
@@ -43,8 +51,45 @@ type Order struct {
 	SlipagePercentage      decimal.Decimal `json:"slipage_percentage"`
 	Price                  decimal.Decimal `json:"price"`
 	SourceTokenSymbol      string          `json:"source_token_symbol"`
+	RefundReason           string          `json:"refund_reason"`
+	ExchangeOrderID        *string         `json:"exchange_order_id" gorm:"index"`
+	RetryCount             int             `json:"retry_count" gorm:"not null;default:0"`
+	CollectedFee           decimal.Decimal `json:"collected_fee" gorm:"type:numeric;not null;default:0"`
+	// ExchangeOrderParams is stored as JSON, NULL for plain market orders
+	ExchangeOrderParams *domain.ExchangeOrderParams `json:"exchange_order_params" gorm:"serializer:json;type:jsonb"`
+	// IdempotencyKey is unique per user through uidx_orders_user_idempotency_key, see migrateIdempotencyIndex
+	IdempotencyKey *string `json:"idempotency_key"`
 }
 
+// OrderEvent is appended every time an order enters a status
+type OrderEvent struct {
+	ID        uint      `gorm:"primarykey"`
+	OrderID   uint      `gorm:"not null;index"`
+	Status    string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// OrderStatusHistory is the audit trail of every status change
+type OrderStatusHistory struct {
+	ID         uint   `gorm:"primarykey"`
+	OrderID    uint   `gorm:"not null;index"`
+	FromStatus string `gorm:"not null"`
+	ToStatus   string `gorm:"not null"`
+	Reason     string
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (OrderStatusHistory) TableName() string { return "order_status_history" }
+
+// ArchivedOrder is a terminal order moved out of the hot orders table. It keeps its id, so
+// its events and status history still point at it.
+type ArchivedOrder struct {
+	Order
+	ArchivedAt time.Time `gorm:"index"`
+}
+
+func (ArchivedOrder) TableName() string { return "orders_archive" }
+
 // ---------- REPO ----------
 
 type OrderRepo struct {
@@ -53,18 +98,67 @@ type OrderRepo struct {
 }
 
 func NewOrderRepo(db *gorm.DB, log *logger.Logger) *OrderRepo {
-	if err := db.AutoMigrate(&Order{}); err != nil {
+	if err := db.AutoMigrate(&Order{}, &OrderEvent{}, &OrderStatusHistory{}, &ArchivedOrder{}, &TreasuryPause{}); err != nil {
 		log.Fatalf("failed to migrate schema: %v", err)
 	}
+	if err := migrateSignatureColumns(db); err != nil {
+		log.Fatalf("failed to migrate order signatures: %v", err)
+	}
+	if err := migrateIdempotencyIndex(db); err != nil {
+		log.Fatalf("failed to create order idempotency index: %v", err)
+	}
 	return &OrderRepo{db: db, log: log}
 }
 
 // ---------- ORDER CRUD ----------
 
 func (r *OrderRepo) SaveOrder(ctx context.Context, o *domain.Order) (*domain.Order, error) {
-	// check if signature exist convert it to string use marshal
+	model := toOrderModel(o)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createOrderTx(tx, model)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetOrderByID(ctx, model.ID)
+}
+
+func (r *OrderRepo) SaveOrders(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	models := make([]*Order, len(orders))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, o := range orders {
+			models[i] = toOrderModel(o)
+			if err := createOrderTx(tx, models[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	saved := make([]*domain.Order, len(models))
+	for i, m := range models {
+		if saved[i], err = r.GetOrderByID(ctx, m.ID); err != nil {
+			return nil, err
+		}
+	}
+	return saved, nil
+}
 
-	model := Order{
+// createOrderTx inserts a new order along with its first event and history entry
+func createOrderTx(tx *gorm.DB, model *Order) error {
+	if err := tx.Create(model).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&OrderEvent{OrderID: model.ID, Status: model.Status}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&OrderStatusHistory{OrderID: model.ID, ToStatus: model.Status, Reason: "created"}).Error
+}
+
+func toOrderModel(o *domain.Order) *Order {
+	return &Order{
 		Status:                 string(o.Status),
 		Volume:                 o.Volume,
 		FromNetwork:            o.FromNetwork,
@@ -77,7 +171,9 @@ func (r *OrderRepo) SaveOrder(ctx context.Context, o *domain.Order) (*domain.Ord
 		Deadline:               o.Deadline,
 		DestinationAddress:     o.DestinationAddress,
 		TokenAddress:           o.TokenAddress,
-		Signature:              marshalToString(o.Signature),
+		SigV:                   o.Signature.V,
+		SigR:                   o.Signature.R.Hex(),
+		SigS:                   o.Signature.S.Hex(),
 		DepositTxHash:          o.DepositTxHash,
 		ReleaseTxHash:          o.ReleaseTxHash,
 		UserId:                 o.UserId,
@@ -85,11 +181,9 @@ func (r *OrderRepo) SaveOrder(ctx context.Context, o *domain.Order) (*domain.Ord
 		SlipagePercentage:      o.SlipagePercentage,
 		Price:                  o.Price,
 		SourceTokenSymbol:      o.SourceTokenSymbol,
+		ExchangeOrderParams:    o.ExchangeOrderParams,
+		IdempotencyKey:         o.IdempotencyKey,
 	}
-	if err := r.db.WithContext(ctx).Create(&model).Error; err != nil {
-		return nil, err
-	}
-	return r.GetOrderByID(ctx, model.ID)
 }
 
 func (r *OrderRepo) GetOrderByID(ctx context.Context, id uint) (*domain.Order, error) {
@@ -103,6 +197,19 @@ func (r *OrderRepo) GetOrderByID(ctx context.Context, id uint) (*domain.Order, e
 	return r.toDomainOrder(&o), nil
 }
 
+func (r *OrderRepo) GetOrderByIdempotencyKey(ctx context.Context, userId, key string) (*domain.Order, error) {
+	var o Order
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND idempotency_key = ?", userId, key).
+		First(&o).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return r.toDomainOrder(&o), nil
+}
+
 func (r *OrderRepo) UpdateOrder(ctx context.Context, o *domain.Order) error {
 	return r.db.WithContext(ctx).Model(&Order{}).
 		Where("id = ?", o.ID).
@@ -118,7 +225,9 @@ func (r *OrderRepo) UpdateOrder(ctx context.Context, o *domain.Order) error {
 			Deadline:               o.Deadline,
 			DestinationAddress:     o.DestinationAddress,
 			TokenAddress:           o.TokenAddress,
-			Signature:              marshalToString(o.Signature),
+			SigV:                   o.Signature.V,
+			SigR:                   o.Signature.R.Hex(),
+			SigS:                   o.Signature.S.Hex(),
 			DepositTxHash:          o.DepositTxHash,
 			ReleaseTxHash:          o.ReleaseTxHash,
 			UserId:                 o.UserId,
@@ -150,6 +259,57 @@ func (r *OrderRepo) GetOrdersByUserId(ctx context.Context, userId string) ([]dom
 	return r.toDomainOrders(models), nil
 }
 
+// defaultPageLimit is used when ListOrders is called without a limit
+const defaultPageLimit = 50
+
+// ListOrders returns a page of orders matching filter, oldest first.
+func (r *OrderRepo) ListOrders(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, *domain.Pagination, error) {
+	q := r.db.WithContext(ctx).Model(&Order{})
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.UserId != "" {
+		q = q.Where("user_id = ?", filter.UserId)
+	}
+	if filter.MarketID != 0 {
+		q = q.Where("market_id = ?", filter.MarketID)
+	}
+	if filter.CreatedFrom != nil {
+		q = q.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedUntil != nil {
+		q = q.Where("created_at < ?", *filter.CreatedUntil)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	page, limit := filter.Page, filter.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
+	var models []Order
+	if err := q.Order("created_at, id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&models).Error; err != nil {
+		return nil, nil, err
+	}
+
+	pagination := &domain.Pagination{
+		TotalRecords: int(total),
+		PerPage:      limit,
+		Page:         page,
+		TotalPages:   int((total + int64(limit) - 1) / int64(limit)),
+	}
+	return r.toDomainOrders(models), pagination, nil
+}
+
 func (r *OrderRepo) GetOrdersByStatus(ctx context.Context, status domain.OrderStatus) ([]domain.Order, error) {
 	var models []Order
 	if err := r.db.WithContext(ctx).
@@ -160,10 +320,361 @@ func (r *OrderRepo) GetOrdersByStatus(ctx context.Context, status domain.OrderSt
 	return r.toDomainOrders(models), nil
 }
 
+// ChangeStatusByIds moves the orders to status and appends an OrderEvent per order.
+// The whole batch is rejected with ErrInvalidTransition if any order cannot reach status.
+// The time spent in the previous status is observed on the step-latency metric.
 func (r *OrderRepo) ChangeStatusByIds(ctx context.Context, ids []uint, status domain.OrderStatus) error {
+	return r.changeStatus(ctx, ids, status, nil, "")
+}
+
+// RefundOrder routes the order to refund and records why.
+func (r *OrderRepo) RefundOrder(ctx context.Context, id uint, reason domain.RefundReason) error {
+	// the retry count starts over for the refund attempts
+	return r.changeStatus(ctx, []uint{id}, domain.OrderRefundUserOrder,
+		map[string]any{"refund_reason": string(reason), "retry_count": 0}, string(reason))
+}
+
+// RetryOrder sends the order back to status for another attempt and bumps its retry count.
+func (r *OrderRepo) RetryOrder(ctx context.Context, id uint, status domain.OrderStatus) error {
+	return r.changeStatus(ctx, []uint{id}, status, map[string]any{"retry_count": gorm.Expr("retry_count + 1")}, "retry")
+}
+
+// DeadLetterOrder moves the order to the dead-letter status, it is left to an operator from there.
+func (r *OrderRepo) DeadLetterOrder(ctx context.Context, id uint, reason string) error {
+	return r.changeStatus(ctx, []uint{id}, domain.OrderDeadLetter, nil, reason)
+}
+
+// ReconcileOrder moves the order to awaiting reconciliation, it is left to an operator from there.
+func (r *OrderRepo) ReconcileOrder(ctx context.Context, id uint, reason string) error {
+	return r.changeStatus(ctx, []uint{id}, domain.OrderAwaitingReconciliation, nil, reason)
+}
+
+// SetExchangeOrderID stores the id the exchange assigned to the order's market order.
+func (r *OrderRepo) SetExchangeOrderID(ctx context.Context, id uint, exchangeOrderID string) error {
+	return r.db.WithContext(ctx).Model(&Order{}).
+		Where("id = ?", id).
+		Update("exchange_order_id", exchangeOrderID).Error
+}
+
+// SetTxHashes stores the on-chain hashes of the user debit and the treasury release.
+// Nil hashes are left untouched.
+func (r *OrderRepo) SetTxHashes(ctx context.Context, id uint, depositTxHash, releaseTxHash *string) error {
+	if depositTxHash == nil && releaseTxHash == nil {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&Order{}).
+		Where("id = ?", id).
+		Updates(Order{DepositTxHash: depositTxHash, ReleaseTxHash: releaseTxHash}).Error
+}
+
+// SetCollectedFee stores the fee kept from the order's treasury credit.
+func (r *OrderRepo) SetCollectedFee(ctx context.Context, id uint, fee decimal.Decimal) error {
 	return r.db.WithContext(ctx).Model(&Order{}).
+		Where("id = ?", id).
+		Update("collected_fee", fee).Error
+}
+
+// changeStatus applies status plus the extra column updates in the same transaction,
+// recording an OrderStatusHistory row with reason for every order.
+func (r *OrderRepo) changeStatus(ctx context.Context, ids []uint, status domain.OrderStatus, updates map[string]any, reason string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var previous []OrderEvent
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		previous, err = r.changeStatusTx(tx, ids, status, updates, reason, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	r.observeTransitions(previous, status, now)
+	return nil
+}
+
+// ClaimOrders moves up to limit of the oldest orders in status from to status to and
+// returns them, oldest first. The rows are picked and moved by a single UPDATE ... RETURNING;
+// rows locked by another instance are skipped, so several workers can claim concurrently
+// without picking the same orders.
+func (r *OrderRepo) ClaimOrders(ctx context.Context, from, to domain.OrderStatus, limit int, skipTokens []string) ([]domain.Order, error) {
+	if !domain.CanTransition(from, to) {
+		return nil, fmt.Errorf("%w: %s -> %s", domain.ErrInvalidTransition, from, to)
+	}
+	var (
+		models   []Order
+		previous []OrderEvent
+	)
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(&Order{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Select("id").
+			Where("status = ?", from)
+		if len(skipTokens) > 0 {
+			claim = claim.Where("destination_token_symbol NOT IN ?", skipTokens)
+		}
+		claim = claim.Order("created_at, id").Limit(limit)
+		if err := tx.Raw(`UPDATE orders SET status = ?, updated_at = ? WHERE id IN (?) RETURNING *`,
+			string(to), now, claim).
+			Scan(&models).Error; err != nil {
+			return err
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		// RETURNING doesn't keep the subquery order
+		sort.Slice(models, func(i, j int) bool {
+			if !models[i].CreatedAt.Equal(models[j].CreatedAt) {
+				return models[i].CreatedAt.Before(models[j].CreatedAt)
+			}
+			return models[i].ID < models[j].ID
+		})
+		moved := make([]Order, len(models))
+		for i, m := range models {
+			moved[i] = Order{Model: gorm.Model{ID: m.ID}, Status: string(from)}
+		}
+		var err error
+		previous, err = r.recordStatusTx(tx, moved, to, "", now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observeTransitions(previous, to, now)
+	return r.toDomainOrders(models), nil
+}
+
+// RefundStaleOrders routes orders in one of statuses created before createdBefore to refund.
+// Orders that already have an exchange order are skipped, the trade may have filled.
+func (r *OrderRepo) RefundStaleOrders(ctx context.Context, statuses []domain.OrderStatus, createdBefore time.Time, reason domain.RefundReason, limit int) ([]domain.Order, error) {
+	var (
+		models   []Order
+		previous []OrderEvent
+	)
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND created_at < ? AND exchange_order_id IS NULL", statuses, createdBefore).
+			Order("created_at, id").
+			Limit(limit).
+			Find(&models).Error; err != nil {
+			return err
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(models))
+		for i, m := range models {
+			ids[i] = m.ID
+		}
+		var err error
+		previous, err = r.changeStatusTx(tx, ids, domain.OrderRefundUserOrder,
+			map[string]any{"refund_reason": string(reason)}, string(reason), now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.observeTransitions(previous, domain.OrderRefundUserOrder, now)
+	for i := range models {
+		models[i].Status = string(domain.OrderRefundUserOrder)
+		models[i].RefundReason = string(reason)
+	}
+	return r.toDomainOrders(models), nil
+}
+
+// ArchiveOrders moves up to limit orders in one of statuses, last updated before
+// updatedBefore, to the archive table in one transaction.
+func (r *OrderRepo) ArchiveOrders(ctx context.Context, statuses []domain.OrderStatus, updatedBefore time.Time, limit int) (int, error) {
+	var models []Order
+	now := time.Now()
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND updated_at < ?", statuses, updatedBefore).
+			Order("updated_at, id").
+			Limit(limit).
+			Find(&models).Error; err != nil {
+			return err
+		}
+		if len(models) == 0 {
+			return nil
+		}
+		archived := make([]ArchivedOrder, len(models))
+		ids := make([]uint, len(models))
+		for i, m := range models {
+			archived[i] = ArchivedOrder{Order: m, ArchivedAt: now}
+			ids[i] = m.ID
+		}
+		if err := tx.Create(&archived).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Order{}, ids).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(models), nil
+}
+
+// changeStatusTx validates and applies a status change inside tx. It returns the events
+// the orders are leaving, to be observed once the transaction commits.
+func (r *OrderRepo) changeStatusTx(tx *gorm.DB, ids []uint, status domain.OrderStatus, updates map[string]any, reason string, now time.Time) ([]OrderEvent, error) {
+	var current []Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		Where("id IN ?", ids).
+		Find(&current).Error; err != nil {
+		return nil, err
+	}
+	for _, o := range current {
+		if !domain.CanTransition(domain.OrderStatus(o.Status), status) {
+			return nil, fmt.Errorf("%w: order %d %s -> %s", domain.ErrInvalidTransition, o.ID, o.Status, status)
+		}
+	}
+	if updates == nil {
+		updates = map[string]any{}
+	}
+	updates["status"] = string(status)
+	if err := tx.Model(&Order{}).
 		Where("id in ?", ids).
-		Updates(Order{Status: string(status)}).Error
+		Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return r.recordStatusTx(tx, current, status, reason, now)
+}
+
+// recordStatusTx appends the event and history rows for orders that moved from the status
+// in current to status inside tx. It returns the events that were latest before the move.
+func (r *OrderRepo) recordStatusTx(tx *gorm.DB, current []Order, status domain.OrderStatus, reason string, now time.Time) ([]OrderEvent, error) {
+	var previous []OrderEvent
+	ids := make([]uint, len(current))
+	for i, o := range current {
+		ids[i] = o.ID
+	}
+	// latest event per order = the status it is leaving
+	if err := tx.Raw(`SELECT DISTINCT ON (order_id) * FROM order_events
+		WHERE order_id IN ? ORDER BY order_id, created_at DESC, id DESC`, ids).
+		Scan(&previous).Error; err != nil {
+		return nil, err
+	}
+	events := make([]OrderEvent, len(ids))
+	for i, id := range ids {
+		events[i] = OrderEvent{OrderID: id, Status: string(status), CreatedAt: now}
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		return nil, err
+	}
+	history := make([]OrderStatusHistory, len(current))
+	for i, o := range current {
+		history[i] = OrderStatusHistory{
+			OrderID:    o.ID,
+			FromStatus: o.Status,
+			ToStatus:   string(status),
+			Reason:     reason,
+			CreatedAt:  now,
+		}
+	}
+	if len(history) > 0 {
+		if err := tx.Create(&history).Error; err != nil {
+			return nil, err
+		}
+	}
+	return previous, nil
+}
+
+// observeTransitions records the step latency and transition of every order leaving the
+// status of its previous event for to.
+func (r *OrderRepo) observeTransitions(previous []OrderEvent, to domain.OrderStatus, now time.Time) {
+	for _, e := range previous {
+		metrics.ObserveOrderStepLatency(e.Status, now.Sub(e.CreatedAt))
+		metrics.IncOrderTransition(e.Status, string(to))
+	}
+}
+
+// CountOrdersByStatus returns how many orders are in each status; statuses without
+// orders are left out.
+func (r *OrderRepo) CountOrdersByStatus(ctx context.Context) (map[domain.OrderStatus]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).Model(&Order{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[domain.OrderStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[domain.OrderStatus(row.Status)] = row.Count
+	}
+	return counts, nil
+}
+
+// GetOrderHistory returns the status changes of the order, oldest first.
+func (r *OrderRepo) GetOrderHistory(ctx context.Context, id uint) ([]domain.OrderStatusHistory, error) {
+	var rows []OrderStatusHistory
+	if err := r.db.WithContext(ctx).
+		Where("order_id = ?", id).
+		Order("created_at, id").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.OrderStatusHistory, len(rows))
+	for i, h := range rows {
+		out[i] = domain.OrderStatusHistory{
+			ID:         h.ID,
+			OrderID:    h.OrderID,
+			FromStatus: domain.OrderStatus(h.FromStatus),
+			ToStatus:   domain.OrderStatus(h.ToStatus),
+			Reason:     h.Reason,
+			CreatedAt:  h.CreatedAt,
+		}
+	}
+	return out, nil
+}
+
+// GetStepLatencies computes p50/p90/p99 of the time spent in each status,
+// from the gap between consecutive events of the same order since the given time.
+func (r *OrderRepo) GetStepLatencies(ctx context.Context, since time.Time) ([]domain.StepLatency, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+		P50    float64
+		P90    float64
+		P99    float64
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT status,
+			COUNT(*) AS count,
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY elapsed) AS p50,
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY elapsed) AS p90,
+			percentile_cont(0.99) WITHIN GROUP (ORDER BY elapsed) AS p99
+		FROM (
+			SELECT status,
+				EXTRACT(EPOCH FROM (LEAD(created_at) OVER (PARTITION BY order_id ORDER BY created_at, id) - created_at)) AS elapsed
+			FROM order_events
+			WHERE created_at >= ?
+		) steps
+		WHERE elapsed IS NOT NULL
+		GROUP BY status
+		ORDER BY p50 DESC`, since).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.StepLatency, len(rows))
+	for i, row := range rows {
+		out[i] = domain.StepLatency{
+			Status: domain.OrderStatus(row.Status),
+			Count:  row.Count,
+			P50:    secondsToDuration(row.P50),
+			P90:    secondsToDuration(row.P90),
+			P99:    secondsToDuration(row.P99),
+		}
+	}
+	return out, nil
 }
 
 // ---------- HELPERS ----------
@@ -182,7 +693,7 @@ func (r *OrderRepo) toDomainOrder(o *Order) *domain.Order {
 		Deadline:               o.Deadline,
 		DestinationAddress:     o.DestinationAddress,
 		TokenAddress:           o.TokenAddress,
-		Signature:              unmarshalFromJSON[domain.OrderSignature](o.Signature),
+		Signature:              domain.OrderSignature{V: o.SigV, R: common.HexToHash(o.SigR), S: common.HexToHash(o.SigS)},
 		DepositTxHash:          o.DepositTxHash,
 		ReleaseTxHash:          o.ReleaseTxHash,
 		UserId:                 o.UserId,
@@ -191,6 +702,12 @@ func (r *OrderRepo) toDomainOrder(o *Order) *domain.Order {
 		SlipagePercentage:      o.SlipagePercentage,
 		Price:                  o.Price,
 		SourceTokenSymbol:      o.SourceTokenSymbol,
+		RefundReason:           domain.RefundReason(o.RefundReason),
+		ExchangeOrderID:        o.ExchangeOrderID,
+		RetryCount:             o.RetryCount,
+		CollectedFee:           o.CollectedFee,
+		ExchangeOrderParams:    o.ExchangeOrderParams,
+		IdempotencyKey:         o.IdempotencyKey,
 	}
 }
 func (r *OrderRepo) toDomainOrders(os []Order) []domain.Order {
@@ -200,64 +717,42 @@ func (r *OrderRepo) toDomainOrders(os []Order) []domain.Order {
 	}
 	return dos
 }
-func marshalToString(v interface{}) *string {
-	if v == nil {
-		return nil
-	}
-	b, err := json.Marshal(v)
-	if err != nil {
-		return nil
-	}
-	s := string(b)
-	return &s
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
 }
-func unmarshalFromJSON[T any](data interface{}) T {
-	// Get the type of T to determine if it's a pointer
-	var zero T
-	tType := reflect.TypeOf(zero)
 
-	if data == nil {
-		// For pointer types, return nil, for non-pointer return zero value
-		if tType != nil && tType.Kind() == reflect.Ptr {
-			return zero
-		}
-		return zero
-	}
-
-	// Check if data is already the correct type
-	if typedData, ok := data.(T); ok {
-		return typedData
-	}
-
-	// For pointer types, also check if data is the underlying type
-	if tType != nil && tType.Kind() == reflect.Ptr {
-		elemType := tType.Elem()
-		// Check if data is of the underlying type (e.g., T is *string, data is string)
-		if reflect.TypeOf(data) == elemType {
-			val := reflect.ValueOf(data)
-			result := reflect.New(elemType)
-			result.Elem().Set(val)
-			return result.Interface().(T)
-		}
+// migrateSignatureColumns moves signatures still stored as JSON in the legacy signature
+// column into sig_v/sig_r/sig_s. Migrated rows get a NULL signature, so it runs once.
+func migrateSignatureColumns(db *gorm.DB) error {
+	var legacy []Order
+	if err := db.Unscoped().Select("id", "signature").
+		Where("signature IS NOT NULL").Find(&legacy).Error; err != nil {
+		return err
 	}
-
-	// Handle JSON string unmarshaling
-	if str, ok := data.(string); ok {
-		// For pointer types, unmarshal and take address
-		if tType != nil && tType.Kind() == reflect.Ptr {
-			elemType := tType.Elem()
-			result := reflect.New(elemType).Interface()
-			if err := json.Unmarshal([]byte(str), result); err == nil {
-				return reflect.ValueOf(result).Convert(tType).Interface().(T)
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, o := range legacy {
+			var sig domain.OrderSignature
+			if err := json.Unmarshal([]byte(*o.Signature), &sig); err != nil {
+				return fmt.Errorf("order %d: %w", o.ID, err)
 			}
-		} else {
-			// For non-pointer types
-			var result T
-			if err := json.Unmarshal([]byte(str), &result); err == nil {
-				return result
+			if err := tx.Unscoped().Model(&Order{}).Where("id = ?", o.ID).
+				Updates(map[string]any{
+					"sig_v":     sig.V,
+					"sig_r":     sig.R.Hex(),
+					"sig_s":     sig.S.Hex(),
+					"signature": nil,
+				}).Error; err != nil {
+				return err
 			}
 		}
-	}
+		return nil
+	})
+}
 
-	return zero
+// migrateIdempotencyIndex makes idempotency keys unique per user. It is partial, so orders
+// submitted without a key don't collide, which a gorm index tag can't express; it is also
+// kept off orders_archive, which embeds Order.
+func migrateIdempotencyIndex(db *gorm.DB) error {
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS uidx_orders_user_idempotency_key
+		ON orders (user_id, idempotency_key) WHERE idempotency_key IS NOT NULL`).Error
 }
@@ -0,0 +1,513 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRefundStaleOrders(t *testing.T) {
+	createdBefore := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name  string
+		found []uint
+	}{
+		{name: "nothing stale"},
+		{name: "stale order refunded", found: []uint{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"id", "status", "created_at"})
+			for _, id := range tt.found {
+				rows.AddRow(id, string(domain.OrderMarketUserOrderFailed), createdBefore.Add(-time.Minute))
+			}
+			// only the statuses asked for, rows another worker holds are skipped
+			mock.ExpectQuery(`SELECT \* FROM "orders" WHERE \(status IN \(\$1,\$2\) AND created_at < \$3 AND exchange_order_id IS NULL\) .* ORDER BY created_at, id LIMIT \$4 FOR UPDATE SKIP LOCKED`).
+				WithArgs(string(domain.OrderUserDebitSuccess), string(domain.OrderMarketUserOrderFailed), createdBefore, 10).
+				WillReturnRows(rows)
+			if len(tt.found) > 0 {
+				mock.ExpectQuery(`SELECT "id","status" FROM "orders" WHERE id IN \(\$1\) .* FOR UPDATE$`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(4, string(domain.OrderMarketUserOrderFailed)))
+				mock.ExpectExec(`UPDATE "orders" SET "refund_reason"=\$1,"status"=\$2`).
+					WithArgs(string(domain.RefundReasonMaxLifetimeExceeded), string(domain.OrderRefundUserOrder), sqlmock.AnyArg(), 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRecordStatus(mock)
+			}
+			mock.ExpectCommit()
+
+			got, err := r.RefundStaleOrders(context.Background(), domain.StaleRefundableStatuses, createdBefore,
+				domain.RefundReasonMaxLifetimeExceeded, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.found) {
+				t.Fatalf("refunded %d orders, want %d", len(got), len(tt.found))
+			}
+			for _, o := range got {
+				if o.Status != domain.OrderRefundUserOrder || o.RefundReason != domain.RefundReasonMaxLifetimeExceeded {
+					t.Errorf("order %d is %s/%s", o.ID, o.Status, o.RefundReason)
+				}
+			}
+		})
+	}
+}
+
+func TestRefundOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    domain.OrderStatus
+		reason  domain.RefundReason
+		wantErr error
+	}{
+		{name: "slippage", from: domain.OrderMarketUserOrderFailed, reason: domain.RefundReasonSlippageExceeded},
+		{name: "payout failed", from: domain.OrderTreasuryCreditInProgress, reason: domain.RefundReasonTreasuryCreditFailed},
+		{name: "already completed", from: domain.OrderCompleted, reason: domain.RefundReasonTreasuryCreditFailed, wantErr: domain.ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT "id","status" FROM "orders" WHERE id IN \(\$1\) .* FOR UPDATE$`).
+				WithArgs(4).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(4, string(tt.from)))
+			if tt.wantErr != nil {
+				mock.ExpectRollback()
+			} else {
+				// the reason goes on the order, and the refund attempts start a fresh retry count
+				mock.ExpectExec(`UPDATE "orders" SET "refund_reason"=\$1,"retry_count"=\$2,"status"=\$3`).
+					WithArgs(string(tt.reason), 0, string(domain.OrderRefundUserOrder), sqlmock.AnyArg(), 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(`SELECT DISTINCT ON \(order_id\) \* FROM order_events`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "status", "created_at"}))
+				mock.ExpectQuery(`INSERT INTO "order_events"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				// and in the status history
+				mock.ExpectQuery(`INSERT INTO "order_status_history"`).
+					WithArgs(4, string(tt.from), string(domain.OrderRefundUserOrder), string(tt.reason), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			}
+
+			err := r.RefundOrder(context.Background(), 4, tt.reason)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetTxHashes(t *testing.T) {
+	deposit, release := "0xdeposit", "0xrelease"
+	tests := []struct {
+		name     string
+		deposit  *string
+		release  *string
+		wantSQL  string
+		wantArgs []driver.Value
+	}{
+		{name: "nothing to store"},
+		{
+			name: "deposit", deposit: &deposit,
+			wantSQL:  `UPDATE "orders" SET "updated_at"=\$1,"deposit_tx_hash"=\$2 WHERE id = \$3`,
+			wantArgs: []driver.Value{sqlmock.AnyArg(), deposit, 4},
+		},
+		{
+			name: "release", release: &release,
+			wantSQL:  `UPDATE "orders" SET "updated_at"=\$1,"release_tx_hash"=\$2 WHERE id = \$3`,
+			wantArgs: []driver.Value{sqlmock.AnyArg(), release, 4},
+		},
+		{
+			name: "both", deposit: &deposit, release: &release,
+			wantSQL:  `UPDATE "orders" SET "updated_at"=\$1,"deposit_tx_hash"=\$2,"release_tx_hash"=\$3 WHERE id = \$4`,
+			wantArgs: []driver.Value{sqlmock.AnyArg(), deposit, release, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			if tt.wantSQL != "" {
+				mock.ExpectBegin()
+				// only the hashes given are written, a nil one never clears a stored hash
+				mock.ExpectExec(tt.wantSQL).
+					WithArgs(tt.wantArgs...).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			if err := r.SetTxHashes(context.Background(), 4, tt.deposit, tt.release); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRetryOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    domain.OrderStatus
+		to      domain.OrderStatus
+		wantErr error
+	}{
+		{name: "market order retried", from: domain.OrderMarketUserOrderInProgress, to: domain.OrderUserDebitSuccess},
+		{name: "refund retried", from: domain.OrderRefundUserOrderInProgress, to: domain.OrderRefundUserOrder},
+		{name: "completed order", from: domain.OrderCompleted, to: domain.OrderUserDebitSuccess, wantErr: domain.ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT "id","status" FROM "orders" WHERE id IN \(\$1\) .* FOR UPDATE$`).
+				WithArgs(4).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(4, string(tt.from)))
+			if tt.wantErr != nil {
+				mock.ExpectRollback()
+			} else {
+				// the count is bumped in sql, so concurrent retries can't lose an attempt
+				mock.ExpectExec(`UPDATE "orders" SET "retry_count"=retry_count \+ 1,"status"=\$1`).
+					WithArgs(string(tt.to), sqlmock.AnyArg(), 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRecordStatus(mock)
+				mock.ExpectCommit()
+			}
+
+			err := r.RetryOrder(context.Background(), 4, tt.to)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestClaimOrdersBatch checks the claim is capped at the batch size and skips rows another
+// worker holds, so instances can claim side by side
+func TestClaimOrdersBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int
+		skipTokens []string
+		wantWhere  string
+		wantArgs   []driver.Value
+	}{
+		{
+			name: "batch", limit: 2,
+			wantWhere: `status = \$3 AND "orders"."deleted_at" IS NULL`,
+			wantArgs:  []driver.Value{string(domain.OrderUserDebitInProgress), sqlmock.AnyArg(), string(domain.OrderPending), 2},
+		},
+		{
+			name: "paused tokens left out", limit: 50, skipTokens: []string{"USDT"},
+			wantWhere: `status = \$3 AND destination_token_symbol NOT IN \(\$4\) AND "orders"."deleted_at" IS NULL`,
+			wantArgs:  []driver.Value{string(domain.OrderUserDebitInProgress), sqlmock.AnyArg(), string(domain.OrderPending), "USDT", 50},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`UPDATE orders SET status = \$1, updated_at = \$2 WHERE id IN \(SELECT "id" FROM "orders" WHERE ` +
+				tt.wantWhere + ` ORDER BY created_at, id LIMIT \$\d+ FOR UPDATE SKIP LOCKED\) RETURNING \*`).
+				WithArgs(tt.wantArgs...).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+			mock.ExpectCommit()
+
+			orders, err := r.ClaimOrders(context.Background(), domain.OrderPending, domain.OrderUserDebitInProgress, tt.limit, tt.skipTokens)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != 0 {
+				t.Errorf("claimed %d orders, want none", len(orders))
+			}
+		})
+	}
+}
+
+func TestArchiveOrders(t *testing.T) {
+	updatedBefore := time.Now().Add(-30 * 24 * time.Hour)
+	tests := []struct {
+		name  string
+		found []uint
+	}{
+		{name: "nothing to archive"},
+		{name: "old terminal orders archived", found: []uint{3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"id", "status", "updated_at"})
+			for _, id := range tt.found {
+				rows.AddRow(id, string(domain.OrderCompleted), updatedBefore.Add(-time.Hour))
+			}
+			// only the statuses asked for, soft deleted orders included, rows another worker holds skipped
+			mock.ExpectQuery(`SELECT \* FROM "orders" WHERE status IN \(\$1,\$2\) AND updated_at < \$3 ORDER BY updated_at, id LIMIT \$4 FOR UPDATE SKIP LOCKED`).
+				WithArgs(string(domain.OrderCompleted), string(domain.OrderExpired), updatedBefore, 10).
+				WillReturnRows(rows)
+			if len(tt.found) > 0 {
+				// copied into the archive with their ids, then gone from the hot table
+				mock.ExpectQuery(`INSERT INTO "orders_archive" .*"archived_at".* RETURNING "id"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(5))
+				mock.ExpectExec(`DELETE FROM "orders" WHERE "orders"."id" IN \(\$1,\$2\)`).
+					WithArgs(3, 5).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+			}
+			mock.ExpectCommit()
+
+			n, err := r.ArchiveOrders(context.Background(), []domain.OrderStatus{domain.OrderCompleted, domain.OrderExpired}, updatedBefore, 10)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(tt.found) {
+				t.Errorf("archived %d, want %d", n, len(tt.found))
+			}
+		})
+	}
+}
+
+// TestChangeStatusByIdsStaleWriter moves orders another run may already have advanced; the
+// rows are locked and re-checked, so a stale move is rejected as a whole
+func TestChangeStatusByIdsStaleWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		current []domain.OrderStatus
+		wantErr error
+	}{
+		{name: "still in progress", current: []domain.OrderStatus{domain.OrderUserDebitInProgress}},
+		{name: "already advanced", current: []domain.OrderStatus{domain.OrderUserDebitSuccess}, wantErr: domain.ErrInvalidTransition},
+		{name: "one of two advanced", current: []domain.OrderStatus{domain.OrderUserDebitInProgress, domain.OrderExpired}, wantErr: domain.ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			ids := make([]uint, len(tt.current))
+			args := make([]driver.Value, len(tt.current))
+			rows := sqlmock.NewRows([]string{"id", "status"})
+			for i, s := range tt.current {
+				ids[i] = uint(i + 1)
+				args[i] = i + 1
+				rows.AddRow(i+1, string(s))
+			}
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT "id","status" FROM "orders" WHERE id IN .* FOR UPDATE$`).
+				WithArgs(args...).
+				WillReturnRows(rows)
+			if tt.wantErr != nil {
+				// nothing is written
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(`UPDATE "orders" SET "status"=\$1`).
+					WithArgs(string(domain.OrderUserDebitSuccess), sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRecordStatus(mock)
+				mock.ExpectCommit()
+			}
+
+			err := r.ChangeStatusByIds(context.Background(), ids, domain.OrderUserDebitSuccess)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetOrderByIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    *sqlmock.Rows
+		wantID  uint
+		wantNil bool
+	}{
+		{name: "found", rows: sqlmock.NewRows([]string{"id", "user_id", "idempotency_key"}).AddRow(5, "alice", "k1"), wantID: 5},
+		{name: "not found", rows: sqlmock.NewRows([]string{"id"}), wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			// the key is only unique per user
+			mock.ExpectQuery(`SELECT \* FROM "orders" WHERE \(user_id = \$1 AND idempotency_key = \$2\) AND "orders"."deleted_at" IS NULL`).
+				WithArgs("alice", "k1", 1).
+				WillReturnRows(tt.rows)
+
+			got, err := r.GetOrderByIdempotencyKey(context.Background(), "alice", "k1")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("order = %+v, want nil: %v", got, tt.wantNil)
+			}
+			if got != nil && (got.ID != tt.wantID || got.IdempotencyKey == nil || *got.IdempotencyKey != "k1") {
+				t.Errorf("order = %+v, want id %d with key k1", got, tt.wantID)
+			}
+		})
+	}
+}
+
+// TestClaimOrdersOrdering claims in one UPDATE ... RETURNING, whose rows come back in no
+// particular order; the claimed orders must still be handed out oldest first
+func TestClaimOrdersOrdering(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	claimed := string(domain.OrderUserDebitInProgress)
+	tests := []struct {
+		name      string
+		from, to  domain.OrderStatus
+		returning *sqlmock.Rows
+		updateErr error
+		wantIDs   []uint
+		wantErr   error
+	}{
+		{
+			name: "oldest first, id breaks ties", from: domain.OrderPending, to: domain.OrderUserDebitInProgress,
+			returning: sqlmock.NewRows([]string{"id", "status", "created_at"}).
+				AddRow(9, claimed, t0.Add(time.Minute)).
+				AddRow(7, claimed, t0).
+				AddRow(3, claimed, t0.Add(time.Minute)).
+				AddRow(5, claimed, t0),
+			wantIDs: []uint{5, 7, 3, 9},
+		},
+		{
+			name: "claim fails", from: domain.OrderPending, to: domain.OrderUserDebitInProgress,
+			updateErr: errors.New("deadlock detected"), wantErr: errors.New("deadlock detected"),
+		},
+		{
+			// rejected before touching the database
+			name: "invalid transition", from: domain.OrderCompleted, to: domain.OrderPending,
+			wantErr: domain.ErrInvalidTransition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			if !errors.Is(tt.wantErr, domain.ErrInvalidTransition) {
+				mock.ExpectBegin()
+				exp := mock.ExpectQuery(`UPDATE orders SET status = \$1, updated_at = \$2 WHERE id IN \(SELECT "id" FROM "orders" .* ORDER BY created_at, id LIMIT \$\d+ FOR UPDATE SKIP LOCKED\) RETURNING \*`).
+					WithArgs(string(tt.to), sqlmock.AnyArg(), string(tt.from), 10)
+				if tt.updateErr != nil {
+					exp.WillReturnError(tt.updateErr)
+					mock.ExpectRollback()
+				} else {
+					exp.WillReturnRows(tt.returning)
+					mock.ExpectQuery(`SELECT DISTINCT ON \(order_id\) \* FROM order_events`).
+						WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "status", "created_at"}))
+					mock.ExpectQuery(`INSERT INTO "order_events"`).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}))
+					// the history records the status the orders were claimed from
+					var history []driver.Value
+					for _, id := range tt.wantIDs {
+						history = append(history, id, string(tt.from), string(tt.to), "", sqlmock.AnyArg())
+					}
+					mock.ExpectQuery(`INSERT INTO "order_status_history"`).
+						WithArgs(history...).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}))
+					mock.ExpectCommit()
+				}
+			}
+
+			orders, err := r.ClaimOrders(context.Background(), tt.from, tt.to, 10, nil)
+
+			if tt.wantErr != nil {
+				if err == nil || !errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error() {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != len(tt.wantIDs) {
+				t.Fatalf("claimed %d orders, want %d", len(orders), len(tt.wantIDs))
+			}
+			for i, o := range orders {
+				if o.ID != tt.wantIDs[i] || o.Status != tt.to {
+					t.Errorf("order %d = %d %s, want %d %s", i, o.ID, o.Status, tt.wantIDs[i], tt.to)
+				}
+			}
+		})
+	}
+}
+
+// stepLatency reads the sample count and sum of the step latency histogram of status
+func stepLatency(t *testing.T, status domain.OrderStatus) (uint64, float64) {
+	t.Helper()
+	var m dto.Metric
+	if err := metrics.OrderStepLatency.WithLabelValues(string(status)).(prometheus.Metric).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum()
+}
+
+// TestStepLatencyObserved moves an order out of a status it entered a known time ago; the
+// step latency of that status must observe the time between the two events
+func TestStepLatencyObserved(t *testing.T) {
+	const from = domain.OrderMarketUserOrderInProgress
+	tests := []struct {
+		name       string
+		inStatus   time.Duration // how long before the change the order entered from
+		noPrevious bool
+	}{
+		{name: "seconds", inStatus: 90 * time.Second},
+		{name: "minutes", inStatus: 45 * time.Minute},
+		// orders from before the events table have no event to measure from
+		{name: "no previous event", noPrevious: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			entered := time.Now().Add(-tt.inStatus)
+			previous := sqlmock.NewRows([]string{"id", "order_id", "status", "created_at"})
+			if !tt.noPrevious {
+				previous.AddRow(1, 4, string(from), entered)
+			}
+			left := &captureArg{}
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT "id","status" FROM "orders"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(4, string(from)))
+			mock.ExpectExec(`UPDATE "orders"`).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectQuery(`SELECT DISTINCT ON \(order_id\) \* FROM order_events`).WillReturnRows(previous)
+			mock.ExpectQuery(`INSERT INTO "order_events"`).
+				WithArgs(4, string(domain.OrderUserDebitSuccess), left).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+			mock.ExpectQuery(`INSERT INTO "order_status_history"`).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			mock.ExpectCommit()
+			countBefore, sumBefore := stepLatency(t, from)
+
+			if err := r.RetryOrder(context.Background(), 4, domain.OrderUserDebitSuccess); err != nil {
+				t.Fatal(err)
+			}
+
+			count, sum := stepLatency(t, from)
+			if tt.noPrevious {
+				if count != countBefore {
+					t.Errorf("observed %d samples without a previous event", count-countBefore)
+				}
+				return
+			}
+			if count != countBefore+1 {
+				t.Fatalf("observed %d samples, want 1", count-countBefore)
+			}
+			leftAt, ok := left.v.(time.Time)
+			if !ok {
+				t.Fatalf("event created_at = %#v", left.v)
+			}
+			want := leftAt.Sub(entered).Seconds()
+			if got := sum - sumBefore; math.Abs(got-want) > 1e-6 {
+				t.Errorf("observed %.6fs, want %.6fs between the two events", got, want)
+			}
+		})
+	}
+}
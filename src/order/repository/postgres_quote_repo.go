@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/MMN3003/mega/src/logger"
 	"github.com/MMN3003/mega/src/order/domain"
@@ -16,15 +17,48 @@ type PostgresQuoteRepo struct {
 }
 
 func NewPostgresQuoteRepo(db *sql.DB, log *logger.Logger) *PostgresQuoteRepo {
+	if err := migrateQuotes(db); err != nil {
+		log.Fatalf("failed to migrate quotes: %v", err)
+	}
 	return &PostgresQuoteRepo{db: db, log: log}
 }
 
+// migrateQuotes creates the quotes table; the partial index serves ExpireStale and ListActive
+func migrateQuotes(db *sql.DB) error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS quotes (
+		id              TEXT PRIMARY KEY,
+		from_network    TEXT NOT NULL DEFAULT '',
+		from_token      TEXT NOT NULL DEFAULT '',
+		to_network      TEXT NOT NULL DEFAULT '',
+		to_token        TEXT NOT NULL DEFAULT '',
+		amount_in       NUMERIC NOT NULL,
+		amount_out      NUMERIC NOT NULL,
+		expires_at      TIMESTAMPTZ NOT NULL,
+		created_at      TIMESTAMPTZ NOT NULL,
+		used            BOOLEAN NOT NULL DEFAULT false,
+		user_address    TEXT NOT NULL DEFAULT '',
+		mega_market_id  BIGINT NOT NULL DEFAULT 0,
+		market_id       BIGINT NOT NULL DEFAULT 0,
+		exchange_name   TEXT NOT NULL DEFAULT '',
+		is_buy          BOOLEAN NOT NULL DEFAULT false,
+		price           NUMERIC NOT NULL DEFAULT 0,
+		effective_price NUMERIC NOT NULL DEFAULT 0,
+		price_impact    NUMERIC NOT NULL DEFAULT 0,
+		user_id         TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_quotes_unused_expires_at ON quotes (expires_at) WHERE NOT used;
+	`)
+	return err
+}
+
+const quoteColumns = `id, from_network, from_token, to_network, to_token, amount_in, amount_out, expires_at, created_at, used, user_address,
+	mega_market_id, market_id, exchange_name, is_buy, price, effective_price, price_impact, user_id`
+
 func (r *PostgresQuoteRepo) Save(ctx context.Context, q *domain.Quote) error {
 	query := `
-	INSERT INTO quotes (
-		id, from_network, from_token, to_network, to_token,
-		amount_in, amount_out, expires_at, created_at, used, user_address
-	) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	INSERT INTO quotes (` + quoteColumns + `)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
@@ -39,6 +73,14 @@ func (r *PostgresQuoteRepo) Save(ctx context.Context, q *domain.Quote) error {
 		q.CreatedAt,
 		q.Used,
 		q.UserAddress,
+		q.MegaMarketID,
+		q.MarketID,
+		q.ExchangeName,
+		q.IsBuy,
+		q.Price.String(),
+		q.EffectivePrice.String(),
+		q.PriceImpact.String(),
+		q.UserID,
 	)
 	if err != nil {
 		r.log.Errorf("failed to save quote: %v", err)
@@ -47,25 +89,8 @@ func (r *PostgresQuoteRepo) Save(ctx context.Context, q *domain.Quote) error {
 }
 
 func (r *PostgresQuoteRepo) GetByID(ctx context.Context, id string) (*domain.Quote, error) {
-	query := `SELECT id, from_network, from_token, to_network, to_token, amount_in, amount_out, expires_at, created_at, used, user_address FROM quotes WHERE id=$1`
-	row := r.db.QueryRowContext(ctx, query, id)
-
-	var q domain.Quote
-	var amountInStr, amountOutStr string
-
-	err := row.Scan(
-		&q.ID,
-		&q.FromNetwork,
-		&q.FromToken,
-		&q.ToNetwork,
-		&q.ToToken,
-		&amountInStr,
-		&amountOutStr,
-		&q.ExpiresAt,
-		&q.CreatedAt,
-		&q.Used,
-		&q.UserAddress,
-	)
+	query := `SELECT ` + quoteColumns + ` FROM quotes WHERE id=$1`
+	q, err := scanQuote(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil // or custom NotFound error
@@ -73,18 +98,7 @@ func (r *PostgresQuoteRepo) GetByID(ctx context.Context, id string) (*domain.Quo
 		r.log.Errorf("failed to get quote by id: %v", err)
 		return nil, err
 	}
-
-	// Parse decimal strings into decimal.Decimal
-	q.AmountIn, err = decimal.NewFromString(amountInStr)
-	if err != nil {
-		return nil, err
-	}
-	q.AmountOut, err = decimal.NewFromString(amountOutStr)
-	if err != nil {
-		return nil, err
-	}
-
-	return &q, nil
+	return q, nil
 }
 
 func (r *PostgresQuoteRepo) MarkUsed(ctx context.Context, id string) error {
@@ -95,8 +109,21 @@ func (r *PostgresQuoteRepo) MarkUsed(ctx context.Context, id string) error {
 	return err
 }
 
+func (r *PostgresQuoteRepo) ExpireStale(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM quotes WHERE used=false AND expires_at < $1", before)
+	if err != nil {
+		r.log.Errorf("failed to expire stale quotes: %v", err)
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
 func (r *PostgresQuoteRepo) ListActive(ctx context.Context) ([]*domain.Quote, error) {
-	query := `SELECT id, from_network, from_token, to_network, to_token, amount_in, amount_out, expires_at, created_at, used, user_address FROM quotes WHERE used=false AND expires_at > now()`
+	query := `SELECT ` + quoteColumns + ` FROM quotes WHERE used=false AND expires_at > now()`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		r.log.Errorf("failed to list active quotes: %v", err)
@@ -107,37 +134,12 @@ func (r *PostgresQuoteRepo) ListActive(ctx context.Context) ([]*domain.Quote, er
 	var out []*domain.Quote
 
 	for rows.Next() {
-		var q domain.Quote
-		var amountInStr, amountOutStr string
-
-		err := rows.Scan(
-			&q.ID,
-			&q.FromNetwork,
-			&q.FromToken,
-			&q.ToNetwork,
-			&q.ToToken,
-			&amountInStr,
-			&amountOutStr,
-			&q.ExpiresAt,
-			&q.CreatedAt,
-			&q.Used,
-			&q.UserAddress,
-		)
+		q, err := scanQuote(rows)
 		if err != nil {
 			r.log.Errorf("failed to scan quote row: %v", err)
 			return nil, err
 		}
-
-		q.AmountIn, err = decimal.NewFromString(amountInStr)
-		if err != nil {
-			return nil, err
-		}
-		q.AmountOut, err = decimal.NewFromString(amountOutStr)
-		if err != nil {
-			return nil, err
-		}
-
-		out = append(out, &q)
+		out = append(out, q)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -147,3 +149,52 @@ func (r *PostgresQuoteRepo) ListActive(ctx context.Context) ([]*domain.Quote, er
 
 	return out, nil
 }
+
+// scanQuote reads one row of quoteColumns from a *sql.Row or *sql.Rows
+func scanQuote(row interface{ Scan(dest ...any) error }) (*domain.Quote, error) {
+	var q domain.Quote
+	var amountInStr, amountOutStr, priceStr, effectivePriceStr, priceImpactStr string
+
+	err := row.Scan(
+		&q.ID,
+		&q.FromNetwork,
+		&q.FromToken,
+		&q.ToNetwork,
+		&q.ToToken,
+		&amountInStr,
+		&amountOutStr,
+		&q.ExpiresAt,
+		&q.CreatedAt,
+		&q.Used,
+		&q.UserAddress,
+		&q.MegaMarketID,
+		&q.MarketID,
+		&q.ExchangeName,
+		&q.IsBuy,
+		&priceStr,
+		&effectivePriceStr,
+		&priceImpactStr,
+		&q.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse decimal strings into decimal.Decimal
+	for _, f := range []struct {
+		dst *decimal.Decimal
+		src string
+	}{
+		{&q.AmountIn, amountInStr},
+		{&q.AmountOut, amountOutStr},
+		{&q.Price, priceStr},
+		{&q.EffectivePrice, effectivePriceStr},
+		{&q.PriceImpact, priceImpactStr},
+	} {
+		if *f.dst, err = decimal.NewFromString(f.src); err != nil {
+			return nil, err
+		}
+	}
+
+	return &q, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm/clause"
+)
+
+// TreasuryPause is a payout token paused on a depleted treasury. It lives in the database so
+// every instance skips its orders and a restart doesn't lift it before the top-up.
+type TreasuryPause struct {
+	Token     string          `gorm:"primaryKey"`
+	Network   string          `gorm:"not null"`
+	Needed    decimal.Decimal `gorm:"type:numeric;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PauseToken inserts the pause, or raises Needed of the token's existing pause. A pause
+// that already needs as much is left alone, so the alert isn't repeated for every order.
+func (r *OrderRepo) PauseToken(ctx context.Context, p domain.TreasuryPause) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"network", "needed", "updated_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "treasury_pauses.needed < excluded.needed"},
+			}},
+		}).
+		Create(&TreasuryPause{Token: p.Token, Network: p.Network, Needed: p.Needed})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ListTreasuryPauses returns every paused token, sorted by token.
+func (r *OrderRepo) ListTreasuryPauses(ctx context.Context) ([]domain.TreasuryPause, error) {
+	var rows []TreasuryPause
+	if err := r.db.WithContext(ctx).Order("token").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	pauses := make([]domain.TreasuryPause, len(rows))
+	for i, row := range rows {
+		pauses[i] = domain.TreasuryPause{Token: row.Token, Network: row.Network, Needed: row.Needed}
+	}
+	return pauses, nil
+}
+
+// ResumeToken deletes the token's pause.
+func (r *OrderRepo) ResumeToken(ctx context.Context, token string) error {
+	return r.db.WithContext(ctx).Where("token = ?", token).Delete(&TreasuryPause{}).Error
+}
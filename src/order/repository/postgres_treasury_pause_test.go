@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestPauseToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{name: "new or raised pause", affected: 1, want: true},
+		{name: "already paused for as much", affected: 0, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			mock.ExpectBegin()
+			// an existing pause is only ever raised
+			mock.ExpectExec(`INSERT INTO "treasury_pauses" .* ON CONFLICT \("token"\) DO UPDATE SET "network"="excluded"."network","needed"="excluded"."needed","updated_at"="excluded"."updated_at" WHERE treasury_pauses.needed < excluded.needed`).
+				WithArgs("IRT", "sepolia", decimal.NewFromInt(500), sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			mock.ExpectCommit()
+
+			got, err := r.PauseToken(context.Background(), domain.TreasuryPause{Token: "IRT", Network: "sepolia", Needed: decimal.NewFromInt(500)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("changed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListTreasuryPauses(t *testing.T) {
+	r, mock := newMockOrderRepo(t)
+	mock.ExpectQuery(`SELECT \* FROM "treasury_pauses" ORDER BY token`).
+		WillReturnRows(sqlmock.NewRows([]string{"token", "network", "needed"}).
+			AddRow("IRT", "sepolia", "500").
+			AddRow("USDT", "mumbai", "12.5"))
+
+	got, err := r.ListTreasuryPauses(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.TreasuryPause{
+		{Token: "IRT", Network: "sepolia", Needed: decimal.NewFromInt(500)},
+		{Token: "USDT", Network: "mumbai", Needed: decimal.RequireFromString("12.5")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d pauses, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Token != want[i].Token || got[i].Network != want[i].Network || !got[i].Needed.Equal(want[i].Needed) {
+			t.Errorf("pause %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResumeToken(t *testing.T) {
+	r, mock := newMockOrderRepo(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "treasury_pauses" WHERE token = \$1`).
+		WithArgs("IRT").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := r.ResumeToken(context.Background(), "IRT"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSignatureRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		sig  domain.OrderSignature
+	}{
+		{name: "zero signature"},
+		{name: "v 27", sig: domain.OrderSignature{V: 27, R: common.HexToHash("0x01"), S: common.HexToHash("0x02")}},
+		{name: "full width", sig: domain.OrderSignature{
+			V: 28,
+			R: common.HexToHash("0xffeeddccbbaa99887766554433221100ffeeddccbbaa99887766554433221100"),
+			S: common.HexToHash("0x7fffffffffffffffffffffffffffffff5d576e7357a4501ddfe92f46681b20a0"),
+		}},
+	}
+	r := &OrderRepo{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := toOrderModel(&domain.Order{Signature: tt.sig})
+			if m.Signature != nil {
+				t.Errorf("legacy signature column = %q, want NULL", *m.Signature)
+			}
+			if got := r.toDomainOrder(m).Signature; got != tt.sig {
+				t.Errorf("signature = %+v, want %+v", got, tt.sig)
+			}
+		})
+	}
+}
+
+func TestMigrateSignatureColumns(t *testing.T) {
+	sig := domain.OrderSignature{V: 27, R: common.HexToHash("0x01"), S: common.HexToHash("0x02")}
+	legacy := `{"v":27,"r":"` + sig.R.Hex() + `","s":"` + sig.S.Hex() + `"}`
+	tests := []struct {
+		name    string
+		stored  string // legacy signature column, empty for none left
+		wantErr bool
+	}{
+		{name: "nothing left to migrate"},
+		{name: "legacy json moved into columns", stored: legacy},
+		{name: "malformed json rolls back", stored: `{"v":`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockOrderRepo(t)
+			rows := sqlmock.NewRows([]string{"id", "signature"})
+			if tt.stored != "" {
+				rows.AddRow(3, tt.stored)
+			}
+			mock.ExpectQuery(`SELECT "id","signature" FROM "orders" WHERE signature IS NOT NULL$`).
+				WillReturnRows(rows)
+			mock.ExpectBegin()
+			switch {
+			case tt.wantErr:
+				mock.ExpectRollback()
+			case tt.stored != "":
+				// the legacy column is cleared, so the migration runs once
+				mock.ExpectExec(`UPDATE "orders" SET "sig_r"=\$1,"sig_s"=\$2,"sig_v"=\$3,"signature"=\$4,"updated_at"=\$5 WHERE id = \$6`).
+					WithArgs(sig.R.Hex(), sig.S.Hex(), sig.V, nil, sqlmock.AnyArg(), 3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			default:
+				mock.ExpectCommit()
+			}
+
+			err := migrateSignatureColumns(r.db)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
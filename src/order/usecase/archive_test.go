@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestArchiveOrders(t *testing.T) {
+	old, recent := time.Now().Add(-31*24*time.Hour), time.Now().Add(-time.Hour)
+	tests := []struct {
+		name         string
+		status       domain.OrderStatus
+		updatedAt    time.Time
+		retention    time.Duration
+		wantArchived bool
+	}{
+		{name: "old completed", status: domain.OrderCompleted, updatedAt: old, retention: 30 * 24 * time.Hour, wantArchived: true},
+		{name: "old refunded", status: domain.OrderRefundUserOrderSuccess, updatedAt: old, retention: 30 * 24 * time.Hour, wantArchived: true},
+		{name: "old expired", status: domain.OrderExpired, updatedAt: old, retention: 30 * 24 * time.Hour, wantArchived: true},
+		{name: "recent completed", status: domain.OrderCompleted, updatedAt: recent, retention: 30 * 24 * time.Hour},
+		{name: "old in flight", status: domain.OrderMarketUserOrderFailed, updatedAt: old, retention: 30 * 24 * time.Hour},
+		// failed refunds still need an operator
+		{name: "old failed refund", status: domain.OrderRefundUserOrderFailed, updatedAt: old, retention: 30 * 24 * time.Hour},
+		{name: "archiving disabled", status: domain.OrderCompleted, updatedAt: old},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: tt.status, UpdatedAt: tt.updatedAt})
+			svc := newTestService(repo)
+			svc.orderArchiveRetention = tt.retention
+
+			if err := svc.ArchiveOrders(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			_, archived := repo.archived[1]
+			_, hot := repo.orders[1]
+			if archived != tt.wantArchived || hot == tt.wantArchived {
+				t.Errorf("archived = %v, in orders = %v, want archived %v", archived, hot, tt.wantArchived)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestSubmitOrders(t *testing.T) {
+	// order builds a valid order on market 2, or one on unknown market 9 when invalid
+	order := func(t *testing.T, invalid bool) *domain.Order {
+		o := &domain.Order{MarketID: 2, Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), IsBuy: true,
+			FromNetwork: testNetwork, ToNetwork: testNetwork, Deadline: time.Now().Add(time.Hour).Unix()}
+		if invalid {
+			o.MarketID = 9
+		}
+		signPermit(t, o, 6)
+		return o
+	}
+	tests := []struct {
+		name      string
+		invalid   []bool // per order
+		unsigned  int    // index of an order whose permit is left unsigned, -1 for none
+		atomic    bool
+		wantSaved int
+		wantErrs  []error // per order, nil for a created order
+	}{
+		{name: "all valid", invalid: []bool{false, false, false}, unsigned: -1, atomic: true,
+			wantSaved: 3, wantErrs: []error{nil, nil, nil}},
+		{name: "all valid, per item", invalid: []bool{false, false}, unsigned: -1,
+			wantSaved: 2, wantErrs: []error{nil, nil}},
+		{name: "partial failure aborts the batch", invalid: []bool{false, true, false}, unsigned: -1, atomic: true,
+			wantErrs: []error{domain.ErrBulkAborted, domain.ErrInvalidOrder, domain.ErrBulkAborted}},
+		{name: "partial failure, per item", invalid: []bool{false, true, false}, unsigned: -1,
+			wantSaved: 2, wantErrs: []error{nil, domain.ErrInvalidOrder, nil}},
+		{name: "bad permit, per item", invalid: []bool{false, false}, unsigned: 1,
+			wantSaved: 1, wantErrs: []error{nil, domain.ErrInvalidOrder}},
+		{name: "all invalid", invalid: []bool{true, true}, unsigned: -1, atomic: true,
+			wantErrs: []error{domain.ErrInvalidOrder, domain.ErrInvalidOrder}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo()
+			svc := newTestService(repo)
+			svc.chains, _ = newFailingChains(t, fakeNode{decimals: 6})
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1, ExchangeName: "wallex", IsActive: true}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1, IsActive: true,
+					SourceTokenSymbol: "IRT", DestinationTokenSymbol: "USDT"}},
+			}
+			orders := make([]*domain.Order, len(tt.invalid))
+			for i, invalid := range tt.invalid {
+				orders[i] = order(t, invalid)
+			}
+			if tt.unsigned >= 0 {
+				orders[tt.unsigned].Signature = domain.OrderSignature{}
+			}
+
+			results, err := svc.SubmitOrders(context.Background(), orders, tt.atomic)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(orders) {
+				t.Fatalf("%d results for %d orders", len(results), len(orders))
+			}
+			for i, want := range tt.wantErrs {
+				r := results[i]
+				if want == nil {
+					if r.Err != nil || r.Order == nil || r.Order.ID == 0 {
+						t.Errorf("result %d = %+v, want a created order", i, r)
+					} else if r.Order.Status != domain.OrderPending || r.Order.FeeBreakdown == nil {
+						t.Errorf("result %d order = %+v, want pending with a fee breakdown", i, r.Order)
+					}
+					continue
+				}
+				if !errors.Is(r.Err, want) || r.Order != nil {
+					t.Errorf("result %d = %+v, want err %v", i, r, want)
+				}
+			}
+			if len(repo.orders) != tt.wantSaved {
+				t.Errorf("%d orders saved, want %d", len(repo.orders), tt.wantSaved)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestCancelOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     domain.OrderStatus
+		id         uint
+		userID     string
+		wantErr    error
+		wantStatus domain.OrderStatus
+	}{
+		{name: "owner cancels pending", status: domain.OrderPending, id: 1, userID: "alice", wantStatus: domain.OrderCancelled},
+		{name: "other user sees not found", status: domain.OrderPending, id: 1, userID: "mallory", wantErr: domain.ErrOrderNotFound, wantStatus: domain.OrderPending},
+		{name: "unknown id", status: domain.OrderPending, id: 2, userID: "alice", wantErr: domain.ErrOrderNotFound, wantStatus: domain.OrderPending},
+		{name: "already debiting", status: domain.OrderUserDebitInProgress, id: 1, userID: "alice", wantErr: domain.ErrOrderNotCancellable, wantStatus: domain.OrderUserDebitInProgress},
+		{name: "auth off cancels any order", status: domain.OrderPending, id: 1, userID: "", wantStatus: domain.OrderCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: tt.status, UserId: "alice"})
+
+			got, err := newTestService(repo).CancelOrder(context.Background(), tt.id, tt.userID)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Status != domain.OrderCancelled {
+				t.Errorf("returned status %s", got.Status)
+			}
+			if status := repo.order(t, 1).Status; status != tt.wantStatus {
+				t.Errorf("stored status = %s, want %s", status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+// TestClaimBatchSize runs the pending processor over a backlog of expired orders: each run
+// must claim at most the batch size, leaving the rest pending for the next run
+func TestClaimBatchSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		backlog     int
+		batchSize   int
+		wantPerRun  []int // orders expired after each run
+		wantPending int
+	}{
+		{name: "backlog larger than the batch", backlog: 5, batchSize: 2, wantPerRun: []int{2, 4, 5}},
+		{name: "backlog fits one batch", backlog: 3, batchSize: 10, wantPerRun: []int{3}},
+		{name: "one run leaves the rest", backlog: 4, batchSize: 3, wantPerRun: []int{3}, wantPending: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var orders []domain.Order
+			for id := 1; id <= tt.backlog; id++ {
+				orders = append(orders, domain.Order{ID: uint(id), Status: domain.OrderPending, Deadline: time.Now().Add(-time.Minute).Unix()})
+			}
+			repo := newFakeOrderRepo(orders...)
+			svc := newTestService(repo)
+			svc.claimBatchSize = tt.batchSize
+
+			for run, want := range tt.wantPerRun {
+				if err := svc.FetchPendingOrders(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				if got := countStatus(repo, domain.OrderExpired); got != want {
+					t.Fatalf("run %d: %d orders expired, want %d", run+1, got, want)
+				}
+			}
+			if got := countStatus(repo, domain.OrderPending); got != tt.wantPending {
+				t.Errorf("%d orders left pending, want %d", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func countStatus(repo *fakeOrderRepo, status domain.OrderStatus) int {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	n := 0
+	for _, o := range repo.orders {
+		if o.Status == status {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+// countingRepo counts the status changes a processor makes per order after claiming it
+type countingRepo struct {
+	*fakeOrderRepo
+
+	mu      sync.Mutex
+	changes map[uint]int
+}
+
+func (r *countingRepo) ChangeStatusByIds(ctx context.Context, ids []uint, status domain.OrderStatus) error {
+	r.mu.Lock()
+	for _, id := range ids {
+		r.changes[id]++
+	}
+	r.mu.Unlock()
+	return r.fakeOrderRepo.ChangeStatusByIds(ctx, ids, status)
+}
+
+// TestOverlappingRunsClaimDisjointOrders runs FetchPendingOrders concurrently, as two
+// instances would when the cron lock fails, and checks no order is processed twice
+func TestOverlappingRunsClaimDisjointOrders(t *testing.T) {
+	const orders = 20
+	tests := []struct {
+		name      string
+		runs      int
+		batchSize int
+		want      int
+	}{
+		{name: "single run", runs: 1, batchSize: 100, want: orders},
+		{name: "overlapping runs", runs: 8, batchSize: 100, want: orders},
+		{name: "overlapping small batches", runs: 3, batchSize: 5, want: 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := make([]domain.Order, orders)
+			for i := range pending {
+				// an expired deadline is handled without a chain
+				pending[i] = domain.Order{ID: uint(i + 1), Status: domain.OrderPending, Deadline: time.Now().Add(-time.Minute).Unix()}
+			}
+			repo := &countingRepo{fakeOrderRepo: newFakeOrderRepo(pending...), changes: make(map[uint]int)}
+			svc := newTestService(repo)
+			svc.claimBatchSize = tt.batchSize
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.runs; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := svc.FetchPendingOrders(context.Background()); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			processed := 0
+			for id := uint(1); id <= orders; id++ {
+				switch n := repo.changes[id]; n {
+				case 0:
+					if got := repo.order(t, id).Status; got != domain.OrderPending {
+						t.Errorf("unprocessed order %d status = %s, want %s", id, got, domain.OrderPending)
+					}
+				case 1:
+					processed++
+					if got := repo.order(t, id).Status; got != domain.OrderExpired {
+						t.Errorf("order %d status = %s, want %s", id, got, domain.OrderExpired)
+					}
+				default:
+					t.Errorf("order %d processed %d times", id, n)
+				}
+			}
+			if processed != tt.want {
+				t.Errorf("processed %d orders, want %d", processed, tt.want)
+			}
+		})
+	}
+}
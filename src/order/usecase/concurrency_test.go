@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestWithMaxConcurrency(t *testing.T) {
+	tests := []struct {
+		name      string
+		option    int
+		wantLimit int
+	}{
+		{name: "serial", option: 1, wantLimit: 1},
+		{name: "configured", option: 3, wantLimit: 3},
+		{name: "zero keeps the default", option: 0, wantLimit: defaultMaxConcurrency},
+		{name: "negative keeps the default", option: -2, wantLimit: defaultMaxConcurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeOrderRepo())
+			WithMaxConcurrency(tt.option)(svc)
+			orders := make([]domain.Order, 20)
+			for i := range orders {
+				orders[i] = domain.Order{ID: uint(i + 1)}
+			}
+			var (
+				mu                    sync.Mutex
+				inFlight, peak, count int
+			)
+
+			svc.forEachOrder(orders, func(order domain.Order) {
+				mu.Lock()
+				inFlight++
+				count++
+				peak = max(peak, inFlight)
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			})
+
+			if count != len(orders) {
+				t.Errorf("processed %d orders, want %d", count, len(orders))
+			}
+			if peak > tt.wantLimit {
+				t.Errorf("%d orders processed at once, want at most %d", peak, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -2,9 +2,14 @@ package usecase
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
 
+	"github.com/MMN3003/mega/src/config"
+	"github.com/MMN3003/mega/src/logger"
 	cron_adapter "github.com/MMN3003/mega/src/order/adapter/cron"
 	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/MMN3003/mega/src/tracing"
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 )
@@ -15,87 +20,82 @@ var (
 	ReturnUserOrdersID             = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e2")
 	MarketUserOrderSuccessOrdersID = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e3")
 	MarketUserOrderFailedOrdersID  = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e4")
+	StaleOrdersRefundID            = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e5")
+	StaleQuotesExpiryID            = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e6")
+	OrderArchiveID                 = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e7")
+	MarketRefreshID                = uuid.MustParse("62444ba0-b2dd-4b8f-afee-c04f7b2ab6e8")
 )
 
-func NewCronService(c *cron.Cron, s domain.OrderUsecase, ca cron_adapter.CronAdapter) {
-	c.AddFunc("1 * * * * *", func() {
-		handlePendingOrders(context.Background(), s, ca)
-	})
-	c.AddFunc("1 * * * * *", func() {
-		handleSuccessDebitOrders(context.Background(), s, ca)
-	})
-	c.AddFunc("1 * * * * *", func() {
-		handleReturnUserOrders(context.Background(), s, ca)
-	})
-	c.AddFunc("1 * * * * *", func() {
-		handleMarketUserOrderSuccessOrders(context.Background(), s, ca)
-	})
-	c.AddFunc("1 * * * * *", func() {
-		handleFailedMarketUserOrderOrders(context.Background(), s, ca)
-	})
+// Job is a step run on a schedule under the cron lock, so only one instance runs it at a time
+type Job struct {
+	ID       uuid.UUID
+	Name     string
+	Schedule string
+	Run      func(ctx context.Context) error
 }
 
-func handlePendingOrders(ctx context.Context, o domain.OrderUsecase, ca cron_adapter.CronAdapter) {
-
-	err := ca.CreateCron(ctx, PendingOrdersCronID)
-	if err != nil {
-		return
-	}
-	o.FetchPendingOrders(ctx)
-
-	err = ca.DeleteCron(ctx, PendingOrdersCronID)
-	if err != nil {
-		return
+func orderCronJobs(s domain.OrderUsecase, cfg config.CronConfig) []Job {
+	return []Job{
+		{PendingOrdersCronID, "pending_orders", cfg.PendingOrders, s.FetchPendingOrders},
+		{SuccessDebitCronID, "success_debit_orders", cfg.SuccessDebitOrders, s.FetchSuccessDebitOrders},
+		{ReturnUserOrdersID, "return_user_orders", cfg.ReturnUserOrders, s.FetchReturnUserOrders},
+		{MarketUserOrderSuccessOrdersID, "market_user_order_success_orders", cfg.MarketUserOrderSuccessOrders, s.FetchMarketUserOrderSuccessOrders},
+		{MarketUserOrderFailedOrdersID, "market_user_order_failed_orders", cfg.MarketUserOrderFailedOrders, s.FetchFailedMarketUserOrderOrders},
+		{StaleOrdersRefundID, "stale_orders_refund", cfg.StaleOrdersRefund, s.RefundStaleOrders},
+		{OrderArchiveID, "order_archive", cfg.OrderArchive, s.ArchiveOrders},
+		{StaleQuotesExpiryID, "stale_quotes_expiry", cfg.StaleQuotesExpiry, s.ExpireStaleQuotes},
 	}
 }
 
-func handleSuccessDebitOrders(ctx context.Context, o domain.OrderUsecase, ca cron_adapter.CronAdapter) {
-	err := ca.CreateCron(ctx, SuccessDebitCronID)
-	if err != nil {
-		return
-	}
-	o.FetchSuccessDebitOrders(ctx)
-
-	err = ca.DeleteCron(ctx, SuccessDebitCronID)
-	if err != nil {
-		return
+// NewCronService schedules every order pipeline job on its configured schedule.
+// The specs are validated when the config loads, so AddFunc can't fail here.
+func NewCronService(c *cron.Cron, s domain.OrderUsecase, ca cron_adapter.CronAdapter, cfg config.CronConfig, logg *logger.Logger) {
+	for _, job := range orderCronJobs(s, cfg) {
+		ScheduleJob(c, ca, job, logg)
 	}
 }
 
-func handleReturnUserOrders(ctx context.Context, o domain.OrderUsecase, ca cron_adapter.CronAdapter) {
-	err := ca.CreateCron(ctx, ReturnUserOrdersID)
-	if err != nil {
-		return
-	}
-	o.FetchReturnUserOrders(ctx)
-
-	err = ca.DeleteCron(ctx, ReturnUserOrdersID)
-	if err != nil {
-		return
-	}
+// ScheduleJob registers job and runs it on its schedule next to the order pipeline jobs
+func ScheduleJob(c *cron.Cron, ca cron_adapter.CronAdapter, job Job, logg *logger.Logger) {
+	_ = ca.RegisterJob(context.Background(), job.ID, job.Name, job.Schedule)
+	c.AddFunc(job.Schedule, func() {
+		RunJob(context.Background(), ca, job, logg)
+	})
 }
 
-func handleMarketUserOrderSuccessOrders(ctx context.Context, o domain.OrderUsecase, ca cron_adapter.CronAdapter) {
-	err := ca.CreateCron(ctx, MarketUserOrderSuccessOrdersID)
+// RunJob takes the job lock, runs it once and records the outcome. A held lock means
+// another instance is already running the job, so the tick is skipped.
+func RunJob(ctx context.Context, ca cron_adapter.CronAdapter, job Job, logg *logger.Logger) {
+	stale, err := ca.CreateCron(ctx, job.ID)
 	if err != nil {
 		return
 	}
-	o.FetchMarketUserOrderSuccessOrders(ctx)
-
-	err = ca.DeleteCron(ctx, MarketUserOrderSuccessOrdersID)
-	if err != nil {
-		return
+	if stale {
+		logg.WithContext(ctx).Errorf("cron job %s: reclaimed an abandoned lock, the previous run did not finish", job.Name)
 	}
+	// kept fresh while the job runs and released even when it panics, so the next tick
+	// isn't blocked
+	stop := ca.KeepCron(ctx, job.ID)
+	defer func() {
+		stop()
+		_ = ca.DeleteCron(ctx, job.ID)
+	}()
+
+	_ = ca.StartRun(ctx, job.ID)
+	runErr := runRecovered(ctx, job, logg)
+	_ = ca.FinishRun(ctx, job.ID, runErr)
 }
-func handleFailedMarketUserOrderOrders(ctx context.Context, o domain.OrderUsecase, ca cron_adapter.CronAdapter) {
-	err := ca.CreateCron(ctx, MarketUserOrderFailedOrdersID)
-	if err != nil {
-		return
-	}
-	o.FetchFailedMarketUserOrderOrders(ctx)
 
-	err = ca.DeleteCron(ctx, MarketUserOrderFailedOrdersID)
-	if err != nil {
-		return
-	}
+// runRecovered runs the job in its own trace, turning a panic into its run error instead
+// of letting it take down the process.
+func runRecovered(ctx context.Context, job Job, logg *logger.Logger) (err error) {
+	ctx, span := tracing.Start(ctx, "cron "+job.Name)
+	defer func() {
+		if r := recover(); r != nil {
+			logg.WithContext(ctx).Errorf("cron job %s panicked: %v\n%s", job.Name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+		tracing.End(span, err)
+	}()
+	return job.Run(ctx)
 }
@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cron_domain "github.com/MMN3003/mega/src/cron/domain"
+	cron_usecase "github.com/MMN3003/mega/src/cron/usecase"
+	"github.com/MMN3003/mega/src/logger"
+	cron_adapter "github.com/MMN3003/mega/src/order/adapter/cron"
+	"github.com/google/uuid"
+)
+
+var _ cron_domain.CronRepository = (*fakeCronRepo)(nil)
+
+// fakeCronRepo keeps the job locks, by their created_at, and run bookkeeping in memory
+type fakeCronRepo struct {
+	mu    sync.Mutex
+	locks map[uuid.UUID]time.Time
+	jobs  map[uuid.UUID]*cron_domain.CronJob
+}
+
+func newFakeCronRepo() *fakeCronRepo {
+	return &fakeCronRepo{locks: make(map[uuid.UUID]time.Time), jobs: make(map[uuid.UUID]*cron_domain.CronJob)}
+}
+
+// lockedAt returns when the lock on id was taken or last refreshed
+func (r *fakeCronRepo) lockedAt(id uuid.UUID) (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	at, ok := r.locks[id]
+	return at, ok
+}
+
+func (r *fakeCronRepo) SaveCron(ctx context.Context, c *cron_domain.Cron) (*cron_domain.Cron, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, held := r.locks[c.ID]; held {
+		return nil, errors.New("duplicate key")
+	}
+	r.locks[c.ID] = time.Now()
+	return c, nil
+}
+
+func (r *fakeCronRepo) DeleteCron(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.locks, id)
+	return nil
+}
+
+func (r *fakeCronRepo) ReclaimStaleCron(ctx context.Context, id uuid.UUID, staleBefore, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	createdAt, held := r.locks[id]
+	if !held || !createdAt.Before(staleBefore) {
+		return false, nil
+	}
+	r.locks[id] = now
+	return true, nil
+}
+
+func (r *fakeCronRepo) RefreshCron(ctx context.Context, id uuid.UUID, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, held := r.locks[id]; held {
+		r.locks[id] = now
+	}
+	return nil
+}
+
+func (r *fakeCronRepo) UpsertJob(ctx context.Context, id uuid.UUID, name, schedule string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id] = &cron_domain.CronJob{ID: id, Name: name, Schedule: schedule}
+	return nil
+}
+
+func (r *fakeCronRepo) MarkJobStarted(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id].LastStartedAt = &at
+	return nil
+}
+
+func (r *fakeCronRepo) MarkJobFinished(ctx context.Context, id uuid.UUID, at time.Time, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs[id].LastFinishedAt = &at
+	r.jobs[id].LastError = lastError
+	return nil
+}
+
+func (r *fakeCronRepo) ListJobs(ctx context.Context) ([]cron_domain.CronJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []cron_domain.CronJob
+	for id, j := range r.jobs {
+		job := *j
+		_, job.Running = r.locks[id]
+		out = append(out, job)
+	}
+	return out, nil
+}
+
+func TestRunJobRecordsLastRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(ctx context.Context) error
+		lockHeld    bool
+		wantRan     bool
+		wantLastErr string
+	}{
+		{name: "succeeded", run: func(ctx context.Context) error { return nil }, wantRan: true},
+		{name: "failed", run: func(ctx context.Context) error { return errors.New("db down") }, wantRan: true, wantLastErr: "db down"},
+		{name: "panicked", run: func(ctx context.Context) error { panic("boom") }, wantRan: true, wantLastErr: "panic: boom"},
+		{name: "another instance runs it", run: func(ctx context.Context) error { return nil }, lockHeld: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := logger.New("prod")
+			_ = l.SetLevel("disabled")
+			repo := newFakeCronRepo()
+			ca := cron_adapter.NewCronPort(cron_usecase.NewService(repo, l))
+			job := Job{ID: uuid.New(), Name: "test_job", Schedule: "@every 1m", Run: tt.run}
+			if err := ca.RegisterJob(context.Background(), job.ID, job.Name, job.Schedule); err != nil {
+				t.Fatal(err)
+			}
+			if tt.lockHeld {
+				repo.locks[job.ID] = time.Now()
+			}
+			before := time.Now()
+
+			RunJob(context.Background(), ca, job, l)
+
+			jobs, err := repo.ListJobs(context.Background())
+			if err != nil || len(jobs) != 1 {
+				t.Fatalf("jobs = %+v, err = %v", jobs, err)
+			}
+			got := jobs[0]
+			if !tt.wantRan {
+				if got.LastStartedAt != nil || got.LastFinishedAt != nil {
+					t.Errorf("job ran while locked: %+v", got)
+				}
+				return
+			}
+			if got.LastStartedAt == nil || got.LastStartedAt.Before(before) {
+				t.Errorf("last started = %v, want after %v", got.LastStartedAt, before)
+			}
+			if got.LastFinishedAt == nil || got.LastFinishedAt.Before(*got.LastStartedAt) {
+				t.Errorf("last finished = %v, want after start %v", got.LastFinishedAt, got.LastStartedAt)
+			}
+			if got.LastError != tt.wantLastErr {
+				t.Errorf("last error = %q, want %q", got.LastError, tt.wantLastErr)
+			}
+			if got.Running {
+				t.Error("job still marked running, the lock was not released")
+			}
+		})
+	}
+}
+
+// TestCreateCronReclaimsStaleLock leaves a lock behind as a crashed run would; only a lock
+// older than the TTL is taken over, and the takeover is reported
+func TestCreateCronReclaimsStaleLock(t *testing.T) {
+	const ttl = 10 * time.Minute
+	tests := []struct {
+		name      string
+		lockAge   time.Duration // zero means no lock is held
+		wantErr   bool
+		wantStale bool
+	}{
+		{name: "free"},
+		{name: "held by a live run", lockAge: time.Minute, wantErr: true},
+		{name: "just under the ttl", lockAge: ttl - time.Second, wantErr: true},
+		{name: "abandoned", lockAge: ttl + time.Minute, wantStale: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := logger.New("prod")
+			_ = l.SetLevel("disabled")
+			repo := newFakeCronRepo()
+			svc := cron_usecase.NewService(repo, l, cron_usecase.WithLockTTL(ttl))
+			id := uuid.New()
+			var abandoned time.Time
+			if tt.lockAge > 0 {
+				abandoned = time.Now().Add(-tt.lockAge)
+				repo.locks[id] = abandoned
+			}
+			before := time.Now()
+
+			stale, err := svc.CreateCron(context.Background(), id)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if stale != tt.wantStale {
+				t.Errorf("stale = %v, want %v", stale, tt.wantStale)
+			}
+			lockedAt, held := repo.lockedAt(id)
+			if !held {
+				t.Fatal("lock not held")
+			}
+			// a live lock is left as it was, a taken one is stamped now
+			if tt.wantErr && !lockedAt.Equal(abandoned) {
+				t.Errorf("live lock restamped to %v", lockedAt)
+			}
+			if !tt.wantErr && lockedAt.Before(before) {
+				t.Errorf("lock stamped %v, want after %v", lockedAt, before)
+			}
+		})
+	}
+}
+
+// TestRunJobKeepsLockFresh runs a job for several lock TTLs; another instance must not
+// mistake the live lock for an abandoned one while it runs
+func TestRunJobKeepsLockFresh(t *testing.T) {
+	const ttl = 60 * time.Millisecond
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	repo := newFakeCronRepo()
+	ca := cron_adapter.NewCronPort(cron_usecase.NewService(repo, l, cron_usecase.WithLockTTL(ttl)))
+	other := cron_usecase.NewService(repo, l, cron_usecase.WithLockTTL(ttl))
+	id := uuid.New()
+	if err := ca.RegisterJob(context.Background(), id, "slow_job", "@every 1m"); err != nil {
+		t.Fatal(err)
+	}
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	job := Job{ID: id, Name: "slow_job", Schedule: "@every 1m", Run: func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}}
+	go func() {
+		defer close(done)
+		RunJob(context.Background(), ca, job, l)
+	}()
+	<-started
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(ttl)
+		if stale, err := other.CreateCron(context.Background(), id); err == nil || stale {
+			t.Fatalf("after %v the live lock was taken over (stale = %v)", time.Duration(i+1)*ttl, stale)
+		}
+	}
+	close(release)
+	<-done
+
+	if _, held := repo.lockedAt(id); held {
+		t.Error("lock not released after the run")
+	}
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+var (
+	// errRefundReverted is the cause recorded when a refund transaction was mined but reverted
+	errRefundReverted = errors.New("refund transaction reverted")
+	// errMarketOrderFailed is the cause recorded when the exchange order kept failing
+	errMarketOrderFailed = errors.New("exchange market order failed")
+)
+
+// isPermanent reports whether err can't go away by retrying, only by an operator fixing
+// the order or the configuration
+func isPermanent(err error) bool {
+	return errors.Is(err, ethereum.ErrUnknownNetwork) ||
+		errors.Is(err, ethereum.ErrUnsupportedToken) ||
+		errors.Is(err, ethereum.ErrInvalidAmount) ||
+		errors.Is(err, ethereum.ErrInvalidAddress)
+}
+
+// retryOrRefund sends a debited order back to status for another attempt at its market
+// order after it failed with cause. Once it used up its retries or cause is permanent the
+// user is refunded instead; the refund starts its own retries, and only a refund that keeps
+// failing ends up in dead letter.
+func (s *Service) retryOrRefund(ctx context.Context, order domain.Order, status domain.OrderStatus, cause error) {
+	if isPermanent(cause) || order.RetryCount >= s.maxOrderRetries {
+		s.logger.WithContext(ctx).Errorf("order %d market order failed after %d retries, refunding: %v", order.ID, order.RetryCount, cause)
+		if err := s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonMarketOrderFailed); err != nil {
+			s.logger.Errorf("RefundOrder order=%d err: %v", order.ID, err)
+		}
+		return
+	}
+	if err := s.orderRepo.RetryOrder(ctx, order.ID, status); err != nil {
+		s.logger.Errorf("RetryOrder order=%d err: %v", order.ID, err)
+	}
+}
+
+// retryOrDeadLetter sends order back to status for another attempt after it failed with
+// cause. It is dead-lettered instead once it used up its retries or cause is permanent.
+func (s *Service) retryOrDeadLetter(ctx context.Context, order domain.Order, status domain.OrderStatus, cause error) {
+	if isPermanent(cause) || order.RetryCount >= s.maxOrderRetries {
+		s.deadLetter(ctx, order, cause)
+		return
+	}
+	if err := s.orderRepo.RetryOrder(ctx, order.ID, status); err != nil {
+		s.logger.Errorf("RetryOrder order=%d err: %v", order.ID, err)
+	}
+}
+
+// deadLetter gives up on order, which failed with cause, and parks it for an operator
+// instead of cycling it through the cron processors forever.
+func (s *Service) deadLetter(ctx context.Context, order domain.Order, cause error) {
+	log := s.logger.WithContext(ctx).WithFields(map[string]interface{}{
+		"order_id":    order.ID,
+		"status":      order.Status,
+		"retry_count": order.RetryCount,
+		"user_id":     order.UserId,
+		"error":       cause.Error(),
+	})
+	if err := s.orderRepo.DeadLetterOrder(ctx, order.ID, cause.Error()); err != nil {
+		log.Errorf("DeadLetterOrder order=%d err: %v", order.ID, err)
+		return
+	}
+	metrics.IncOrderDeadLettered(string(order.Status))
+	log.Errorf("ALERT order %d moved to dead letter after %d retries: %v", order.ID, order.RetryCount, cause)
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestFetchFailedMarketUserOrderOrders(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryCount int
+		price      int64
+		priceErr   error
+		wantStatus domain.OrderStatus
+		wantReason domain.RefundReason
+		wantRetry  int
+	}{
+		{name: "retried", price: 100, wantStatus: domain.OrderUserDebitSuccess, wantRetry: 1},
+		{name: "last retry", retryCount: 2, price: 100, wantStatus: domain.OrderUserDebitSuccess, wantRetry: 3},
+		// the user was debited, so giving up on the market order refunds them
+		{name: "retries used up", retryCount: 3, price: 100, wantStatus: domain.OrderRefundUserOrder, wantReason: domain.RefundReasonMarketOrderFailed},
+		{name: "slippage exceeded refunds", retryCount: 3, price: 200, wantStatus: domain.OrderRefundUserOrder, wantReason: domain.RefundReasonSlippageExceeded},
+		{name: "no price retried", priceErr: errors.New("exchanges down"), wantStatus: domain.OrderMarketUserOrderFailed, wantRetry: 1},
+		{name: "no price, retries used up", retryCount: 3, priceErr: errors.New("exchanges down"), wantStatus: domain.OrderRefundUserOrder, wantReason: domain.RefundReasonMarketOrderFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderMarketUserOrderFailed, MegaMarketID: 1,
+				Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), SlipagePercentage: decimal.NewFromFloat(0.01),
+				RetryCount: tt.retryCount})
+			svc := newTestService(repo)
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1}},
+				price:   decimal.NewFromInt(tt.price), priceErr: tt.priceErr,
+			}
+
+			if err := svc.FetchFailedMarketUserOrderOrders(context.Background()); err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			got := repo.order(t, 1)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if got.RetryCount != tt.wantRetry {
+				t.Errorf("retry count = %d, want %d", got.RetryCount, tt.wantRetry)
+			}
+			if got.RefundReason != tt.wantReason {
+				t.Errorf("refund reason = %q, want %q", got.RefundReason, tt.wantReason)
+			}
+		})
+	}
+}
+
+// an order that can neither be traded nor refunded must stop cycling: its market order is
+// given up on and the user refunded, then the refund is given up on and dead-lettered
+func TestRepeatedlyFailingOrderLandsInDeadLetter(t *testing.T) {
+	repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderMarketUserOrderFailed, MegaMarketID: 1,
+		Volume: decimal.NewFromInt(1), FromNetwork: testNetwork, SourceTokenSymbol: "USDT",
+		UserAddress: "0x4444444444444444444444444444444444444444"})
+	svc := newTestService(repo)
+	svc.marketAdapter = &fakeMarketAdapter{priceErr: errors.New("exchanges down")}
+	svc.chains, _ = newFailingChains(t, fakeNode{decimals: 18})
+
+	for run := 0; run < 20; run++ {
+		if err := svc.FetchFailedMarketUserOrderOrders(context.Background()); err != nil {
+			t.Fatalf("run %d err = %v", run, err)
+		}
+		if err := svc.FetchReturnUserOrders(context.Background()); err != nil {
+			t.Fatalf("run %d err = %v", run, err)
+		}
+	}
+
+	got := repo.order(t, 1)
+	if got.Status != domain.OrderDeadLetter {
+		t.Fatalf("status = %s, want %s", got.Status, domain.OrderDeadLetter)
+	}
+	if got.RefundReason != domain.RefundReasonMarketOrderFailed {
+		t.Errorf("refund reason = %q, want %q", got.RefundReason, domain.RefundReasonMarketOrderFailed)
+	}
+	// the refund got its own retries
+	if got.RetryCount != svc.maxOrderRetries {
+		t.Errorf("retry count = %d, want %d", got.RetryCount, svc.maxOrderRetries)
+	}
+	refunded := false
+	for _, reason := range repo.reasons[1] {
+		refunded = refunded || reason == string(domain.RefundReasonMarketOrderFailed)
+	}
+	if !refunded {
+		t.Errorf("reasons = %q, want a refund before the dead letter", repo.reasons[1])
+	}
+}
+
+func TestFetchReturnUserOrdersDeadLettersPermanentErrors(t *testing.T) {
+	// chains know no network, which no retry can fix
+	repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderRefundUserOrder, FromNetwork: "nowhere"})
+
+	if err := newTestService(repo).FetchReturnUserOrders(context.Background()); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	if got := repo.order(t, 1); got.Status != domain.OrderDeadLetter || got.RetryCount != 0 {
+		t.Errorf("status = %s retry count = %d, want %s without retrying", got.Status, got.RetryCount, domain.OrderDeadLetter)
+	}
+}
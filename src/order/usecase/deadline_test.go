@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestFetchPendingOrdersDeadline(t *testing.T) {
+	tests := []struct {
+		name       string
+		deadline   time.Duration // from now
+		wantStatus domain.OrderStatus
+		wantCalls  bool
+	}{
+		{name: "expired", deadline: -time.Minute, wantStatus: domain.OrderExpired},
+		{name: "expires now", deadline: 0, wantStatus: domain.OrderExpired},
+		// the debit is attempted, and fails on the test node
+		{name: "live", deadline: time.Hour, wantStatus: domain.OrderFailedUserDebit, wantCalls: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderPending, MarketID: 2, MegaMarketID: 1,
+				Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), Deadline: time.Now().Add(tt.deadline).Unix(),
+				FromNetwork: testNetwork, ToNetwork: testNetwork, SourceTokenSymbol: "USDT", DestinationTokenSymbol: "USDT"})
+			svc := newTestService(repo)
+			chains, calls := newFailingChains(t, fakeNode{decimals: 6})
+			svc.chains = chains
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets:     map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1}},
+			}
+
+			if err := svc.FetchPendingOrders(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := repo.order(t, 1).Status; got != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got, tt.wantStatus)
+			}
+			if n := calls.Load(); (n > 0) != tt.wantCalls {
+				t.Errorf("%d ethereum calls, want calls: %v", n, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// TestTreasuryRecheckBeforeDebit drains the treasury between submission and the debit: an
+// order whose payout it can no longer cover goes back to PENDING without debiting the user.
+// The node rejects every transaction, so an order that was debited lands in
+// FAILED_USER_DEBIT.
+func TestTreasuryRecheckBeforeDebit(t *testing.T) {
+	balance := func(v int64) *decimal.Decimal {
+		d := decimal.NewFromInt(v)
+		return &d
+	}
+	tests := []struct {
+		name       string
+		balance    *decimal.Decimal
+		marketID   uint
+		toNetwork  string
+		wantStatus domain.OrderStatus
+		wantPaused bool
+	}{
+		{name: "funded", balance: balance(150), marketID: 2, toNetwork: testNetwork, wantStatus: domain.OrderFailedUserDebit},
+		{name: "exactly funded", balance: balance(100), marketID: 2, toNetwork: testNetwork, wantStatus: domain.OrderFailedUserDebit},
+		{name: "underfunded", balance: balance(50), marketID: 2, toNetwork: testNetwork, wantStatus: domain.OrderPending, wantPaused: true},
+		{name: "balance unreadable", balance: balance(150), marketID: 2, toNetwork: "mainnet", wantStatus: domain.OrderPending},
+		{name: "payout unknown", balance: balance(150), marketID: 9, toNetwork: testNetwork, wantStatus: domain.OrderPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := "0x3333333333333333333333333333333333333333"
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderPending, MarketID: tt.marketID, MegaMarketID: 1,
+				Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), Deadline: time.Now().Add(time.Hour).Unix(),
+				FromNetwork: testNetwork, ToNetwork: tt.toNetwork, SourceTokenSymbol: "IRT", DestinationTokenSymbol: "USDT",
+				UserAddress: "0x4444444444444444444444444444444444444444", DestinationAddress: &destination})
+			svc := newTestService(repo)
+			svc.chains, _ = newFailingChains(t, fakeNode{decimals: 6, balance: tt.balance})
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets:     map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1}},
+			}
+
+			if err := svc.FetchPendingOrders(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := repo.order(t, 1).Status; got != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got, tt.wantStatus)
+			}
+			if got := svc.isTokenPaused(context.Background(), "USDT"); got != tt.wantPaused {
+				t.Errorf("USDT paused = %v, want %v", got, tt.wantPaused)
+			}
+		})
+	}
+}
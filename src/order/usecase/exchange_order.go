@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+// venueOrderSupport is what an exchange accepts beyond its default market order
+type venueOrderSupport struct {
+	types       map[domain.ExchangeOrderType]bool
+	timeInForce map[domain.TimeInForce]bool
+	postOnly    bool
+}
+
+// venueOrderSupports lists the exchange-native order features each exchange client can place
+var venueOrderSupports = map[string]venueOrderSupport{
+	"ompfinex": {
+		types: map[domain.ExchangeOrderType]bool{
+			domain.ExchangeOrderMarket: true, domain.ExchangeOrderLimit: true, domain.ExchangeOrderStopLimit: true,
+		},
+		timeInForce: map[domain.TimeInForce]bool{domain.TimeInForceGTC: true},
+	},
+	"wallex": {
+		types: map[domain.ExchangeOrderType]bool{
+			domain.ExchangeOrderMarket: true, domain.ExchangeOrderLimit: true,
+		},
+		timeInForce: map[domain.TimeInForce]bool{
+			domain.TimeInForceGTC: true, domain.TimeInForceIOC: true, domain.TimeInForceFOK: true,
+		},
+		postOnly: true,
+	},
+	"nobitex": {
+		types: map[domain.ExchangeOrderType]bool{domain.ExchangeOrderMarket: true},
+	},
+}
+
+// checkVenueSupport rejects exchange order params the exchange can't place
+func checkVenueSupport(exchangeName string, p *domain.ExchangeOrderParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	support, ok := venueOrderSupports[exchangeName]
+	if !ok || !support.types[p.Type] {
+		return fmt.Errorf("%w: %s does not support %s orders", domain.ErrUnsupportedOrderType, exchangeName, p.Type)
+	}
+	if tif := timeInForce(p); p.Type != domain.ExchangeOrderMarket && !support.timeInForce[tif] {
+		return fmt.Errorf("%w: %s does not support time in force %s", domain.ErrUnsupportedOrderType, exchangeName, tif)
+	}
+	if p.PostOnly && !support.postOnly {
+		return fmt.Errorf("%w: %s does not support post only orders", domain.ErrUnsupportedOrderType, exchangeName)
+	}
+	return nil
+}
+
+// timeInForce returns the params' time in force, GTC when unset
+func timeInForce(p *domain.ExchangeOrderParams) domain.TimeInForce {
+	if p.TimeInForce == "" {
+		return domain.TimeInForceGTC
+	}
+	return p.TimeInForce
+}
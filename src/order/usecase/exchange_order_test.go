@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/wallex"
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// newWallexStub returns a wallex client whose OTC order endpoint answers with clientOrderID
+func newWallexStub(t *testing.T, clientOrderID string) *wallex.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"message": "ok",
+			"result":  map[string]any{"clientOrderId": clientOrderID, "status": "FILLED"},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	c, err := wallex.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestFetchSuccessDebitOrders(t *testing.T) {
+	tests := []struct {
+		name          string
+		clientOrderID string
+		wantStatus    domain.OrderStatus
+		wantID        bool
+	}{
+		{name: "placed", clientOrderID: "abc", wantStatus: domain.OrderMarketUserOrderSuccess, wantID: true},
+		{name: "placed without an id is not left in progress", clientOrderID: "", wantStatus: domain.OrderAwaitingReconciliation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderUserDebitSuccess, MarketID: 7, Volume: decimal.NewFromInt(2)})
+			s := newTestService(repo)
+			s.wallexClient = newWallexStub(t, tt.clientOrderID)
+			s.marketAdapter = &fakeMarketAdapter{markets: map[uint]*market_domain.Market{
+				7: {ID: 7, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true},
+			}}
+
+			if err := s.FetchSuccessDebitOrders(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			o := repo.order(t, 1)
+			if o.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", o.Status, tt.wantStatus)
+			}
+			if got := o.ExchangeOrderID != nil; got != tt.wantID {
+				t.Errorf("exchange order id stored = %v, want %v", got, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestPlaceMarketOrderRoundsVolume(t *testing.T) {
+	precision := int32(6)
+	tests := []struct {
+		name         string
+		volume       string
+		wantQuantity string
+		wantErr      error
+	}{
+		{name: "rounded to the market precision", volume: "0.12345678", wantQuantity: "0.123456"},
+		{name: "below the market minimum", volume: "0.00001", wantErr: market_domain.ErrBelowMinimumSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent struct {
+				Quantity decimal.Decimal `json:"quantity"`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+					t.Errorf("decode order: %v", err)
+				}
+				_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "result": map[string]any{"clientOrderId": "abc"}})
+			}))
+			t.Cleanup(srv.Close)
+			wlx, err := wallex.NewClient(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := newTestService(newFakeOrderRepo())
+			s.wallexClient = wlx
+			s.marketAdapter = &fakeMarketAdapter{markets: map[uint]*market_domain.Market{
+				7: {ID: 7, ExchangeName: "wallex", ExchangeMarketIdentifier: "BTCUSDT", IsActive: true,
+					AmountPrecision: &precision, MinAmount: decimal.RequireFromString("0.0001")},
+			}}
+
+			_, err = s.PlaceMarketOrder(context.Background(), 7, decimal.RequireFromString(tt.volume), true)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !sent.Quantity.Equal(decimal.RequireFromString(tt.wantQuantity)) {
+				t.Errorf("quantity sent = %s, want %s", sent.Quantity, tt.wantQuantity)
+			}
+		})
+	}
+}
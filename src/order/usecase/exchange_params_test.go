@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ompfinex"
+	"github.com/MMN3003/mega/src/Infrastructure/wallex"
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestCheckVenueSupport(t *testing.T) {
+	price := decimal.NewFromInt(100)
+	limit := func(tif domain.TimeInForce, postOnly bool) *domain.ExchangeOrderParams {
+		return &domain.ExchangeOrderParams{Type: domain.ExchangeOrderLimit, Price: &price, TimeInForce: tif, PostOnly: postOnly}
+	}
+	stopLimit := &domain.ExchangeOrderParams{Type: domain.ExchangeOrderStopLimit, Price: &price, StopPrice: &price}
+	tests := []struct {
+		name     string
+		exchange string
+		params   *domain.ExchangeOrderParams
+		wantErr  error
+	}{
+		{name: "wallex ioc", exchange: "wallex", params: limit(domain.TimeInForceIOC, false)},
+		{name: "wallex fok", exchange: "wallex", params: limit(domain.TimeInForceFOK, false)},
+		{name: "wallex post only", exchange: "wallex", params: limit("", true)},
+		{name: "wallex stop limit", exchange: "wallex", params: stopLimit, wantErr: domain.ErrUnsupportedOrderType},
+		{name: "ompfinex stop limit", exchange: "ompfinex", params: stopLimit},
+		{name: "ompfinex ioc", exchange: "ompfinex", params: limit(domain.TimeInForceIOC, false), wantErr: domain.ErrUnsupportedOrderType},
+		{name: "ompfinex post only", exchange: "ompfinex", params: limit("", true), wantErr: domain.ErrUnsupportedOrderType},
+		{name: "nobitex market", exchange: "nobitex", params: &domain.ExchangeOrderParams{Type: domain.ExchangeOrderMarket}},
+		{name: "nobitex limit", exchange: "nobitex", params: limit("", false), wantErr: domain.ErrUnsupportedOrderType},
+		{name: "unknown exchange", exchange: "binance", params: limit("", false), wantErr: domain.ErrUnsupportedOrderType},
+		{name: "inconsistent params", exchange: "wallex", params: &domain.ExchangeOrderParams{Type: domain.ExchangeOrderLimit}, wantErr: domain.ErrInvalidOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkVenueSupport(tt.exchange, tt.params); !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlaceExchangeOrder(t *testing.T) {
+	price, stop := decimal.NewFromInt(100), decimal.NewFromInt(95)
+	tests := []struct {
+		name     string
+		exchange string
+		params   *domain.ExchangeOrderParams
+		wantPath string
+		wantBody map[string]any
+		wantErr  error
+	}{
+		{
+			name:     "wallex ioc limit on the spot book",
+			exchange: "wallex",
+			params:   &domain.ExchangeOrderParams{Type: domain.ExchangeOrderLimit, Price: &price, TimeInForce: domain.TimeInForceIOC},
+			wantPath: "/v1/account/orders",
+			wantBody: map[string]any{"symbol": "BTCUSDT", "type": "LIMIT", "side": "BUY", "price": "100", "quantity": "2", "timeInForce": "IOC"},
+		},
+		{
+			name:     "wallex post only defaults to gtc",
+			exchange: "wallex",
+			params:   &domain.ExchangeOrderParams{Type: domain.ExchangeOrderLimit, Price: &price, PostOnly: true},
+			wantPath: "/v1/account/orders",
+			wantBody: map[string]any{"symbol": "BTCUSDT", "type": "LIMIT", "side": "BUY", "price": "100", "quantity": "2", "timeInForce": "GTC", "postOnly": true},
+		},
+		{
+			name:     "ompfinex stop limit",
+			exchange: "ompfinex",
+			params:   &domain.ExchangeOrderParams{Type: domain.ExchangeOrderStopLimit, Price: &price, StopPrice: &stop},
+			wantPath: "/v1/market/12/order",
+			wantBody: map[string]any{"market_id": float64(12), "side": "buy", "type": "stop_limit", "price": "100", "stop_price": "95", "amount": "2"},
+		},
+		{
+			name:     "unsupported type never reaches the venue",
+			exchange: "wallex",
+			params:   &domain.ExchangeOrderParams{Type: domain.ExchangeOrderStopLimit, Price: &price, StopPrice: &stop},
+			wantErr:  domain.ErrUnsupportedOrderType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				gotPath string
+				gotBody map[string]any
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+					t.Errorf("decode order: %v", err)
+				}
+				// one body both venues accept: wallex reads result, ompfinex data
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"success": true, "status": "OK",
+					"result": map[string]any{"clientOrderId": "abc"},
+					"data":   map[string]any{"id": 42},
+				})
+			}))
+			t.Cleanup(srv.Close)
+			s := newTestService(newFakeOrderRepo())
+			var err error
+			if s.wallexClient, err = wallex.NewClient(srv.URL); err != nil {
+				t.Fatal(err)
+			}
+			if s.ompfinexClient, err = ompfinex.NewClient(srv.URL); err != nil {
+				t.Fatal(err)
+			}
+			identifier := "BTCUSDT"
+			if tt.exchange == "ompfinex" {
+				identifier = "12"
+			}
+			s.marketAdapter = &fakeMarketAdapter{markets: map[uint]*market_domain.Market{
+				7: {ID: 7, ExchangeName: tt.exchange, ExchangeMarketIdentifier: identifier, IsActive: true},
+			}}
+
+			_, err = s.PlaceExchangeOrder(context.Background(), 7, decimal.NewFromInt(2), true, tt.params)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if gotPath != "" {
+					t.Errorf("order sent to %s", gotPath)
+				}
+				return
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("path = %s, want %s", gotPath, tt.wantPath)
+			}
+			if len(gotBody) != len(tt.wantBody) {
+				t.Errorf("body = %v, want %v", gotBody, tt.wantBody)
+			}
+			for k, want := range tt.wantBody {
+				if gotBody[k] != want {
+					t.Errorf("%s = %v, want %v", k, gotBody[k], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// TestProcessorsSurviveFailingChain runs every processor that sends a transaction against a
+// node rejecting it: the order must land in the processor's failure status rather than
+// stay in progress, which is where a panic on the missing receipt would leave it.
+func TestProcessorsSurviveFailingChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     domain.OrderStatus
+		run        func(s *Service, ctx context.Context) error
+		wantStatus domain.OrderStatus
+		wantReason domain.RefundReason
+		wantRetry  int
+	}{
+		{
+			name:       "user debit",
+			status:     domain.OrderPending,
+			run:        (*Service).FetchPendingOrders,
+			wantStatus: domain.OrderFailedUserDebit,
+		},
+		{
+			name:       "treasury payout",
+			status:     domain.OrderMarketUserOrderSuccess,
+			run:        (*Service).FetchMarketUserOrderSuccessOrders,
+			wantStatus: domain.OrderRefundUserOrder,
+			wantReason: domain.RefundReasonTreasuryCreditFailed,
+		},
+		{
+			name:       "user refund",
+			status:     domain.OrderRefundUserOrder,
+			run:        (*Service).FetchReturnUserOrders,
+			wantStatus: domain.OrderRefundUserOrder,
+			wantRetry:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destination := "0x3333333333333333333333333333333333333333"
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: tt.status, MarketID: 2, MegaMarketID: 1,
+				Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), Deadline: time.Now().Add(time.Hour).Unix(),
+				FromNetwork: testNetwork, ToNetwork: testNetwork, SourceTokenSymbol: "USDT", DestinationTokenSymbol: "USDT",
+				UserAddress: "0x4444444444444444444444444444444444444444", DestinationAddress: &destination})
+			svc := newTestService(repo)
+			svc.chains, _ = newFailingChains(t, fakeNode{decimals: 18})
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets:     map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1}},
+			}
+
+			if err := tt.run(svc, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := repo.order(t, 1)
+			if got.Status != tt.wantStatus || got.RefundReason != tt.wantReason || got.RetryCount != tt.wantRetry {
+				t.Errorf("order is %s/%q retry %d, want %s/%q retry %d", got.Status, got.RefundReason, got.RetryCount,
+					tt.wantStatus, tt.wantReason, tt.wantRetry)
+			}
+		})
+	}
+}
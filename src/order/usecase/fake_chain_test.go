@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+// testNetwork is the only chain newFailingChains serves
+const testNetwork = "sepolia"
+
+// testDomainSeparator is the permit domain separator of every token on the test node,
+// where every owner's permit nonce is zero
+var testDomainSeparator = common.HexToHash("0xd0d0")
+
+// testPhoenixContract is the permit spender on testNetwork
+var testPhoenixContract = common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+// fakeNode is what the node of newFailingChains reports for every token
+type fakeNode struct {
+	decimals uint8
+	// balance is the treasury balance in whole tokens; nil is more than any test pays out
+	balance *decimal.Decimal
+}
+
+// newFailingChains returns Chains with one client, on testNetwork, whose node answers reads
+// (chain id, token decimals, the treasury balance of node and permit nonces) but rejects
+// every other contract call and gas estimate, so any transaction the services try fails
+// before it is sent.
+// calls counts the requests the node served once the chain was dialed.
+func newFailingChains(t *testing.T, node fakeNode) (chains *ethereum.Chains, calls *atomic.Int64) {
+	t.Helper()
+	const (
+		decimalsSelector        = "0x313ce567"
+		balanceOfSelector       = "0x70a08231"
+		domainSeparatorSelector = "0x3644e515"
+		noncesSelector          = "0x7ecebe00"
+	)
+	word := func(hex string) string { return "0x" + strings.Repeat("0", 64-len(hex)) + hex }
+	balance := "ffffffffffffffffffffffff"
+	if node.balance != nil {
+		balance = fmt.Sprintf("%x", node.balance.Shift(int32(node.decimals)).BigInt())
+	}
+	calls = new(atomic.Int64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode rpc request: %v", err)
+			return
+		}
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		reject := map[string]any{"code": 3, "message": "execution reverted"}
+		switch req.Method {
+		case "eth_chainId":
+			resp["result"] = "0xaa36a7"
+		case "eth_call":
+			var call struct {
+				Input string `json:"input"`
+			}
+			_ = json.Unmarshal(req.Params[0], &call)
+			switch input := call.Input; {
+			case strings.HasPrefix(input, decimalsSelector):
+				resp["result"] = word(fmt.Sprintf("%x", node.decimals))
+			case strings.HasPrefix(input, balanceOfSelector):
+				resp["result"] = word(balance)
+			case strings.HasPrefix(input, domainSeparatorSelector):
+				resp["result"] = testDomainSeparator.Hex()
+			case strings.HasPrefix(input, noncesSelector):
+				resp["result"] = word("0")
+			default:
+				resp["error"] = reject
+			}
+		default:
+			resp["error"] = reject
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	chains, err := ethereum.NewChains(context.Background(), []ethereum.Config{{
+		Network:         testNetwork,
+		RPCURL:          srv.URL,
+		PrivateKey:      "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318",
+		PhoenixContract: testPhoenixContract.Hex(),
+		SupportedTokens: map[string]string{
+			"USDT": "0x2222222222222222222222222222222222222222",
+			"IRT":  "0x3333333333333333333333333333333333333333",
+		},
+	}})
+	if err != nil {
+		t.Fatalf("new chains: %v", err)
+	}
+	t.Cleanup(chains.Close)
+	calls.Store(0)
+	return chains, calls
+}
+
+// signPermit has a test user sign the permit o debits on testNetwork, as the node of
+// newFailingChains with decimals sees it
+func signPermit(t *testing.T, o *domain.Order, decimals uint8) {
+	t.Helper()
+	key, err := crypto.HexToECDSA("8da4ef21b864d2cc526dbdb2a120bd2874c36c9d0a1fb7f8c63d7f7a8b41de8f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := crypto.PubkeyToAddress(key.PublicKey)
+	digest := ethereum.PermitDigest(testDomainSeparator, user, testPhoenixContract,
+		o.Volume.Shift(int32(decimals)).Floor().BigInt(), big.NewInt(0), big.NewInt(o.Deadline))
+	sig, err := crypto.Sign(digest.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.UserAddress = user.Hex()
+	o.Signature = domain.OrderSignature{V: sig[64] + 27, R: common.BytesToHash(sig[:32]), S: common.BytesToHash(sig[32:64])}
+}
@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/adapter/market"
+	"github.com/shopspring/decimal"
+)
+
+var _ market.MarketAdapter = (*fakeMarketAdapter)(nil)
+
+// fakeMarketAdapter serves fixed markets; best prices come from price, or priceErr when set
+type fakeMarketAdapter struct {
+	markets     map[uint]*market_domain.Market
+	megaMarkets map[uint]*market_domain.MegaMarket
+	price       decimal.Decimal
+	priceErr    error
+}
+
+func (f *fakeMarketAdapter) GetMarketByID(ctx context.Context, id uint) (*market_domain.Market, error) {
+	return f.markets[id], nil
+}
+
+func (f *fakeMarketAdapter) GetMegaMarketByID(ctx context.Context, id uint) (*market_domain.MegaMarket, error) {
+	return f.megaMarkets[id], nil
+}
+
+func (f *fakeMarketAdapter) GetMarketsByMegaMarketID(ctx context.Context, megaMarketId uint) ([]market_domain.Market, error) {
+	var out []market_domain.Market
+	for _, m := range f.markets {
+		if m.MegaMarketID == megaMarketId {
+			out = append(out, *m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMarketAdapter) GetBestExchangePriceByVolume(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (decimal.Decimal, *market_domain.Market, *market_domain.MegaMarket, error) {
+	if f.priceErr != nil {
+		return decimal.Zero, nil, nil, f.priceErr
+	}
+	for _, m := range f.markets {
+		if m.MegaMarketID == megaMarketId {
+			return f.price, m, f.megaMarkets[megaMarketId], nil
+		}
+	}
+	return f.price, nil, f.megaMarkets[megaMarketId], nil
+}
+
+func (f *fakeMarketAdapter) GetBestPrice(ctx context.Context, megaMarketId uint, volume decimal.Decimal, isBuy bool) (*market_domain.BestPrice, error) {
+	price, m, mega, err := f.GetBestExchangePriceByVolume(ctx, megaMarketId, volume, isBuy)
+	if err != nil {
+		return nil, err
+	}
+	best := &market_domain.BestPrice{Price: price, TopOfBook: price}
+	if m != nil {
+		best.Market = *m
+	}
+	if mega != nil {
+		best.MegaMarket = *mega
+	}
+	return best, nil
+}
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
+	"github.com/MMN3003/mega/src/logger"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// fakeOrderRepo keeps orders in memory and moves them between statuses like the postgres
+// repo, rejecting transitions the state machine doesn't allow. Methods the tests don't
+// need fall through to the nil embedded interface and panic.
+type fakeOrderRepo struct {
+	domain.OrderRepository
+
+	mu      sync.Mutex
+	orders  map[uint]*domain.Order
+	reasons map[uint][]string // status history reasons per order
+	pauses  map[string]domain.TreasuryPause
+	// archived holds the orders ArchiveOrders moved out of orders
+	archived map[uint]*domain.Order
+}
+
+func newFakeOrderRepo(orders ...domain.Order) *fakeOrderRepo {
+	r := &fakeOrderRepo{
+		orders:   make(map[uint]*domain.Order),
+		reasons:  make(map[uint][]string),
+		pauses:   make(map[string]domain.TreasuryPause),
+		archived: make(map[uint]*domain.Order),
+	}
+	for i := range orders {
+		o := orders[i]
+		r.orders[o.ID] = &o
+	}
+	return r
+}
+
+// order returns a copy of the stored order id
+func (r *fakeOrderRepo) order(t *testing.T, id uint) domain.Order {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.orders[id]
+	if !ok {
+		t.Fatalf("order %d not found", id)
+	}
+	return *o
+}
+
+func (r *fakeOrderRepo) SaveOrders(ctx context.Context, orders []*domain.Order) ([]*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := make([]*domain.Order, len(orders))
+	for i, o := range orders {
+		cp := *o
+		cp.ID = uint(len(r.orders) + 1)
+		r.orders[cp.ID] = &cp
+		out := cp
+		saved[i] = &out
+	}
+	return saved, nil
+}
+
+// SaveOrder stores o under the next id; like uidx_orders_user_idempotency_key, a key the
+// user already used is rejected
+func (r *fakeOrderRepo) SaveOrder(ctx context.Context, o *domain.Order) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if o.IdempotencyKey != nil {
+		for _, existing := range r.orders {
+			if existing.UserId == o.UserId && existing.IdempotencyKey != nil && *existing.IdempotencyKey == *o.IdempotencyKey {
+				return nil, fmt.Errorf("duplicate key value violates unique constraint %q", "uidx_orders_user_idempotency_key")
+			}
+		}
+	}
+	cp := *o
+	cp.ID = uint(len(r.orders) + 1)
+	r.orders[cp.ID] = &cp
+	out := cp
+	return &out, nil
+}
+
+func (r *fakeOrderRepo) GetOrderByIdempotencyKey(ctx context.Context, userId, key string) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, o := range r.orders {
+		if o.UserId == userId && o.IdempotencyKey != nil && *o.IdempotencyKey == key {
+			cp := *o
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeOrderRepo) GetOrderByID(ctx context.Context, id uint) (*domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := *o
+	return &cp, nil
+}
+
+// move applies a transition to id under r.mu
+func (r *fakeOrderRepo) move(id uint, status domain.OrderStatus, reason string) error {
+	o, ok := r.orders[id]
+	if !ok {
+		return fmt.Errorf("order %d not found", id)
+	}
+	if !domain.CanTransition(o.Status, status) {
+		return fmt.Errorf("%w: order %d %s -> %s", domain.ErrInvalidTransition, id, o.Status, status)
+	}
+	o.Status = status
+	r.reasons[id] = append(r.reasons[id], reason)
+	return nil
+}
+
+func (r *fakeOrderRepo) ChangeStatusByIds(ctx context.Context, ids []uint, status domain.OrderStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		if err := r.move(id, status, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeOrderRepo) ClaimOrders(ctx context.Context, from, to domain.OrderStatus, limit int, skipTokens []string) ([]domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	skip := make(map[string]bool, len(skipTokens))
+	for _, token := range skipTokens {
+		skip[token] = true
+	}
+	var ids []uint
+	for id, o := range r.orders {
+		if o.Status == from && !skip[o.DestinationTokenSymbol] {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	claimed := make([]domain.Order, 0, len(ids))
+	for _, id := range ids {
+		if err := r.move(id, to, ""); err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, *r.orders[id])
+	}
+	return claimed, nil
+}
+
+func (r *fakeOrderRepo) RefundOrder(ctx context.Context, id uint, reason domain.RefundReason) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.move(id, domain.OrderRefundUserOrder, string(reason)); err != nil {
+		return err
+	}
+	r.orders[id].RefundReason = reason
+	r.orders[id].RetryCount = 0
+	return nil
+}
+
+func (r *fakeOrderRepo) RefundStaleOrders(ctx context.Context, statuses []domain.OrderStatus, createdBefore time.Time, reason domain.RefundReason, limit int) ([]domain.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	refundable := make(map[domain.OrderStatus]bool, len(statuses))
+	for _, status := range statuses {
+		refundable[status] = true
+	}
+	var ids []uint
+	for id, o := range r.orders {
+		if refundable[o.Status] && o.CreatedAt.Before(createdBefore) && o.ExchangeOrderID == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	refunded := make([]domain.Order, 0, len(ids))
+	for _, id := range ids {
+		if err := r.move(id, domain.OrderRefundUserOrder, string(reason)); err != nil {
+			return nil, err
+		}
+		r.orders[id].RefundReason = reason
+		refunded = append(refunded, *r.orders[id])
+	}
+	return refunded, nil
+}
+
+func (r *fakeOrderRepo) ArchiveOrders(ctx context.Context, statuses []domain.OrderStatus, updatedBefore time.Time, limit int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	archivable := make(map[domain.OrderStatus]bool, len(statuses))
+	for _, status := range statuses {
+		archivable[status] = true
+	}
+	n := 0
+	for id, o := range r.orders {
+		if n < limit && archivable[o.Status] && o.UpdatedAt.Before(updatedBefore) {
+			r.archived[id] = o
+			delete(r.orders, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *fakeOrderRepo) RetryOrder(ctx context.Context, id uint, status domain.OrderStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.move(id, status, "retry"); err != nil {
+		return err
+	}
+	r.orders[id].RetryCount++
+	return nil
+}
+
+func (r *fakeOrderRepo) DeadLetterOrder(ctx context.Context, id uint, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.move(id, domain.OrderDeadLetter, reason)
+}
+
+func (r *fakeOrderRepo) ReconcileOrder(ctx context.Context, id uint, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.move(id, domain.OrderAwaitingReconciliation, reason)
+}
+
+func (r *fakeOrderRepo) SetTxHashes(ctx context.Context, id uint, depositTxHash, releaseTxHash *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.orders[id]
+	if !ok {
+		return fmt.Errorf("order %d not found", id)
+	}
+	if depositTxHash != nil {
+		o.DepositTxHash = depositTxHash
+	}
+	if releaseTxHash != nil {
+		o.ReleaseTxHash = releaseTxHash
+	}
+	return nil
+}
+
+func (r *fakeOrderRepo) SetExchangeOrderID(ctx context.Context, id uint, exchangeOrderID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[id].ExchangeOrderID = &exchangeOrderID
+	return nil
+}
+
+func (r *fakeOrderRepo) SetCollectedFee(ctx context.Context, id uint, fee decimal.Decimal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[id].CollectedFee = fee
+	return nil
+}
+
+func (r *fakeOrderRepo) PauseToken(ctx context.Context, p domain.TreasuryPause) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.pauses[p.Token]; ok && current.Needed.GreaterThanOrEqual(p.Needed) {
+		return false, nil
+	}
+	r.pauses[p.Token] = p
+	return true, nil
+}
+
+func (r *fakeOrderRepo) ListTreasuryPauses(ctx context.Context) ([]domain.TreasuryPause, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pauses := make([]domain.TreasuryPause, 0, len(r.pauses))
+	for _, p := range r.pauses {
+		pauses = append(pauses, p)
+	}
+	sort.Slice(pauses, func(i, j int) bool { return pauses[i].Token < pauses[j].Token })
+	return pauses, nil
+}
+
+func (r *fakeOrderRepo) ResumeToken(ctx context.Context, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pauses, token)
+	return nil
+}
+
+// newTestService returns a Service on repo with no chains or exchanges configured
+func newTestService(repo domain.OrderRepository) *Service {
+	l := logger.New("prod")
+	_ = l.SetLevel("disabled")
+	return &Service{
+		orderRepo:       repo,
+		logger:          l,
+		chains:          &ethereum.Chains{},
+		maxConcurrency:  defaultMaxConcurrency,
+		maxOrderRetries: 3,
+		claimBatchSize:  100,
+	}
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestDeductFee(t *testing.T) {
+	tests := []struct {
+		name        string
+		price       string
+		exchangeFee string
+		megaFee     string
+		wantNet     string
+		wantFee     string
+	}{
+		{name: "no fee", price: "100", exchangeFee: "0", megaFee: "0", wantNet: "100", wantFee: "0"},
+		{name: "mega market fee", price: "100", exchangeFee: "0", megaFee: "0.01", wantNet: "99", wantFee: "1"},
+		{name: "both fees", price: "250", exchangeFee: "0.002", megaFee: "0.003", wantNet: "248.75", wantFee: "0.75"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderTreasuryCreditInProgress, MarketID: 2, MegaMarketID: 1,
+				Price: decimal.RequireFromString(tt.price)})
+			svc := newTestService(repo)
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1,
+					ExchangeMarketFeePercentage: decimal.RequireFromString(tt.exchangeFee)}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1, FeePercentage: decimal.RequireFromString(tt.megaFee)}},
+			}
+			order := repo.order(t, 1)
+
+			net, err := svc.deductFee(context.Background(), &order)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the recipient gets the price minus the fees
+			if !net.Equal(decimal.RequireFromString(tt.wantNet)) {
+				t.Errorf("net = %s, want %s", net, tt.wantNet)
+			}
+			if stored := repo.order(t, 1).CollectedFee; !stored.Equal(decimal.RequireFromString(tt.wantFee)) {
+				t.Errorf("stored fee = %s, want %s", stored, tt.wantFee)
+			}
+			if !order.CollectedFee.Equal(decimal.RequireFromString(tt.wantFee)) {
+				t.Errorf("order fee = %s, want %s", order.CollectedFee, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestFeeBreakdown(t *testing.T) {
+	tests := []struct {
+		name        string
+		gross       string
+		exchangeFee string
+		platformFee string
+	}{
+		{name: "no fees", gross: "100", exchangeFee: "0", platformFee: "0"},
+		{name: "platform fee only", gross: "100", exchangeFee: "0", platformFee: "0.01"},
+		{name: "both fees", gross: "65000.5", exchangeFee: "0.002", platformFee: "0.0035"},
+		{name: "tiny gross", gross: "0.000001", exchangeFee: "0.001", platformFee: "0.001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gross := decimal.RequireFromString(tt.gross)
+
+			b := feeBreakdown(gross, "USDT", decimal.RequireFromString(tt.exchangeFee), decimal.RequireFromString(tt.platformFee))
+
+			if b.Currency != "USDT" || !b.Gross.Equal(gross) {
+				t.Errorf("breakdown = %+v", b)
+			}
+			// every line item is accounted for, nothing is taken off the books
+			if items := b.ExchangeFee.Add(b.PlatformFee).Add(b.EstimatedGas); !items.Equal(b.Gross.Sub(b.Net)) {
+				t.Errorf("fees sum to %s, gross - net is %s", items, b.Gross.Sub(b.Net))
+			}
+			if want := gross.Mul(decimal.RequireFromString(tt.platformFee)); !b.PlatformFee.Equal(want) {
+				t.Errorf("platform fee = %s, want %s", b.PlatformFee, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestGetOrderById(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     uint
+		userID string
+		found  bool
+	}{
+		{name: "owner reads own order", id: 1, userID: "alice", found: true},
+		{name: "other user sees nothing", id: 1, userID: "mallory"},
+		{name: "unknown id", id: 2, userID: "alice"},
+		{name: "auth off reads any order", id: 1, userID: "", found: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderPending, UserId: "alice"}))
+			svc.marketAdapter = &fakeMarketAdapter{}
+
+			got, err := svc.GetOrderById(context.Background(), tt.id, tt.userID)
+
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if found := got != nil; found != tt.found {
+				t.Fatalf("found = %v, want %v", found, tt.found)
+			}
+		})
+	}
+}
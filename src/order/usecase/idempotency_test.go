@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// racingRepo misses the key on its first lookup, as when a concurrent retry saves the same
+// key between SubmitOrder's lookup and its insert
+type racingRepo struct {
+	*fakeOrderRepo
+	lookups int
+}
+
+func (r *racingRepo) GetOrderByIdempotencyKey(ctx context.Context, userId, key string) (*domain.Order, error) {
+	r.lookups++
+	if r.lookups == 1 {
+		return nil, nil
+	}
+	return r.fakeOrderRepo.GetOrderByIdempotencyKey(ctx, userId, key)
+}
+
+func TestSubmitOrderIdempotencyKey(t *testing.T) {
+	key := func(k string) *string { return &k }
+	tests := []struct {
+		name       string
+		stored     *domain.Order // submitted earlier
+		racing     bool
+		userID     string
+		key        *string
+		wantID     uint
+		wantOrders int
+	}{
+		{name: "first submission", userID: "alice", key: key("k1"), wantID: 1, wantOrders: 1},
+		{name: "no key", userID: "alice", wantID: 1, wantOrders: 1},
+		{
+			name:   "repeated key returns the first order",
+			stored: &domain.Order{ID: 1, UserId: "alice", IdempotencyKey: key("k1"), Status: domain.OrderUserDebitSuccess},
+			userID: "alice", key: key("k1"), wantID: 1, wantOrders: 1,
+		},
+		{
+			name:   "another key creates an order",
+			stored: &domain.Order{ID: 1, UserId: "alice", IdempotencyKey: key("k1"), Status: domain.OrderPending},
+			userID: "alice", key: key("k2"), wantID: 2, wantOrders: 2,
+		},
+		{
+			// keys are unique per user only
+			name:   "another user's key",
+			stored: &domain.Order{ID: 1, UserId: "bob", IdempotencyKey: key("k1"), Status: domain.OrderPending},
+			userID: "alice", key: key("k1"), wantID: 2, wantOrders: 2,
+		},
+		{
+			name:   "concurrent retry wins the insert",
+			stored: &domain.Order{ID: 1, UserId: "alice", IdempotencyKey: key("k1"), Status: domain.OrderPending},
+			racing: true, userID: "alice", key: key("k1"), wantID: 1, wantOrders: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeOrderRepo()
+			if tt.stored != nil {
+				fake = newFakeOrderRepo(*tt.stored)
+			}
+			var repo domain.OrderRepository = fake
+			if tt.racing {
+				repo = &racingRepo{fakeOrderRepo: fake}
+			}
+			svc := newTestService(repo)
+			svc.chains, _ = newFailingChains(t, fakeNode{decimals: 6})
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1, ExchangeName: "wallex", IsActive: true}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1, IsActive: true,
+					SourceTokenSymbol: "IRT", DestinationTokenSymbol: "USDT"}},
+			}
+			o := &domain.Order{UserId: tt.userID, IdempotencyKey: tt.key, MarketID: 2, Volume: decimal.NewFromInt(1),
+				Price: decimal.NewFromInt(100), IsBuy: true, FromNetwork: testNetwork, ToNetwork: testNetwork,
+				Deadline: time.Now().Add(time.Hour).Unix()}
+			signPermit(t, o, 6)
+
+			got, err := svc.SubmitOrder(context.Background(), o)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil || got.ID != tt.wantID {
+				t.Fatalf("order = %+v, want id %d", got, tt.wantID)
+			}
+			if n := len(fake.orders); n != tt.wantOrders {
+				t.Errorf("%d orders stored, want %d", n, tt.wantOrders)
+			}
+			if tt.stored != nil && got.ID == tt.stored.ID && got.Status != tt.stored.Status {
+				t.Errorf("status = %s, want the first order's %s", got.Status, tt.stored.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestForEachOrderRecoversPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		panicked map[uint]bool
+	}{
+		{name: "no panic"},
+		{name: "one order panics", panicked: map[uint]bool{2: true}},
+		{name: "every order panics", panicked: map[uint]bool{1: true, 2: true, 3: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeOrderRepo())
+			var (
+				mu   sync.Mutex
+				done = make(map[uint]bool)
+			)
+			orders := []domain.Order{{ID: 1}, {ID: 2}, {ID: 3}}
+
+			// a panic must neither crash the test binary nor stop the siblings
+			svc.forEachOrder(orders, func(order domain.Order) {
+				if tt.panicked[order.ID] {
+					var receipt *struct{ Status uint64 }
+					_ = receipt.Status // the nil receipt deref this guards against
+				}
+				mu.Lock()
+				done[order.ID] = true
+				mu.Unlock()
+			})
+
+			for _, o := range orders {
+				if done[o.ID] == tt.panicked[o.ID] {
+					t.Errorf("order %d done = %v, panicked = %v", o.ID, done[o.ID], tt.panicked[o.ID])
+				}
+			}
+			if n := svc.ActiveWorkers(); n != 0 {
+				t.Errorf("%d workers still active", n)
+			}
+		})
+	}
+}
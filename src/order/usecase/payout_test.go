@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPayoutAmount(t *testing.T) {
+	tests := []struct {
+		name     string
+		decimals uint8
+		amount   string
+		want     string
+	}{
+		{name: "exact", decimals: 6, amount: "12.5", want: "12.5"},
+		{name: "dust dropped", decimals: 6, amount: "12.3456789", want: "12.345678"},
+		{name: "never rounded up", decimals: 6, amount: "0.9999999", want: "0.999999"},
+		{name: "below one unit", decimals: 6, amount: "0.0000009", want: "0"},
+		{name: "whole token precision", decimals: 0, amount: "3.99", want: "3"},
+		{name: "eighteen decimals", decimals: 18, amount: "1.0000000000000000019", want: "1.000000000000000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeOrderRepo())
+			svc.chains, _ = newFailingChains(t, fakeNode{decimals: tt.decimals})
+			amount := decimal.RequireFromString(tt.amount)
+
+			got, err := svc.payoutAmount(context.Background(), testNetwork, "USDT", amount)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("payout = %s, want %s", got, tt.want)
+			}
+			if got.GreaterThan(amount) {
+				t.Errorf("payout %s is more than the %s owed", got, amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestPreflightOrder(t *testing.T) {
+	balance := func(v int64) *decimal.Decimal {
+		d := decimal.NewFromInt(v)
+		return &d
+	}
+	tests := []struct {
+		name         string
+		marketID     uint
+		volume       string
+		network      string
+		inactive     bool
+		noMegaMarket bool
+		paused       bool
+		treasury     *decimal.Decimal
+		wantFailures []string // substrings, one per failure
+	}{
+		{name: "every prerequisite met"},
+		{name: "unknown market", marketID: 9, wantFailures: []string{"market 9 not found"}},
+		{name: "inactive exchange market", inactive: true,
+			wantFailures: []string{"market 2 on wallex is not active", "mega market 1 has no active exchange market"}},
+		{name: "below min size", volume: "0.0001", wantFailures: []string{"below market minimum size"}},
+		{name: "mega market inactive", noMegaMarket: true, wantFailures: []string{"mega market 1 is not active"}},
+		{name: "payout token paused", paused: true, wantFailures: []string{"payouts in USDT are paused"}},
+		{name: "unknown network", network: "mumbai", wantFailures: []string{"mumbai", "mumbai"}},
+		{name: "treasury short", treasury: balance(10), wantFailures: []string{"treasury lacks 99 USDT liquidity on sepolia"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo()
+			if tt.paused {
+				repo.pauses["USDT"] = domain.TreasuryPause{Token: "USDT", Network: testNetwork, Needed: decimal.NewFromInt(1)}
+			}
+			svc := newTestService(repo)
+			svc.chains, _ = newFailingChains(t, fakeNode{decimals: 6, balance: tt.treasury})
+			megaMarkets := map[uint]*market_domain.MegaMarket{1: {ID: 1, SourceTokenSymbol: "BTC", DestinationTokenSymbol: "USDT",
+				FeePercentage: decimal.NewFromFloat(0.01)}}
+			if tt.noMegaMarket {
+				megaMarkets = nil
+			}
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1, ExchangeName: "wallex",
+					IsActive: !tt.inactive, MinAmount: decimal.RequireFromString("0.001")}},
+				megaMarkets: megaMarkets,
+			}
+			o := &domain.Order{MarketID: 2, Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), IsBuy: true,
+				FromNetwork: testNetwork, ToNetwork: testNetwork}
+			if tt.marketID != 0 {
+				o.MarketID = tt.marketID
+			}
+			if tt.volume != "" {
+				o.Volume = decimal.RequireFromString(tt.volume)
+			}
+			if tt.network != "" {
+				o.FromNetwork, o.ToNetwork = tt.network, tt.network
+			}
+
+			err := svc.PreflightOrder(context.Background(), o)
+
+			if len(tt.wantFailures) == 0 {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			var preflight *domain.PreflightError
+			if !errors.As(err, &preflight) || !errors.Is(err, domain.ErrInvalidOrder) {
+				t.Fatalf("err = %v, want a preflight error", err)
+			}
+			if len(preflight.Failures) != len(tt.wantFailures) {
+				t.Fatalf("failures = %q, want %q", preflight.Failures, tt.wantFailures)
+			}
+			for i, want := range tt.wantFailures {
+				if !strings.Contains(preflight.Failures[i], want) {
+					t.Errorf("failure %d = %q, want it to mention %q", i, preflight.Failures[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/google/uuid"
+)
+
+// errQuotesDisabled is returned when the service has no quote repository to persist quotes in
+var errQuotesDisabled = errors.New("quotes are not enabled")
+
+// CreateQuote prices req.Volume of a mega market on its best exchange market and saves
+// the quote until it expires, so it can be executed at the quoted terms later. The
+// effective price includes the exchange and platform fees: a buyer pays them on top of
+// the exchange price, a seller has them taken off. A ValidateOnly request is priced the
+// same way but neither saved nor signed.
+func (s *Service) CreateQuote(ctx context.Context, req domain.QuoteRequest) (*domain.Quote, error) {
+	if s.quoteRepo == nil && !req.ValidateOnly {
+		return nil, errQuotesDisabled
+	}
+	best, err := s.marketAdapter.GetBestPrice(ctx, req.MegaMarketID, req.Volume, req.IsBuy)
+	if err != nil {
+		return nil, err
+	}
+
+	megaMarket := best.MegaMarket
+	breakdown := feeBreakdown(best.Price.Mul(req.Volume), megaMarket.DestinationTokenSymbol,
+		best.Market.ExchangeMarketFeePercentage, megaMarket.FeePercentage)
+	fees := breakdown.ExchangeFee.Add(breakdown.PlatformFee).Add(breakdown.EstimatedGas)
+	amountOut := breakdown.Gross.Sub(fees)
+	if req.IsBuy {
+		amountOut = breakdown.Gross.Add(fees)
+	}
+
+	now := time.Now().UTC()
+	q := &domain.Quote{
+		ID:             uuid.NewString(),
+		AmountIn:       req.Volume,
+		AmountOut:      amountOut,
+		ExpiresAt:      now.Add(s.quoteTTL),
+		CreatedAt:      now,
+		MegaMarketID:   megaMarket.ID,
+		MarketID:       best.Market.ID,
+		ExchangeName:   best.Market.ExchangeName,
+		IsBuy:          req.IsBuy,
+		Price:          best.Price,
+		EffectivePrice: amountOut.Div(req.Volume),
+		PriceImpact:    best.PriceImpact,
+		UserID:         req.UserID,
+		FeeBreakdown:   breakdown,
+	}
+	// same token direction as the order the quote would become, see prepareOrder
+	q.FromToken, q.ToToken = megaMarket.DestinationTokenSymbol, megaMarket.SourceTokenSymbol
+	if req.IsBuy {
+		q.FromToken, q.ToToken = megaMarket.SourceTokenSymbol, megaMarket.DestinationTokenSymbol
+	}
+	if req.ValidateOnly {
+		q.ID = ""
+		return q, nil
+	}
+
+	q.Signature = s.signQuote(q)
+	if err := s.quoteRepo.Save(ctx, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// GetQuote resolves a signed quote id from CreateQuote. A forged or unknown id is
+// domain.ErrQuoteNotFound; a quote that expired or was executed can't be used again.
+func (s *Service) GetQuote(ctx context.Context, signedID string) (*domain.Quote, error) {
+	if s.quoteRepo == nil {
+		return nil, errQuotesDisabled
+	}
+	id, sig, ok := strings.Cut(signedID, ".")
+	if !ok {
+		return nil, domain.ErrQuoteNotFound
+	}
+	q, err := s.quoteRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q == nil || !hmac.Equal([]byte(sig), []byte(s.signQuote(q))) {
+		return nil, domain.ErrQuoteNotFound
+	}
+	q.Signature = sig
+	if q.Used {
+		return nil, domain.ErrQuoteUsed
+	}
+	if time.Now().After(q.ExpiresAt) {
+		return nil, domain.ErrQuoteExpired
+	}
+	return q, nil
+}
+
+// signQuote MACs the id together with the terms of q, so neither a guessed id nor a
+// tampered row resolves to a quote
+func (s *Service) signQuote(q *domain.Quote) string {
+	mac := hmac.New(sha256.New, s.quoteKey)
+	fmt.Fprintf(mac, "%s|%d|%d|%t|%s|%s|%d|%s",
+		q.ID, q.MegaMarketID, q.MarketID, q.IsBuy,
+		q.AmountIn.String(), q.EffectivePrice.String(), q.ExpiresAt.Unix(), q.UserID)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// quoteSigningKey returns secret, or a random key when it is empty, in which case quotes
+// don't survive a restart
+func quoteSigningKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("quote signing key: %v", err))
+	}
+	return key
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// fakeQuoteRepo records the quotes saved through it
+type fakeQuoteRepo struct {
+	domain.QuoteRepository
+	saved []*domain.Quote
+}
+
+func (f *fakeQuoteRepo) Save(ctx context.Context, q *domain.Quote) error {
+	f.saved = append(f.saved, q)
+	return nil
+}
+
+func TestCreateQuote(t *testing.T) {
+	tests := []struct {
+		name         string
+		noRepo       bool
+		validateOnly bool
+		wantErr      error
+		wantSaved    int
+	}{
+		{name: "quote is saved", wantSaved: 1},
+		{name: "validate only saves nothing", validateOnly: true},
+		{name: "validate only without quote repo", noRepo: true, validateOnly: true},
+		{name: "quotes disabled", noRepo: true, wantErr: errQuotesDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes := &fakeQuoteRepo{}
+			svc := newTestService(newFakeOrderRepo())
+			svc.quoteTTL, svc.quoteKey = time.Minute, []byte("secret")
+			if !tt.noRepo {
+				svc.quoteRepo = quotes
+			}
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1, ExchangeName: "wallex"}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1,
+					SourceTokenSymbol: "USDT", DestinationTokenSymbol: "IRT"}},
+				price: decimal.NewFromInt(100),
+			}
+
+			q, err := svc.CreateQuote(context.Background(), domain.QuoteRequest{
+				MegaMarketID: 1, Volume: decimal.NewFromInt(2), IsBuy: true, UserID: "alice",
+				ValidateOnly: tt.validateOnly,
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(quotes.saved) != tt.wantSaved {
+				t.Errorf("saved %d quotes, want %d", len(quotes.saved), tt.wantSaved)
+			}
+			if err != nil {
+				return
+			}
+			if !q.AmountOut.Equal(decimal.NewFromInt(200)) {
+				t.Errorf("AmountOut = %s, want 200", q.AmountOut)
+			}
+			if preview := q.ID == "" && q.Signature == ""; preview != tt.validateOnly {
+				t.Errorf("id %q signature %q for validateOnly=%v", q.ID, q.Signature, tt.validateOnly)
+			}
+		})
+	}
+}
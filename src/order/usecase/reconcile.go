@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// errNoExchangeOrderID is the cause recorded when the exchange took an order without an id
+var errNoExchangeOrderID = errors.New("exchange accepted the order without an order id")
+
+// saveTxHash returns an OnSent callback storing the hash of the order's transfer as soon as
+// it is broadcast, so an order whose outcome is unknown can be reconciled by it. The user
+// debit is the deposit hash, treasury payouts and refunds are the release hash.
+func (s *Service) saveTxHash(ctx context.Context, orderID uint, deposit bool) func(common.Hash) {
+	return func(hash common.Hash) {
+		txHash := hash.Hex()
+		var err error
+		if deposit {
+			err = s.orderRepo.SetTxHashes(ctx, orderID, &txHash, nil)
+		} else {
+			err = s.orderRepo.SetTxHashes(ctx, orderID, nil, &txHash)
+		}
+		if err != nil {
+			s.logger.Errorf("SetTxHashes order=%d tx=%s err: %v", orderID, txHash, err)
+		}
+	}
+}
+
+// awaitReconciliation parks an order whose transfer or exchange order was sent but whose
+// outcome is unknown. Failing or retrying it could debit, pay or trade twice.
+func (s *Service) awaitReconciliation(ctx context.Context, order domain.Order, cause error) {
+	log := s.logger.WithContext(ctx).WithFields(map[string]interface{}{
+		"order_id": order.ID,
+		"status":   order.Status,
+		"user_id":  order.UserId,
+		"error":    cause.Error(),
+	})
+	if err := s.orderRepo.ReconcileOrder(ctx, order.ID, cause.Error()); err != nil {
+		log.Errorf("ReconcileOrder order=%d err: %v", order.ID, err)
+		return
+	}
+	log.Errorf("ALERT order %d awaits reconciliation, its transfer outcome is unknown: %v", order.ID, cause)
+}
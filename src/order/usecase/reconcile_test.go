@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSaveTxHash(t *testing.T) {
+	hash := common.HexToHash("0xabc")
+	tests := []struct {
+		name        string
+		deposit     bool
+		wantDeposit bool
+	}{
+		{name: "user debit is the deposit", deposit: true, wantDeposit: true},
+		{name: "payout is the release", deposit: false, wantDeposit: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderUserDebitInProgress})
+			s := newTestService(repo)
+
+			s.saveTxHash(context.Background(), 1, tt.deposit)(hash)
+
+			o := repo.order(t, 1)
+			got, other := o.ReleaseTxHash, o.DepositTxHash
+			if tt.wantDeposit {
+				got, other = o.DepositTxHash, o.ReleaseTxHash
+			}
+			if got == nil || *got != hash.Hex() {
+				t.Errorf("hash = %v, want %s", got, hash.Hex())
+			}
+			if other != nil {
+				t.Errorf("other hash set to %s", *other)
+			}
+		})
+	}
+}
+
+func TestAwaitReconciliation(t *testing.T) {
+	cause := fmt.Errorf("%w: 0xabc not confirmed", ethereum.ErrTxPending)
+	tests := []struct {
+		status domain.OrderStatus
+	}{
+		{domain.OrderUserDebitInProgress},
+		{domain.OrderTreasuryCreditInProgress},
+		{domain.OrderRefundUserOrderInProgress},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			order := domain.Order{ID: 1, Status: tt.status}
+			repo := newFakeOrderRepo(order)
+
+			newTestService(repo).awaitReconciliation(context.Background(), order, cause)
+
+			if got := repo.order(t, 1).Status; got != domain.OrderAwaitingReconciliation {
+				t.Errorf("status = %s, want %s", got, domain.OrderAwaitingReconciliation)
+			}
+			if got := repo.reasons[1]; len(got) != 1 || got[0] != cause.Error() {
+				t.Errorf("reasons = %v, want [%s]", got, cause)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestRefundReasons(t *testing.T) {
+	destination := "0xdest"
+	tests := []struct {
+		name       string
+		status     domain.OrderStatus
+		price      int64 // best exchange price now; the order was placed at 100
+		run        func(s *Service, ctx context.Context) error
+		wantReason domain.RefundReason
+	}{
+		{
+			name:       "price moved past slippage",
+			status:     domain.OrderMarketUserOrderFailed,
+			price:      200,
+			run:        (*Service).FetchFailedMarketUserOrderOrders,
+			wantReason: domain.RefundReasonSlippageExceeded,
+		},
+		{
+			// the test service knows no chain to pay out on
+			name:       "payout impossible",
+			status:     domain.OrderMarketUserOrderSuccess,
+			run:        (*Service).FetchMarketUserOrderSuccessOrders,
+			wantReason: domain.RefundReasonTreasuryCreditFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: tt.status, MarketID: 2, MegaMarketID: 1,
+				Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), SlipagePercentage: decimal.NewFromFloat(0.01),
+				DestinationTokenSymbol: "USDT", DestinationAddress: &destination})
+			svc := newTestService(repo)
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets:     map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1}},
+				megaMarkets: map[uint]*market_domain.MegaMarket{1: {ID: 1}},
+				price:       decimal.NewFromInt(tt.price),
+			}
+
+			if err := tt.run(svc, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := repo.order(t, 1)
+			if got.Status != domain.OrderRefundUserOrder || got.RefundReason != tt.wantReason {
+				t.Errorf("order is %s/%s, want %s/%s", got.Status, got.RefundReason, domain.OrderRefundUserOrder, tt.wantReason)
+			}
+		})
+	}
+}
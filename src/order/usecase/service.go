@@ -5,17 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"runtime/debug"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/MMN3003/mega/src/Infrastructure/ethereum"
+	"github.com/MMN3003/mega/src/Infrastructure/nobitex"
 	"github.com/MMN3003/mega/src/Infrastructure/ompfinex"
 	"github.com/MMN3003/mega/src/Infrastructure/wallex"
 	"github.com/MMN3003/mega/src/config"
 	"github.com/MMN3003/mega/src/logger"
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/metrics"
 	"github.com/MMN3003/mega/src/order/adapter/market"
 	"github.com/MMN3003/mega/src/order/domain"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/shopspring/decimal"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
 var _ domain.OrderUsecase = (*Service)(nil)
@@ -25,35 +33,148 @@ type Service struct {
 	logger         *logger.Logger
 	ompfinexClient *ompfinex.Client
 	wallexClient   *wallex.Client
-	ethereumClient *ethereum.EthereumClient
+	nobitexClient  *nobitex.Client
+	chains         *ethereum.Chains
 	marketAdapter  market.MarketAdapter
+	// venueSlots bounds in-flight market orders per exchange name
+	venueSlots map[string]*semaphore.Weighted
+	// venueSlippage overrides the MegaMarket slippage tolerance per exchange name
+	venueSlippage map[string]decimal.Decimal
+	// maxConcurrency bounds how many orders a cron processor handles at once
+	maxConcurrency  int
+	maxOrderRetries int
+	// claimBatchSize caps how many orders a cron processor claims per tick
+	claimBatchSize int
+	// orderMaxLifetime is how long a debited order may wait for its market order before it is refunded
+	orderMaxLifetime time.Duration
+	// orderArchiveRetention is how long terminal orders stay in the orders table; 0 disables archiving
+	orderArchiveRetention time.Duration
+	// quoteRepo stores the quotes of CreateQuote and is cleaned by ExpireStaleQuotes; nil disables quotes
+	quoteRepo domain.QuoteRepository
+	// quoteTTL is how long a quote can be executed; quoteKey signs quote ids
+	quoteTTL time.Duration
+	quoteKey []byte
+	// activeWorkers counts orders being processed right now, reported on shutdown
+	activeWorkers atomic.Int64
 }
 
-func NewService(o domain.OrderRepository, logg *logger.Logger, cfg *config.Config, ethereumClient *ethereum.EthereumClient) *Service {
-	ompfinexClient, _ := ompfinex.NewClient(cfg.OMP.BaseURL,
+// defaultMaxConcurrency is used when no WithMaxConcurrency option is given
+const defaultMaxConcurrency = 8
+
+// Option configures optional Service behaviour
+type Option func(*Service)
+
+// WithMaxConcurrency limits the number of orders processed concurrently by each cron processor
+func WithMaxConcurrency(n int) Option {
+	return func(s *Service) {
+		if n > 0 {
+			s.maxConcurrency = n
+		}
+	}
+}
+
+// WithQuoteRepository enables CreateQuote and lets ExpireStaleQuotes clean up the quotes table
+func WithQuoteRepository(q domain.QuoteRepository) Option {
+	return func(s *Service) { s.quoteRepo = q }
+}
+
+func NewService(o domain.OrderRepository, logg *logger.Logger, cfg *config.Config, chains *ethereum.Chains, opts ...Option) (*Service, error) {
+	ompfinexClient, err := ompfinex.NewClient(cfg.OMP.BaseURL,
 		ompfinex.WithAuthToken(cfg.OMP.Token),
+		ompfinex.WithHTTPClient(cfg.OMP.HTTP.Client()),
+		ompfinex.WithStrictDecode(cfg.OMP.StrictDecode),
+		ompfinex.WithResponseLogging(cfg.OMP.LogResponses),
 	)
-	wallexClient, _ := wallex.NewClient(cfg.Wallex.BaseURL,
+	if err != nil {
+		return nil, fmt.Errorf("ompfinex client: %w", err)
+	}
+	wallexClient, err := wallex.NewClient(cfg.Wallex.BaseURL,
 		wallex.WithAPIKey(cfg.Wallex.APIKey),
+		wallex.WithHTTPClient(cfg.Wallex.HTTP.Client()),
+		wallex.WithStrictDecode(cfg.Wallex.StrictDecode),
+		wallex.WithResponseLogging(cfg.Wallex.LogResponses),
 	)
+	if err != nil {
+		return nil, fmt.Errorf("wallex client: %w", err)
+	}
+	nobitexClient, err := nobitex.NewClient(cfg.Nobitex.BaseURL,
+		nobitex.WithAuthToken(cfg.Nobitex.Token),
+		nobitex.WithHTTPClient(cfg.Nobitex.HTTP.Client()),
+		nobitex.WithStrictDecode(cfg.Nobitex.StrictDecode),
+		nobitex.WithResponseLogging(cfg.Nobitex.LogResponses),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("nobitex client: %w", err)
+	}
 	s := &Service{
 		orderRepo:      o,
 		logger:         logg,
 		ompfinexClient: ompfinexClient,
 		wallexClient:   wallexClient,
-		ethereumClient: ethereumClient,
+		nobitexClient:  nobitexClient,
+		chains:         chains,
+		venueSlots: map[string]*semaphore.Weighted{
+			"ompfinex": semaphore.NewWeighted(int64(cfg.OMP.MaxConcurrentOrders)),
+			"wallex":   semaphore.NewWeighted(int64(cfg.Wallex.MaxConcurrentOrders)),
+			"nobitex":  semaphore.NewWeighted(int64(cfg.Nobitex.MaxConcurrentOrders)),
+		},
+		venueSlippage: map[string]decimal.Decimal{
+			"ompfinex": cfg.OMP.SlippagePercentage,
+			"wallex":   cfg.Wallex.SlippagePercentage,
+			"nobitex":  cfg.Nobitex.SlippagePercentage,
+		},
+		maxConcurrency:   defaultMaxConcurrency,
+		maxOrderRetries:  cfg.MaxOrderRetries,
+		claimBatchSize:   cfg.OrderClaimBatchSize,
+		orderMaxLifetime: cfg.OrderMaxLifetime,
+		quoteTTL:         cfg.QuoteTTL,
+		quoteKey:         quoteSigningKey(cfg.QuoteSigningSecret),
 	}
-	return s
+	if cfg.OrderArchiveEnabled {
+		s.orderArchiveRetention = cfg.OrderArchiveRetention
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
 }
 func (s *Service) SetAdapters(ctx context.Context, marketAdapter market.MarketAdapter) error {
 	s.marketAdapter = marketAdapter
 	return nil
 }
 func (s *Service) PlaceMarketOrder(ctx context.Context, marketId uint, volume decimal.Decimal, isBuy bool) (string, error) {
+	return s.PlaceExchangeOrder(ctx, marketId, volume, isBuy, nil)
+}
+
+// PlaceExchangeOrder places the order on the market's exchange, as the exchange-native
+// order described by params or as a market order when params is nil.
+func (s *Service) PlaceExchangeOrder(ctx context.Context, marketId uint, volume decimal.Decimal, isBuy bool, params *domain.ExchangeOrderParams) (string, error) {
 	market, err := s.marketAdapter.GetMarketByID(ctx, marketId)
 	if err != nil {
 		return "", err
 	}
+	if market == nil {
+		return "", fmt.Errorf("market %d not found", marketId)
+	}
+	if params != nil {
+		if err := checkVenueSupport(market.ExchangeName, params); err != nil {
+			return "", err
+		}
+		if params.Type == domain.ExchangeOrderMarket {
+			params = nil
+		}
+	}
+	// exchanges reject amounts with more decimals than the market allows
+	volume, err = market.RoundAmount(volume)
+	if err != nil {
+		return "", err
+	}
+	release, err := s.acquireVenueSlot(ctx, market.ExchangeName)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
 	switch market.ExchangeName {
 	case "ompfinex":
 		marketId, _ := strconv.ParseInt(market.ExchangeMarketIdentifier, 10, 64)
@@ -61,13 +182,22 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, marketId uint, volume de
 		if isBuy {
 			side = ompfinex.SideBuy
 		}
-		order, err := s.ompfinexClient.PlaceOrder(ctx, ompfinex.PlaceOrderRequest{
+		req := ompfinex.PlaceOrderRequest{
 			MarketID: marketId,
 			Side:     side,
 			Type:     ompfinex.OrderMarket,
 			Price:    nil,
 			Amount:   volume,
-		})
+		}
+		if params != nil {
+			req.Type = ompfinex.OrderLimit
+			if params.Type == domain.ExchangeOrderStopLimit {
+				req.Type = ompfinex.OrderStopLimit
+			}
+			req.Price = params.Price
+			req.StopPrice = params.StopPrice
+		}
+		order, err := s.ompfinexClient.PlaceOrder(ctx, req)
 		if err != nil {
 			return "", err
 		}
@@ -77,20 +207,144 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, marketId uint, volume de
 		if isBuy {
 			side = wallex.OrderSideBuy
 		}
+		if params != nil {
+			// the OTC endpoint only takes market orders, the rest go to the spot book
+			order, err := s.wallexClient.PlaceOrder(ctx, wallex.PlaceOrderRequest{
+				Symbol:      market.ExchangeMarketIdentifier,
+				Type:        wallex.OrderTypeLimit,
+				Side:        side,
+				Price:       params.Price,
+				Quantity:    volume,
+				TimeInForce: wallex.TimeInForce(timeInForce(params)),
+				PostOnly:    params.PostOnly,
+			})
+			if err != nil {
+				return "", err
+			}
+			return order.ClientOrderID, nil
+		}
 		order, err := s.wallexClient.PlaceMarketOrder(ctx, market.ExchangeMarketIdentifier, side, volume)
 		if err != nil {
 			return "", err
 		}
 		return order.ClientOrderID, nil
+	case "nobitex":
+		side := nobitex.OrderSideSell
+		if isBuy {
+			side = nobitex.OrderSideBuy
+		}
+		order, err := s.nobitexClient.PlaceMarketOrder(ctx, market.ExchangeMarketIdentifier, side, volume)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(order.ID, 10), nil
 	default:
 		return "", errors.New("unsupported exchange")
 	}
 }
+
+// slippageFor returns the exchange's slippage override, or fallback when it has none
+func (s *Service) slippageFor(exchangeName string, fallback decimal.Decimal) decimal.Decimal {
+	if override, ok := s.venueSlippage[exchangeName]; ok && override.IsPositive() {
+		return override
+	}
+	return fallback
+}
+
+// acquireVenueSlot blocks until the exchange has a free order slot or ctx is done.
+func (s *Service) acquireVenueSlot(ctx context.Context, exchangeName string) (func(), error) {
+	slots, ok := s.venueSlots[exchangeName]
+	if !ok {
+		return func() {}, nil
+	}
+	if err := slots.Acquire(ctx, 1); err != nil {
+		return nil, fmt.Errorf("wait for %s order slot: %w", exchangeName, err)
+	}
+	return func() { slots.Release(1) }, nil
+}
+
+// SubmitOrder validates and saves o. An order with an IdempotencyKey the user already
+// submitted is not created again: the order of the first submission is returned instead.
 func (s *Service) SubmitOrder(ctx context.Context, o *domain.Order) (*domain.Order, error) {
+	if o.IdempotencyKey != nil {
+		existing, err := s.orderRepo.GetOrderByIdempotencyKey(ctx, o.UserId, *o.IdempotencyKey)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+	breakdown, err := s.prepareOrder(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	order, err := s.orderRepo.SaveOrder(ctx, o)
+	if err != nil && o.IdempotencyKey != nil {
+		// a concurrent retry with the same key won the unique index
+		if existing, lookupErr := s.orderRepo.GetOrderByIdempotencyKey(ctx, o.UserId, *o.IdempotencyKey); lookupErr == nil && existing != nil {
+			return existing, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	order.FeeBreakdown = breakdown
+	return order, nil
+}
+
+// SubmitOrders validates every order like SubmitOrder and saves the valid ones in a single
+// transaction. Results follow the order of orders. An invalid order only fails its own
+// result, unless atomic is set, in which case nothing is saved and the valid orders fail
+// with domain.ErrBulkAborted. Errors other than invalid orders fail the whole call.
+func (s *Service) SubmitOrders(ctx context.Context, orders []*domain.Order, atomic bool) ([]domain.BulkOrderResult, error) {
+	results := make([]domain.BulkOrderResult, len(orders))
+	breakdowns := make([]*domain.FeeBreakdown, len(orders))
+	var valid []int
+	for i, o := range orders {
+		breakdown, err := s.prepareOrder(ctx, o)
+		if errors.Is(err, domain.ErrInvalidOrder) {
+			results[i].Err = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		breakdowns[i] = breakdown
+		valid = append(valid, i)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+	if atomic && len(valid) < len(orders) {
+		for _, i := range valid {
+			results[i].Err = domain.ErrBulkAborted
+		}
+		return results, nil
+	}
+
+	toSave := make([]*domain.Order, len(valid))
+	for j, i := range valid {
+		toSave[j] = orders[i]
+	}
+	saved, err := s.orderRepo.SaveOrders(ctx, toSave)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range valid {
+		saved[j].FeeBreakdown = breakdowns[i]
+		results[i].Order = saved[j]
+	}
+	return results, nil
+}
+
+// prepareOrder runs every check an order must pass before it is saved and fills in what
+// its markets decide, returning its fee breakdown.
+func (s *Service) prepareOrder(ctx context.Context, o *domain.Order) (*domain.FeeBreakdown, error) {
 	market, err := s.marketAdapter.GetMarketByID(ctx, o.MarketID)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.PreflightOrder(ctx, o); err != nil {
+		return nil, err
+	}
 	megaMarket, err := s.marketAdapter.GetMegaMarketByID(ctx, market.MegaMarketID)
 	if err != nil {
 		return nil, err
@@ -107,219 +361,611 @@ func (s *Service) SubmitOrder(ctx context.Context, o *domain.Order) (*domain.Ord
 			megaMarket.DestinationTokenSymbol, megaMarket.SourceTokenSymbol
 	}
 
-	order, err := s.orderRepo.SaveOrder(ctx, o)
-	if err != nil {
+	// a permit that doesn't recover to the user would only revert on-chain
+	if err := s.verifyPermit(ctx, o); err != nil {
 		return nil, err
 	}
-	return order, nil
+	return feeBreakdown(o.Price, o.DestinationTokenSymbol,
+		market.ExchangeMarketFeePercentage, megaMarket.FeePercentage), nil
 }
 
-func (s *Service) FetchPendingOrders(ctx context.Context) error {
-	orders, err := s.orderRepo.GetOrdersByStatus(ctx, domain.OrderPending)
+// PreflightOrder checks everything the order needs downstream — an active mega market
+// with an active exchange market, limits the exchange accepts and treasury liquidity for
+// the payout — so an order bound to be refunded is rejected up front. Every failed
+// prerequisite is reported in a *domain.PreflightError.
+func (s *Service) PreflightOrder(ctx context.Context, o *domain.Order) error {
+	market, err := s.marketAdapter.GetMarketByID(ctx, o.MarketID)
 	if err != nil {
 		return err
 	}
-	ids := make([]uint, len(orders))
-	for i, o := range orders {
-		s.logger.Infof("Order %d is pending", o.ID)
-		ids[i] = o.ID
+	if market == nil {
+		return &domain.PreflightError{Failures: []string{fmt.Sprintf("market %d not found", o.MarketID)}}
+	}
+
+	var failures []string
+	if !market.IsActive {
+		failures = append(failures, fmt.Sprintf("market %d on %s is not active", market.ID, market.ExchangeName))
+	}
+	if err := s.ValidateOrder(market, o); err != nil {
+		failures = append(failures, err.Error())
 	}
-	err = s.orderRepo.ChangeStatusByIds(ctx, ids, domain.OrderUserDebitInProgress)
+
+	megaMarket, err := s.marketAdapter.GetMegaMarketByID(ctx, market.MegaMarketID)
 	if err != nil {
 		return err
 	}
-	for _, o := range orders {
-		order := o
-		go func(order domain.Order) {
-			s.logger.Infof("Order %d is pending", order.ID)
-			receipt, err := s.ethereumClient.ExecuteTradeWithPermit(ctx, ethereum.Params{
-				TokenAddress: common.HexToAddress(order.TokenAddress),
-				Amount:       order.Volume.BigInt(),
-				Deadline:     big.NewInt(order.Deadline),
-				QuoteID:      fmt.Sprintf("%d", order.ID),
-				UserAddress:  common.HexToAddress(order.UserAddress),
-				Signature: struct {
-					V uint8
-					R common.Hash
-					S common.Hash
-				}{
-					V: order.Signature.V,
-					R: order.Signature.R,
-					S: order.Signature.S,
-				},
-			})
-			if err != nil {
-				s.logger.Errorf("ExecuteTradeWithPermit err: %v", err)
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderFailedUserDebit)
-			}
+	if megaMarket == nil {
+		failures = append(failures, fmt.Sprintf("mega market %d is not active", market.MegaMarketID))
+		return &domain.PreflightError{Failures: failures}
+	}
 
-			if receipt.Status == 1 {
-				// TODO: store receipt
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderUserDebitSuccess)
-			}
-			if err != nil {
-				s.logger.Errorf("ChangeStatusByIds err: %v", err)
-			}
-		}(order)
+	venues, err := s.marketAdapter.GetMarketsByMegaMarketID(ctx, megaMarket.ID)
+	if err != nil {
+		return err
+	}
+	hasActiveVenue := false
+	for _, v := range venues {
+		if v.IsActive {
+			hasActiveVenue = true
+			break
+		}
+	}
+	if !hasActiveVenue {
+		failures = append(failures, fmt.Sprintf("mega market %d has no active exchange market", megaMarket.ID))
 	}
 
+	payoutToken := megaMarket.DestinationTokenSymbol
+	if !o.IsBuy {
+		payoutToken = megaMarket.SourceTokenSymbol
+	}
+	payout := feeBreakdown(o.Price, payoutToken, market.ExchangeMarketFeePercentage, megaMarket.FeePercentage).Net
+	if s.isTokenPaused(ctx, payoutToken) {
+		failures = append(failures, fmt.Sprintf("payouts in %s are paused until the treasury is topped up", payoutToken))
+	}
+	if _, err := s.chains.ClientFor(o.FromNetwork); err != nil {
+		failures = append(failures, err.Error())
+	}
+	if _, err := s.chains.ClientFor(o.ToNetwork); err != nil {
+		failures = append(failures, err.Error())
+		return &domain.PreflightError{Failures: failures}
+	}
+	enough, err := s.hasTreasuryLiquidity(ctx, o.ToNetwork, payoutToken, payout)
+	if err != nil {
+		return err
+	}
+	if !enough {
+		failures = append(failures, fmt.Sprintf("treasury lacks %s %s liquidity on %s", payout, payoutToken, o.ToNetwork))
+	}
+
+	if len(failures) > 0 {
+		return &domain.PreflightError{Failures: failures}
+	}
 	return nil
 }
-func (s *Service) FetchSuccessDebitOrders(ctx context.Context) error {
-	orders, err := s.orderRepo.GetOrdersByStatus(ctx, domain.OrderUserDebitSuccess)
+
+// verifyPermit checks the order's permit signature against the source chain before it is
+// accepted.
+func (s *Service) verifyPermit(ctx context.Context, o *domain.Order) error {
+	client, err := s.chains.ClientFor(o.FromNetwork)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidOrder, err)
+	}
+	amount, err := client.ToUnits(ctx, o.SourceTokenSymbol, o.Volume)
 	if err != nil {
 		return err
 	}
-	ids := make([]uint, len(orders))
-	for i, o := range orders {
-		s.logger.Infof("Order %d is pending", o.ID)
-		ids[i] = o.ID
+	err = client.VerifyPermitSignature(ctx, permitParams(o, amount))
+	if errors.Is(err, ethereum.ErrInvalidSignature) {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidOrder, err)
+	}
+	return err
+}
+
+// permitParams builds the executeTradeWithPermit call of an order debiting amount
+func permitParams(o *domain.Order, amount *big.Int) ethereum.Params {
+	return ethereum.Params{
+		TokenAddress: common.HexToAddress(o.TokenAddress),
+		Amount:       amount,
+		Deadline:     big.NewInt(o.Deadline),
+		QuoteID:      fmt.Sprintf("%d", o.ID),
+		UserAddress:  common.HexToAddress(o.UserAddress),
+		Signature: struct {
+			V uint8
+			R common.Hash
+			S common.Hash
+		}{
+			V: o.Signature.V,
+			R: o.Signature.R,
+			S: o.Signature.S,
+		},
 	}
-	err = s.orderRepo.ChangeStatusByIds(ctx, ids, domain.OrderMarketUserOrderInProgress)
+}
+
+// hasTreasuryLiquidity reports whether the treasury on network can pay amount of the token
+func (s *Service) hasTreasuryLiquidity(ctx context.Context, network, tokenSymbol string, amount decimal.Decimal) (bool, error) {
+	client, err := s.chains.ClientFor(network)
+	if err != nil {
+		return false, err
+	}
+	decimals, err := client.TokenDecimals(ctx, tokenSymbol)
+	if err != nil {
+		return false, err
+	}
+	balance, err := client.TreasuryBalance(ctx, tokenSymbol)
+	if err != nil {
+		return false, err
+	}
+	return decimal.NewFromBigInt(balance, -int32(decimals)).GreaterThanOrEqual(amount), nil
+}
+
+// ValidateOrder rejects an order the exchange would refuse, before it is persisted.
+func (s *Service) ValidateOrder(market *market_domain.Market, o *domain.Order) error {
+	if market == nil {
+		return fmt.Errorf("%w: market %d not found", domain.ErrInvalidOrder, o.MarketID)
+	}
+	if !o.Volume.IsPositive() {
+		return fmt.Errorf("%w: volume must be positive", domain.ErrInvalidOrder)
+	}
+	if err := market.ValidateOrder(o.Volume, o.Price); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidOrder, err)
+	}
+	if o.ExchangeOrderParams != nil {
+		if err := checkVenueSupport(market.ExchangeName, o.ExchangeOrderParams); err != nil {
+			if errors.Is(err, domain.ErrInvalidOrder) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", domain.ErrInvalidOrder, err)
+		}
+	}
+	return nil
+}
+
+// CancelOrder cancels an order of userID that has not been debited yet. Another user's
+// order is reported as not found, so ids can't be probed. An empty userID, when
+// authentication is off, may cancel any order.
+func (s *Service) CancelOrder(ctx context.Context, id uint, userID string) (*domain.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || (userID != "" && order.UserId != userID) {
+		return nil, domain.ErrOrderNotFound
+	}
+	if !domain.CanTransition(order.Status, domain.OrderCancelled) {
+		return nil, fmt.Errorf("%w: order %d is %s", domain.ErrOrderNotCancellable, id, order.Status)
+	}
+	// the repo re-checks under a row lock, so a cron claiming the order meanwhile wins
+	if err := s.orderRepo.ChangeStatusByIds(ctx, []uint{id}, domain.OrderCancelled); err != nil {
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			return nil, fmt.Errorf("%w: %v", domain.ErrOrderNotCancellable, err)
+		}
+		return nil, err
+	}
+	return s.orderRepo.GetOrderByID(ctx, id)
+}
+
+func (s *Service) FetchPendingOrders(ctx context.Context) error {
+	orders, err := s.orderRepo.ClaimOrders(ctx, domain.OrderPending, domain.OrderUserDebitInProgress, s.claimBatchSize, s.pausedTokens(ctx))
 	if err != nil {
 		return err
 	}
-	for _, o := range orders {
-		order := o
-		go func(order domain.Order) {
-			s.logger.Infof("Order %d is pending", order.ID)
-			exchangeOrderId, err := s.PlaceMarketOrder(ctx, order.MarketID, order.Volume, order.IsBuy)
-			if err != nil {
-				s.logger.Errorf("PlaceMarketOrder err: %v", err)
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderMarketUserOrderFailed)
+	s.forEachOrder(orders, func(order domain.Order) {
+		s.logger.Infof("Order %d is pending", order.ID)
+		// the permit would revert on-chain, don't spend gas on it
+		if order.Deadline <= time.Now().Unix() {
+			s.logger.Infof("Order %d deadline %d passed, expiring", order.ID, order.Deadline)
+			if err := s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderExpired); err != nil {
+				s.logger.Errorf("ChangeStatusByIds err: %v", err)
+			}
+			return
+		}
+		// the debit is the point of no return, so the payout must still be covered
+		if s.parkIfTreasuryShort(ctx, &order) {
+			return
+		}
+		client, err := s.chains.ClientFor(order.FromNetwork)
+		if err != nil {
+			s.logger.Errorf("ClientFor order=%d err: %v", order.ID, err)
+			if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderFailedUserDebit); err != nil {
+				s.logger.Errorf("ChangeStatusByIds err: %v", err)
 			}
-			if exchangeOrderId != "" {
-				// store exchange order id
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderMarketUserOrderSuccess)
+			return
+		}
+		amount, err := client.ToUnits(ctx, order.SourceTokenSymbol, order.Volume)
+		if err != nil {
+			s.logger.Errorf("ToUnits order=%d err: %v", order.ID, err)
+			if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderFailedUserDebit); err != nil {
+				s.logger.Errorf("ChangeStatusByIds err: %v", err)
 			}
-			if err != nil {
+			return
+		}
+		params := permitParams(&order, amount)
+		params.OnSent = s.saveTxHash(ctx, order.ID, true)
+		receipt, err := client.ExecuteTradeWithPermit(ctx, params)
+		if errors.Is(err, ethereum.ErrTxPending) {
+			s.logger.Errorf("ExecuteTradeWithPermit err: %v", err)
+			s.awaitReconciliation(ctx, order, err)
+			return
+		}
+		if err != nil {
+			s.logger.Errorf("ExecuteTradeWithPermit err: %v", err)
+			if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderFailedUserDebit); err != nil {
 				s.logger.Errorf("ChangeStatusByIds err: %v", err)
 			}
-		}(order)
-	}
+			return
+		}
+
+		if receipt != nil && receipt.Status == 1 {
+			err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderUserDebitSuccess)
+		}
+		if err != nil {
+			s.logger.Errorf("ChangeStatusByIds err: %v", err)
+		}
+	})
 
 	return nil
 }
-func (s *Service) FetchMarketUserOrderSuccessOrders(ctx context.Context) error {
-	orders, err := s.orderRepo.GetOrdersByStatus(ctx, domain.OrderMarketUserOrderSuccess)
+func (s *Service) FetchSuccessDebitOrders(ctx context.Context) error {
+	orders, err := s.orderRepo.ClaimOrders(ctx, domain.OrderUserDebitSuccess, domain.OrderMarketUserOrderInProgress, s.claimBatchSize, s.pausedTokens(ctx))
 	if err != nil {
 		return err
 	}
-	ids := make([]uint, len(orders))
-	for i, o := range orders {
-		s.logger.Infof("Order %d is pending", o.ID)
-		ids[i] = o.ID
-	}
-	err = s.orderRepo.ChangeStatusByIds(ctx, ids, domain.OrderTreasuryCreditInProgress)
+	s.forEachOrder(orders, func(order domain.Order) {
+		s.logger.Infof("Order %d is pending", order.ID)
+		exchangeOrderId, err := s.PlaceExchangeOrder(ctx, order.MarketID, order.Volume, order.IsBuy, order.ExchangeOrderParams)
+		if err != nil {
+			s.logger.Errorf("PlaceExchangeOrder err: %v", err)
+			if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderMarketUserOrderFailed); err != nil {
+				s.logger.Errorf("ChangeStatusByIds err: %v", err)
+			}
+			return
+		}
+		if exchangeOrderId == "" {
+			// the order went through but can't be traced: retrying could trade twice
+			s.awaitReconciliation(ctx, order, errNoExchangeOrderID)
+			return
+		}
+		if err = s.orderRepo.SetExchangeOrderID(ctx, order.ID, exchangeOrderId); err != nil {
+			s.logger.Errorf("SetExchangeOrderID order=%d exchangeOrderId=%s err: %v", order.ID, exchangeOrderId, err)
+		}
+		if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderMarketUserOrderSuccess); err != nil {
+			s.logger.Errorf("ChangeStatusByIds err: %v", err)
+		}
+	})
+
+	return nil
+}
+func (s *Service) FetchMarketUserOrderSuccessOrders(ctx context.Context) error {
+	s.resumeToppedUpTokens(ctx)
+	orders, err := s.orderRepo.ClaimOrders(ctx, domain.OrderMarketUserOrderSuccess, domain.OrderTreasuryCreditInProgress, s.claimBatchSize, s.pausedTokens(ctx))
 	if err != nil {
 		return err
 	}
-	for _, o := range orders {
-		order := o
-		go func(order domain.Order) {
-			s.logger.Infof("Order %d is pending", order.ID)
-			//TODO: minus our fee from destination price
-			receipt, err := s.ethereumClient.WithdrawTreasury(ctx, ethereum.WithdrawTreasuryParams{
-				RecipientAddress: *order.DestinationAddress,
-				Amount:           order.Price.String(),
-				TokenSymbol:      order.DestinationTokenSymbol,
-			})
-			if err != nil {
-				// store reciept log
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderRefundUserOrder)
+	s.forEachOrder(orders, func(order domain.Order) {
+		s.logger.Infof("Order %d is pending", order.ID)
+		net, err := s.deductFee(ctx, &order)
+		if err != nil {
+			s.logger.Errorf("deductFee order=%d err: %v", order.ID, err)
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonTreasuryCreditFailed); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
+			}
+			return
+		}
+		amount, err := s.payoutAmount(ctx, order.ToNetwork, order.DestinationTokenSymbol, net)
+		if err != nil {
+			s.logger.Errorf("payoutAmount order=%d err: %v", order.ID, err)
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonTreasuryCreditFailed); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
 			}
-			if receipt.Status == 1 {
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderCompleted)
+			return
+		}
+		// a transfer the treasury can't cover would only revert and burn gas
+		enough, err := s.hasTreasuryLiquidity(ctx, order.ToNetwork, order.DestinationTokenSymbol, net)
+		if err != nil {
+			s.logger.Errorf("treasury balance order=%d err: %v", order.ID, err)
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonTreasuryCreditFailed); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
 			}
-			if err != nil {
+			return
+		}
+		if !enough {
+			// refunding would repeat for every order of the token, park them until a top-up instead
+			s.pauseToken(ctx, order.ToNetwork, order.DestinationTokenSymbol, net)
+			if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderMarketUserOrderSuccess); err != nil {
 				s.logger.Errorf("ChangeStatusByIds err: %v", err)
 			}
-		}(order)
-	}
+			return
+		}
+		client, err := s.chains.ClientFor(order.ToNetwork)
+		if err != nil {
+			s.logger.Errorf("ClientFor order=%d err: %v", order.ID, err)
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonTreasuryCreditFailed); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
+			}
+			return
+		}
+		receipt, err := client.WithdrawTreasury(ctx, ethereum.WithdrawTreasuryParams{
+			RecipientAddress: *order.DestinationAddress,
+			Amount:           amount,
+			TokenSymbol:      order.DestinationTokenSymbol,
+			OnSent:           s.saveTxHash(ctx, order.ID, false),
+		})
+		if errors.Is(err, ethereum.ErrTxPending) {
+			// the payout may have landed, a refund could pay the user twice
+			s.logger.Errorf("WithdrawTreasury err: %v", err)
+			s.awaitReconciliation(ctx, order, err)
+			return
+		}
+		if err != nil {
+			// store reciept log
+			s.logger.Errorf("WithdrawTreasury err: %v", err)
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonTreasuryCreditFailed); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
+			}
+			return
+		}
+		if receipt == nil || receipt.Status != 1 {
+			// a reverted payout moved nothing, refund the user instead
+			s.logger.Errorf("WithdrawTreasury order=%d reverted", order.ID)
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonTreasuryCreditFailed); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
+			}
+			return
+		}
+		if err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderCompleted); err != nil {
+			s.logger.Errorf("ChangeStatusByIds err: %v", err)
+		}
+	})
 
 	return nil
 }
 func (s *Service) FetchFailedMarketUserOrderOrders(ctx context.Context) error {
-	orders, err := s.orderRepo.GetOrdersByStatus(ctx, domain.OrderMarketUserOrderFailed)
+	orders, err := s.orderRepo.ClaimOrders(ctx, domain.OrderMarketUserOrderFailed, domain.OrderMarketUserOrderInProgress, s.claimBatchSize, nil)
 	if err != nil {
 		return err
 	}
-	ids := make([]uint, len(orders))
-	for i, o := range orders {
-		s.logger.Infof("Order %d is pending", o.ID)
-		ids[i] = o.ID
-	}
-	err = s.orderRepo.ChangeStatusByIds(ctx, ids, domain.OrderMarketUserOrderInProgress)
+	s.forEachOrder(orders, func(order domain.Order) {
+		s.logger.Infof("Order %d is pending", order.ID)
+		price, market, _, err := s.marketAdapter.GetBestExchangePriceByVolume(ctx, order.MegaMarketID, order.Volume, order.IsBuy)
+
+		if err != nil {
+			s.logger.Errorf("GetBestExchangePriceByVolume err: %v", err)
+			// back to failed for the next run, returning here would strand it in progress
+			s.retryOrRefund(ctx, order, domain.OrderMarketUserOrderFailed, err)
+			return
+		}
+		slippage := order.SlipagePercentage
+		if market != nil {
+			slippage = s.slippageFor(market.ExchangeName, slippage)
+		}
+		//  check slipage if slipage fail return the user money
+		if price.GreaterThan(order.Price.Add(order.Price.Mul(slippage))) {
+			if err = s.orderRepo.RefundOrder(ctx, order.ID, domain.RefundReasonSlippageExceeded); err != nil {
+				s.logger.Errorf("RefundOrder err: %v", err)
+			}
+			return
+		}
+		s.retryOrRefund(ctx, order, domain.OrderUserDebitSuccess, errMarketOrderFailed) // try again
+	})
+
+	return nil
+}
+
+// RefundStaleOrders refunds debited orders that outlived the max order lifetime before
+// their market order went through, instead of leaving them stuck.
+func (s *Service) RefundStaleOrders(ctx context.Context) error {
+	orders, err := s.orderRepo.RefundStaleOrders(ctx, domain.StaleRefundableStatuses,
+		time.Now().Add(-s.orderMaxLifetime), domain.RefundReasonMaxLifetimeExceeded, s.claimBatchSize)
 	if err != nil {
 		return err
 	}
-	for _, o := range orders {
-		order := o
-		go func(order domain.Order) {
-			s.logger.Infof("Order %d is pending", order.ID)
-			price, _, _, err := s.marketAdapter.GetBestExchangePriceByVolume(ctx, order.MegaMarketID, order.Volume, order.IsBuy)
-
-			if err != nil {
-				s.logger.Errorf("GetBestExchangePriceByVolume err: %v", err)
-				return
-			}
-			//  check slipage if slipage fail return the user money
-			if price.GreaterThan(order.Price.Add(order.Price.Mul(order.SlipagePercentage))) {
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderRefundUserOrder)
-			} else {
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderUserDebitSuccess) // try again
-			}
+	for _, order := range orders {
+		s.logger.Errorf("order %d exceeded max lifetime %s, refunding", order.ID, s.orderMaxLifetime)
+	}
+	return nil
+}
 
-			if err != nil {
-				s.logger.Errorf("ChangeStatusByIds err: %v", err)
-			}
-		}(order)
+// ArchiveOrders moves terminal orders past the retention period out of the orders table,
+// keeping it small. Recent and in-flight orders stay where they are.
+func (s *Service) ArchiveOrders(ctx context.Context) error {
+	if s.orderArchiveRetention == 0 {
+		return nil
+	}
+	n, err := s.orderRepo.ArchiveOrders(ctx, domain.ArchivableStatuses,
+		time.Now().Add(-s.orderArchiveRetention), s.claimBatchSize)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.Infof("archived %d orders older than %s", n, s.orderArchiveRetention)
 	}
+	return nil
+}
 
+// ExpireStaleQuotes deletes quotes that expired without being used, so they don't pile up.
+func (s *Service) ExpireStaleQuotes(ctx context.Context) error {
+	if s.quoteRepo == nil {
+		return nil
+	}
+	n, err := s.quoteRepo.ExpireStale(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	s.logger.Infof("expired %d stale quotes", n)
 	return nil
 }
 
 func (s *Service) FetchReturnUserOrders(ctx context.Context) error {
-	orders, err := s.orderRepo.GetOrdersByStatus(ctx, domain.OrderRefundUserOrder)
+	orders, err := s.orderRepo.ClaimOrders(ctx, domain.OrderRefundUserOrder, domain.OrderRefundUserOrderInProgress, s.claimBatchSize, nil)
 	if err != nil {
 		return err
 	}
-	ids := make([]uint, len(orders))
-	for i, o := range orders {
-		s.logger.Infof("Order %d is pending", o.ID)
-		ids[i] = o.ID
+	s.forEachOrder(orders, func(order domain.Order) {
+		s.logger.Infof("Order %d is pending", order.ID)
+		// the user is refunded the source token on the chain it was debited on
+		client, err := s.chains.ClientFor(order.FromNetwork)
+		if err != nil {
+			s.logger.Errorf("ClientFor order=%d err: %v", order.ID, err)
+			s.retryOrDeadLetter(ctx, order, domain.OrderRefundUserOrder, err)
+			return
+		}
+		receipt, err := client.WithdrawTreasury(ctx, ethereum.WithdrawTreasuryParams{
+			RecipientAddress: order.UserAddress,
+			Amount:           order.Volume,
+			TokenSymbol:      order.SourceTokenSymbol,
+			OnSent:           s.saveTxHash(ctx, order.ID, false),
+		})
+		if errors.Is(err, ethereum.ErrTxPending) {
+			// the refund may have landed, retrying it could pay the user twice
+			s.logger.Errorf("WithdrawTreasury err: %v", err)
+			s.awaitReconciliation(ctx, order, err)
+			return
+		}
+		if err != nil {
+			s.logger.Errorf("WithdrawTreasury err: %v", err)
+			s.retryOrDeadLetter(ctx, order, domain.OrderRefundUserOrder, err)
+			return
+		}
+		if receipt != nil && receipt.Status != 1 {
+			// left in progress, the order would stall here
+			s.retryOrDeadLetter(ctx, order, domain.OrderRefundUserOrder, errRefundReverted)
+			return
+		}
+
+		//TODO:  market user order
+		if receipt != nil {
+			err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderRefundUserOrderSuccess) // canceled completly
+		}
+		if err != nil {
+			s.logger.Errorf("ChangeStatusByIds err: %v", err)
+		}
+	})
+
+	return nil
+}
+
+// deductFee computes the fee breakdown of the order, stores our fee on it and returns
+// the net amount left for the user.
+func (s *Service) deductFee(ctx context.Context, order *domain.Order) (decimal.Decimal, error) {
+	breakdown, err := s.feeBreakdownFor(ctx, order)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if err := s.orderRepo.SetCollectedFee(ctx, order.ID, breakdown.PlatformFee); err != nil {
+		return decimal.Zero, err
 	}
-	err = s.orderRepo.ChangeStatusByIds(ctx, ids, domain.OrderRefundUserOrderInProgress)
+	order.CollectedFee = breakdown.PlatformFee
+	return breakdown.Net, nil
+}
+
+// feeBreakdownFor loads the order's markets and itemizes its fees
+func (s *Service) feeBreakdownFor(ctx context.Context, order *domain.Order) (*domain.FeeBreakdown, error) {
+	market, err := s.marketAdapter.GetMarketByID(ctx, order.MarketID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if market == nil {
+		return nil, fmt.Errorf("market %d not found", order.MarketID)
+	}
+	megaMarket, err := s.marketAdapter.GetMegaMarketByID(ctx, order.MegaMarketID)
+	if err != nil {
+		return nil, err
+	}
+	if megaMarket == nil {
+		return nil, fmt.Errorf("mega market %d not found", order.MegaMarketID)
+	}
+	return feeBreakdown(order.Price, order.DestinationTokenSymbol,
+		market.ExchangeMarketFeePercentage, megaMarket.FeePercentage), nil
+}
+
+// feeBreakdown splits gross into the exchange fee, our fee and the net payout.
+// Gas is paid by the treasury and not passed on yet, so it is itemized as zero.
+func feeBreakdown(gross decimal.Decimal, currency string, exchangeFeePct, platformFeePct decimal.Decimal) *domain.FeeBreakdown {
+	b := &domain.FeeBreakdown{
+		Currency:     currency,
+		Gross:        gross,
+		ExchangeFee:  gross.Mul(exchangeFeePct),
+		PlatformFee:  gross.Mul(platformFeePct),
+		EstimatedGas: decimal.Zero,
+	}
+	b.Net = gross.Sub(b.ExchangeFee).Sub(b.PlatformFee).Sub(b.EstimatedGas)
+	return b
+}
+
+// payoutAmount rounds amount down to the token's precision, so the treasury never pays
+// more than owed. The truncated remainder is tracked as dust.
+func (s *Service) payoutAmount(ctx context.Context, network, tokenSymbol string, amount decimal.Decimal) (decimal.Decimal, error) {
+	client, err := s.chains.ClientFor(network)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	decimals, err := client.TokenDecimals(ctx, tokenSymbol)
+	if err != nil {
+		return decimal.Zero, err
 	}
+	payout := amount.RoundFloor(int32(decimals))
+	if dust := amount.Sub(payout); dust.IsPositive() {
+		metrics.AddPayoutDust(tokenSymbol, dust.InexactFloat64())
+	}
+	return payout, nil
+}
+
+// forEachOrder runs fn for every order on a pool bounded by maxConcurrency and waits for all
+// of them. fn handles its own errors, so one failing order never cancels its siblings, and a
+// panic is logged with the order id rather than crashing the process.
+func (s *Service) forEachOrder(orders []domain.Order, fn func(order domain.Order)) {
+	var g errgroup.Group
+	g.SetLimit(s.maxConcurrency)
 	for _, o := range orders {
 		order := o
-		go func(order domain.Order) {
-			s.logger.Infof("Order %d is pending", order.ID)
-			receipt, err := s.ethereumClient.WithdrawTreasury(ctx, ethereum.WithdrawTreasuryParams{
-				RecipientAddress: order.UserAddress,
-				Amount:           order.Volume.String(),
-				TokenSymbol:      order.SourceTokenSymbol,
-			})
+		g.Go(func() error {
+			s.activeWorkers.Add(1)
+			defer s.activeWorkers.Add(-1)
+			defer func() {
+				if r := recover(); r != nil {
+					s.logger.Errorf("order %d panicked: %v\n%s", order.ID, r, debug.Stack())
+				}
+			}()
+			fn(order)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
 
-			if err != nil {
-				s.logger.Errorf("GetBestExchangePriceByVolume err: %v", err)
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderRefundUserOrder) // try again
-			}
+// ActiveWorkers returns how many orders are being processed right now. Workers only run
+// inside cron jobs, which wait for them, so waiting for the cron to stop drains them.
+func (s *Service) ActiveWorkers() int64 {
+	return s.activeWorkers.Load()
+}
 
-			//TODO:  market user order
-			if receipt.Status == 1 {
-				err = s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderRefundUserOrderSuccess) // canceled completly
-			}
-			if err != nil {
-				s.logger.Errorf("ChangeStatusByIds err: %v", err)
-			}
-		}(order)
+// GetOrderById returns userID's order with its fee breakdown, or nil when there is no such
+// order; userID is empty only when authentication is off
+func (s *Service) GetOrderById(ctx context.Context, id uint, userID string) (*domain.Order, error) {
+	order, err := s.orderRepo.GetOrderByID(ctx, id)
+	if err != nil || order == nil {
+		return order, err
 	}
-
-	return nil
+	if userID != "" && order.UserId != userID {
+		// someone else's order reads the same as a missing one
+		return nil, nil
+	}
+	breakdown, err := s.feeBreakdownFor(ctx, order)
+	if err != nil {
+		// the order itself is still worth returning
+		s.logger.Errorf("feeBreakdownFor order=%d err: %v", order.ID, err)
+		return order, nil
+	}
+	order.FeeBreakdown = breakdown
+	return order, nil
 }
-func (s *Service) GetOrderById(ctx context.Context, id uint) (*domain.Order, error) {
-	return s.orderRepo.GetOrderByID(ctx, id)
+func (s *Service) ListOrders(ctx context.Context, filter domain.OrderFilter) ([]domain.Order, *domain.Pagination, error) {
+	return s.orderRepo.ListOrders(ctx, filter)
+}
+func (s *Service) GetOrderHistory(ctx context.Context, id uint) ([]domain.OrderStatusHistory, error) {
+	return s.orderRepo.GetOrderHistory(ctx, id)
+}
+
+func (s *Service) GetStepLatencies(ctx context.Context, since time.Time) ([]domain.StepLatency, error) {
+	return s.orderRepo.GetStepLatencies(ctx, since)
 }
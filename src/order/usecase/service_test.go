@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/config"
+	"github.com/MMN3003/mega/src/logger"
+)
+
+func TestNewService(t *testing.T) {
+	valid := func() *config.Config {
+		cfg := &config.Config{}
+		cfg.OMP.BaseURL = "https://api.ompfinex.com"
+		cfg.Wallex.BaseURL = "https://api.wallex.ir"
+		cfg.Nobitex.BaseURL = "https://api.nobitex.ir"
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		mutate  func(cfg *config.Config)
+		wantErr bool
+	}{
+		{name: "every exchange configured", mutate: func(cfg *config.Config) {}},
+		{name: "no ompfinex url", mutate: func(cfg *config.Config) { cfg.OMP.BaseURL = "" }, wantErr: true},
+		{name: "no wallex url", mutate: func(cfg *config.Config) { cfg.Wallex.BaseURL = "" }, wantErr: true},
+		{name: "no nobitex url", mutate: func(cfg *config.Config) { cfg.Nobitex.BaseURL = "" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.mutate(cfg)
+
+			svc, err := NewService(newFakeOrderRepo(), logger.New("prod"), cfg, nil)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (svc.ompfinexClient == nil || svc.wallexClient == nil || svc.nobitexClient == nil) {
+				t.Error("exchange client left nil")
+			}
+		})
+	}
+}
+
+// each exchange gets a client of its own, tuned from its own settings
+func TestNewServiceHTTPClients(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OMP.BaseURL, cfg.OMP.HTTP.Timeout = "https://api.ompfinex.com", 3*time.Second
+	cfg.Wallex.BaseURL, cfg.Wallex.HTTP.Timeout = "https://api.wallex.ir", 5*time.Second
+	cfg.Nobitex.BaseURL, cfg.Nobitex.HTTP.Timeout = "https://api.nobitex.ir", 7*time.Second
+
+	svc, err := NewService(newFakeOrderRepo(), logger.New("prod"), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		exchange    string
+		client      *http.Client
+		wantTimeout time.Duration
+	}{
+		{exchange: "ompfinex", client: svc.ompfinexClient.HTTP, wantTimeout: 3 * time.Second},
+		{exchange: "wallex", client: svc.wallexClient.HTTP, wantTimeout: 5 * time.Second},
+		{exchange: "nobitex", client: svc.nobitexClient.HTTP, wantTimeout: 7 * time.Second},
+	}
+	transports := map[http.RoundTripper]string{}
+	for _, tt := range tests {
+		t.Run(tt.exchange, func(t *testing.T) {
+			if tt.client.Timeout != tt.wantTimeout {
+				t.Errorf("timeout = %s, want %s", tt.client.Timeout, tt.wantTimeout)
+			}
+			if other, ok := transports[tt.client.Transport]; ok {
+				t.Errorf("shares its transport with %s", other)
+			}
+			transports[tt.client.Transport] = tt.exchange
+		})
+	}
+}
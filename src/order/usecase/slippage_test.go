@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestVenueSlippage(t *testing.T) {
+	tests := []struct {
+		name       string
+		exchange   string
+		overrides  map[string]decimal.Decimal
+		wantStatus domain.OrderStatus
+	}{
+		// 102 is within the MegaMarket's 5% of 100
+		{name: "default allows", exchange: "wallex", wantStatus: domain.OrderUserDebitSuccess},
+		{name: "zero override keeps default", exchange: "wallex",
+			overrides: map[string]decimal.Decimal{"wallex": decimal.Zero}, wantStatus: domain.OrderUserDebitSuccess},
+		{name: "other venue override ignored", exchange: "wallex",
+			overrides: map[string]decimal.Decimal{"nobitex": decimal.NewFromFloat(0.01)}, wantStatus: domain.OrderUserDebitSuccess},
+		{name: "stricter venue refunds", exchange: "wallex",
+			overrides: map[string]decimal.Decimal{"wallex": decimal.NewFromFloat(0.01)}, wantStatus: domain.OrderRefundUserOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: domain.OrderMarketUserOrderFailed, MegaMarketID: 1,
+				Volume: decimal.NewFromInt(1), Price: decimal.NewFromInt(100), SlipagePercentage: decimal.NewFromFloat(0.05)})
+			svc := newTestService(repo)
+			svc.venueSlippage = tt.overrides
+			svc.marketAdapter = &fakeMarketAdapter{
+				markets: map[uint]*market_domain.Market{2: {ID: 2, MegaMarketID: 1, ExchangeName: tt.exchange}},
+				price:   decimal.NewFromInt(102),
+			}
+
+			if err := svc.FetchFailedMarketUserOrderOrders(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := repo.order(t, 1)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == domain.OrderRefundUserOrder && got.RefundReason != domain.RefundReasonSlippageExceeded {
+				t.Errorf("refund reason = %s, want %s", got.RefundReason, domain.RefundReasonSlippageExceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MMN3003/mega/src/order/domain"
+)
+
+func TestRefundStaleOrders(t *testing.T) {
+	placed := "wallex-1"
+	tests := []struct {
+		name            string
+		status          domain.OrderStatus
+		age             time.Duration
+		exchangeOrderID *string
+		wantStatus      domain.OrderStatus
+	}{
+		{name: "aged debited order refunded", status: domain.OrderUserDebitSuccess, age: 25 * time.Hour, wantStatus: domain.OrderRefundUserOrder},
+		{name: "aged failed market order refunded", status: domain.OrderMarketUserOrderFailed, age: 25 * time.Hour, wantStatus: domain.OrderRefundUserOrder},
+		{name: "young order left alone", status: domain.OrderUserDebitSuccess, age: time.Hour, wantStatus: domain.OrderUserDebitSuccess},
+		{name: "order being placed left alone", status: domain.OrderMarketUserOrderInProgress, age: 25 * time.Hour, wantStatus: domain.OrderMarketUserOrderInProgress},
+		{name: "exchange order placed left alone", status: domain.OrderMarketUserOrderFailed, age: 25 * time.Hour,
+			exchangeOrderID: &placed, wantStatus: domain.OrderMarketUserOrderFailed},
+		// past the point of no return, the treasury credit may already be on its way
+		{name: "treasury credit left alone", status: domain.OrderTreasuryCreditInProgress, age: 25 * time.Hour, wantStatus: domain.OrderTreasuryCreditInProgress},
+		{name: "completed left alone", status: domain.OrderCompleted, age: 25 * time.Hour, wantStatus: domain.OrderCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo(domain.Order{ID: 1, Status: tt.status, CreatedAt: time.Now().Add(-tt.age),
+				ExchangeOrderID: tt.exchangeOrderID})
+			svc := newTestService(repo)
+			svc.orderMaxLifetime = 24 * time.Hour
+
+			if err := svc.RefundStaleOrders(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := repo.order(t, 1)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == domain.OrderRefundUserOrder && got.RefundReason != domain.RefundReasonMaxLifetimeExceeded {
+				t.Errorf("refund reason = %s, want %s", got.RefundReason, domain.RefundReasonMaxLifetimeExceeded)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"github.com/MMN3003/mega/src/metrics"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+// pauseToken stops claiming and accepting orders that pay out token until the treasury
+// on network holds at least needed of it again. The pause is stored, so it holds for
+// every instance and across restarts.
+func (s *Service) pauseToken(ctx context.Context, network, token string, needed decimal.Decimal) {
+	token = strings.ToUpper(token)
+	changed, err := s.orderRepo.PauseToken(ctx, domain.TreasuryPause{Token: token, Network: network, Needed: needed})
+	if err != nil {
+		s.logger.Errorf("PauseToken %s err: %v", token, err)
+		return
+	}
+	if !changed {
+		return
+	}
+	metrics.SetTreasuryPaused(token, true)
+	s.logger.Errorf("ALERT treasury depleted: pausing %s payouts until it holds %s on %s", token, needed, network)
+}
+
+// treasuryPauses returns the stored pauses. When they can't be read nothing counts as
+// paused: the payout still checks the treasury balance before it is sent.
+func (s *Service) treasuryPauses(ctx context.Context) []domain.TreasuryPause {
+	pauses, err := s.orderRepo.ListTreasuryPauses(ctx)
+	if err != nil {
+		s.logger.Errorf("ListTreasuryPauses err: %v", err)
+		return nil
+	}
+	return pauses
+}
+
+func (s *Service) isTokenPaused(ctx context.Context, token string) bool {
+	for _, p := range s.treasuryPauses(ctx) {
+		if p.Token == strings.ToUpper(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// pausedTokens lists the paused payout tokens, for excluding their orders from claims
+func (s *Service) pausedTokens(ctx context.Context) []string {
+	pauses := s.treasuryPauses(ctx)
+	tokens := make([]string, len(pauses))
+	for i, p := range pauses {
+		tokens[i] = p.Token
+	}
+	return tokens
+}
+
+// resumeToppedUpTokens lifts the pause of every token whose treasury balance covers the
+// amount it was paused on again.
+func (s *Service) resumeToppedUpTokens(ctx context.Context) {
+	for _, p := range s.treasuryPauses(ctx) {
+		enough, err := s.hasTreasuryLiquidity(ctx, p.Network, p.Token, p.Needed)
+		if err != nil {
+			s.logger.Errorf("treasury balance %s err: %v", p.Token, err)
+			continue
+		}
+		if !enough {
+			// set on every tick, a pause stored before a restart shows up too
+			metrics.SetTreasuryPaused(p.Token, true)
+			continue
+		}
+		if err := s.orderRepo.ResumeToken(ctx, p.Token); err != nil {
+			s.logger.Errorf("ResumeToken %s err: %v", p.Token, err)
+			continue
+		}
+		metrics.SetTreasuryPaused(p.Token, false)
+		s.logger.Infof("treasury topped up: resuming %s payouts", p.Token)
+	}
+}
+
+// parkIfTreasuryShort checks, before the user of a claimed PENDING order is debited, that the
+// treasury still covers the order's payout; it may have been drained since the order passed
+// its preflight. When it doesn't, or the balance can't be read, the order goes back to
+// PENDING undebited, and a shortfall also pauses the payout token so its orders aren't
+// claimed until a top-up. It reports whether it parked the order.
+func (s *Service) parkIfTreasuryShort(ctx context.Context, order *domain.Order) bool {
+	park := func() bool {
+		if err := s.orderRepo.ChangeStatusByIds(ctx, []uint{order.ID}, domain.OrderPending); err != nil {
+			s.logger.Errorf("ChangeStatusByIds err: %v", err)
+		}
+		return true
+	}
+	breakdown, err := s.feeBreakdownFor(ctx, order)
+	if err != nil {
+		s.logger.Errorf("feeBreakdownFor order=%d err: %v", order.ID, err)
+		return park()
+	}
+	enough, err := s.hasTreasuryLiquidity(ctx, order.ToNetwork, order.DestinationTokenSymbol, breakdown.Net)
+	if err != nil {
+		s.logger.Errorf("treasury balance order=%d err: %v", order.ID, err)
+		return park()
+	}
+	if enough {
+		return false
+	}
+	s.logger.Infof("Order %d parked before debit: treasury lacks %s %s on %s",
+		order.ID, breakdown.Net, order.DestinationTokenSymbol, order.ToNetwork)
+	s.pauseToken(ctx, order.ToNetwork, order.DestinationTokenSymbol, breakdown.Net)
+	return park()
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestPauseToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		pause      []string // payout tokens depleted, in order
+		wantPaused []string
+	}{
+		{name: "nothing depleted", wantPaused: []string{}},
+		{name: "one token", pause: []string{"irt"}, wantPaused: []string{"IRT"}},
+		{name: "paused twice", pause: []string{"IRT", "irt"}, wantPaused: []string{"IRT"}},
+		{name: "two tokens", pause: []string{"USDT", "IRT"}, wantPaused: []string{"IRT", "USDT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeOrderRepo()
+			svc := newTestService(repo)
+			for _, token := range tt.pause {
+				svc.pauseToken(context.Background(), "sepolia", token, decimal.NewFromInt(100))
+			}
+
+			// a restarted service reads the same pauses
+			restarted := newTestService(repo)
+			got := restarted.pausedTokens(context.Background())
+			if len(got) != len(tt.wantPaused) {
+				t.Fatalf("paused %v, want %v", got, tt.wantPaused)
+			}
+			for i := range got {
+				if got[i] != tt.wantPaused[i] {
+					t.Errorf("paused %v, want %v", got, tt.wantPaused)
+				}
+			}
+			for _, token := range tt.wantPaused {
+				if !restarted.isTokenPaused(context.Background(), token) {
+					t.Errorf("%s not paused", token)
+				}
+			}
+		})
+	}
+}
+
+// a depleted payout token holds back its own orders while orders paying out in other
+// tokens keep going
+func TestPausedTokenOrdersNotClaimed(t *testing.T) {
+	repo := newFakeOrderRepo(
+		domain.Order{ID: 1, Status: domain.OrderUserDebitSuccess, DestinationTokenSymbol: "IRT"},
+		domain.Order{ID: 2, Status: domain.OrderUserDebitSuccess, DestinationTokenSymbol: "USDT"},
+	)
+	svc := newTestService(repo)
+	svc.marketAdapter = &fakeMarketAdapter{}
+	svc.pauseToken(context.Background(), "sepolia", "IRT", decimal.NewFromInt(100))
+
+	if err := svc.FetchSuccessDebitOrders(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := repo.order(t, 1).Status; got != domain.OrderUserDebitSuccess {
+		t.Errorf("paused token order moved to %s", got)
+	}
+	// no such market, but it was claimed and tried
+	if got := repo.order(t, 2).Status; got != domain.OrderMarketUserOrderFailed {
+		t.Errorf("other token order is %s, want %s", got, domain.OrderMarketUserOrderFailed)
+	}
+}
+
+func TestResumeToppedUpTokensKeepsPauseWhenBalanceUnknown(t *testing.T) {
+	repo := newFakeOrderRepo()
+	svc := newTestService(repo)
+	// the test service knows no chain, so the treasury balance can't be read
+	svc.pauseToken(context.Background(), "sepolia", "IRT", decimal.NewFromInt(100))
+
+	svc.resumeToppedUpTokens(context.Background())
+
+	if !svc.isTokenPaused(context.Background(), "IRT") {
+		t.Error("pause lifted without a confirmed top-up")
+	}
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	market_domain "github.com/MMN3003/mega/src/market/domain"
+	"github.com/MMN3003/mega/src/order/domain"
+	"github.com/shopspring/decimal"
+)
+
+func TestValidateOrder(t *testing.T) {
+	market := &market_domain.Market{ID: 2, ExchangeName: "ompfinex", MarketName: "BTC/IRT",
+		MinAmount: decimal.RequireFromString("0.001"), MaxPrice: decimal.NewFromInt(100000)}
+	tests := []struct {
+		name      string
+		market    *market_domain.Market
+		volume    string
+		price     string
+		wantErr   error
+		wantCause error
+	}{
+		{name: "valid", market: market, volume: "0.5", price: "50000"},
+		{name: "unknown market", volume: "0.5", price: "50000", wantErr: domain.ErrInvalidOrder},
+		{name: "zero volume", market: market, volume: "0", price: "50000", wantErr: domain.ErrInvalidOrder},
+		{name: "below min size", market: market, volume: "0.0001", price: "50000", wantErr: domain.ErrInvalidOrder,
+			wantCause: market_domain.ErrBelowMinimumSize},
+		{name: "above max price", market: market, volume: "0.5", price: "200000", wantErr: domain.ErrInvalidOrder,
+			wantCause: market_domain.ErrPriceOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeOrderRepo())
+			o := &domain.Order{MarketID: 2, Volume: decimal.RequireFromString(tt.volume), Price: decimal.RequireFromString(tt.price)}
+
+			err := svc.ValidateOrder(tt.market, o)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// the cause is spelled out for the client
+			if tt.wantCause != nil && !strings.Contains(err.Error(), tt.wantCause.Error()) {
+				t.Errorf("err = %v, want it to name %v", err, tt.wantCause)
+			}
+		})
+	}
+}
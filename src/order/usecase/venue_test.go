@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestAcquireVenueSlot(t *testing.T) {
+	tests := []struct {
+		name    string
+		venue   string
+		held    int // slots taken before the call
+		wantErr error
+	}{
+		{name: "free slot", venue: "wallex"},
+		{name: "last free slot", venue: "wallex", held: 1},
+		{name: "venue full", venue: "wallex", held: 2, wantErr: context.DeadlineExceeded},
+		{name: "venue without limit", venue: "binance", held: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(newFakeOrderRepo())
+			slots := semaphore.NewWeighted(2)
+			svc.venueSlots = map[string]*semaphore.Weighted{"wallex": slots}
+			for i := 0; i < tt.held; i++ {
+				if _, err := svc.acquireVenueSlot(context.Background(), tt.venue); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			release, err := svc.acquireVenueSlot(ctx, tt.venue)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			release()
+			// the slot is back once released
+			if tt.venue == "wallex" && !slots.TryAcquire(1) {
+				t.Error("slot not released")
+			}
+		})
+	}
+}
+
+// an order waiting for a full venue goes through as soon as one in flight finishes
+func TestAcquireVenueSlotWaitsForRelease(t *testing.T) {
+	svc := newTestService(newFakeOrderRepo())
+	svc.venueSlots = map[string]*semaphore.Weighted{"nobitex": semaphore.NewWeighted(1)}
+	release, err := svc.acquireVenueSlot(context.Background(), "nobitex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		_, err := svc.acquireVenueSlot(context.Background(), "nobitex")
+		acquired <- err
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired a slot of a full venue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("still waiting after the slot was released")
+	}
+}